@@ -2,6 +2,9 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -16,6 +19,8 @@ type Backend interface {
 	// Get retrieves a value from the cache by key.
 	// Returns ErrNotFound if the key doesn't exist or has expired.
 	// Returns ErrExpired if the key exists but TTL has elapsed.
+	// Get counts as a hit or miss and may update the entry (e.g. its hit
+	// count or a sliding TTL); use Peek to read without side effects.
 	Get(ctx context.Context, key string) (*Entry, error)
 
 	// Set stores a value in the cache with the specified TTL.
@@ -30,15 +35,18 @@ type Backend interface {
 	// Clear removes all entries from the cache.
 	Clear(ctx context.Context) error
 
-	// Keys returns all keys matching the given pattern.
-	// Pattern syntax:
-	//   - "*" matches all keys
-	//   - "prefix:*" matches all keys with prefix
-	//   - "*:suffix" matches all keys with suffix
-	//   - "exact" matches exact key
+	// Keys returns all keys matching the given glob pattern.
+	// Pattern syntax (see MatchPattern):
+	//   - "*" matches any sequence of characters, anywhere in the pattern
+	//   - "?" matches exactly one character
+	//   - "\" escapes the next character
+	// Examples: "*", "light:*", "*:suffix", "light:*:temp", "exact"
+	// A backend whose store can't list keys returns ErrUnsupported; the
+	// cached clients then list from the SDK instead.
 	Keys(ctx context.Context, pattern string) ([]string, error)
 
-	// Stats returns current cache statistics.
+	// Stats returns current cache statistics. A backend that can't count
+	// its entries reports Entries and Size as 0.
 	Stats(ctx context.Context) (*Stats, error)
 
 	// Close releases any resources held by the backend.
@@ -46,95 +54,493 @@ type Backend interface {
 	Close() error
 }
 
+// MetaGetter is implemented by backends that can return an entry's
+// metadata without reading its value, e.g. with a separate metadata key or
+// a head request. It makes metadata reads cheap when values are large or
+// remote.
+type MetaGetter interface {
+	// GetMeta returns metadata for the entry under key, with Cached set.
+	// Like Get, it returns ErrNotFound or ErrExpired for missing entries,
+	// but it does not count as a hit or update the entry.
+	GetMeta(ctx context.Context, key string) (*EntryMeta, error)
+}
+
+// GetMeta returns metadata for the entry under key in backend. It uses
+// the backend's MetaGetter implementation if it has one, and otherwise
+// falls back to a full Get.
+func GetMeta(ctx context.Context, backend Backend, key string) (*EntryMeta, error) {
+	if mg, ok := backend.(MetaGetter); ok {
+		return mg.GetMeta(ctx, key)
+	}
+
+	entry, err := backend.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return newEntryMeta(entry, true), nil
+}
+
+// Peeker is implemented by backends that can read an entry without
+// side effects, for inspection tools that shouldn't skew what they
+// inspect.
+type Peeker interface {
+	// Peek returns the entry under key like Get, but does not count as a
+	// hit or miss, update the entry, extend a sliding TTL, or remove an
+	// expired entry.
+	Peek(ctx context.Context, key string) (*Entry, error)
+}
+
+// Peek returns the entry under key in backend. It uses the backend's
+// Peeker implementation if it has one, and otherwise falls back to Get,
+// which may update statistics.
+func Peek(ctx context.Context, backend Backend, key string) (*Entry, error) {
+	if p, ok := backend.(Peeker); ok {
+		return p.Peek(ctx, key)
+	}
+	return backend.Get(ctx, key)
+}
+
+// Toucher is implemented by backends that can extend an entry's lifetime
+// in place, without rewriting its value.
+type Toucher interface {
+	// Touch resets the TTL of the entry under key to ttl from now. A TTL
+	// of 0 means no expiration. Returns ErrNotFound if the key doesn't
+	// exist or has expired. Touch counts as neither a hit nor a miss.
+	Touch(ctx context.Context, key string, ttl time.Duration) error
+}
+
+// Touch extends the lifetime of the entry under key in backend, resetting
+// its TTL to ttl from now. It uses the backend's Toucher implementation if
+// it has one. Otherwise it reads the entry with Peek and writes it back
+// with its tags, which isn't atomic (a write to key in between is lost)
+// and restarts the entry's Age.
+func Touch(ctx context.Context, backend Backend, key string, ttl time.Duration) error {
+	if t, ok := backend.(Toucher); ok {
+		return t.Touch(ctx, key, ttl)
+	}
+
+	entry, err := Peek(ctx, backend, key)
+	if errors.Is(err, ErrExpired) || (err == nil && entry.IsExpired()) {
+		return NewError("Touch", key, ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	return SetWithTags(ctx, backend, key, entry.Value, ttl, entry.Tags)
+}
+
+// ConditionalSetter is implemented by backends that can write an entry
+// only if the entry it replaces passes a check, as one atomic step.
+type ConditionalSetter interface {
+	// SetIf stores a value like Set, but only if the key is absent or
+	// expired, or cond approves the entry it would replace. cond and the
+	// write are atomic: no other write to key can happen in between.
+	// applied reports whether the value was stored. cond may run while
+	// the backend holds a lock, so it must be quick, must not call the
+	// backend, and must not modify or retain the entry.
+	SetIf(ctx context.Context, key string, value []byte, ttl time.Duration, cond func(existing *Entry) bool) (applied bool, err error)
+}
+
+// SetIf stores value under key in backend if the key is absent or
+// expired, or cond approves the entry it would replace, and reports
+// whether it did. It uses the backend's ConditionalSetter implementation
+// if it has one. Otherwise it checks the entry read with Peek and then
+// calls Set, which isn't atomic: concurrent writers can both succeed.
+func SetIf(ctx context.Context, backend Backend, key string, value []byte, ttl time.Duration, cond func(existing *Entry) bool) (bool, error) {
+	if cs, ok := backend.(ConditionalSetter); ok {
+		return cs.SetIf(ctx, key, value, ttl, cond)
+	}
+
+	existing, err := Peek(ctx, backend, key)
+	if err != nil && !isMiss(err) {
+		return false, err
+	}
+	if err == nil && !existing.IsExpired() && !cond(existing) {
+		return false, nil
+	}
+	if err := backend.Set(ctx, key, value, ttl); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Pinger is implemented by backends that can check their own health,
+// e.g. that a server is reachable or a directory writable.
+type Pinger interface {
+	// Ping reports whether the backend is functioning: nil if it can serve
+	// reads and writes, otherwise the reason it can't (e.g. an unreachable
+	// server or an unwritable directory). It is meant for readiness
+	// probes, so it must be cheap and must not touch entries or
+	// statistics. A closed backend returns ErrBackendClosed.
+	Ping(ctx context.Context) error
+}
+
+// Ping reports whether backend is functioning. It uses the backend's
+// Pinger implementation if it has one; backends without it are assumed
+// healthy and return nil.
+func Ping(ctx context.Context, backend Backend) error {
+	if p, ok := backend.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// Tagger is implemented by backends that can tag entries, grouping
+// resources across types (e.g. everything on one floor) so they can be
+// deleted together with DeleteTag. Tags are stored with the entry (see
+// Entry.Tags), so persistent backends keep them.
+type Tagger interface {
+	// SetWithTags stores a value like Set, tagged with tags.
+	SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error
+}
+
+// SetWithTags stores value under key in backend like Set, tagged with
+// tags. Without tags it is Set. Backends that don't implement Tagger
+// can't store tags and return ErrUnsupported.
+func SetWithTags(ctx context.Context, backend Backend, key string, value []byte, ttl time.Duration, tags []string) error {
+	if len(tags) == 0 {
+		return backend.Set(ctx, key, value, ttl)
+	}
+	if t, ok := backend.(Tagger); ok {
+		return t.SetWithTags(ctx, key, value, ttl, tags)
+	}
+	return NewError("SetWithTags", key, ErrUnsupported)
+}
+
+// TagDeleter is implemented by backends that can delete every entry with
+// a tag in one atomic operation, like PatternDeleter.
+type TagDeleter interface {
+	// DeleteTag deletes all entries tagged with tag and returns how many
+	// were deleted.
+	DeleteTag(ctx context.Context, tag string) (int, error)
+}
+
+// DeleteTag deletes all entries tagged with tag from backend and returns
+// how many were deleted. It uses the backend's TagDeleter implementation
+// if it has one. Otherwise it reads every entry with Peek and deletes the
+// tagged ones one by one, which is neither atomic nor fast.
+func DeleteTag(ctx context.Context, backend Backend, tag string) (int, error) {
+	if td, ok := backend.(TagDeleter); ok {
+		return td.DeleteTag(ctx, tag)
+	}
+
+	keys, err := backend.Keys(ctx, "*")
+	if err != nil {
+		return 0, fmt.Errorf("getting keys for tag %q: %w", tag, err)
+	}
+
+	deleted := 0
+	for _, key := range keys {
+		entry, err := Peek(ctx, backend, key)
+		if err != nil || !entry.HasTag(tag) {
+			// Deleted or expired since Keys, or untagged
+			continue
+		}
+		if err := backend.Delete(ctx, key); err != nil {
+			continue
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// EntryLister is implemented by backends that can list entry metadata
+// for a pattern in one pass, instead of a Keys call followed by a read
+// per key.
+type EntryLister interface {
+	// Entries returns the metadata of every unexpired entry whose key
+	// matches pattern (see Backend.Keys), in no particular order. Like
+	// GetMeta it doesn't count as hits or update the entries.
+	Entries(ctx context.Context, pattern string) ([]*EntryInfo, error)
+}
+
+// ListEntries returns the metadata of the entries matching pattern in
+// backend. It uses the backend's EntryLister implementation if it has
+// one, and otherwise falls back to Keys and a GetMeta per key, skipping
+// keys that expire or are deleted in between.
+func ListEntries(ctx context.Context, backend Backend, pattern string) ([]*EntryInfo, error) {
+	if lister, ok := backend.(EntryLister); ok {
+		return lister.Entries(ctx, pattern)
+	}
+
+	keys, err := backend.Keys(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*EntryInfo, 0, len(keys))
+	for _, key := range keys {
+		meta, err := GetMeta(ctx, backend, key)
+		if err != nil {
+			if isMiss(err) {
+				continue
+			}
+			return nil, err
+		}
+		infos = append(infos, &EntryInfo{Key: key, EntryMeta: *meta})
+	}
+	return infos, nil
+}
+
+// PatternDeleter is implemented by backends that can delete every key
+// matching a pattern in one atomic operation, such as a single database
+// transaction. Readers see either all the matching entries or none of
+// them, never a half-cleared set, and remote backends need one round-trip
+// instead of one per key.
+type PatternDeleter interface {
+	// DeletePattern deletes all keys matching pattern (see Backend.Keys)
+	// and returns how many were deleted.
+	DeletePattern(ctx context.Context, pattern string) (int, error)
+}
+
+// DeletePattern deletes all keys matching pattern from backend and returns
+// how many were deleted. It uses the backend's PatternDeleter
+// implementation if it has one. Otherwise it lists the keys and deletes
+// them one by one, which is not atomic; keys that fail to delete are
+// skipped so that as many as possible are removed.
+func DeletePattern(ctx context.Context, backend Backend, pattern string) (int, error) {
+	if pd, ok := backend.(PatternDeleter); ok {
+		return pd.DeletePattern(ctx, pattern)
+	}
+
+	keys, err := backend.Keys(ctx, pattern)
+	if err != nil {
+		return 0, fmt.Errorf("getting keys for pattern %q: %w", pattern, err)
+	}
+
+	deleted := 0
+	for _, key := range keys {
+		if err := backend.Delete(ctx, key); err != nil {
+			// Continue on error, try to delete as many as possible
+			continue
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// BatchDeleter is implemented by backends that can delete many keys in
+// one pass, e.g. under a single lock or in one transaction, instead of
+// one operation per key.
+type BatchDeleter interface {
+	// DeleteMany deletes keys and returns how many were deleted. Absent
+	// keys are skipped, as with Delete.
+	DeleteMany(ctx context.Context, keys []string) (int, error)
+}
+
+// DeleteMany deletes keys from backend and returns how many were deleted.
+// It uses the backend's BatchDeleter implementation if it has one.
+// Otherwise it deletes the keys one by one, counting every key Delete
+// succeeded for, since Delete doesn't report whether a key existed. Keys
+// that fail to delete are skipped, and their errors are joined.
+func DeleteMany(ctx context.Context, backend Backend, keys []string) (int, error) {
+	if bd, ok := backend.(BatchDeleter); ok {
+		return bd.DeleteMany(ctx, keys)
+	}
+
+	deleted := 0
+	var errs []error
+	for _, key := range keys {
+		if err := backend.Delete(ctx, key); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted, errors.Join(errs...)
+}
+
 // KeyBuilder provides helper methods for constructing cache keys.
-type KeyBuilder struct{}
+// A KeyBuilder with a prefix namespaces every key and pattern it builds,
+// so several bridges or environments can share one backend without their
+// keys colliding.
+type KeyBuilder struct {
+	// prefix is "" or the namespace followed by a colon
+	prefix string
+}
 
 // NewKeyBuilder creates a new KeyBuilder.
 func NewKeyBuilder() *KeyBuilder {
 	return &KeyBuilder{}
 }
 
+// NewKeyBuilderWithPrefix creates a KeyBuilder whose keys are namespaced
+// by prefix: Light("123") is "prefix:light:123" and AllLights() is
+// "prefix:light:*". An empty prefix is the same as NewKeyBuilder.
+func NewKeyBuilderWithPrefix(prefix string) *KeyBuilder {
+	if prefix == "" {
+		return NewKeyBuilder()
+	}
+	return &KeyBuilder{prefix: prefix + ":"}
+}
+
+// Prefix returns the namespace keys are prefixed with, or "" if none.
+func (kb *KeyBuilder) Prefix() string {
+	return strings.TrimSuffix(kb.prefix, ":")
+}
+
 // Light creates a cache key for a light resource.
 func (kb *KeyBuilder) Light(id string) string {
-	return "light:" + id
+	return kb.prefix + "light:" + id
 }
 
 // Room creates a cache key for a room resource.
 func (kb *KeyBuilder) Room(id string) string {
-	return "room:" + id
+	return kb.prefix + "room:" + id
 }
 
 // Zone creates a cache key for a zone resource.
 func (kb *KeyBuilder) Zone(id string) string {
-	return "zone:" + id
+	return kb.prefix + "zone:" + id
 }
 
 // Scene creates a cache key for a scene resource.
 func (kb *KeyBuilder) Scene(id string) string {
-	return "scene:" + id
+	return kb.prefix + "scene:" + id
 }
 
 // SmartScene creates a cache key for a smart scene resource.
 func (kb *KeyBuilder) SmartScene(id string) string {
-	return "smart_scene:" + id
+	return kb.prefix + "smart_scene:" + id
 }
 
 // GroupedLight creates a cache key for a grouped light resource.
 func (kb *KeyBuilder) GroupedLight(id string) string {
-	return "grouped_light:" + id
+	return kb.prefix + "grouped_light:" + id
 }
 
 // Device creates a cache key for a device resource.
 func (kb *KeyBuilder) Device(id string) string {
-	return "device:" + id
+	return kb.prefix + "device:" + id
 }
 
 // Bridge creates a cache key for a bridge resource.
 func (kb *KeyBuilder) Bridge(id string) string {
-	return "bridge:" + id
+	return kb.prefix + "bridge:" + id
 }
 
 // BridgeHome creates a cache key for a bridge home resource.
 func (kb *KeyBuilder) BridgeHome(id string) string {
-	return "bridge_home:" + id
+	return kb.prefix + "bridge_home:" + id
 }
 
 // Resource creates a cache key for any resource type.
 func (kb *KeyBuilder) Resource(resourceType, id string) string {
-	return resourceType + ":" + id
+	return kb.prefix + resourceType + ":" + id
+}
+
+// Raw creates the key holding the raw bridge JSON for the resource under
+// key (e.g. "raw:light:abc-123"). The "raw:" prefix keeps raw entries out
+// of resource patterns like "light:*". With a namespace, it goes after
+// the namespace ("ns:raw:light:abc-123").
+func (kb *KeyBuilder) Raw(key string) string {
+	return kb.prefix + "raw:" + kb.local(key)
+}
+
+// Version creates the key holding the LastWriteWins version of the entry
+// under key (e.g. "version:light:abc-123"). Like "raw:", the "version:"
+// prefix keeps version entries out of resource patterns.
+func (kb *KeyBuilder) Version(key string) string {
+	return kb.prefix + "version:" + kb.local(key)
+}
+
+// local strips the namespace from key, if it has it.
+func (kb *KeyBuilder) local(key string) string {
+	return strings.TrimPrefix(key, kb.prefix)
+}
+
+// ParseKey splits a resource key into its resource type and ID, reversing
+// Resource. It splits on the first colon after the namespace, so IDs
+// containing colons, which the cached clients reject, still parse. ok is
+// false if the key has no colon, either part is empty, or the key is
+// outside the KeyBuilder's namespace.
+func (kb *KeyBuilder) ParseKey(key string) (resourceType, id string, ok bool) {
+	key, inNamespace := strings.CutPrefix(key, kb.prefix)
+	if !inNamespace {
+		return "", "", false
+	}
+	resourceType, id, found := strings.Cut(key, ":")
+	if !found || resourceType == "" || id == "" {
+		return "", "", false
+	}
+	return resourceType, id, true
+}
+
+// reservedIDChars are the characters a resource ID may not contain: the
+// key separator, and the MatchPattern wildcards and escape.
+const reservedIDChars = `:*?\`
+
+// checkID reports whether id can be embedded in a cache key. It returns an
+// error wrapping ErrInvalidKey if id is empty or contains a reserved
+// character, which would make its key collide with, or match as a pattern
+// against, other resources' keys.
+func checkID(resourceType, id string) error {
+	if id == "" {
+		return fmt.Errorf("%w: empty %s ID", ErrInvalidKey, resourceType)
+	}
+	if strings.ContainsAny(id, reservedIDChars) {
+		return fmt.Errorf("%w: %s ID %q contains a reserved character", ErrInvalidKey, resourceType, id)
+	}
+	return nil
 }
 
 // AllLights returns the pattern for all light keys.
 func (kb *KeyBuilder) AllLights() string {
-	return "light:*"
+	return kb.pattern("light:*")
 }
 
 // AllRooms returns the pattern for all room keys.
 func (kb *KeyBuilder) AllRooms() string {
-	return "room:*"
+	return kb.pattern("room:*")
 }
 
 // AllZones returns the pattern for all zone keys.
 func (kb *KeyBuilder) AllZones() string {
-	return "zone:*"
+	return kb.pattern("zone:*")
 }
 
 // AllScenes returns the pattern for all scene keys.
 func (kb *KeyBuilder) AllScenes() string {
-	return "scene:*"
+	return kb.pattern("scene:*")
 }
 
 // AllGroupedLights returns the pattern for all grouped light keys.
 func (kb *KeyBuilder) AllGroupedLights() string {
-	return "grouped_light:*"
+	return kb.pattern("grouped_light:*")
+}
+
+// AllBridges returns the pattern for all bridge keys.
+func (kb *KeyBuilder) AllBridges() string {
+	return kb.pattern("bridge:*")
+}
+
+// AllBridgeHomes returns the pattern for all bridge home keys.
+func (kb *KeyBuilder) AllBridgeHomes() string {
+	return kb.pattern("bridge_home:*")
 }
 
 // AllResources returns the pattern for all resource types.
 func (kb *KeyBuilder) AllResources(resourceType string) string {
-	return resourceType + ":*"
+	return kb.pattern(resourceType + ":*")
 }
 
-// All returns the pattern for all keys.
+// All returns the pattern for all keys, or all keys in the namespace.
 func (kb *KeyBuilder) All() string {
-	return "*"
+	return kb.pattern("*")
+}
+
+// pattern prefixes pattern with the namespace, escaping any glob
+// characters in it.
+func (kb *KeyBuilder) pattern(pattern string) string {
+	if kb.prefix == "" {
+		return pattern
+	}
+	var escaped strings.Builder
+	for _, r := range kb.prefix {
+		if r == '*' || r == '?' || r == '\\' {
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String() + pattern
 }
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// metaOnlyBackend implements MetaGetter and fails every value read, like a
+// remote backend where fetching the value blob is expensive.
+type metaOnlyBackend struct {
+	*mockBackend
+	gets int
+}
+
+func (b *metaOnlyBackend) Get(ctx context.Context, key string) (*Entry, error) {
+	b.gets++
+	return nil, errors.New("value read not allowed")
+}
+
+func (b *metaOnlyBackend) GetMeta(ctx context.Context, key string) (*EntryMeta, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	entry, ok := b.data[key]
+	if !ok {
+		return nil, NewError("GetMeta", key, ErrNotFound)
+	}
+	return entry.Meta(), nil
+}
+
+// basicBackend has only the required Backend methods of the backend it
+// embeds, hiding its optional interfaces.
+type basicBackend struct {
+	Backend
+}
+
+func TestGetMeta_UsesMetaGetter(t *testing.T) {
+	backend := &metaOnlyBackend{mockBackend: newMockBackend()}
+	ctx := context.Background()
+	backend.Set(ctx, "scene:1", make([]byte, 4096), time.Minute)
+
+	// Also through the timeout decorator cached clients wrap backends in
+	for _, b := range []Backend{backend, withBackendTimeout(backend, time.Second)} {
+		meta, err := GetMeta(ctx, b, "scene:1")
+		if err != nil {
+			t.Fatalf("GetMeta() failed: %v", err)
+		}
+		if meta.Size != 4096 || meta.TTL != time.Minute || !meta.Cached {
+			t.Errorf("GetMeta() = size %d, ttl %v, cached %v; want 4096, 1m, true", meta.Size, meta.TTL, meta.Cached)
+		}
+	}
+
+	if backend.gets != 0 {
+		t.Errorf("value was read %d times, want 0", backend.gets)
+	}
+
+	if _, err := GetMeta(ctx, backend, "scene:missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetMeta() of missing key error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestGetMeta_FallsBackToGet(t *testing.T) {
+	backend := newMockBackend()
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("value"), 0)
+
+	meta, err := GetMeta(ctx, backend, "light:1")
+	if err != nil {
+		t.Fatalf("GetMeta() failed: %v", err)
+	}
+	if meta.Size != 5 || meta.TTL != 0 || !meta.Cached {
+		t.Errorf("GetMeta() = size %d, ttl %v, cached %v; want 5, 0, true", meta.Size, meta.TTL, meta.Cached)
+	}
+
+	if _, err := GetMeta(ctx, backend, "light:missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetMeta() of missing key error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestTouch_FallsBackToSet(t *testing.T) {
+	mock := newMockBackend()
+	backend := basicBackend{mock}
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("value"), time.Minute)
+
+	if err := Touch(ctx, backend, "light:1", time.Hour); err != nil {
+		t.Fatalf("Touch() failed: %v", err)
+	}
+	entry := mock.data["light:1"]
+	if string(entry.Value) != "value" || entry.TTL != time.Hour {
+		t.Errorf("After Touch(), entry = %q with TTL %v, want value with TTL 1h", entry.Value, entry.TTL)
+	}
+
+	if err := Touch(ctx, backend, "light:missing", time.Hour); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Touch() of missing key error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSetIf_FallsBackToSet(t *testing.T) {
+	backend := basicBackend{newMockBackend()}
+	ctx := context.Background()
+	isV1 := func(existing *Entry) bool { return string(existing.Value) == "v1" }
+
+	applied, err := SetIf(ctx, backend, "light:1", []byte("v1"), 0, isV1)
+	if err != nil || !applied {
+		t.Fatalf("SetIf() of absent key = %v, %v; want true, nil", applied, err)
+	}
+	applied, err = SetIf(ctx, backend, "light:1", []byte("v2"), 0, isV1)
+	if err != nil || !applied {
+		t.Errorf("SetIf() with true cond = %v, %v; want true, nil", applied, err)
+	}
+	applied, err = SetIf(ctx, backend, "light:1", []byte("v3"), 0, isV1)
+	if err != nil || applied {
+		t.Errorf("SetIf() with false cond = %v, %v; want false, nil", applied, err)
+	}
+
+	entry, _ := backend.Get(ctx, "light:1")
+	if string(entry.Value) != "v2" {
+		t.Errorf("After SetIf(), value = %q, want v2", entry.Value)
+	}
+}
@@ -0,0 +1,638 @@
+package backends
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	cache "github.com/rmrfslashbin/hue-cache"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bolt implements a durable cache backend on an embedded bbolt database.
+// Every write is committed to disk in its own transaction, so the cache
+// survives crashes without an external server and without rewriting the
+// whole dataset the way File.Save does.
+//
+// Entries are stored gob-encoded in a single bucket. Expired entries are
+// purged lazily when read. Reads don't write, so Entry.Hits and
+// Entry.UpdatedAt are not persisted and sliding TTLs are not supported.
+type Bolt struct {
+	db     *bolt.DB
+	bucket []byte
+
+	// stats tracks hits, misses, evictions, and errors; entry counts and
+	// sizes are read from the bucket
+	stats *cache.StatsCollector
+
+	logger cache.Logger
+
+	// grace is how long past ExpiresAt entries are still served
+	grace time.Duration
+
+	// closed tracks if backend is closed
+	closed atomic.Bool
+}
+
+// BoltConfig contains configuration options for the bbolt backend.
+type BoltConfig struct {
+	// Path is the path to the database file.
+	// Default: "./hue-cache.db"
+	Path string
+
+	// Bucket is the name of the bucket holding cache entries.
+	// Default: "entries"
+	Bucket string
+
+	// Timeout is how long to wait for the database file lock, which is
+	// held by another process that has the database open.
+	// Default: 1 second
+	Timeout time.Duration
+
+	// Logger receives diagnostics for lazy purges that fail.
+	// Default: no-op logger
+	Logger cache.Logger
+
+	// ClockSkewGrace keeps serving entries for this long after they
+	// expire. Expiry times are written with the writing host's clock, so
+	// when the database moves between hosts (or is on shared storage),
+	// skew makes entries expire early or late by as much as the clocks
+	// differ. A grace of about the expected skew stops early expiry, at
+	// the cost of serving every entry up to that much longer than its TTL.
+	// Default: 0
+	ClockSkewGrace time.Duration
+}
+
+// DefaultBoltConfig returns default configuration for the bbolt backend.
+func DefaultBoltConfig() *BoltConfig {
+	return &BoltConfig{
+		Path:    "./hue-cache.db",
+		Bucket:  "entries",
+		Timeout: time.Second,
+	}
+}
+
+// NewBolt opens (or creates) a bbolt-backed cache.
+// If config is nil, defaults are used.
+//
+// Example:
+//
+//	config := backends.DefaultBoltConfig()
+//	config.Path = "/var/cache/hue/cache.db"
+//	backend, err := backends.NewBolt(config)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer backend.Close()
+func NewBolt(config *BoltConfig) (*Bolt, error) {
+	defaults := DefaultBoltConfig()
+	if config == nil {
+		config = defaults
+	}
+
+	path := config.Path
+	if path == "" {
+		path = defaults.Path
+	}
+	bucket := config.Bucket
+	if bucket == "" {
+		bucket = defaults.Bucket
+	}
+
+	// Create directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: config.Timeout})
+	if err != nil {
+		return nil, fmt.Errorf("opening bolt database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating bucket %q: %w", bucket, err)
+	}
+
+	logger := config.Logger
+	if logger == nil {
+		logger = cache.NopLogger()
+	}
+
+	return &Bolt{
+		db:     db,
+		bucket: []byte(bucket),
+		stats:  cache.NewStatsCollector(),
+		logger: logger,
+		grace:  config.ClockSkewGrace,
+	}, nil
+}
+
+// Get retrieves a value from the cache. An expired entry is deleted and
+// reported as ErrExpired.
+func (b *Bolt) Get(ctx context.Context, key string) (*cache.Entry, error) {
+	if b.closed.Load() {
+		return nil, cache.NewError("Get", key, cache.ErrBackendClosed)
+	}
+
+	if key == "" {
+		return nil, cache.NewError("Get", key, cache.ErrInvalidKey)
+	}
+
+	var entry *cache.Entry
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		entry, err = decodeEntry(tx.Bucket(b.bucket).Get([]byte(key)))
+		return err
+	})
+	if err != nil {
+		b.stats.RecordError(err)
+		return nil, cache.NewError("Get", key, err)
+	}
+
+	if entry == nil {
+		b.stats.RecordMiss()
+		return nil, cache.NewError("Get", key, cache.ErrNotFound)
+	}
+
+	if entry.IsExpiredWithGrace(b.grace) {
+		b.stats.RecordMiss()
+		b.purge(key)
+		return nil, cache.NewError("Get", key, cache.ErrExpired)
+	}
+
+	b.stats.RecordHit()
+	return entry, nil
+}
+
+// Peek returns the entry under key like Get, but without recording a hit
+// or miss or purging an expired entry. See cache.Peeker.
+func (b *Bolt) Peek(ctx context.Context, key string) (*cache.Entry, error) {
+	if b.closed.Load() {
+		return nil, cache.NewError("Peek", key, cache.ErrBackendClosed)
+	}
+
+	if key == "" {
+		return nil, cache.NewError("Peek", key, cache.ErrInvalidKey)
+	}
+
+	var entry *cache.Entry
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		entry, err = decodeEntry(tx.Bucket(b.bucket).Get([]byte(key)))
+		return err
+	})
+	if err != nil {
+		return nil, cache.NewError("Peek", key, err)
+	}
+
+	if entry == nil {
+		return nil, cache.NewError("Peek", key, cache.ErrNotFound)
+	}
+	if entry.IsExpiredWithGrace(b.grace) {
+		return nil, cache.NewError("Peek", key, cache.ErrExpired)
+	}
+	return entry, nil
+}
+
+// Set stores a value in the cache.
+func (b *Bolt) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return b.SetWithTags(ctx, key, value, ttl, nil)
+}
+
+// SetWithTags stores a value tagged with tags, which are stored with the
+// entry. See cache.Tagger.
+func (b *Bolt) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	if b.closed.Load() {
+		return cache.NewError("Set", key, cache.ErrBackendClosed)
+	}
+
+	if key == "" {
+		return cache.NewError("Set", key, cache.ErrInvalidKey)
+	}
+
+	if err := cache.ValidateValue(value, 0); err != nil {
+		return cache.NewError("Set", key, err)
+	}
+
+	entry := cache.NewEntry(key, value, ttl)
+	entry.Tags = tags
+	data, err := encodeEntry(entry)
+	if err != nil {
+		return cache.NewError("Set", key, err)
+	}
+
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.bucket).Put([]byte(key), data)
+	})
+	if err != nil {
+		b.stats.RecordError(err)
+		return cache.NewError("Set", key, err)
+	}
+
+	return nil
+}
+
+// SetIf stores a value if the key is absent or expired, or cond approves
+// the stored entry, reading and writing in one transaction. See
+// cache.ConditionalSetter.
+func (b *Bolt) SetIf(ctx context.Context, key string, value []byte, ttl time.Duration, cond func(*cache.Entry) bool) (bool, error) {
+	if b.closed.Load() {
+		return false, cache.NewError("SetIf", key, cache.ErrBackendClosed)
+	}
+
+	if key == "" {
+		return false, cache.NewError("SetIf", key, cache.ErrInvalidKey)
+	}
+
+	if err := cache.ValidateValue(value, 0); err != nil {
+		return false, cache.NewError("SetIf", key, err)
+	}
+
+	data, err := encodeEntry(cache.NewEntry(key, value, ttl))
+	if err != nil {
+		return false, cache.NewError("SetIf", key, err)
+	}
+
+	applied := false
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+		existing, err := decodeEntry(bucket.Get([]byte(key)))
+		if err != nil {
+			return err
+		}
+		if existing != nil && !existing.IsExpiredWithGrace(b.grace) && !cond(existing) {
+			return nil
+		}
+		applied = true
+		return bucket.Put([]byte(key), data)
+	})
+	if err != nil {
+		b.stats.RecordError(err)
+		return false, cache.NewError("SetIf", key, err)
+	}
+
+	return applied, nil
+}
+
+// Delete removes a key from the cache.
+func (b *Bolt) Delete(ctx context.Context, key string) error {
+	if b.closed.Load() {
+		return cache.NewError("Delete", key, cache.ErrBackendClosed)
+	}
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.bucket).Delete([]byte(key))
+	})
+	if err != nil {
+		b.stats.RecordError(err)
+		return cache.NewError("Delete", key, err)
+	}
+
+	return nil
+}
+
+// Touch resets the TTL of an unexpired entry without changing its value.
+// See cache.Toucher.
+func (b *Bolt) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if b.closed.Load() {
+		return cache.NewError("Touch", key, cache.ErrBackendClosed)
+	}
+
+	if key == "" {
+		return cache.NewError("Touch", key, cache.ErrInvalidKey)
+	}
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+		entry, err := decodeEntry(bucket.Get([]byte(key)))
+		if err != nil {
+			return err
+		}
+		if entry == nil || entry.IsExpiredWithGrace(b.grace) {
+			return cache.ErrNotFound
+		}
+
+		entry.TTL = ttl
+		entry.ExpiresAt = time.Time{}
+		if ttl > 0 {
+			entry.ExpiresAt = time.Now().Add(ttl)
+		}
+
+		data, err := encodeEntry(entry)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), data)
+	})
+	if err != nil {
+		return cache.NewError("Touch", key, err)
+	}
+
+	return nil
+}
+
+// Clear removes all entries by deleting and recreating the bucket.
+func (b *Bolt) Clear(ctx context.Context) error {
+	if b.closed.Load() {
+		return cache.NewError("Clear", "", cache.ErrBackendClosed)
+	}
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(b.bucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(b.bucket)
+		return err
+	})
+	if err != nil {
+		b.stats.RecordError(err)
+		return cache.NewError("Clear", "", err)
+	}
+
+	return nil
+}
+
+// DeletePattern deletes all keys matching the pattern, expired or not, in
+// a single transaction. See cache.PatternDeleter.
+func (b *Bolt) DeletePattern(ctx context.Context, pattern string) (int, error) {
+	if b.closed.Load() {
+		return 0, cache.NewError("DeletePattern", "", cache.ErrBackendClosed)
+	}
+
+	prefix := []byte(patternPrefix(pattern))
+
+	deleted := 0
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+
+		// Collect first; deleting while iterating would skip keys
+		var keys [][]byte
+		c := bucket.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			if matchPattern(string(k), pattern) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+		}
+
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		deleted = len(keys)
+		return nil
+	})
+	if err != nil {
+		b.stats.RecordError(err)
+		return 0, cache.NewError("DeletePattern", "", err)
+	}
+
+	return deleted, nil
+}
+
+// DeleteTag deletes all entries tagged with tag, expired or not, in a
+// single transaction. Bolt keeps no tag index, so every entry is decoded.
+// See cache.TagDeleter.
+func (b *Bolt) DeleteTag(ctx context.Context, tag string) (int, error) {
+	if b.closed.Load() {
+		return 0, cache.NewError("DeleteTag", "", cache.ErrBackendClosed)
+	}
+
+	deleted := 0
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+
+		// Collect first; deleting while iterating would skip keys
+		var keys [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			entry, err := decodeEntry(v)
+			if err != nil {
+				return err
+			}
+			if entry.HasTag(tag) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		deleted = len(keys)
+		return nil
+	})
+	if err != nil {
+		b.stats.RecordError(err)
+		return 0, cache.NewError("DeleteTag", "", err)
+	}
+
+	return deleted, nil
+}
+
+// Keys returns all unexpired keys matching the pattern. The bucket is
+// scanned with a cursor, starting at the pattern's literal prefix.
+func (b *Bolt) Keys(ctx context.Context, pattern string) ([]string, error) {
+	if b.closed.Load() {
+		return nil, cache.NewError("Keys", "", cache.ErrBackendClosed)
+	}
+
+	prefix := []byte(patternPrefix(pattern))
+
+	var keys []string
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(b.bucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			key := string(k)
+			if !matchPattern(key, pattern) {
+				continue
+			}
+
+			entry, err := decodeEntry(v)
+			if err != nil {
+				return fmt.Errorf("decoding %s: %w", key, err)
+			}
+			// Skip expired entries
+			if !entry.IsExpiredWithGrace(b.grace) {
+				keys = append(keys, key)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.stats.RecordError(err)
+		return nil, cache.NewError("Keys", "", err)
+	}
+
+	return keys, nil
+}
+
+// Entries returns the metadata of unexpired entries matching pattern, in
+// one read transaction. See cache.EntryLister.
+func (b *Bolt) Entries(ctx context.Context, pattern string) ([]*cache.EntryInfo, error) {
+	if b.closed.Load() {
+		return nil, cache.NewError("Entries", "", cache.ErrBackendClosed)
+	}
+
+	prefix := []byte(patternPrefix(pattern))
+
+	var infos []*cache.EntryInfo
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(b.bucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			key := string(k)
+			if !matchPattern(key, pattern) {
+				continue
+			}
+
+			entry, err := decodeEntry(v)
+			if err != nil {
+				return fmt.Errorf("decoding %s: %w", key, err)
+			}
+			if !entry.IsExpiredWithGrace(b.grace) {
+				infos = append(infos, entry.Info())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.stats.RecordError(err)
+		return nil, cache.NewError("Entries", "", err)
+	}
+
+	return infos, nil
+}
+
+// Stats returns current cache statistics. Entries and Size are counted
+// by scanning the bucket; Size is the encoded size of the stored entries.
+func (b *Bolt) Stats(ctx context.Context) (*cache.Stats, error) {
+	if b.closed.Load() {
+		return nil, cache.NewError("Stats", "", cache.ErrBackendClosed)
+	}
+
+	var entries, size int64
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.bucket).ForEach(func(k, v []byte) error {
+			entries++
+			size += int64(len(v))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, cache.NewError("Stats", "", err)
+	}
+
+	b.stats.SetEntries(entries)
+	b.stats.SetSize(size)
+	return b.stats.Stats(), nil
+}
+
+// ResetStats zeroes hit, miss, eviction, and error counters. Entries and
+// Size are recounted from the bucket by Stats. See cache.StatsResetter.
+func (b *Bolt) ResetStats(ctx context.Context) error {
+	if b.closed.Load() {
+		return cache.NewError("ResetStats", "", cache.ErrBackendClosed)
+	}
+
+	b.stats.Reset()
+	return nil
+}
+
+// Ping checks that the database can be read, with a read-only
+// transaction that finds the bucket. See cache.Pinger.
+func (b *Bolt) Ping(ctx context.Context) error {
+	if b.closed.Load() {
+		return cache.NewError("Ping", "", cache.ErrBackendClosed)
+	}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(b.bucket) == nil {
+			return fmt.Errorf("bucket %q not found", b.bucket)
+		}
+		return nil
+	})
+	if err != nil {
+		return cache.NewError("Ping", "", err)
+	}
+	return nil
+}
+
+// Close closes the database. Writes are already durable, so there is
+// nothing to flush.
+func (b *Bolt) Close() error {
+	if !b.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	return b.db.Close()
+}
+
+// purge deletes key if it is still expired. Purging is best-effort, since
+// an expired entry is never served.
+func (b *Bolt) purge(key string) {
+	purged := false
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.bucket)
+		entry, err := decodeEntry(bucket.Get([]byte(key)))
+		if err != nil || entry == nil || !entry.IsExpiredWithGrace(b.grace) {
+			return err
+		}
+		purged = true
+		return bucket.Delete([]byte(key))
+	})
+	if err != nil {
+		b.logger.Warn("failed to purge expired entry", "key", key, "error", err)
+		return
+	}
+
+	if purged {
+		b.stats.RecordEviction()
+	}
+}
+
+// encodeEntry gob-encodes an entry for storage in Bolt or Memcached.
+func encodeEntry(entry *cache.Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entry); err != nil {
+		return nil, fmt.Errorf("encoding entry: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeEntry decodes a stored entry, returning nil for a missing
+// (nil) value. The result doesn't alias data, which bbolt only keeps
+// valid for the life of the transaction.
+func decodeEntry(data []byte) (*cache.Entry, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	var entry cache.Entry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entry); err != nil {
+		return nil, fmt.Errorf("decoding entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// patternPrefix returns the literal text before a pattern's first
+// wildcard or escape, which every matching key starts with.
+func patternPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
@@ -0,0 +1,223 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	cache "github.com/rmrfslashbin/hue-cache"
+)
+
+// newTestBolt opens a bbolt backend in a temporary directory.
+func newTestBolt(t *testing.T) *Bolt {
+	t.Helper()
+	backend, err := NewBolt(&BoltConfig{Path: filepath.Join(t.TempDir(), "cache.db")})
+	if err != nil {
+		t.Fatalf("NewBolt() failed: %v", err)
+	}
+	return backend
+}
+
+func TestBolt_BackendContract(t *testing.T) {
+	suite := cache.BackendTestSuite{
+		NewBackend: func(t *testing.T) cache.Backend {
+			return newTestBolt(t)
+		},
+	}
+
+	cache.RunBackendTests(t, suite)
+}
+
+func TestBolt_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "cache.db")
+	config := &BoltConfig{Path: path, Bucket: "hue"}
+	ctx := context.Background()
+
+	backend, err := NewBolt(config)
+	if err != nil {
+		t.Fatalf("NewBolt() failed: %v", err)
+	}
+	backend.Set(ctx, "light:1", []byte("kept"), time.Hour)
+	backend.Set(ctx, "light:2", []byte("deleted"), 0)
+	backend.Delete(ctx, "light:2")
+	if err := backend.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	// Writes are durable without an explicit save
+	reopened, err := NewBolt(config)
+	if err != nil {
+		t.Fatalf("NewBolt() reopen failed: %v", err)
+	}
+	defer reopened.Close()
+
+	entry, err := reopened.Get(ctx, "light:1")
+	if err != nil {
+		t.Fatalf("Get() after reopen failed: %v", err)
+	}
+	if string(entry.Value) != "kept" || entry.TTL != time.Hour {
+		t.Errorf("Get() after reopen = %q with TTL %v, want kept with 1h", entry.Value, entry.TTL)
+	}
+	if _, err := reopened.Get(ctx, "light:2"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Get() of deleted key error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestBolt_PurgesExpiredOnRead(t *testing.T) {
+	backend := newTestBolt(t)
+	defer backend.Close()
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("value"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if keys, _ := backend.Keys(ctx, "*"); len(keys) != 0 {
+		t.Errorf("Keys() = %v, want expired entry skipped", keys)
+	}
+
+	if _, err := backend.Get(ctx, "light:1"); !errors.Is(err, cache.ErrExpired) {
+		t.Errorf("Get() error = %v, want ErrExpired", err)
+	}
+
+	stats, err := backend.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() failed: %v", err)
+	}
+	if stats.Entries != 0 || stats.Evictions != 1 || stats.Misses != 1 {
+		t.Errorf("Stats = %d entries, %d evictions, %d misses; want 0, 1, 1",
+			stats.Entries, stats.Evictions, stats.Misses)
+	}
+}
+
+func TestBolt_ClockSkewGrace(t *testing.T) {
+	backend, err := NewBolt(&BoltConfig{
+		Path:           filepath.Join(t.TempDir(), "cache.db"),
+		ClockSkewGrace: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewBolt() failed: %v", err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("value"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	// Expired, but within the grace window
+	if _, err := backend.Get(ctx, "light:1"); err != nil {
+		t.Errorf("Get() within grace failed: %v", err)
+	}
+	if keys, _ := backend.Keys(ctx, "*"); len(keys) != 1 {
+		t.Errorf("Keys() = %v, want the entry within grace", keys)
+	}
+}
+
+func TestBolt_KeysAndClear(t *testing.T) {
+	backend := newTestBolt(t)
+	defer backend.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{"light:1", "light:2", "room:1", "lights"} {
+		backend.Set(ctx, key, []byte("value"), 0)
+	}
+
+	keys, err := backend.Keys(ctx, "light:*")
+	if err != nil {
+		t.Fatalf("Keys() failed: %v", err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "light:1" || keys[1] != "light:2" {
+		t.Errorf("Keys(light:*) = %v, want [light:1 light:2]", keys)
+	}
+
+	if keys, _ := backend.Keys(ctx, "*:1"); len(keys) != 2 {
+		t.Errorf("Keys(*:1) = %v, want 2 keys", keys)
+	}
+
+	if err := backend.Clear(ctx); err != nil {
+		t.Fatalf("Clear() failed: %v", err)
+	}
+	if keys, _ := backend.Keys(ctx, "*"); len(keys) != 0 {
+		t.Errorf("Keys() after Clear() = %v, want none", keys)
+	}
+
+	// The bucket is usable after being recreated
+	if err := backend.Set(ctx, "light:3", []byte("value"), 0); err != nil {
+		t.Errorf("Set() after Clear() failed: %v", err)
+	}
+}
+
+func TestBolt_DeletePattern(t *testing.T) {
+	backend := newTestBolt(t)
+	defer backend.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{"light:1", "light:2", "room:1", "lights"} {
+		backend.Set(ctx, key, []byte("value"), 0)
+	}
+
+	deleted, err := backend.DeletePattern(ctx, "light:*")
+	if err != nil {
+		t.Fatalf("DeletePattern() failed: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("DeletePattern() deleted %d keys, want 2", deleted)
+	}
+
+	keys, _ := backend.Keys(ctx, "*")
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "lights" || keys[1] != "room:1" {
+		t.Errorf("Keys() after DeletePattern() = %v, want [lights room:1]", keys)
+	}
+
+	// The manager clears through the transaction
+	for _, key := range []string{"light:1", "light:2"} {
+		backend.Set(ctx, key, []byte("value"), 0)
+	}
+	if err := cache.NewCacheManager(backend, nil).ClearLights(ctx); err != nil {
+		t.Fatalf("ClearLights() failed: %v", err)
+	}
+	if keys, _ := backend.Keys(ctx, "light:*"); len(keys) != 0 {
+		t.Errorf("Keys(light:*) after ClearLights() = %v, want none", keys)
+	}
+}
+
+func TestBolt_DeleteTag(t *testing.T) {
+	backend := newTestBolt(t)
+	defer backend.Close()
+
+	ctx := context.Background()
+	backend.SetWithTags(ctx, "light:1", []byte("value"), 0, []string{"floor-2"})
+	backend.SetWithTags(ctx, "room:1", []byte("value"), 0, []string{"floor-2", "holiday"})
+	backend.Set(ctx, "light:2", []byte("value"), 0)
+
+	if entry, err := backend.Get(ctx, "room:1"); err != nil || !entry.HasTag("holiday") {
+		t.Errorf("Get(room:1) = %v, %v; want tagged holiday", entry, err)
+	}
+
+	deleted, err := backend.DeleteTag(ctx, "floor-2")
+	if err != nil {
+		t.Fatalf("DeleteTag() failed: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("DeleteTag() deleted %d keys, want 2", deleted)
+	}
+	if keys, _ := backend.Keys(ctx, "*"); len(keys) != 1 || keys[0] != "light:2" {
+		t.Errorf("Keys() after DeleteTag() = %v, want [light:2]", keys)
+	}
+}
+
+func TestBolt_OperationsAfterClose(t *testing.T) {
+	backend := newTestBolt(t)
+	backend.Close()
+
+	if err := backend.Close(); err != nil {
+		t.Errorf("Second Close() error = %v, want nil", err)
+	}
+	if _, err := backend.Get(context.Background(), "light:1"); !errors.Is(err, cache.ErrBackendClosed) {
+		t.Errorf("Get() after Close() error = %v, want ErrBackendClosed", err)
+	}
+}
@@ -1,12 +1,17 @@
 package backends
 
 import (
+	"bufio"
 	"context"
 	"encoding/gob"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	cache "github.com/rmrfslashbin/hue-cache"
@@ -18,11 +23,45 @@ import (
 type File struct {
 	memory           *Memory
 	filePath         string
+	shards           int
 	autoSaveInterval time.Duration
+	autoSaveJitter   time.Duration
+	writeBufferSize  int
+	disableFsync     bool
+	syncFile         func(*os.File) error
+	wrapWriter       func(io.Writer) io.Writer
+	wrapReader       func(io.Reader) io.Reader
+	readsDuringLoad  LoadReadMode
+	clockSkewGrace   time.Duration
+	maxSaveFailures  int
 	saveTicker       *time.Ticker
 	saveStop         chan struct{}
+	logger           cache.Logger
+	onSaveError      func(error)
+	latency          *latencyTracker
 	mu               sync.RWMutex
 	closed           bool
+
+	// loadMu serializes Load with Save, so a save never writes out a
+	// half-loaded cache. It is acquired before mu.
+	loadMu  sync.Mutex
+	loading atomic.Bool
+
+	// dirty is set by writes and cleared when a save starts, so
+	// auto-save can skip saves that would rewrite an unchanged file
+	dirty atomic.Bool
+
+	// wal is the write-ahead log in WAL mode. walMu orders writes with
+	// their log records, and keeps them out while Save compacts the log.
+	wal            *writeAheadLog
+	walMu          sync.Mutex
+	walCompactOps  int
+	walCompactSize int64
+	compact        chan struct{}
+
+	// saveMu protects saveStatus
+	saveMu     sync.Mutex
+	saveStatus SaveStatus
 }
 
 // FileConfig contains configuration for the file backend.
@@ -31,20 +70,158 @@ type FileConfig struct {
 	// Default: "./hue-cache.gob"
 	FilePath string
 
+	// Shards splits the saved cache across this many files, named
+	// FilePath plus ".0", ".1", and so on, by key hash. Save and Load
+	// then encode and decode the files in parallel, which speeds up very
+	// large caches, and a corrupt file loses only its own entries: Load
+	// loads the other files and returns the error. Changing Shards
+	// orphans entries saved under the old count (FilePath, or the files
+	// past the new count), so they are reloaded from the bridge.
+	// Default: 0 (a single file at FilePath)
+	Shards int
+
 	// AutoSaveInterval is how often to automatically flush to disk.
 	// Set to 0 to disable auto-save (manual Save() only).
 	// Default: 5 minutes
 	AutoSaveInterval time.Duration
 
+	// AutoSaveJitter offsets the first auto-save by a random amount of up
+	// to this much in either direction, so it happens at
+	// AutoSaveInterval±AutoSaveJitter. Later saves follow every
+	// AutoSaveInterval after it, so File backends created together keep
+	// saving at staggered times instead of all hitting the disk at once.
+	// Values above AutoSaveInterval are capped at it.
+	// Default: 0 (first save at exactly AutoSaveInterval)
+	AutoSaveJitter time.Duration
+
 	// LoadOnStart loads existing cache from disk on initialization.
 	// Default: true
 	LoadOnStart bool
 
+	// ReadsDuringLoad controls what reads do while Load runs. Blocking
+	// (the default) makes every read wait until the whole file is loaded,
+	// which for a large file stalls startup. The other modes don't wait,
+	// and make NewFile start its LoadOnStart load in the background. See
+	// LoadReadMode.
+	// Default: LoadReadBlock
+	ReadsDuringLoad LoadReadMode
+
 	// MemoryConfig is the configuration for the underlying memory backend.
 	// If nil, defaults are used.
 	MemoryConfig *MemoryConfig
+
+	// TrackLatency records the latency of every Get, Set, Delete, and
+	// Keys, reported by LatencyStats. Unlike MemoryConfig.TrackLatency,
+	// which times the in-memory operation alone, this includes waiting
+	// for loads and compactions and appending to the WAL.
+	// Default: false
+	TrackLatency bool
+
+	// Logger receives load/save diagnostics. It is also used by the
+	// underlying memory backend unless MemoryConfig sets its own.
+	// Default: no-op logger
+	Logger cache.Logger
+
+	// WriteBufferSize is the size in bytes of the buffer gob output is
+	// written through during Save. Larger buffers mean fewer write
+	// syscalls for large caches. Set to a negative value to write
+	// directly to the file without buffering.
+	// Default: 64 KiB
+	WriteBufferSize int
+
+	// DisableFsync skips fsync when saving. Save still writes to a temp
+	// file and renames it into place, so a process crash never leaves a
+	// partially written cache file; but after an OS crash or power loss
+	// the file may be empty or hold older data. Since this is a cache that
+	// can be rebuilt from the bridge, that is often an acceptable trade for
+	// faster saves on filesystems where fsync is slow.
+	// Default: false
+	DisableFsync bool
+
+	// OnSaveError is called when a save that no caller is waiting on
+	// fails: an auto-save, or the final save in Close. Without it such
+	// failures are only logged, and unsaved changes are lost unnoticed
+	// until the next start. See also File.SaveStatus.
+	// Default: nil
+	OnSaveError func(error)
+
+	// MaxAutoSaveFailures stops auto-saving after this many consecutive
+	// failed saves, so a full disk doesn't log an error every interval.
+	// Stopping is logged once and reported by File.SaveStatus; Save and
+	// the final save in Close still run.
+	// Default: 0 (never stop)
+	MaxAutoSaveFailures int
+
+	// ClockSkewGrace is added to the remaining TTL of entries Load reads,
+	// so entries written on a host whose clock runs behind this one's
+	// aren't dropped (or expired) early. The remaining TTL is also capped
+	// at the entry's TTL, so entries written on a host whose clock runs
+	// ahead can't outlive it. Once loaded, entries expire by this host's
+	// clock. The tradeoff: every loaded entry may be served up to
+	// ClockSkewGrace longer than its TTL.
+	// Default: 0
+	ClockSkewGrace time.Duration
+
+	// WAL enables write-ahead log mode. Instead of rewriting the whole
+	// cache every AutoSaveInterval, each write appends a small record to
+	// a log at FilePath plus ".wal", and once the log reaches
+	// WALCompactOps records or WALCompactSize bytes, a background
+	// compaction saves a snapshot and empties the log. Load reads the
+	// snapshot, then replays the log. Writes wait while a compaction runs,
+	// and auto-save only syncs the log to disk (see DisableFsync), so
+	// after a process crash nothing is lost, and after an OS crash at
+	// most the last AutoSaveInterval of writes.
+	// Default: false
+	WAL bool
+
+	// WALCompactOps compacts the log once it holds this many records.
+	// Default: DefaultWALCompactOps if WALCompactSize is also 0
+	WALCompactOps int
+
+	// WALCompactSize compacts the log once it grows to this many bytes.
+	// Default: 0 (no size threshold)
+	WALCompactSize int64
 }
 
+// LoadReadMode selects how the File backend serves reads while loading.
+type LoadReadMode int
+
+const (
+	// LoadReadBlock makes reads wait until Load finishes. Reads never see
+	// a partially loaded cache.
+	LoadReadBlock LoadReadMode = iota
+
+	// LoadReadPartial serves reads from whatever has been loaded so far;
+	// keys not loaded yet are misses. Callers get cached data as soon as
+	// it is available, at the cost of misses (and fetches from the
+	// source) for entries that were about to be loaded.
+	LoadReadPartial
+
+	// LoadReadUnavailable fails reads with cache.ErrLoading until Load
+	// finishes, so callers fall back to the source instead of blocking
+	// or seeing a partial cache. ErrLoading wraps cache.ErrNotFound, so
+	// the cached clients treat it as a miss.
+	LoadReadUnavailable
+)
+
+// String returns the mode's name.
+func (m LoadReadMode) String() string {
+	switch m {
+	case LoadReadBlock:
+		return "block"
+	case LoadReadPartial:
+		return "partial"
+	case LoadReadUnavailable:
+		return "unavailable"
+	default:
+		return fmt.Sprintf("LoadReadMode(%d)", int(m))
+	}
+}
+
+// defaultWriteBufferSize is the Save buffer size used when
+// FileConfig.WriteBufferSize is 0.
+const defaultWriteBufferSize = 64 * 1024
+
 // DefaultFileConfig returns default configuration for file backend.
 func DefaultFileConfig() *FileConfig {
 	return &FileConfig{
@@ -52,6 +229,7 @@ func DefaultFileConfig() *FileConfig {
 		AutoSaveInterval: 5 * time.Minute,
 		LoadOnStart:      true,
 		MemoryConfig:     DefaultMemoryConfig(),
+		WriteBufferSize:  defaultWriteBufferSize,
 	}
 }
 
@@ -74,11 +252,36 @@ func NewFile(config *FileConfig) (*File, error) {
 		config.MemoryConfig = DefaultMemoryConfig()
 	}
 
+	logger := config.Logger
+	if logger == nil {
+		logger = cache.NopLogger()
+	}
+
+	if config.MemoryConfig.Logger == nil {
+		config.MemoryConfig.Logger = logger
+	}
+
+	writeBufferSize := config.WriteBufferSize
+	if writeBufferSize == 0 {
+		writeBufferSize = defaultWriteBufferSize
+	}
+
 	f := &File{
 		memory:           NewMemory(config.MemoryConfig),
 		filePath:         config.FilePath,
+		shards:           config.Shards,
 		autoSaveInterval: config.AutoSaveInterval,
+		autoSaveJitter:   config.AutoSaveJitter,
+		writeBufferSize:  writeBufferSize,
+		disableFsync:     config.DisableFsync,
+		syncFile:         (*os.File).Sync,
 		saveStop:         make(chan struct{}),
+		logger:           logger,
+		onSaveError:      config.OnSaveError,
+		maxSaveFailures:  config.MaxAutoSaveFailures,
+		readsDuringLoad:  config.ReadsDuringLoad,
+		clockSkewGrace:   config.ClockSkewGrace,
+		latency:          newLatencyTracker(config.TrackLatency),
 	}
 
 	// Create directory if it doesn't exist
@@ -87,28 +290,81 @@ func NewFile(config *FileConfig) (*File, error) {
 		return nil, fmt.Errorf("creating cache directory: %w", err)
 	}
 
-	// Load existing cache from disk
+	if config.WAL {
+		wal, err := openWAL(f.walPath())
+		if err != nil {
+			return nil, fmt.Errorf("opening write-ahead log: %w", err)
+		}
+		f.wal = wal
+		f.walCompactOps = config.WALCompactOps
+		f.walCompactSize = config.WALCompactSize
+		if f.walCompactOps <= 0 && f.walCompactSize <= 0 {
+			f.walCompactOps = DefaultWALCompactOps
+		}
+		f.compact = make(chan struct{}, 1)
+		go f.compactLoop()
+	}
+
+	// Load existing cache from disk, in the background if reads needn't
+	// wait for it
 	if config.LoadOnStart {
-		if err := f.Load(); err != nil {
-			// Log error but continue - cache will be empty
-			// This is expected on first run when file doesn't exist
+		if f.readsDuringLoad == LoadReadBlock {
+			f.loadOnStart()
+		} else {
+			f.loading.Store(true) // Reads see the load before it starts
+			go f.loadOnStart()
 		}
 	}
 
 	// Start auto-save ticker if enabled
 	if config.AutoSaveInterval > 0 {
-		f.saveTicker = time.NewTicker(config.AutoSaveInterval)
+		f.saveTicker = time.NewTicker(firstAutoSave(config.AutoSaveInterval, config.AutoSaveJitter))
 		go f.autoSaveLoop()
 	}
 
 	return f, nil
 }
 
+// loadOnStart runs the LoadOnStart load.
+func (f *File) loadOnStart() {
+	if err := f.Load(); err != nil {
+		// Continue with an empty cache - a missing file is not an
+		// error, so this is a corrupt or unreadable cache file
+		f.logger.Warn("failed to load cache file, starting without its entries", "path", f.filePath, "error", err)
+	}
+}
+
+// firstAutoSave returns the delay before the first auto-save: interval
+// offset by a random amount in [-jitter, jitter]. jitter is capped at
+// interval, and the delay is never less than 1ns.
+func firstAutoSave(interval, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	if jitter > interval {
+		jitter = interval
+	}
+
+	delay := interval + rand.N(2*jitter+1) - jitter
+	if delay <= 0 {
+		return 1
+	}
+	return delay
+}
+
 // autoSaveLoop periodically saves the cache to disk.
 func (f *File) autoSaveLoop() {
+	jittered := f.autoSaveJitter > 0
 	for {
 		select {
 		case <-f.saveTicker.C:
+			// The first tick came after the jittered delay; keep its
+			// offset from here on
+			if jittered {
+				f.saveTicker.Reset(f.autoSaveInterval)
+				jittered = false
+			}
+
 			f.mu.RLock()
 			if f.closed {
 				f.mu.RUnlock()
@@ -116,15 +372,88 @@ func (f *File) autoSaveLoop() {
 			}
 			f.mu.RUnlock()
 
-			_ = f.Save()
+			// Nothing changed since the last save
+			if !f.dirty.Load() {
+				continue
+			}
+
+			// Writes are already logged; make them durable
+			if f.wal != nil {
+				f.syncWAL()
+				continue
+			}
+
+			if err := f.Save(); err != nil {
+				f.logger.Error("auto-save failed", "path", f.filePath, "error", err)
+				f.saveFailed(err)
+
+				if f.stopAutoSave() {
+					f.logger.Error("auto-save stopped after repeated failures", "path", f.filePath, "failures", f.maxSaveFailures)
+					return
+				}
+			}
 		case <-f.saveStop:
 			return
 		}
 	}
 }
 
+// saveFailed reports a failed background or final save to OnSaveError.
+func (f *File) saveFailed(err error) {
+	if f.onSaveError != nil {
+		f.onSaveError(err)
+	}
+}
+
+// stopAutoSave reports whether MaxAutoSaveFailures consecutive saves
+// have failed, and if so marks auto-save stopped.
+func (f *File) stopAutoSave() bool {
+	if f.maxSaveFailures <= 0 {
+		return false
+	}
+
+	f.saveMu.Lock()
+	defer f.saveMu.Unlock()
+	if f.saveStatus.ConsecutiveFailures < f.maxSaveFailures {
+		return false
+	}
+	f.saveStatus.AutoSaveStopped = true
+	return true
+}
+
+// SaveStatus describes the outcome of the File backend's saves.
+type SaveStatus struct {
+	// LastSaveTime is when the cache was last saved successfully, or the
+	// zero time if it never was.
+	LastSaveTime time.Time
+
+	// LastSaveError is the error from the most recent save, or nil if it
+	// succeeded.
+	LastSaveError error
+
+	// ConsecutiveFailures is the number of saves that have failed since
+	// the last successful one.
+	ConsecutiveFailures int
+
+	// AutoSaveStopped is set once auto-save has stopped after
+	// MaxAutoSaveFailures consecutive failures.
+	AutoSaveStopped bool
+}
+
+// SaveStatus returns the outcome of the most recent saves, whether made
+// by Save, auto-save, or Close.
+func (f *File) SaveStatus() SaveStatus {
+	f.saveMu.Lock()
+	defer f.saveMu.Unlock()
+	return f.saveStatus
+}
+
 // Get retrieves an entry from the cache.
 func (f *File) Get(ctx context.Context, key string) (*cache.Entry, error) {
+	if f.latency != nil {
+		defer f.latency.get.record(time.Now())
+	}
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
@@ -132,11 +461,58 @@ func (f *File) Get(ctx context.Context, key string) (*cache.Entry, error) {
 		return nil, cache.NewError("Get", key, cache.ErrBackendClosed)
 	}
 
+	if f.readsDuringLoad == LoadReadUnavailable && f.loading.Load() {
+		return nil, cache.NewError("Get", key, cache.ErrLoading)
+	}
+
 	return f.memory.Get(ctx, key)
 }
 
+// GetMeta returns metadata for an entry without copying its value.
+// See Memory.GetMeta.
+func (f *File) GetMeta(ctx context.Context, key string) (*cache.EntryMeta, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.closed {
+		return nil, cache.NewError("GetMeta", key, cache.ErrBackendClosed)
+	}
+
+	if f.readsDuringLoad == LoadReadUnavailable && f.loading.Load() {
+		return nil, cache.NewError("GetMeta", key, cache.ErrLoading)
+	}
+
+	return f.memory.GetMeta(ctx, key)
+}
+
+// Peek returns an entry without side effects. See Memory.Peek.
+func (f *File) Peek(ctx context.Context, key string) (*cache.Entry, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.closed {
+		return nil, cache.NewError("Peek", key, cache.ErrBackendClosed)
+	}
+
+	if f.readsDuringLoad == LoadReadUnavailable && f.loading.Load() {
+		return nil, cache.NewError("Peek", key, cache.ErrLoading)
+	}
+
+	return f.memory.Peek(ctx, key)
+}
+
 // Set stores an entry in the cache.
 func (f *File) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return f.SetWithTags(ctx, key, value, ttl, nil)
+}
+
+// SetWithTags stores an entry tagged with tags, which are saved with it.
+// See Memory.SetWithTags.
+func (f *File) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	if f.latency != nil {
+		defer f.latency.set.record(time.Now())
+	}
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
@@ -144,11 +520,18 @@ func (f *File) Set(ctx context.Context, key string, value []byte, ttl time.Durat
 		return cache.NewError("Set", key, cache.ErrBackendClosed)
 	}
 
-	return f.memory.Set(ctx, key, value, ttl)
+	_, err := f.logWrite(walSet, key, func() (bool, error) {
+		return succeeded(f.memory.SetWithTags(ctx, key, value, ttl, tags))
+	})
+	return err
 }
 
 // Delete removes an entry from the cache.
 func (f *File) Delete(ctx context.Context, key string) error {
+	if f.latency != nil {
+		defer f.latency.delete.record(time.Now())
+	}
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
@@ -156,7 +539,39 @@ func (f *File) Delete(ctx context.Context, key string) error {
 		return cache.NewError("Delete", key, cache.ErrBackendClosed)
 	}
 
-	return f.memory.Delete(ctx, key)
+	_, err := f.logWrite(walDelete, key, func() (bool, error) {
+		return succeeded(f.memory.Delete(ctx, key))
+	})
+	return err
+}
+
+// SetIf conditionally stores an entry. See Memory.SetIf.
+func (f *File) SetIf(ctx context.Context, key string, value []byte, ttl time.Duration, cond func(*cache.Entry) bool) (bool, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.closed {
+		return false, cache.NewError("SetIf", key, cache.ErrBackendClosed)
+	}
+
+	return f.logWrite(walSet, key, func() (bool, error) {
+		return f.memory.SetIf(ctx, key, value, ttl, cond)
+	})
+}
+
+// Touch extends an entry's TTL. See Memory.Touch.
+func (f *File) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.closed {
+		return cache.NewError("Touch", key, cache.ErrBackendClosed)
+	}
+
+	_, err := f.logWrite(walSet, key, func() (bool, error) {
+		return succeeded(f.memory.Touch(ctx, key, ttl))
+	})
+	return err
 }
 
 // Clear removes all entries from the cache.
@@ -168,11 +583,120 @@ func (f *File) Clear(ctx context.Context) error {
 		return cache.ErrBackendClosed
 	}
 
-	return f.memory.Clear(ctx)
+	_, err := f.logWrite(walClear, "", func() (bool, error) {
+		return succeeded(f.memory.Clear(ctx))
+	})
+	return err
+}
+
+// DeletePattern deletes all keys matching the pattern atomically. In WAL
+// mode it is logged as one record. See Memory.DeletePattern.
+func (f *File) DeletePattern(ctx context.Context, pattern string) (int, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.closed {
+		return 0, cache.NewError("DeletePattern", "", cache.ErrBackendClosed)
+	}
+
+	var deleted int
+	_, err := f.logWrite(walDeletePattern, pattern, func() (bool, error) {
+		var err error
+		deleted, err = f.memory.DeletePattern(ctx, pattern)
+		return deleted > 0, err
+	})
+	return deleted, err
+}
+
+// DeleteMany deletes keys in one pass. In WAL mode each deleted key is
+// logged as a Delete. See Memory.DeleteMany.
+func (f *File) DeleteMany(ctx context.Context, keys []string) (int, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.closed {
+		return 0, cache.NewError("DeleteMany", "", cache.ErrBackendClosed)
+	}
+
+	// Like logWrite, but logging one record per deleted key
+	if f.wal != nil {
+		f.walMu.Lock()
+		defer f.walMu.Unlock()
+	}
+
+	deleted := f.memory.deleteMany(keys)
+	if len(deleted) > 0 {
+		f.dirty.Store(true)
+	}
+	if f.wal != nil {
+		for _, key := range deleted {
+			f.appendWAL(walDelete, key)
+		}
+	}
+	return len(deleted), nil
+}
+
+// DeleteTag deletes all entries tagged with tag atomically. In WAL mode
+// it is logged as one record. See Memory.DeleteTag.
+func (f *File) DeleteTag(ctx context.Context, tag string) (int, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.closed {
+		return 0, cache.NewError("DeleteTag", "", cache.ErrBackendClosed)
+	}
+
+	var deleted int
+	_, err := f.logWrite(walDeleteTag, tag, func() (bool, error) {
+		var err error
+		deleted, err = f.memory.DeleteTag(ctx, tag)
+		return deleted > 0, err
+	})
+	return deleted, err
+}
+
+// logWrite applies write to the memory backend. If it changed the cache,
+// the cache is marked dirty and, in WAL mode, the write is logged. Must be
+// called with mu held.
+func (f *File) logWrite(op walOp, key string, write func() (bool, error)) (bool, error) {
+	if f.wal == nil {
+		changed, err := write()
+		if changed {
+			f.dirty.Store(true)
+		}
+		return changed, err
+	}
+
+	// Hold walMu across the write, so records are logged in the order
+	// their writes were applied
+	f.walMu.Lock()
+	defer f.walMu.Unlock()
+
+	changed, err := write()
+	if changed {
+		f.dirty.Store(true)
+		f.appendWAL(op, key)
+	}
+	return changed, err
+}
+
+// succeeded adapts a write returning only an error for logWrite.
+func succeeded(err error) (bool, error) {
+	return err == nil, err
+}
+
+// Dirty reports whether the cache has changed since the last save
+// started. Auto-save skips saving while it is false.
+func (f *File) Dirty() bool {
+	return f.dirty.Load()
 }
 
 // Keys returns all keys matching the pattern.
 func (f *File) Keys(ctx context.Context, pattern string) ([]string, error) {
+	if f.latency != nil {
+		defer f.latency.keys.record(time.Now())
+	}
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
@@ -183,6 +707,19 @@ func (f *File) Keys(ctx context.Context, pattern string) ([]string, error) {
 	return f.memory.Keys(ctx, pattern)
 }
 
+// Entries returns the metadata of entries matching pattern. See
+// Memory.Entries.
+func (f *File) Entries(ctx context.Context, pattern string) ([]*cache.EntryInfo, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.closed {
+		return nil, cache.NewError("Entries", "", cache.ErrBackendClosed)
+	}
+
+	return f.memory.Entries(ctx, pattern)
+}
+
 // Stats returns cache statistics.
 func (f *File) Stats(ctx context.Context) (*cache.Stats, error) {
 	f.mu.RLock()
@@ -195,9 +732,70 @@ func (f *File) Stats(ctx context.Context) (*cache.Stats, error) {
 	return f.memory.Stats(ctx)
 }
 
+// StatsByType returns per-resource-type statistics from the underlying
+// memory backend. See Memory.StatsByType.
+func (f *File) StatsByType(ctx context.Context) (map[string]*cache.Stats, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.closed {
+		return nil, cache.ErrBackendClosed
+	}
+
+	return f.memory.StatsByType(ctx)
+}
+
+// DetailedStats summarizes the entries of the underlying memory backend.
+// See Memory.DetailedStats.
+func (f *File) DetailedStats(ctx context.Context) (*cache.DetailedStats, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.closed {
+		return nil, cache.NewError("DetailedStats", "", cache.ErrBackendClosed)
+	}
+
+	return f.memory.DetailedStats(ctx)
+}
+
+// ResetStats zeroes hit, miss, and eviction counters, and the latency
+// statistics. See Memory.ResetStats.
+func (f *File) ResetStats(ctx context.Context) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.closed {
+		return cache.NewError("ResetStats", "", cache.ErrBackendClosed)
+	}
+
+	f.latency.reset()
+	return f.memory.ResetStats(ctx)
+}
+
+// LatencyStats returns the latencies of Get, Set, Delete, and Keys since
+// the backend was created or ResetStats was last called. It is all zeros
+// unless FileConfig.TrackLatency is set. For the underlying memory
+// backend's own latencies, see MemoryConfig.TrackLatency.
+func (f *File) LatencyStats() LatencyStats {
+	return f.latency.stats()
+}
+
+// OnChange registers listener to be notified of entry changes in the
+// underlying memory backend, including entries restored by Load.
+// See Memory.OnChange.
+func (f *File) OnChange(listener func(cache.ChangeEvent)) (unsubscribe func()) {
+	return f.memory.OnChange(listener)
+}
+
 // Save writes the current cache state to disk.
 // This is called automatically based on AutoSaveInterval, but can also
-// be called manually for immediate persistence.
+// be called manually for immediate persistence. Auto-save only saves
+// after writes; Save always writes the file.
+//
+// The saved file is a point-in-time snapshot (see Memory.Snapshot): each
+// write made during the save is either saved whole or left for the next
+// save, which it marks the cache dirty for. Saving doesn't count as a
+// hit on the saved entries.
 //
 // Example:
 //
@@ -206,6 +804,10 @@ func (f *File) Stats(ctx context.Context) (*cache.Stats, error) {
 //	    log.Printf("Failed to save cache: %v", err)
 //	}
 func (f *File) Save() error {
+	// Wait for a running Load
+	f.loadMu.Lock()
+	defer f.loadMu.Unlock()
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
@@ -213,41 +815,132 @@ func (f *File) Save() error {
 		return cache.ErrBackendClosed
 	}
 
+	// In WAL mode, keep writes out until the log is emptied, so it only
+	// ever holds writes the snapshot doesn't
+	if f.wal != nil {
+		f.walMu.Lock()
+		defer f.walMu.Unlock()
+	}
+
+	// Clear the flag before collecting entries, so writes made during the
+	// save mark the cache dirty again
+	wasDirty := f.dirty.Swap(false)
+	err := f.save()
+	if err == nil && f.wal != nil {
+		err = f.wal.reset(f.fsync())
+	}
+	if err != nil && wasDirty {
+		f.dirty.Store(true)
+	}
+
+	f.saveMu.Lock()
+	f.saveStatus.LastSaveError = err
+	if err == nil {
+		f.saveStatus.LastSaveTime = time.Now()
+		f.saveStatus.ConsecutiveFailures = 0
+	} else {
+		f.saveStatus.ConsecutiveFailures++
+	}
+	f.saveMu.Unlock()
+
+	return err
+}
+
+// save writes all entries to disk. Must be called with mu held.
+func (f *File) save() error {
+	entries, err := f.memory.Snapshot(context.Background())
+	if err != nil {
+		return fmt.Errorf("taking snapshot: %w", err)
+	}
+
+	syncFile := f.fsync()
+	paths := f.shardPaths()
+	if len(paths) == 1 {
+		return writeCacheFile(paths[0], entries, f.writeBufferSize, syncFile, f.wrapWriter)
+	}
+
+	// Partition by key hash and write the shards in parallel
+	shards := make([][]*cache.Entry, len(paths))
+	for _, entry := range entries {
+		i := keyHash(entry.Key) % uint32(len(paths))
+		shards[i] = append(shards[i], entry)
+	}
+
+	errs := make([]error, len(paths))
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := writeCacheFile(path, shards[i], f.writeBufferSize, syncFile, f.wrapWriter); err != nil {
+				errs[i] = fmt.Errorf("saving %s: %w", path, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// shardPaths returns the paths of the files the cache is saved to.
+func (f *File) shardPaths() []string {
+	if f.shards <= 1 {
+		return []string{f.filePath}
+	}
+
+	paths := make([]string, f.shards)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("%s.%d", f.filePath, i)
+	}
+	return paths
+}
+
+// writeCacheFile atomically replaces the cache file at path with entries,
+// writing to a temp file and renaming it into place. Output is buffered
+// by bufferSize bytes (unbuffered if <= 0) and synced with syncFile
+// before the rename, unless syncFile is nil. If wrap is set, the temp
+// file's writer is passed through it (used by tests to inject failures).
+// On any failure the temp file is removed and the existing file is left
+// untouched.
+func writeCacheFile(path string, entries []*cache.Entry, bufferSize int, syncFile func(*os.File) error, wrap func(io.Writer) io.Writer) error {
 	// Create temporary file for atomic write
-	tmpPath := f.filePath + ".tmp"
+	tmpPath := path + ".tmp"
 	file, err := os.Create(tmpPath)
 	if err != nil {
 		return fmt.Errorf("creating temp file: %w", err)
 	}
 	defer file.Close()
 
-	// Collect all entries
-	ctx := context.Background()
-	keys, err := f.memory.Keys(ctx, "*")
-	if err != nil {
-		return fmt.Errorf("getting keys: %w", err)
+	// Encode to GOB, buffered to reduce write syscalls
+	var w io.Writer = file
+	if wrap != nil {
+		w = wrap(w)
 	}
-
-	entries := make([]*cache.Entry, 0, len(keys))
-	for _, key := range keys {
-		entry, err := f.memory.Get(ctx, key)
-		if err != nil {
-			continue // Skip entries that error
-		}
-		entries = append(entries, entry)
+	var buf *bufio.Writer
+	if bufferSize > 0 {
+		buf = bufio.NewWriterSize(w, bufferSize)
+		w = buf
 	}
 
-	// Encode to GOB
-	encoder := gob.NewEncoder(file)
+	encoder := gob.NewEncoder(w)
 	if err := encoder.Encode(entries); err != nil {
 		os.Remove(tmpPath)
 		return fmt.Errorf("encoding cache: %w", err)
 	}
 
+	// Flush buffered output before syncing
+	if buf != nil {
+		if err := buf.Flush(); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("flushing cache: %w", err)
+		}
+	}
+
 	// Sync to disk
-	if err := file.Sync(); err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("syncing file: %w", err)
+	if syncFile != nil {
+		if err := syncFile(file); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("syncing file: %w", err)
+		}
 	}
 
 	// Close file before rename
@@ -257,7 +950,7 @@ func (f *File) Save() error {
 	}
 
 	// Atomic rename
-	if err := os.Rename(tmpPath, f.filePath); err != nil {
+	if err := os.Rename(tmpPath, path); err != nil {
 		os.Remove(tmpPath)
 		return fmt.Errorf("renaming file: %w", err)
 	}
@@ -269,6 +962,10 @@ func (f *File) Save() error {
 // This is called automatically on startup if LoadOnStart is true,
 // but can also be called manually to reload cache.
 //
+// How reads behave while Load runs depends on FileConfig.ReadsDuringLoad.
+// Unless it is LoadReadBlock, writes made during the load take precedence
+// over the loaded entries for the same keys. Saves wait for the load.
+//
 // Example:
 //
 //	// Manually reload cache from disk
@@ -276,51 +973,156 @@ func (f *File) Save() error {
 //	    log.Printf("Failed to load cache: %v", err)
 //	}
 func (f *File) Load() error {
-	f.mu.Lock()
-	defer f.mu.Unlock()
+	f.loadMu.Lock()
+	defer f.loadMu.Unlock()
+
+	f.loading.Store(true)
+	defer f.loading.Store(false)
+
+	// Blocking reads hold mu exclusively for the whole load; otherwise
+	// holding it shared still keeps Close out until the load is done
+	if f.readsDuringLoad == LoadReadBlock {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+	} else {
+		f.mu.RLock()
+		defer f.mu.RUnlock()
+	}
 
 	if f.closed {
 		return cache.ErrBackendClosed
 	}
 
+	if f.wal != nil {
+		return f.loadWAL()
+	}
+
+	paths := f.shardPaths()
+	if len(paths) == 1 {
+		return f.loadFile(paths[0])
+	}
+
+	// Load the shards in parallel; a shard that fails loses only its
+	// own entries
+	errs := make([]error, len(paths))
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f.loadFile(path); err != nil {
+				errs[i] = fmt.Errorf("loading %s: %w", path, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// loadFile loads the entries saved at path. A missing file is not an
+// error. Must be called with mu held.
+func (f *File) loadFile(path string) error {
+	entries, err := f.readFile(path)
+	if err != nil {
+		return err
+	}
+	f.loadEntries(entries)
+	return nil
+}
+
+// readFile decodes the entries saved at path. A missing file has none.
+func (f *File) readFile(path string) ([]*cache.Entry, error) {
 	// Check if file exists
-	if _, err := os.Stat(f.filePath); os.IsNotExist(err) {
-		return nil // Not an error - file doesn't exist yet
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil // Not an error - file doesn't exist yet
 	}
 
-	file, err := os.Open(f.filePath)
+	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("opening cache file: %w", err)
+		return nil, fmt.Errorf("opening cache file: %w", err)
 	}
 	defer file.Close()
 
 	// Decode from GOB
+	var r io.Reader = file
+	if f.wrapReader != nil {
+		r = f.wrapReader(r)
+	}
 	var entries []*cache.Entry
-	decoder := gob.NewDecoder(file)
+	decoder := gob.NewDecoder(r)
 	if err := decoder.Decode(&entries); err != nil {
-		return fmt.Errorf("decoding cache: %w", err)
+		return nil, fmt.Errorf("decoding cache: %w", err)
 	}
+	return entries, nil
+}
 
-	// Load entries into memory
-	ctx := context.Background()
+// loadEntries stores loaded entries in memory, skipping expired ones.
+// Entries keep the CreatedAt they were saved with, so their age (e.g. for
+// cache.SyncConfig.SyncOnStartMaxAge) includes the time before the load.
+// Must be called with mu held.
+func (f *File) loadEntries(entries []*cache.Entry) {
 	for _, entry := range entries {
 		// Skip expired entries
-		if entry.IsExpired() {
+		ttl, ok := loadTTL(entry, f.clockSkewGrace)
+		if !ok {
 			continue
 		}
 
-		// Calculate remaining TTL
-		var ttl time.Duration
-		if !entry.ExpiresAt.IsZero() {
-			ttl = time.Until(entry.ExpiresAt)
-			if ttl < 0 {
-				continue // Expired
-			}
-		}
+		// Unless reads block, keep entries written since the load started
+		f.memory.load(entry, ttl, f.readsDuringLoad == LoadReadBlock)
+	}
+}
+
+// loadTTL returns the TTL to load entry with, or false if it has expired.
+// The entry's ExpiresAt was set by the clock of the host that saved it, so
+// the time remaining is capped at the entry's TTL and extended by grace
+// (see FileConfig.ClockSkewGrace).
+func loadTTL(entry *cache.Entry, grace time.Duration) (time.Duration, bool) {
+	if entry.ExpiresAt.IsZero() {
+		return 0, true
+	}
+
+	remaining := time.Until(entry.ExpiresAt)
+	if entry.TTL > 0 && remaining > entry.TTL {
+		remaining = entry.TTL
+	}
+	remaining += grace
+	return remaining, remaining > 0
+}
 
-		_ = f.memory.Set(ctx, entry.Key, entry.Value, ttl)
+// Loading reports whether Load is running.
+func (f *File) Loading() bool {
+	return f.loading.Load()
+}
+
+// Ping checks that the cache file's directory exists and is writable, by
+// creating and removing an empty probe file in it, so a failing disk is
+// reported before the next save. See cache.Pinger.
+func (f *File) Ping(ctx context.Context) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.closed {
+		return cache.NewError("Ping", "", cache.ErrBackendClosed)
 	}
 
+	dir := filepath.Dir(f.filePath)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return cache.NewError("Ping", "", err)
+	}
+	if !info.IsDir() {
+		return cache.NewError("Ping", "", fmt.Errorf("%s is not a directory", dir))
+	}
+
+	probe, err := os.CreateTemp(dir, ".hue-cache-ping-*")
+	if err != nil {
+		return cache.NewError("Ping", "", fmt.Errorf("directory not writable: %w", err))
+	}
+	probe.Close()
+	if err := os.Remove(probe.Name()); err != nil {
+		return cache.NewError("Ping", "", err)
+	}
 	return nil
 }
 
@@ -334,14 +1136,19 @@ func (f *File) Close() error {
 	}
 	f.mu.RUnlock()
 
-	// Stop auto-save ticker
+	// Stop auto-save ticker and compaction
 	if f.saveTicker != nil {
 		f.saveTicker.Stop()
-		close(f.saveStop) // Signal autoSaveLoop to stop
+	}
+	if f.saveTicker != nil || f.wal != nil {
+		close(f.saveStop) // Signal autoSaveLoop and compactLoop to stop
 	}
 
 	// Save final state (before marking as closed)
 	saveErr := f.Save()
+	if saveErr != nil {
+		f.saveFailed(saveErr)
+	}
 
 	// Mark as closed
 	f.mu.Lock()
@@ -350,6 +1157,9 @@ func (f *File) Close() error {
 
 	// Close memory backend
 	closeErr := f.memory.Close()
+	if f.wal != nil {
+		closeErr = errors.Join(closeErr, f.wal.close())
+	}
 
 	// Return first error encountered
 	if saveErr != nil {
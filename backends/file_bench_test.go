@@ -0,0 +1,49 @@
+package backends
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// benchmarkFileSave measures Save for a 10k-entry cache with the given
+// write buffer size.
+func benchmarkFileSave(b *testing.B, writeBufferSize int) {
+	config := &FileConfig{
+		FilePath:         filepath.Join(b.TempDir(), "bench.gob"),
+		AutoSaveInterval: 0,
+		LoadOnStart:      false,
+		MemoryConfig:     DefaultMemoryConfig(),
+		WriteBufferSize:  writeBufferSize,
+	}
+
+	backend, err := NewFile(config)
+	if err != nil {
+		b.Fatalf("NewFile() failed: %v", err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+	value := []byte(`{"id":"light","on":{"on":true},"dimming":{"brightness":100}}`)
+	for i := 0; i < 10000; i++ {
+		_ = backend.Set(ctx, fmt.Sprintf("light:%d", i), value, 0)
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if err := backend.Save(); err != nil {
+			b.Fatalf("Save() failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkFile_Save10k_Buffered(b *testing.B) {
+	benchmarkFileSave(b, defaultWriteBufferSize)
+}
+
+func BenchmarkFile_Save10k_Unbuffered(b *testing.B) {
+	benchmarkFileSave(b, -1)
+}
@@ -1,12 +1,20 @@
 package backends
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	cache "github.com/rmrfslashbin/hue-cache"
 )
 
 func TestFile_BasicOperations(t *testing.T) {
@@ -184,6 +192,335 @@ func TestFile_AutoSave(t *testing.T) {
 	}
 }
 
+// makeUnwritable stops saves to dir from succeeding. It makes dir
+// read-only; root ignores permissions, so then it replaces dir with a
+// regular file instead.
+func makeUnwritable(t *testing.T, dir string) {
+	t.Helper()
+	if os.Geteuid() == 0 {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dir, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if err := os.Chmod(dir, 0500); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0700) })
+}
+
+func TestFile_OnSaveError(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	filePath := filepath.Join(dir, "cache.gob")
+
+	saveErrs := make(chan error, 16)
+	backend, err := NewFile(&FileConfig{
+		FilePath:         filePath,
+		AutoSaveInterval: 20 * time.Millisecond,
+		MemoryConfig:     DefaultMemoryConfig(),
+		OnSaveError:      func(err error) { saveErrs <- err },
+	})
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	backend.Set(ctx, "test:1", []byte("value1"), 0)
+	if err := backend.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	saved := backend.SaveStatus()
+	if saved.LastSaveTime.IsZero() || saved.LastSaveError != nil {
+		t.Errorf("SaveStatus() after Save() = %+v, want a save time and no error", saved)
+	}
+
+	makeUnwritable(t, dir)
+	backend.Set(ctx, "test:2", []byte("value2"), 0)
+
+	// Auto-save failures reach the callback
+	select {
+	case err := <-saveErrs:
+		if err == nil {
+			t.Error("OnSaveError called with nil")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnSaveError was not called for a failed auto-save")
+	}
+
+	status := backend.SaveStatus()
+	if status.LastSaveError == nil {
+		t.Error("SaveStatus().LastSaveError = nil after a failed save")
+	}
+	if !status.LastSaveTime.Equal(saved.LastSaveTime) {
+		t.Errorf("LastSaveTime = %v, want the last successful save %v", status.LastSaveTime, saved.LastSaveTime)
+	}
+
+	// So does the final save in Close
+	for len(saveErrs) > 0 {
+		<-saveErrs
+	}
+	if err := backend.Close(); err == nil {
+		t.Error("Close() should report the failed final save")
+	}
+	select {
+	case <-saveErrs:
+	default:
+		t.Error("OnSaveError was not called for the failed final save")
+	}
+}
+
+// errDiskFull is returned by diskFullWriter.
+var errDiskFull = errors.New("no space left on device")
+
+// diskFullWriter accepts limit bytes, then fails like a full disk.
+type diskFullWriter struct {
+	w     io.Writer
+	limit int
+}
+
+func (d *diskFullWriter) Write(p []byte) (int, error) {
+	if len(p) > d.limit {
+		n, _ := d.w.Write(p[:d.limit])
+		d.limit = 0
+		return n, errDiskFull
+	}
+	d.limit -= len(p)
+	return d.w.Write(p)
+}
+
+func TestFile_DiskFullMidSave(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "cache.gob")
+	backend, err := NewFile(&FileConfig{FilePath: filePath})
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("value1"), 0)
+	if err := backend.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	before, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The disk fills partway through the next save
+	for i := 0; i < 100; i++ {
+		backend.Set(ctx, fmt.Sprintf("light:%d", i), bytes.Repeat([]byte("x"), 100), 0)
+	}
+	backend.wrapWriter = func(w io.Writer) io.Writer {
+		return &diskFullWriter{w: w, limit: 512}
+	}
+
+	if err := backend.Save(); !errors.Is(err, errDiskFull) {
+		t.Fatalf("Save() error = %v, want errDiskFull", err)
+	}
+
+	after, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("cache file lost: %v", err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("failed save modified the existing cache file")
+	}
+	if _, err := os.Stat(filePath + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temp file left behind: %v", err)
+	}
+
+	status := backend.SaveStatus()
+	if !errors.Is(status.LastSaveError, errDiskFull) || status.ConsecutiveFailures != 1 {
+		t.Errorf("SaveStatus() = %+v, want the disk-full error and 1 failure", status)
+	}
+
+	// The cache itself is unaffected, and recovers once space frees up
+	if _, err := backend.Get(ctx, "light:99"); err != nil {
+		t.Errorf("Get() after failed save: %v", err)
+	}
+	backend.wrapWriter = nil
+	if err := backend.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if status := backend.SaveStatus(); status.LastSaveError != nil || status.ConsecutiveFailures != 0 {
+		t.Errorf("SaveStatus() after recovery = %+v, want no error", status)
+	}
+}
+
+// gatedReader blocks its first Read until gate is closed, like a slow
+// disk.
+type gatedReader struct {
+	r    io.Reader
+	gate chan struct{}
+}
+
+func (g *gatedReader) Read(p []byte) (int, error) {
+	<-g.gate
+	return g.r.Read(p)
+}
+
+func TestFile_ReadsDuringLoad(t *testing.T) {
+	tests := []struct {
+		mode    LoadReadMode
+		wantErr error
+	}{
+		{LoadReadPartial, cache.ErrNotFound},
+		{LoadReadUnavailable, cache.ErrLoading},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "cache.gob")
+			ctx := context.Background()
+
+			saved, err := NewFile(&FileConfig{FilePath: filePath})
+			if err != nil {
+				t.Fatalf("NewFile() failed: %v", err)
+			}
+			saved.Set(ctx, "light:1", []byte("saved"), 0)
+			saved.Set(ctx, "light:2", []byte("saved"), 0)
+			if err := saved.Close(); err != nil {
+				t.Fatalf("Close() failed: %v", err)
+			}
+
+			backend, err := NewFile(&FileConfig{FilePath: filePath, ReadsDuringLoad: tt.mode})
+			if err != nil {
+				t.Fatalf("NewFile() failed: %v", err)
+			}
+			defer backend.Close()
+
+			// NewFile loads in the background in these modes
+			for backend.Loading() {
+				time.Sleep(time.Millisecond)
+			}
+
+			// Reload from a disk that stalls until the gate opens
+			gate := make(chan struct{})
+			backend.wrapReader = func(r io.Reader) io.Reader {
+				return &gatedReader{r: r, gate: gate}
+			}
+			backend.Clear(ctx)
+			loaded := make(chan error, 1)
+			go func() { loaded <- backend.Load() }()
+			for !backend.Loading() {
+				time.Sleep(time.Millisecond)
+			}
+
+			// Reads return promptly instead of waiting for the load
+			got := make(chan error, 1)
+			go func() {
+				_, err := backend.Get(ctx, "light:1")
+				got <- err
+			}()
+			select {
+			case err := <-got:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Get() during load error = %v, want %v", err, tt.wantErr)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Get() blocked on the running Load")
+			}
+
+			// Writes made during the load win over the loaded entries
+			if err := backend.Set(ctx, "light:2", []byte("fresh"), 0); err != nil {
+				t.Fatalf("Set() during load failed: %v", err)
+			}
+
+			close(gate)
+			if err := <-loaded; err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+
+			for key, want := range map[string]string{"light:1": "saved", "light:2": "fresh"} {
+				entry, err := backend.Get(ctx, key)
+				if err != nil {
+					t.Fatalf("Get(%s) after load failed: %v", key, err)
+				}
+				if string(entry.Value) != want {
+					t.Errorf("Get(%s) = %q, want %q", key, entry.Value, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFile_MaxAutoSaveFailures(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	var failures atomic.Int32
+	backend, err := NewFile(&FileConfig{
+		FilePath:            filepath.Join(dir, "cache.gob"),
+		AutoSaveInterval:    10 * time.Millisecond,
+		MaxAutoSaveFailures: 3,
+		OnSaveError:         func(error) { failures.Add(1) },
+	})
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	defer backend.Close()
+
+	// Auto-save only saves changes
+	backend.Set(context.Background(), "test:1", []byte("value1"), 0)
+	makeUnwritable(t, dir)
+
+	deadline := time.Now().Add(time.Second)
+	for !backend.SaveStatus().AutoSaveStopped {
+		if time.Now().After(deadline) {
+			t.Fatal("auto-save did not stop after repeated failures")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// No more attempts once stopped
+	time.Sleep(50 * time.Millisecond)
+	if n := failures.Load(); n != 3 {
+		t.Errorf("OnSaveError called %d times, want 3", n)
+	}
+	if n := backend.SaveStatus().ConsecutiveFailures; n != 3 {
+		t.Errorf("ConsecutiveFailures = %d, want 3", n)
+	}
+}
+
+// TestFile_SaveConcurrentWrites checks each saved file is a point-in-time
+// snapshot; see TestMemory_SnapshotConcurrentWrites.
+func TestFile_SaveConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "cache.gob")
+	backend, err := NewFile(&FileConfig{FilePath: filePath})
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	defer backend.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 5000 {
+			backend.Set(ctx, fmt.Sprintf("light:%d", i), []byte("value"), 0)
+		}
+	}()
+
+	for saves := 0; ; saves++ {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		if err := backend.Save(); err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+		entries, err := backend.readFile(filePath)
+		if err != nil {
+			t.Fatalf("reading saved file: %v", err)
+		}
+		if gap := firstGap(entries); gap >= 0 {
+			t.Fatalf("save %d of %d entries is missing light:%d", saves, len(entries), gap)
+		}
+	}
+}
+
 func TestFile_TTLPersistence(t *testing.T) {
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "ttl.gob")
@@ -232,6 +569,53 @@ func TestFile_TTLPersistence(t *testing.T) {
 	}
 }
 
+func TestFile_ClockSkewGrace(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "skew.gob")
+	now := time.Now()
+
+	// Entries saved by hosts whose clocks run an hour ahead and ten
+	// seconds behind, and one that expired long ago
+	entries := []*cache.Entry{
+		{Key: "light:ahead", Value: []byte("ahead"), TTL: time.Minute, ExpiresAt: now.Add(time.Hour)},
+		{Key: "light:behind", Value: []byte("behind"), TTL: time.Minute, ExpiresAt: now.Add(-10 * time.Second)},
+		{Key: "light:gone", Value: []byte("gone"), TTL: time.Minute, ExpiresAt: now.Add(-time.Hour)},
+	}
+	if err := writeCacheFile(filePath, entries, 0, nil, nil); err != nil {
+		t.Fatalf("writeCacheFile() failed: %v", err)
+	}
+
+	backend, err := NewFile(&FileConfig{
+		FilePath:       filePath,
+		LoadOnStart:    true,
+		ClockSkewGrace: 30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+	ahead, err := backend.Get(ctx, "light:ahead")
+	if err != nil {
+		t.Fatalf("Get(light:ahead) failed: %v", err)
+	}
+	if remaining := ahead.TimeUntilExpiry(); remaining > time.Minute+30*time.Second {
+		t.Errorf("light:ahead expires in %v, want at most TTL + grace", remaining)
+	}
+
+	behind, err := backend.Get(ctx, "light:behind")
+	if err != nil {
+		t.Fatalf("Get(light:behind) failed: %v", err)
+	}
+	if remaining := behind.TimeUntilExpiry(); remaining > 20*time.Second {
+		t.Errorf("light:behind expires in %v, want the rest of the grace", remaining)
+	}
+
+	if _, err := backend.Get(ctx, "light:gone"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Get(light:gone) error = %v, want ErrNotFound", err)
+	}
+}
+
 func TestFile_ClearAndSave(t *testing.T) {
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "clear.gob")
@@ -439,6 +823,77 @@ func TestFile_LargeCache(t *testing.T) {
 	}
 }
 
+func TestFile_Shards(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "sharded.gob")
+	ctx := context.Background()
+	config := func() *FileConfig {
+		return &FileConfig{FilePath: filePath, LoadOnStart: true, Shards: 4}
+	}
+
+	backend, err := NewFile(config())
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	const total = 5000
+	perShard := make([]int, 4)
+	for i := 0; i < total; i++ {
+		key := fmt.Sprintf("light:%d", i)
+		backend.Set(ctx, key, []byte(fmt.Sprintf("value%d", i)), 0)
+		perShard[keyHash(key)%4]++
+	}
+	if err := backend.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := os.Stat(fmt.Sprintf("%s.%d", filePath, i)); err != nil {
+			t.Fatalf("shard %d not saved: %v", i, err)
+		}
+	}
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("unsharded file exists: %v", err)
+	}
+
+	// Round trip
+	reopened, err := NewFile(config())
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	if stats, _ := reopened.Stats(ctx); stats.Entries != total {
+		t.Errorf("loaded %d entries, want %d", stats.Entries, total)
+	}
+	entry, err := reopened.Get(ctx, "light:1234")
+	if err != nil || string(entry.Value) != "value1234" {
+		t.Errorf("Get(light:1234) = %v, %v", entry, err)
+	}
+	if err := reopened.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	// A deleted shard and a corrupt one lose only their own entries
+	if err := os.Remove(filePath + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath+".2", []byte("not gob"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	partial, err := NewFile(config())
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	defer partial.Close()
+	want := int64(perShard[0] + perShard[3])
+	if stats, _ := partial.Stats(ctx); stats.Entries != want {
+		t.Errorf("loaded %d entries, want %d from the intact shards", stats.Entries, want)
+	}
+
+	err = partial.Load()
+	if err == nil || !strings.Contains(err.Error(), filePath+".2") {
+		t.Errorf("Load() error = %v, want the corrupt shard reported", err)
+	}
+}
+
 func TestFile_DefaultConfig(t *testing.T) {
 	config := DefaultFileConfig()
 
@@ -458,3 +913,438 @@ func TestFile_DefaultConfig(t *testing.T) {
 		t.Error("Expected non-nil MemoryConfig")
 	}
 }
+
+func TestFile_LogsLoadFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "corrupt.gob")
+
+	// Write a file that isn't valid gob
+	if err := os.WriteFile(filePath, []byte("not a gob file"), 0644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	config := &FileConfig{
+		FilePath:         filePath,
+		AutoSaveInterval: 0,
+		LoadOnStart:      true,
+		Logger:           cache.NewSlogLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+	}
+
+	backend, err := NewFile(config)
+	if err != nil {
+		t.Fatalf("NewFile() should succeed with a corrupt file: %v", err)
+	}
+	defer backend.Close()
+
+	if !strings.Contains(buf.String(), "failed to load cache file") {
+		t.Errorf("Expected load failure to be logged, got: %q", buf.String())
+	}
+
+	// Memory backend should inherit the file logger
+	if config.MemoryConfig.Logger != config.Logger {
+		t.Error("Expected MemoryConfig to inherit the file backend's logger")
+	}
+}
+
+func TestFile_WriteBufferSizes(t *testing.T) {
+	ctx := context.Background()
+
+	for _, size := range []int{-1, 0, 16, defaultWriteBufferSize} {
+		t.Run(fmt.Sprintf("size=%d", size), func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "cache.gob")
+			config := &FileConfig{
+				FilePath:         filePath,
+				AutoSaveInterval: 0,
+				LoadOnStart:      false,
+				WriteBufferSize:  size,
+			}
+
+			backend, err := NewFile(config)
+			if err != nil {
+				t.Fatalf("NewFile() failed: %v", err)
+			}
+
+			for i := 0; i < 100; i++ {
+				backend.Set(ctx, fmt.Sprintf("light:%d", i), []byte(fmt.Sprintf("value%d", i)), 0)
+			}
+
+			if err := backend.Close(); err != nil {
+				t.Fatalf("Close() failed: %v", err)
+			}
+
+			// Reload and verify every entry survived
+			loaded, err := NewFile(&FileConfig{FilePath: filePath, LoadOnStart: true})
+			if err != nil {
+				t.Fatalf("NewFile() reload failed: %v", err)
+			}
+			defer loaded.Close()
+
+			keys, _ := loaded.Keys(ctx, "light:*")
+			if len(keys) != 100 {
+				t.Errorf("Loaded %d entries, want 100", len(keys))
+			}
+		})
+	}
+}
+
+func TestFile_DisableFsync(t *testing.T) {
+	ctx := context.Background()
+
+	for _, disable := range []bool{false, true} {
+		t.Run(fmt.Sprintf("DisableFsync=%v", disable), func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "cache.gob")
+			backend, err := NewFile(&FileConfig{
+				FilePath:     filePath,
+				DisableFsync: disable,
+			})
+			if err != nil {
+				t.Fatalf("NewFile() failed: %v", err)
+			}
+
+			// Count fsync calls via an injected syncer
+			syncs := 0
+			backend.syncFile = func(file *os.File) error {
+				syncs++
+				return file.Sync()
+			}
+
+			backend.Set(ctx, "light:1", []byte("value1"), 0)
+			if err := backend.Save(); err != nil {
+				t.Fatalf("Save() failed: %v", err)
+			}
+			if err := backend.Close(); err != nil {
+				t.Fatalf("Close() failed: %v", err)
+			}
+
+			wantSyncs := 2 // Save + final save on Close
+			if disable {
+				wantSyncs = 0
+			}
+			if syncs != wantSyncs {
+				t.Errorf("fsync called %d times, want %d", syncs, wantSyncs)
+			}
+
+			// File must be loadable either way
+			loaded, err := NewFile(&FileConfig{FilePath: filePath, LoadOnStart: true})
+			if err != nil {
+				t.Fatalf("NewFile() reload failed: %v", err)
+			}
+			defer loaded.Close()
+
+			entry, err := loaded.Get(ctx, "light:1")
+			if err != nil || string(entry.Value) != "value1" {
+				t.Errorf("Reloaded entry = %v, %v; want value1", entry, err)
+			}
+		})
+	}
+}
+
+func TestFile_LatencyStats(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewFile(&FileConfig{
+		FilePath:     filepath.Join(t.TempDir(), "cache.gob"),
+		TrackLatency: true,
+		WAL:          true,
+	})
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	defer backend.Close()
+
+	backend.Set(ctx, "light:1", []byte("value1"), 0)
+	backend.Set(ctx, "light:2", []byte("value2"), 0)
+	backend.Get(ctx, "light:1")
+	backend.Delete(ctx, "light:2")
+	backend.Keys(ctx, "*")
+
+	stats := backend.LatencyStats()
+	if stats.Get.Count != 1 || stats.Set.Count != 2 || stats.Delete.Count != 1 || stats.Keys.Count != 1 {
+		t.Errorf("LatencyStats() counts = %d get, %d set, %d delete, %d keys; want 1, 2, 1, 1",
+			stats.Get.Count, stats.Set.Count, stats.Delete.Count, stats.Keys.Count)
+	}
+	if stats.Set.Min < 0 || stats.Set.Min > stats.Set.Avg || stats.Set.Avg > stats.Set.Max {
+		t.Errorf("Set latency = %+v", stats.Set)
+	}
+
+	// Only the File's own operations are timed
+	if memory := backend.memory.LatencyStats(); memory != (LatencyStats{}) {
+		t.Errorf("memory LatencyStats() = %+v, want zeros", memory)
+	}
+
+	if err := backend.ResetStats(ctx); err != nil {
+		t.Fatalf("ResetStats() failed: %v", err)
+	}
+	if stats := backend.LatencyStats(); stats != (LatencyStats{}) {
+		t.Errorf("LatencyStats() after reset = %+v, want zeros", stats)
+	}
+}
+
+func TestFile_MaxEntrySize(t *testing.T) {
+	tmpDir := t.TempDir()
+	memConfig := DefaultMemoryConfig()
+	memConfig.MaxEntrySize = 8
+	config := &FileConfig{
+		FilePath:     filepath.Join(tmpDir, "test.gob"),
+		MemoryConfig: memConfig,
+	}
+
+	backend, err := NewFile(config)
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+
+	if err := backend.Set(ctx, "scene:huge", []byte("too large value"), 0); !errors.Is(err, cache.ErrValueTooLarge) {
+		t.Errorf("Set() error = %v, want ErrValueTooLarge", err)
+	}
+	if err := backend.Set(ctx, "light:1", []byte("small"), 0); err != nil {
+		t.Errorf("Set() failed: %v", err)
+	}
+}
+
+func TestFirstAutoSave(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	if got := firstAutoSave(interval, 0); got != interval {
+		t.Errorf("firstAutoSave(%v, 0) = %v, want %v", interval, got, interval)
+	}
+
+	spread := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		got := firstAutoSave(interval, 10*time.Millisecond)
+		if got < 90*time.Millisecond || got > 110*time.Millisecond {
+			t.Fatalf("firstAutoSave() = %v, want within 100ms±10ms", got)
+		}
+		spread[got] = true
+	}
+	if len(spread) < 2 {
+		t.Error("firstAutoSave() returned the same delay every time")
+	}
+
+	// Jitter beyond the interval is capped, and the delay stays positive
+	for i := 0; i < 100; i++ {
+		if got := firstAutoSave(interval, time.Hour); got <= 0 || got > 2*interval {
+			t.Fatalf("firstAutoSave() = %v, want within (0, %v]", got, 2*interval)
+		}
+	}
+}
+
+func TestFile_AutoSaveJitter(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "jitter.gob")
+	backend, err := NewFile(&FileConfig{
+		FilePath:         filePath,
+		AutoSaveInterval: 20 * time.Millisecond,
+		AutoSaveJitter:   10 * time.Millisecond,
+		MemoryConfig:     DefaultMemoryConfig(),
+	})
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	defer backend.Close()
+
+	// Auto-save keeps running after the jittered first save
+	ctx := context.Background()
+	var saves int
+	var last time.Time
+	deadline := time.Now().Add(time.Second)
+	backend.Set(ctx, "test:1", []byte("value1"), 0)
+	for saves < 2 && time.Now().Before(deadline) {
+		if status := backend.SaveStatus(); status.LastSaveTime.After(last) {
+			last = status.LastSaveTime
+			saves++
+			backend.Set(ctx, "test:1", []byte("value1"), 0)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	if saves < 2 {
+		t.Errorf("saw %d auto-saves in a second, want at least 2", saves)
+	}
+}
+
+func TestFile_AutoSaveSkipsClean(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "clean.gob")
+	const interval = 20 * time.Millisecond
+	backend, err := NewFile(&FileConfig{
+		FilePath:         filePath,
+		AutoSaveInterval: interval,
+		MemoryConfig:     DefaultMemoryConfig(),
+	})
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+	backend.Set(ctx, "test:1", []byte("value1"), 0)
+	if !backend.Dirty() {
+		t.Fatal("Dirty() = false after Set")
+	}
+
+	// Wait for the auto-save of the write
+	deadline := time.Now().Add(time.Second)
+	for backend.Dirty() || backend.SaveStatus().LastSaveTime.IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatal("auto-save did not save the write")
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	before, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatalf("Stat() failed: %v", err)
+	}
+	saved := backend.SaveStatus().LastSaveTime
+
+	// Two clean intervals leave the file alone
+	time.Sleep(3 * interval)
+	after, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatalf("Stat() failed: %v", err)
+	}
+	if !after.ModTime().Equal(before.ModTime()) || !backend.SaveStatus().LastSaveTime.Equal(saved) {
+		t.Error("auto-save rewrote a clean cache")
+	}
+
+	// Reads don't dirty the cache, but a manual Save still writes
+	backend.Get(ctx, "test:1")
+	if backend.Dirty() {
+		t.Error("Dirty() = true after Get")
+	}
+	if err := backend.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if !backend.SaveStatus().LastSaveTime.After(saved) {
+		t.Error("Save() skipped a clean cache")
+	}
+}
+
+func TestFile_DirtyAfterFailedSave(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	backend, err := NewFile(&FileConfig{
+		FilePath:     filepath.Join(dir, "cache.gob"),
+		MemoryConfig: DefaultMemoryConfig(),
+	})
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+	if applied, _ := backend.SetIf(ctx, "test:1", []byte("value1"), 0, func(*cache.Entry) bool { return false }); !applied || !backend.Dirty() {
+		t.Fatalf("SetIf() applied = %v, Dirty() = %v; want true, true", applied, backend.Dirty())
+	}
+	if err := backend.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if applied, _ := backend.SetIf(ctx, "test:1", []byte("value2"), 0, func(*cache.Entry) bool { return false }); applied || backend.Dirty() {
+		t.Errorf("rejected SetIf() applied = %v, Dirty() = %v; want false, false", applied, backend.Dirty())
+	}
+
+	backend.Delete(ctx, "test:1")
+	makeUnwritable(t, dir)
+	if err := backend.Save(); err == nil {
+		t.Fatal("Save() to an unwritable directory succeeded")
+	}
+	if !backend.Dirty() {
+		t.Error("Dirty() = false after a failed save")
+	}
+}
+
+func TestFile_Peek(t *testing.T) {
+	backend, err := NewFile(&FileConfig{
+		FilePath:     filepath.Join(t.TempDir(), "test.gob"),
+		MemoryConfig: DefaultMemoryConfig(),
+	})
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("value"), 0)
+
+	entry, err := backend.Peek(ctx, "light:1")
+	if err != nil {
+		t.Fatalf("Peek() failed: %v", err)
+	}
+	if string(entry.Value) != "value" {
+		t.Errorf("Peek() value = %q, want value", entry.Value)
+	}
+
+	// The peek is invisible to the entry and the statistics
+	if meta, _ := backend.GetMeta(ctx, "light:1"); meta.Hits != 0 {
+		t.Errorf("after Peek() hits = %d, want 0", meta.Hits)
+	}
+	if stats, _ := backend.Stats(ctx); stats.Hits != 0 || stats.Misses != 0 {
+		t.Errorf("Stats = %d hits, %d misses; want 0, 0", stats.Hits, stats.Misses)
+	}
+}
+
+func TestFile_TagsPersist(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.gob")
+	ctx := context.Background()
+
+	backend, err := NewFile(&FileConfig{FilePath: filePath, MemoryConfig: DefaultMemoryConfig()})
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	backend.SetWithTags(ctx, "light:1", []byte("one"), 0, []string{"floor-2"})
+	backend.SetWithTags(ctx, "room:1", []byte("room"), time.Hour, []string{"floor-2"})
+	backend.Set(ctx, "light:2", []byte("two"), 0)
+	if err := backend.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	// The snapshot keeps the tags, and the loaded entries are indexed
+	reloaded, err := NewFile(&FileConfig{FilePath: filePath, LoadOnStart: true, MemoryConfig: DefaultMemoryConfig()})
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	defer reloaded.Close()
+
+	if entry, err := reloaded.Get(ctx, "room:1"); err != nil || !entry.HasTag("floor-2") {
+		t.Errorf("Get(room:1) = %v, %v; want tagged floor-2", entry, err)
+	}
+	if deleted, err := reloaded.DeleteTag(ctx, "floor-2"); err != nil || deleted != 2 {
+		t.Errorf("DeleteTag() = %d, %v; want 2", deleted, err)
+	}
+	if keys, _ := reloaded.Keys(ctx, "*"); len(keys) != 1 || keys[0] != "light:2" {
+		t.Errorf("Keys() after DeleteTag() = %v, want [light:2]", keys)
+	}
+}
+
+func TestFile_Ping(t *testing.T) {
+	var _ cache.Pinger = (*File)(nil)
+	var _ cache.Pinger = (*Memory)(nil)
+	var _ cache.Pinger = (*Bolt)(nil)
+	var _ cache.Pinger = (*Memcached)(nil)
+
+	dir := filepath.Join(t.TempDir(), "cache")
+	backend, err := NewFile(&FileConfig{
+		FilePath:     filepath.Join(dir, "test.gob"),
+		MemoryConfig: DefaultMemoryConfig(),
+	})
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	defer backend.Close()
+
+	ctx := context.Background()
+	if err := backend.Ping(ctx); err != nil {
+		t.Errorf("Ping() failed: %v", err)
+	}
+
+	// The probe file is removed
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("directory after Ping() holds %d files, want 0", len(files))
+	}
+
+	// A vanished directory fails the check
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("RemoveAll() failed: %v", err)
+	}
+	if err := backend.Ping(ctx); err == nil {
+		t.Error("Ping() succeeded without a cache directory")
+	}
+}
@@ -0,0 +1,388 @@
+package backends
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+
+	cache "github.com/rmrfslashbin/hue-cache"
+)
+
+// DefaultWALCompactOps is the number of records after which the File
+// backend compacts its write-ahead log when FileConfig sets neither
+// WALCompactOps nor WALCompactSize.
+const DefaultWALCompactOps = 10000
+
+// walOp is the kind of write a log record replays.
+type walOp byte
+
+const (
+	walSet walOp = iota + 1
+	walDelete
+	walClear
+	walDeletePattern
+	walDeleteTag
+)
+
+// walRecord is one logged write.
+type walRecord struct {
+	op    walOp
+	key   string       // the pattern for DeletePattern, the tag for DeleteTag
+	entry *cache.Entry // Set only
+}
+
+// writeAheadLog appends records to the File backend's log. Each record is
+// framed as a uvarint payload length, the payload, and the payload's
+// CRC-32, so a record torn by a crash is detected and ends replay. Its
+// methods must be called with File.walMu held.
+type writeAheadLog struct {
+	path string
+	file *os.File
+	size int64
+	ops  int
+}
+
+// openWAL opens the log at path for appending, creating it if needed. A
+// torn record at the end of an existing log is truncated away, so new
+// records aren't appended after it.
+func openWAL(path string) (*writeAheadLog, error) {
+	records, valid, err := readWAL(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("opening log: %w", err)
+	}
+	if info.Size() > valid {
+		if err := file.Truncate(valid); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("truncating torn log record: %w", err)
+		}
+	}
+
+	return &writeAheadLog{path: path, file: file, size: valid, ops: len(records)}, nil
+}
+
+// append writes record to the end of the log.
+func (w *writeAheadLog) append(record walRecord) error {
+	payload, err := encodeWALRecord(record)
+	if err != nil {
+		return err
+	}
+
+	frame := binary.AppendUvarint(nil, uint64(len(payload)))
+	frame = append(frame, payload...)
+	frame = binary.LittleEndian.AppendUint32(frame, crc32.ChecksumIEEE(payload))
+
+	n, err := w.file.Write(frame)
+	w.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("appending to log: %w", err)
+	}
+	w.ops++
+	return nil
+}
+
+// full reports whether the log has reached either compaction threshold.
+// A threshold of 0 is ignored.
+func (w *writeAheadLog) full(ops int, size int64) bool {
+	return (ops > 0 && w.ops >= ops) || (size > 0 && w.size >= size)
+}
+
+// reset empties the log, once a snapshot holds everything it recorded.
+func (w *writeAheadLog) reset(syncFile func(*os.File) error) error {
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("truncating log: %w", err)
+	}
+	w.size, w.ops = 0, 0
+	return w.sync(syncFile)
+}
+
+// sync flushes the log to disk with syncFile, unless it is nil.
+func (w *writeAheadLog) sync(syncFile func(*os.File) error) error {
+	if syncFile == nil {
+		return nil
+	}
+	if err := syncFile(w.file); err != nil {
+		return fmt.Errorf("syncing log: %w", err)
+	}
+	return nil
+}
+
+func (w *writeAheadLog) close() error {
+	return w.file.Close()
+}
+
+// readWAL reads the records of the log at path, stopping at the first torn
+// or corrupt one, and returns them with the size of the valid prefix. A
+// missing log has no records.
+func readWAL(path string) ([]walRecord, int64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("opening log: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, fmt.Errorf("opening log: %w", err)
+	}
+
+	var records []walRecord
+	var valid int64
+	r := bufio.NewReader(file)
+	for {
+		record, n, err := readWALRecord(r, info.Size()-valid)
+		if err != nil {
+			// io.EOF at a record boundary is the end of the log; anything
+			// else is a record torn by a crash, which ends it too
+			return records, valid, nil
+		}
+		records = append(records, record)
+		valid += n
+	}
+}
+
+// readWALRecord reads one record of at most limit bytes, returning it and
+// its size.
+func readWALRecord(r *bufio.Reader, limit int64) (walRecord, int64, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return walRecord{}, 0, err
+	}
+	header := int64(len(binary.AppendUvarint(nil, length)))
+	if limit < header+4 || length > uint64(limit-header-4) {
+		return walRecord{}, 0, io.ErrUnexpectedEOF
+	}
+
+	frame := make([]byte, length+4)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return walRecord{}, 0, err
+	}
+	payload := frame[:length]
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(frame[length:]) {
+		return walRecord{}, 0, errors.New("log record checksum mismatch")
+	}
+
+	record, err := decodeWALRecord(payload)
+	if err != nil {
+		return walRecord{}, 0, err
+	}
+	return record, header + int64(len(frame)), nil
+}
+
+// encodeWALRecord encodes a record's payload: the op, then the gob-encoded
+// entry for a Set, the key for a Delete, the pattern for a
+// DeletePattern, or the tag for a DeleteTag.
+func encodeWALRecord(record walRecord) ([]byte, error) {
+	payload := []byte{byte(record.op)}
+	switch record.op {
+	case walSet:
+		data, err := encodeEntry(record.entry)
+		if err != nil {
+			return nil, err
+		}
+		return append(payload, data...), nil
+	case walDelete, walDeletePattern, walDeleteTag:
+		return append(payload, record.key...), nil
+	default:
+		return payload, nil
+	}
+}
+
+func decodeWALRecord(payload []byte) (walRecord, error) {
+	if len(payload) == 0 {
+		return walRecord{}, errors.New("empty log record")
+	}
+
+	record := walRecord{op: walOp(payload[0])}
+	switch record.op {
+	case walSet:
+		entry, err := decodeEntry(payload[1:])
+		if err != nil {
+			return walRecord{}, err
+		}
+		record.key, record.entry = entry.Key, entry
+	case walDelete, walDeletePattern, walDeleteTag:
+		record.key = string(payload[1:])
+	case walClear:
+	default:
+		return walRecord{}, fmt.Errorf("unknown log record op %d", record.op)
+	}
+	return record, nil
+}
+
+// replayWAL applies records, in order, to entries.
+func replayWAL(entries map[string]*cache.Entry, records []walRecord) {
+	for _, record := range records {
+		switch record.op {
+		case walSet:
+			entries[record.key] = record.entry
+		case walDelete:
+			delete(entries, record.key)
+		case walClear:
+			clear(entries)
+		case walDeletePattern:
+			for key := range entries {
+				if matchPattern(key, record.key) {
+					delete(entries, key)
+				}
+			}
+		case walDeleteTag:
+			for key, entry := range entries {
+				if entry.HasTag(record.key) {
+					delete(entries, key)
+				}
+			}
+		}
+	}
+}
+
+// walPath returns the path of the write-ahead log.
+func (f *File) walPath() string {
+	return f.filePath + ".wal"
+}
+
+// fsync returns the function saves sync files with, or nil if
+// DisableFsync is set.
+func (f *File) fsync() func(*os.File) error {
+	if f.disableFsync {
+		return nil
+	}
+	return f.syncFile
+}
+
+// appendWAL logs a write that was just applied to the memory backend,
+// requesting a compaction once the log is full. Must be called with mu
+// and walMu held.
+func (f *File) appendWAL(op walOp, key string) {
+	record := walRecord{op: op, key: key}
+	if op == walSet {
+		// Log the entry as stored, with its expiry
+		entry, err := f.memory.Peek(context.Background(), key)
+		if err != nil {
+			// Already expired: replaying it would resurrect an older value
+			record.op = walDelete
+		} else {
+			record.entry = entry
+		}
+	}
+
+	if err := f.wal.append(record); err != nil {
+		f.logger.Error("write-ahead log append failed", "path", f.wal.path, "error", err)
+		f.saveFailed(err)
+
+		// A partial record ends replay, losing the writes logged after
+		// it; compacting empties the log
+		f.requestCompaction()
+		return
+	}
+
+	if f.wal.full(f.walCompactOps, f.walCompactSize) {
+		f.requestCompaction()
+	}
+}
+
+// requestCompaction wakes compactLoop, unless a compaction is already
+// pending.
+func (f *File) requestCompaction() {
+	select {
+	case f.compact <- struct{}{}:
+	default:
+	}
+}
+
+// compactLoop compacts the write-ahead log when requested, by saving a
+// snapshot, until the backend closes.
+func (f *File) compactLoop() {
+	for {
+		select {
+		case <-f.compact:
+			if err := f.Save(); err != nil && !errors.Is(err, cache.ErrBackendClosed) {
+				f.logger.Error("write-ahead log compaction failed", "path", f.filePath, "error", err)
+				f.saveFailed(err)
+			}
+		case <-f.saveStop:
+			return
+		}
+	}
+}
+
+// syncWAL syncs the write-ahead log to disk for auto-save.
+func (f *File) syncWAL() {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.closed {
+		return
+	}
+
+	f.walMu.Lock()
+	defer f.walMu.Unlock()
+
+	wasDirty := f.dirty.Swap(false)
+	if err := f.wal.sync(f.fsync()); err != nil {
+		if wasDirty {
+			f.dirty.Store(true)
+		}
+		f.logger.Error("write-ahead log sync failed", "path", f.wal.path, "error", err)
+		f.saveFailed(err)
+	}
+}
+
+// loadWAL loads the snapshot, replaying the write-ahead log over it. Must
+// be called with mu held.
+func (f *File) loadWAL() error {
+	paths := f.shardPaths()
+	shards := make([][]*cache.Entry, len(paths))
+	errs := make([]error, len(paths))
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entries, err := f.readFile(path)
+			if err != nil {
+				errs[i] = fmt.Errorf("loading %s: %w", path, err)
+			}
+			shards[i] = entries
+		}()
+	}
+	wg.Wait()
+
+	entries := make(map[string]*cache.Entry)
+	for _, shard := range shards {
+		for _, entry := range shard {
+			entries[entry.Key] = entry
+		}
+	}
+
+	// The log holds the writes made since the snapshot
+	records, _, err := readWAL(f.walPath())
+	if err != nil {
+		errs = append(errs, fmt.Errorf("loading %s: %w", f.walPath(), err))
+	}
+	replayWAL(entries, records)
+
+	loaded := make([]*cache.Entry, 0, len(entries))
+	for _, entry := range entries {
+		loaded = append(loaded, entry)
+	}
+	f.loadEntries(loaded)
+	return errors.Join(errs...)
+}
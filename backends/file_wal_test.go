@@ -0,0 +1,223 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cache "github.com/rmrfslashbin/hue-cache"
+)
+
+// newWALFile opens a File backend in WAL mode, closing it at the end of
+// the test.
+func newWALFile(t *testing.T, filePath string, configure func(*FileConfig)) *File {
+	t.Helper()
+	config := &FileConfig{
+		FilePath:     filePath,
+		LoadOnStart:  true,
+		MemoryConfig: DefaultMemoryConfig(),
+		WAL:          true,
+	}
+	if configure != nil {
+		configure(config)
+	}
+
+	backend, err := NewFile(config)
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	t.Cleanup(func() { backend.Close() })
+	return backend
+}
+
+func TestFile_WAL_Replay(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "cache.gob")
+	ctx := context.Background()
+
+	// Writes are logged without writing a snapshot
+	backend := newWALFile(t, filePath, nil)
+	backend.Set(ctx, "light:1", []byte("one"), 0)
+	backend.Set(ctx, "light:2", []byte("two"), time.Hour)
+	backend.Set(ctx, "light:3", []byte("three"), 0)
+	backend.Delete(ctx, "light:3")
+	backend.SetIf(ctx, "light:1", []byte("uno"), 0, func(*cache.Entry) bool { return true })
+	backend.Touch(ctx, "light:2", 2*time.Hour)
+
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("Stat(snapshot) error = %v, want no snapshot before compaction", err)
+	}
+
+	// A second backend on the same files sees the state as of the last
+	// write, as it would after a crash
+	reopened := newWALFile(t, filePath, nil)
+	if entry, err := reopened.Get(ctx, "light:1"); err != nil || string(entry.Value) != "uno" {
+		t.Errorf("Get(light:1) = %v, %v; want uno", entry, err)
+	}
+	if _, err := reopened.Get(ctx, "light:3"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Get(light:3) error = %v, want ErrNotFound", err)
+	}
+	entry, err := reopened.Get(ctx, "light:2")
+	if err != nil {
+		t.Fatalf("Get(light:2) failed: %v", err)
+	}
+	if remaining := time.Until(entry.ExpiresAt); remaining < time.Hour {
+		t.Errorf("light:2 expires in %v, want the touched TTL", remaining)
+	}
+
+	// Clear is logged too
+	backend.Clear(ctx)
+	backend.Set(ctx, "room:1", []byte("room"), 0)
+	cleared := newWALFile(t, filePath, nil)
+	if keys, _ := cleared.Keys(ctx, "*"); len(keys) != 1 || keys[0] != "room:1" {
+		t.Errorf("Keys() after Clear = %v, want [room:1]", keys)
+	}
+
+	// And so is a pattern delete, as one record
+	backend.Set(ctx, "light:4", []byte("four"), 0)
+	backend.Set(ctx, "light:5", []byte("five"), 0)
+	if deleted, err := backend.DeletePattern(ctx, "light:*"); err != nil || deleted != 2 {
+		t.Errorf("DeletePattern() = %d, %v; want 2", deleted, err)
+	}
+	patterned := newWALFile(t, filePath, nil)
+	if keys, _ := patterned.Keys(ctx, "*"); len(keys) != 1 || keys[0] != "room:1" {
+		t.Errorf("Keys() after DeletePattern = %v, want [room:1]", keys)
+	}
+
+	// Tags are logged with the entry, and a tag delete as one record
+	backend.SetWithTags(ctx, "light:6", []byte("six"), 0, []string{"floor-2"})
+	backend.SetWithTags(ctx, "light:7", []byte("seven"), 0, []string{"floor-2", "holiday"})
+	tagged := newWALFile(t, filePath, nil)
+	if entry, err := tagged.Get(ctx, "light:7"); err != nil || !entry.HasTag("holiday") {
+		t.Errorf("Get(light:7) = %v, %v; want tagged holiday", entry, err)
+	}
+	if deleted, err := backend.DeleteTag(ctx, "floor-2"); err != nil || deleted != 2 {
+		t.Errorf("DeleteTag() = %d, %v; want 2", deleted, err)
+	}
+	untagged := newWALFile(t, filePath, nil)
+	if keys, _ := untagged.Keys(ctx, "*"); len(keys) != 1 || keys[0] != "room:1" {
+		t.Errorf("Keys() after DeleteTag = %v, want [room:1]", keys)
+	}
+
+	// A batch delete logs each deleted key
+	backend.Set(ctx, "light:8", []byte("eight"), 0)
+	backend.Set(ctx, "light:9", []byte("nine"), 0)
+	if deleted, err := backend.DeleteMany(ctx, []string{"light:8", "light:9", "light:missing"}); err != nil || deleted != 2 {
+		t.Errorf("DeleteMany() = %d, %v; want 2", deleted, err)
+	}
+	batched := newWALFile(t, filePath, nil)
+	if keys, _ := batched.Keys(ctx, "*"); len(keys) != 1 || keys[0] != "room:1" {
+		t.Errorf("Keys() after DeleteMany = %v, want [room:1]", keys)
+	}
+}
+
+func TestFile_WAL_Compaction(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "cache.gob")
+	ctx := context.Background()
+
+	backend := newWALFile(t, filePath, func(config *FileConfig) {
+		config.WALCompactOps = 5
+	})
+	for _, key := range []string{"light:1", "light:2", "light:3", "light:4", "light:5", "light:6"} {
+		backend.Set(ctx, key, []byte(key), 0)
+	}
+
+	// The fifth write triggers a compaction in the background
+	deadline := time.Now().Add(time.Second)
+	for backend.SaveStatus().LastSaveTime.IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatal("log was not compacted")
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	if _, err := os.Stat(filePath); err != nil {
+		t.Fatalf("Stat(snapshot) failed: %v", err)
+	}
+
+	reopened := newWALFile(t, filePath, nil)
+	if keys, _ := reopened.Keys(ctx, "*"); len(keys) != 6 {
+		t.Errorf("Keys() = %v, want all 6 from the snapshot and the log", keys)
+	}
+}
+
+func TestFile_WAL_CloseCompacts(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "cache.gob")
+	ctx := context.Background()
+
+	backend := newWALFile(t, filePath, func(config *FileConfig) {
+		config.Shards = 2
+	})
+	backend.Set(ctx, "light:1", []byte("one"), 0)
+	backend.Set(ctx, "light:2", []byte("two"), 0)
+	if err := backend.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	if info, err := os.Stat(filePath + ".wal"); err != nil || info.Size() != 0 {
+		t.Errorf("log after Close = %v, %v; want empty", info, err)
+	}
+
+	reopened := newWALFile(t, filePath, func(config *FileConfig) {
+		config.Shards = 2
+	})
+	if keys, _ := reopened.Keys(ctx, "*"); len(keys) != 2 {
+		t.Errorf("Keys() = %v, want both from the snapshot", keys)
+	}
+}
+
+func TestFile_WAL_TornRecord(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "cache.gob")
+	ctx := context.Background()
+
+	backend := newWALFile(t, filePath, nil)
+	backend.Set(ctx, "light:1", []byte("one"), 0)
+
+	// A crash mid-append leaves part of a record
+	log, err := os.OpenFile(filePath+".wal", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("opening log: %v", err)
+	}
+	log.Write([]byte{0x40, 0x01, 0x02})
+	log.Close()
+
+	// The torn record is dropped, and writes after it replay
+	reopened := newWALFile(t, filePath, nil)
+	if _, err := reopened.Get(ctx, "light:1"); err != nil {
+		t.Errorf("Get(light:1) failed: %v", err)
+	}
+	reopened.Set(ctx, "light:2", []byte("two"), 0)
+
+	again := newWALFile(t, filePath, nil)
+	if keys, _ := again.Keys(ctx, "*"); len(keys) != 2 {
+		t.Errorf("Keys() = %v, want both writes", keys)
+	}
+}
+
+func TestWALRecord_RoundTrip(t *testing.T) {
+	records := []walRecord{
+		{op: walSet, key: "light:1", entry: cache.NewEntry("light:1", []byte("one"), time.Minute)},
+		{op: walDelete, key: "light:2"},
+		{op: walClear},
+		{op: walDeletePattern, key: "light:*"},
+		{op: walDeleteTag, key: "floor-2"},
+	}
+	for _, record := range records {
+		payload, err := encodeWALRecord(record)
+		if err != nil {
+			t.Fatalf("encodeWALRecord(%v) failed: %v", record.op, err)
+		}
+		got, err := decodeWALRecord(payload)
+		if err != nil {
+			t.Fatalf("decodeWALRecord(%v) failed: %v", record.op, err)
+		}
+		if got.op != record.op || got.key != record.key {
+			t.Errorf("round trip = %+v, want %+v", got, record)
+		}
+	}
+
+	if _, err := decodeWALRecord([]byte{99}); err == nil {
+		t.Error("decodeWALRecord() accepted an unknown op")
+	}
+}
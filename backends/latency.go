@@ -0,0 +1,127 @@
+package backends
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyStats holds latency statistics for a backend's core operations,
+// recorded when MemoryConfig.TrackLatency (or FileConfig.TrackLatency) is
+// set. Failed operations are recorded too.
+type LatencyStats struct {
+	// Get covers Get.
+	Get OpLatency
+
+	// Set covers Set and SetWithTags.
+	Set OpLatency
+
+	// Delete covers Delete.
+	Delete OpLatency
+
+	// Keys covers Keys.
+	Keys OpLatency
+}
+
+// OpLatency summarizes the latencies of one operation. All fields are
+// zero until the operation has been recorded.
+type OpLatency struct {
+	// Count is the number of operations recorded.
+	Count int64
+
+	// Min, Avg, and Max are the shortest, mean, and longest latencies.
+	Min time.Duration
+	Avg time.Duration
+	Max time.Duration
+}
+
+// opLatency accumulates the latencies of one operation with atomics, so
+// recording never contends for a lock. A snapshot taken while operations
+// are recorded may be off by the operations in flight.
+type opLatency struct {
+	count atomic.Int64
+	total atomic.Int64
+	min   atomic.Int64
+	max   atomic.Int64
+}
+
+// record adds the latency of an operation that started at start. It is
+// meant to be deferred: defer op.record(time.Now()).
+func (o *opLatency) record(start time.Time) {
+	d := int64(time.Since(start))
+	o.count.Add(1)
+	o.total.Add(d)
+	for {
+		cur := o.min.Load()
+		if d >= cur || o.min.CompareAndSwap(cur, d) {
+			break
+		}
+	}
+	for {
+		cur := o.max.Load()
+		if d <= cur || o.max.CompareAndSwap(cur, d) {
+			break
+		}
+	}
+}
+
+// snapshot returns the recorded statistics.
+func (o *opLatency) snapshot() OpLatency {
+	count := o.count.Load()
+	if count == 0 {
+		return OpLatency{}
+	}
+	return OpLatency{
+		Count: count,
+		Min:   time.Duration(o.min.Load()),
+		Avg:   time.Duration(o.total.Load() / count),
+		Max:   time.Duration(o.max.Load()),
+	}
+}
+
+// reset discards the recorded latencies.
+func (o *opLatency) reset() {
+	o.count.Store(0)
+	o.total.Store(0)
+	o.min.Store(math.MaxInt64)
+	o.max.Store(0)
+}
+
+// latencyTracker records the latencies of a backend's core operations.
+type latencyTracker struct {
+	get, set, delete, keys opLatency
+}
+
+// newLatencyTracker returns a tracker, or nil if enabled is false. The
+// backends skip recording when their tracker is nil.
+func newLatencyTracker(enabled bool) *latencyTracker {
+	if !enabled {
+		return nil
+	}
+	t := &latencyTracker{}
+	t.reset()
+	return t
+}
+
+// stats returns the recorded statistics. A nil tracker reports zeros.
+func (t *latencyTracker) stats() LatencyStats {
+	if t == nil {
+		return LatencyStats{}
+	}
+	return LatencyStats{
+		Get:    t.get.snapshot(),
+		Set:    t.set.snapshot(),
+		Delete: t.delete.snapshot(),
+		Keys:   t.keys.snapshot(),
+	}
+}
+
+// reset discards the recorded latencies. A nil tracker is a no-op.
+func (t *latencyTracker) reset() {
+	if t == nil {
+		return
+	}
+	for _, op := range []*opLatency{&t.get, &t.set, &t.delete, &t.keys} {
+		op.reset()
+	}
+}
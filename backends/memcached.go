@@ -0,0 +1,734 @@
+package backends
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	cache "github.com/rmrfslashbin/hue-cache"
+)
+
+// Memcached implements a cache backend on memcached servers, for
+// deployments that already run memcached and share the cache across
+// processes.
+//
+// Entries are stored gob-encoded with their metadata, under KeyPrefix
+// plus the cache key. Memcached can't list keys, so the backend keeps
+// an index of the keys it has written in one extra item; Keys, Entries,
+// Stats, and Clear read it. Compared to the other backends:
+//
+//   - Set also reads the index, and updates it for a new key, so it
+//     costs two or three round trips instead of one.
+//   - Keys and Entries fetch every indexed key matching the pattern in
+//     one GetMulti, so they cost a round trip to each server.
+//   - The index is only as good as memcached keeps it: if memcached
+//     evicts the index item, keys written before the eviction are no
+//     longer listed or cleared, though Get still finds them.
+//   - Deleted keys stay in the index until Clear, so it grows with every
+//     distinct key written. Keep the key space bounded (a bridge's
+//     resources are) and below memcached's item size limit (1 MB by
+//     default, about 15,000 keys).
+//   - Reads don't write, so Entry.Hits and Entry.UpdatedAt are not
+//     persisted and sliding TTLs are not supported.
+//   - Memcached expires items on whole seconds; entries expire on time
+//     because Get also checks ExpiresAt.
+//   - gomemcache doesn't take a context: operations are bounded by
+//     MemcachedConfig.Timeout, not ctx.
+//
+// With MemcachedConfig.DisableKeyIndex there is no index: Set is a
+// single round trip, but Keys and Entries return cache.ErrUnsupported,
+// Stats reports no entry counts, and Clear requires FlushOnClear.
+type Memcached struct {
+	client memcacheClient
+	prefix string
+
+	// flushOnClear makes Clear flush the servers
+	flushOnClear bool
+
+	// noIndex disables the key index
+	noIndex bool
+
+	// hashKeys stores invalid keys under a digest; see HashLongKeys
+	hashKeys bool
+
+	// stats tracks hits, misses, and errors; entry counts and sizes are
+	// read through the index
+	stats *cache.StatsCollector
+
+	// closed tracks if backend is closed
+	closed atomic.Bool
+}
+
+// memcacheClient is the subset of *memcache.Client the backend uses.
+type memcacheClient interface {
+	Get(key string) (*memcache.Item, error)
+	GetMulti(keys []string) (map[string]*memcache.Item, error)
+	Set(item *memcache.Item) error
+	Add(item *memcache.Item) error
+	CompareAndSwap(item *memcache.Item) error
+	Delete(key string) error
+	FlushAll() error
+	Ping() error
+	Close() error
+}
+
+// MemcachedConfig contains configuration options for the memcached
+// backend.
+type MemcachedConfig struct {
+	// Servers are the addresses (host:port) of the memcached servers.
+	// Keys are spread across them by hash.
+	// Default: ["localhost:11211"]
+	Servers []string
+
+	// KeyPrefix is prepended to every key stored in memcached, so the
+	// cache can share servers with other applications. Cache keys plus
+	// the prefix must fit memcached's 250-byte key limit (see
+	// HashLongKeys).
+	// Default: "hue-cache:"
+	KeyPrefix string
+
+	// Timeout bounds each request to a server.
+	// Default: memcache.DefaultTimeout (500ms)
+	Timeout time.Duration
+
+	// FlushOnClear makes Clear flush every server with FlushAll instead
+	// of deleting the indexed keys. It also removes keys the index lost,
+	// but it removes every other application's items too, so only use it
+	// on servers dedicated to this cache.
+	// Default: false
+	FlushOnClear bool
+
+	// DisableKeyIndex stops maintaining the key index, saving its round
+	// trips on every Set. Without it the backend can't list keys: Keys and
+	// Entries return cache.ErrUnsupported (the cached clients then list
+	// from the bridge), and Clear returns it unless FlushOnClear is set.
+	// Default: false
+	DisableKeyIndex bool
+
+	// HashLongKeys stores keys memcached can't hold, because with the
+	// prefix they exceed 250 bytes or they contain spaces or control
+	// characters, under a fixed-length SHA-256 digest of the key instead
+	// of rejecting them with cache.ErrInvalidKey. The original key is kept
+	// in the stored entry, so Get, Keys, and Entries still see it. Use it
+	// when long bridge IDs or a namespace prefix push keys over the limit.
+	// Default: false (invalid keys are rejected)
+	HashLongKeys bool
+}
+
+// DefaultMemcachedConfig returns default configuration for the memcached
+// backend.
+func DefaultMemcachedConfig() *MemcachedConfig {
+	return &MemcachedConfig{
+		Servers:   []string{"localhost:11211"},
+		KeyPrefix: "hue-cache:",
+		Timeout:   memcache.DefaultTimeout,
+	}
+}
+
+// maxMemcachedKey is memcached's key length limit in bytes.
+const maxMemcachedKey = 250
+
+// maxCASAttempts bounds the compare-and-swap retries of a conditional
+// write that keeps losing to concurrent writers.
+const maxCASAttempts = 32
+
+// errCASContention is returned when a conditional write loses
+// maxCASAttempts races in a row.
+var errCASContention = errors.New("too many concurrent writes")
+
+// NewMemcached creates a backend on memcached servers. Connections are
+// made lazily, so NewMemcached doesn't fail if a server is down.
+// If config is nil, defaults are used.
+//
+// Example:
+//
+//	config := backends.DefaultMemcachedConfig()
+//	config.Servers = []string{"cache1:11211", "cache2:11211"}
+//	backend := backends.NewMemcached(config)
+//	defer backend.Close()
+func NewMemcached(config *MemcachedConfig) *Memcached {
+	defaults := DefaultMemcachedConfig()
+	if config == nil {
+		config = defaults
+	}
+
+	servers := config.Servers
+	if len(servers) == 0 {
+		servers = defaults.Servers
+	}
+
+	client := memcache.New(servers...)
+	if config.Timeout > 0 {
+		client.Timeout = config.Timeout
+	}
+
+	return newMemcached(client, config)
+}
+
+// newMemcached creates a backend on client, for tests to substitute a
+// fake server.
+func newMemcached(client memcacheClient, config *MemcachedConfig) *Memcached {
+	return &Memcached{
+		client:       client,
+		prefix:       config.KeyPrefix,
+		flushOnClear: config.FlushOnClear,
+		noIndex:      config.DisableKeyIndex,
+		hashKeys:     config.HashLongKeys,
+		stats:        cache.NewStatsCollector(),
+	}
+}
+
+// Get retrieves a value from the cache.
+func (m *Memcached) Get(ctx context.Context, key string) (*cache.Entry, error) {
+	if m.closed.Load() {
+		return nil, cache.NewError("Get", key, cache.ErrBackendClosed)
+	}
+
+	stored, ok := m.storageKey(key)
+	if !ok {
+		return nil, cache.NewError("Get", key, cache.ErrInvalidKey)
+	}
+
+	entry, _, err := m.read(stored, key)
+	if err != nil {
+		m.stats.RecordError(err)
+		return nil, cache.NewError("Get", key, err)
+	}
+
+	if entry == nil {
+		m.stats.RecordMiss()
+		return nil, cache.NewError("Get", key, cache.ErrNotFound)
+	}
+
+	if entry.IsExpired() {
+		m.stats.RecordMiss()
+		return nil, cache.NewError("Get", key, cache.ErrExpired)
+	}
+
+	m.stats.RecordHit()
+	return entry, nil
+}
+
+// Peek returns the entry under key like Get, but without recording a hit,
+// miss, or error in the backend's statistics. The memcached server still
+// counts the read in its own statistics. See cache.Peeker.
+func (m *Memcached) Peek(ctx context.Context, key string) (*cache.Entry, error) {
+	if m.closed.Load() {
+		return nil, cache.NewError("Peek", key, cache.ErrBackendClosed)
+	}
+
+	stored, ok := m.storageKey(key)
+	if !ok {
+		return nil, cache.NewError("Peek", key, cache.ErrInvalidKey)
+	}
+
+	entry, _, err := m.read(stored, key)
+	if err != nil {
+		return nil, cache.NewError("Peek", key, err)
+	}
+
+	if entry == nil {
+		return nil, cache.NewError("Peek", key, cache.ErrNotFound)
+	}
+
+	if entry.IsExpired() {
+		return nil, cache.NewError("Peek", key, cache.ErrExpired)
+	}
+
+	return entry, nil
+}
+
+// Set stores a value in the cache.
+func (m *Memcached) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return m.SetWithTags(ctx, key, value, ttl, nil)
+}
+
+// SetWithTags stores a value tagged with tags, which are stored with the
+// entry. Memcached keeps no tag index, so cache.DeleteTag scans Keys.
+func (m *Memcached) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	if m.closed.Load() {
+		return cache.NewError("Set", key, cache.ErrBackendClosed)
+	}
+
+	stored, ok := m.storageKey(key)
+	if !ok {
+		return cache.NewError("Set", key, cache.ErrInvalidKey)
+	}
+
+	if err := cache.ValidateValue(value, 0); err != nil {
+		return cache.NewError("Set", key, err)
+	}
+
+	entry := cache.NewEntry(key, value, ttl)
+	entry.Tags = tags
+	item, err := m.item(stored, entry)
+	if err != nil {
+		return cache.NewError("Set", key, err)
+	}
+
+	// Index first: an indexed key without an item is skipped by Keys,
+	// but an item without an indexed key is never listed
+	if err := m.index(stored); err != nil {
+		m.stats.RecordError(err)
+		return cache.NewError("Set", key, err)
+	}
+
+	if err := m.client.Set(item); err != nil {
+		m.stats.RecordError(err)
+		return cache.NewError("Set", key, err)
+	}
+
+	return nil
+}
+
+// SetIf stores a value if the key is absent or expired, or cond approves
+// the stored entry, using memcached's add and compare-and-swap. See
+// cache.ConditionalSetter.
+func (m *Memcached) SetIf(ctx context.Context, key string, value []byte, ttl time.Duration, cond func(*cache.Entry) bool) (bool, error) {
+	if m.closed.Load() {
+		return false, cache.NewError("SetIf", key, cache.ErrBackendClosed)
+	}
+
+	stored, ok := m.storageKey(key)
+	if !ok {
+		return false, cache.NewError("SetIf", key, cache.ErrInvalidKey)
+	}
+
+	if err := cache.ValidateValue(value, 0); err != nil {
+		return false, cache.NewError("SetIf", key, err)
+	}
+
+	replacement, err := m.item(stored, cache.NewEntry(key, value, ttl))
+	if err != nil {
+		return false, cache.NewError("SetIf", key, err)
+	}
+
+	applied := false
+	err = m.update(stored, key, func(existing *cache.Entry) (*memcache.Item, error) {
+		applied = existing == nil || existing.IsExpired() || cond(existing)
+		if !applied {
+			return nil, nil
+		}
+		return replacement, nil
+	})
+	if err != nil {
+		m.stats.RecordError(err)
+		return false, cache.NewError("SetIf", key, err)
+	}
+
+	return applied, nil
+}
+
+// Delete removes a key from the cache. The key stays in the index until
+// Clear; Keys skips it.
+func (m *Memcached) Delete(ctx context.Context, key string) error {
+	if m.closed.Load() {
+		return cache.NewError("Delete", key, cache.ErrBackendClosed)
+	}
+
+	stored, ok := m.storageKey(key)
+	if !ok {
+		return cache.NewError("Delete", key, cache.ErrInvalidKey)
+	}
+
+	err := m.client.Delete(m.prefix + stored)
+	if err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+		m.stats.RecordError(err)
+		return cache.NewError("Delete", key, err)
+	}
+
+	return nil
+}
+
+// Touch resets the TTL of an unexpired entry without changing its value,
+// rewriting the entry with compare-and-swap so its metadata stays
+// current. See cache.Toucher.
+func (m *Memcached) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if m.closed.Load() {
+		return cache.NewError("Touch", key, cache.ErrBackendClosed)
+	}
+
+	stored, ok := m.storageKey(key)
+	if !ok {
+		return cache.NewError("Touch", key, cache.ErrInvalidKey)
+	}
+
+	found := false
+	err := m.update(stored, key, func(entry *cache.Entry) (*memcache.Item, error) {
+		found = entry != nil && !entry.IsExpired()
+		if !found {
+			return nil, nil
+		}
+
+		entry.TTL = ttl
+		entry.ExpiresAt = time.Time{}
+		if ttl > 0 {
+			entry.ExpiresAt = time.Now().Add(ttl)
+		}
+		return m.item(stored, entry)
+	})
+	if err != nil {
+		return cache.NewError("Touch", key, err)
+	}
+	if !found {
+		return cache.NewError("Touch", key, cache.ErrNotFound)
+	}
+
+	return nil
+}
+
+// Clear removes every indexed entry and the index, or flushes the
+// servers if FlushOnClear is set.
+func (m *Memcached) Clear(ctx context.Context) error {
+	if m.closed.Load() {
+		return cache.NewError("Clear", "", cache.ErrBackendClosed)
+	}
+
+	if m.flushOnClear {
+		if err := m.client.FlushAll(); err != nil {
+			m.stats.RecordError(err)
+			return cache.NewError("Clear", "", err)
+		}
+		return nil
+	}
+
+	if m.noIndex {
+		return cache.NewError("Clear", "", cache.ErrUnsupported)
+	}
+
+	keys, err := m.indexedKeys()
+	if err != nil {
+		m.stats.RecordError(err)
+		return cache.NewError("Clear", "", err)
+	}
+
+	for _, key := range append(keys, indexKey) {
+		err := m.client.Delete(m.prefix + key)
+		if err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+			m.stats.RecordError(err)
+			return cache.NewError("Clear", key, err)
+		}
+	}
+
+	return nil
+}
+
+// Keys returns all indexed, unexpired keys matching the pattern.
+func (m *Memcached) Keys(ctx context.Context, pattern string) ([]string, error) {
+	if m.closed.Load() {
+		return nil, cache.NewError("Keys", "", cache.ErrBackendClosed)
+	}
+
+	if m.noIndex {
+		return nil, cache.NewError("Keys", "", cache.ErrUnsupported)
+	}
+
+	entries, err := m.matching(pattern)
+	if err != nil {
+		m.stats.RecordError(err)
+		return nil, cache.NewError("Keys", "", err)
+	}
+
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.Key
+	}
+	return keys, nil
+}
+
+// Entries returns the metadata of the indexed, unexpired entries matching
+// pattern, fetched in one GetMulti. See cache.EntryLister.
+func (m *Memcached) Entries(ctx context.Context, pattern string) ([]*cache.EntryInfo, error) {
+	if m.closed.Load() {
+		return nil, cache.NewError("Entries", "", cache.ErrBackendClosed)
+	}
+
+	if m.noIndex {
+		return nil, cache.NewError("Entries", "", cache.ErrUnsupported)
+	}
+
+	entries, err := m.matching(pattern)
+	if err != nil {
+		m.stats.RecordError(err)
+		return nil, cache.NewError("Entries", "", err)
+	}
+
+	infos := make([]*cache.EntryInfo, len(entries))
+	for i, entry := range entries {
+		infos[i] = entry.Info()
+	}
+	return infos, nil
+}
+
+// Stats returns current cache statistics. Entries and Size are counted
+// by fetching the indexed entries; Size is the size of their values.
+// Without the index they are 0.
+func (m *Memcached) Stats(ctx context.Context) (*cache.Stats, error) {
+	if m.closed.Load() {
+		return nil, cache.NewError("Stats", "", cache.ErrBackendClosed)
+	}
+
+	if m.noIndex {
+		return m.stats.Stats(), nil
+	}
+
+	entries, err := m.matching("*")
+	if err != nil {
+		return nil, cache.NewError("Stats", "", err)
+	}
+
+	var size int64
+	for _, entry := range entries {
+		size += entry.Size
+	}
+
+	m.stats.SetEntries(int64(len(entries)))
+	m.stats.SetSize(size)
+	return m.stats.Stats(), nil
+}
+
+// Ping checks that every server is reachable. See cache.Pinger.
+func (m *Memcached) Ping(ctx context.Context) error {
+	if m.closed.Load() {
+		return cache.NewError("Ping", "", cache.ErrBackendClosed)
+	}
+
+	if err := m.client.Ping(); err != nil {
+		return cache.NewError("Ping", "", err)
+	}
+	return nil
+}
+
+// Close closes the connections to the servers.
+func (m *Memcached) Close() error {
+	if !m.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	return m.client.Close()
+}
+
+// indexKey is the cache key of the index item, which can't collide with
+// a resource key ("type:id").
+const indexKey = "#keys"
+
+// validKey reports whether key is non-empty, isn't the index, and with
+// the prefix is a legal memcached key: at most 250 bytes, without spaces
+// or control characters.
+func (m *Memcached) validKey(key string) bool {
+	if key == "" || key == indexKey || len(m.prefix)+len(key) > maxMemcachedKey {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// hashedKeyPrefix starts the storage keys of hashed keys (see
+// HashLongKeys).
+const hashedKeyPrefix = "#sha256:"
+
+// storageKey returns the key, without the prefix, that key is stored
+// under in memcached, or false if it can't be stored. That is key itself
+// if it is valid, and with hashKeys set a digest of it otherwise.
+func (m *Memcached) storageKey(key string) (string, bool) {
+	if m.validKey(key) {
+		return key, true
+	}
+	if !m.hashKeys || key == "" || key == indexKey {
+		return "", false
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	stored := hashedKeyPrefix + hex.EncodeToString(sum[:])
+	return stored, len(m.prefix)+len(stored) <= maxMemcachedKey
+}
+
+// item encodes entry as the memcached item storing it under stored.
+func (m *Memcached) item(stored string, entry *cache.Entry) (*memcache.Item, error) {
+	data, err := encodeEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+	return &memcache.Item{Key: m.prefix + stored, Value: data, Expiration: memcachedExpiration(entry.TTL)}, nil
+}
+
+// read returns the entry for key, stored under stored, and the item
+// storing it. The entry is nil if there is none, or if the item holds
+// another key's entry (a hashed key's digest can't tell them apart); the
+// item is nil only if there is none.
+func (m *Memcached) read(stored, key string) (*cache.Entry, *memcache.Item, error) {
+	item, err := m.client.Get(m.prefix + stored)
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entry, err := decodeEntry(item.Value)
+	if err != nil {
+		return nil, nil, err
+	}
+	if entry.Key != key {
+		return nil, item, nil
+	}
+	return entry, item, nil
+}
+
+// update replaces the entry under key with the item returned by replace,
+// which gets the current entry (nil if there is none). Nothing is written
+// if replace returns a nil item. The write is made with add or
+// compare-and-swap, and replace is called again if another write got in
+// first.
+func (m *Memcached) update(stored, key string, replace func(*cache.Entry) (*memcache.Item, error)) error {
+	for attempt := 0; attempt < maxCASAttempts; attempt++ {
+		entry, current, err := m.read(stored, key)
+		if err != nil {
+			return err
+		}
+
+		replacement, err := replace(entry)
+		if err != nil || replacement == nil {
+			return err
+		}
+
+		if err := m.index(stored); err != nil {
+			return err
+		}
+
+		if current == nil {
+			err = m.client.Add(replacement)
+		} else {
+			current.Value = replacement.Value
+			current.Expiration = replacement.Expiration
+			err = m.client.CompareAndSwap(current)
+		}
+		switch {
+		case errors.Is(err, memcache.ErrNotStored), errors.Is(err, memcache.ErrCASConflict), errors.Is(err, memcache.ErrCacheMiss):
+			continue // Lost a race; retry with the new entry
+		case err != nil:
+			return err
+		}
+		return nil
+	}
+	return errCASContention
+}
+
+// index adds a storage key to the index if it isn't there yet.
+func (m *Memcached) index(key string) error {
+	if m.noIndex {
+		return nil
+	}
+
+	line := []byte(key + "\n")
+	for attempt := 0; attempt < maxCASAttempts; attempt++ {
+		item, err := m.client.Get(m.prefix + indexKey)
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			err = m.client.Add(&memcache.Item{Key: m.prefix + indexKey, Value: line})
+			if errors.Is(err, memcache.ErrNotStored) {
+				continue
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if bytes.HasPrefix(item.Value, line) || bytes.Contains(item.Value, append([]byte("\n"), line...)) {
+			return nil
+		}
+
+		item.Value = append(item.Value, line...)
+		err = m.client.CompareAndSwap(item)
+		if errors.Is(err, memcache.ErrCASConflict) || errors.Is(err, memcache.ErrCacheMiss) {
+			continue
+		}
+		return err
+	}
+	return fmt.Errorf("updating key index: %w", errCASContention)
+}
+
+// indexedKeys returns the storage keys in the index.
+func (m *Memcached) indexedKeys() ([]string, error) {
+	item, err := m.client.Get(m.prefix + indexKey)
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading key index: %w", err)
+	}
+
+	var keys []string
+	for _, line := range bytes.Split(item.Value, []byte("\n")) {
+		if len(line) > 0 {
+			keys = append(keys, string(line))
+		}
+	}
+	return keys, nil
+}
+
+// matching returns the unexpired entries of the indexed keys matching
+// pattern.
+func (m *Memcached) matching(pattern string) ([]*cache.Entry, error) {
+	keys, err := m.indexedKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, key := range keys {
+		// A hashed key's original is only known once its entry is read
+		if strings.HasPrefix(key, hashedKeyPrefix) || matchPattern(key, pattern) {
+			names = append(names, m.prefix+key)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	items, err := m.client.GetMulti(names)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*cache.Entry
+	for _, name := range names {
+		item, ok := items[name]
+		if !ok {
+			continue // Deleted or evicted
+		}
+		entry, err := decodeEntry(item.Value)
+		if err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", name, err)
+		}
+		if !entry.IsExpired() && matchPattern(entry.Key, pattern) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// memcachedExpiration converts ttl to a memcached expiration: 0 for none,
+// seconds (rounded up, so memcached never drops an entry before it
+// expires) up to 30 days, and a Unix time beyond that.
+func memcachedExpiration(ttl time.Duration) int32 {
+	if ttl <= 0 {
+		return 0
+	}
+
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	if seconds > 30*24*60*60 {
+		return int32(time.Now().Add(ttl).Unix() + 1)
+	}
+	return int32(seconds)
+}
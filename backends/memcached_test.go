@@ -0,0 +1,406 @@
+package backends
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	cache "github.com/rmrfslashbin/hue-cache"
+)
+
+// fakeMemcache is an in-process memcached for tests. Items returned by
+// Get remember the version they were read at, for CompareAndSwap.
+type fakeMemcache struct {
+	mu      sync.Mutex
+	items   map[string]fakeItem
+	issued  map[*memcache.Item]uint64
+	version uint64
+	flushes int
+	pingErr error
+}
+
+type fakeItem struct {
+	value   []byte
+	version uint64
+	expires time.Time
+}
+
+func newFakeMemcache() *fakeMemcache {
+	return &fakeMemcache{
+		items:  make(map[string]fakeItem),
+		issued: make(map[*memcache.Item]uint64),
+	}
+}
+
+// lookup returns the unexpired item under key. Must be called with mu held.
+func (f *fakeMemcache) lookup(key string) (fakeItem, bool) {
+	item, ok := f.items[key]
+	if ok && !item.expires.IsZero() && time.Now().After(item.expires) {
+		delete(f.items, key)
+		return fakeItem{}, false
+	}
+	return item, ok
+}
+
+// store writes item. Must be called with mu held.
+func (f *fakeMemcache) store(item *memcache.Item) {
+	var expires time.Time
+	switch {
+	case item.Expiration > 30*24*60*60:
+		expires = time.Unix(int64(item.Expiration), 0)
+	case item.Expiration > 0:
+		expires = time.Now().Add(time.Duration(item.Expiration) * time.Second)
+	}
+	f.version++
+	f.items[item.Key] = fakeItem{value: append([]byte(nil), item.Value...), version: f.version, expires: expires}
+}
+
+func (f *fakeMemcache) Get(key string) (*memcache.Item, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stored, ok := f.lookup(key)
+	if !ok {
+		return nil, memcache.ErrCacheMiss
+	}
+	item := &memcache.Item{Key: key, Value: append([]byte(nil), stored.value...)}
+	f.issued[item] = stored.version
+	return item, nil
+}
+
+func (f *fakeMemcache) GetMulti(keys []string) (map[string]*memcache.Item, error) {
+	items := make(map[string]*memcache.Item)
+	for _, key := range keys {
+		if item, err := f.Get(key); err == nil {
+			items[key] = item
+		}
+	}
+	return items, nil
+}
+
+func (f *fakeMemcache) Set(item *memcache.Item) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.store(item)
+	return nil
+}
+
+func (f *fakeMemcache) Add(item *memcache.Item) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.lookup(item.Key); ok {
+		return memcache.ErrNotStored
+	}
+	f.store(item)
+	return nil
+}
+
+func (f *fakeMemcache) CompareAndSwap(item *memcache.Item) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stored, ok := f.lookup(item.Key)
+	if !ok {
+		return memcache.ErrCacheMiss
+	}
+	if version, read := f.issued[item]; !read || version != stored.version {
+		return memcache.ErrCASConflict
+	}
+	f.store(item)
+	return nil
+}
+
+func (f *fakeMemcache) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.lookup(key); !ok {
+		return memcache.ErrCacheMiss
+	}
+	delete(f.items, key)
+	return nil
+}
+
+func (f *fakeMemcache) FlushAll() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.items = make(map[string]fakeItem)
+	f.flushes++
+	return nil
+}
+
+func (f *fakeMemcache) Ping() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pingErr
+}
+
+func (f *fakeMemcache) Close() error { return nil }
+
+func newTestMemcached(t *testing.T) (*Memcached, *fakeMemcache) {
+	t.Helper()
+	server := newFakeMemcache()
+	return newMemcached(server, DefaultMemcachedConfig()), server
+}
+
+func TestMemcached_BackendContract(t *testing.T) {
+	suite := cache.BackendTestSuite{
+		NewBackend: func(t *testing.T) cache.Backend {
+			backend, _ := newTestMemcached(t)
+			return backend
+		},
+	}
+
+	cache.RunBackendTests(t, suite)
+}
+
+// TestMemcached_Server runs the contract against a real server, if
+// HUE_CACHE_MEMCACHED names one (e.g. "localhost:11211").
+func TestMemcached_Server(t *testing.T) {
+	server := os.Getenv("HUE_CACHE_MEMCACHED")
+	if server == "" {
+		t.Skip("HUE_CACHE_MEMCACHED not set")
+	}
+
+	suite := cache.BackendTestSuite{
+		NewBackend: func(t *testing.T) cache.Backend {
+			config := DefaultMemcachedConfig()
+			config.Servers = []string{server}
+			config.KeyPrefix = "hue-cache-test:" + strings.ReplaceAll(t.Name(), "/", ".") + ":"
+			backend := NewMemcached(config)
+			if err := backend.Clear(context.Background()); err != nil {
+				t.Fatalf("Clear() failed: %v", err)
+			}
+			return backend
+		},
+	}
+
+	cache.RunBackendTests(t, suite)
+}
+
+func TestMemcached_KeyPrefixAndIndex(t *testing.T) {
+	backend, server := newTestMemcached(t)
+	defer backend.Close()
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("one"), 0)
+	backend.Set(ctx, "light:2", []byte("two"), 0)
+	backend.Set(ctx, "room:1", []byte("room"), 0)
+	backend.Delete(ctx, "light:2")
+
+	// Another application's item on the same server
+	server.Set(&memcache.Item{Key: "other-app:key", Value: []byte("theirs")})
+
+	if _, ok := server.items["hue-cache:light:1"]; !ok {
+		t.Errorf("items = %v, want keys under the prefix", server.items)
+	}
+
+	keys, err := backend.Keys(ctx, "light:*")
+	if err != nil {
+		t.Fatalf("Keys() failed: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "light:1" {
+		t.Errorf("Keys(light:*) = %v, want [light:1]", keys)
+	}
+
+	// Clear deletes only this cache's items
+	if err := backend.Clear(ctx); err != nil {
+		t.Fatalf("Clear() failed: %v", err)
+	}
+	var remaining []string
+	for key := range server.items {
+		remaining = append(remaining, key)
+	}
+	sort.Strings(remaining)
+	if len(remaining) != 1 || remaining[0] != "other-app:key" {
+		t.Errorf("after Clear(), server holds %v, want only the other application's item", remaining)
+	}
+}
+
+func TestMemcached_FlushOnClear(t *testing.T) {
+	server := newFakeMemcache()
+	config := DefaultMemcachedConfig()
+	config.FlushOnClear = true
+	backend := newMemcached(server, config)
+	defer backend.Close()
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("one"), 0)
+	if err := backend.Clear(ctx); err != nil {
+		t.Fatalf("Clear() failed: %v", err)
+	}
+	if server.flushes != 1 || len(server.items) != 0 {
+		t.Errorf("flushes = %d, items = %d; want 1, 0", server.flushes, len(server.items))
+	}
+}
+
+func TestMemcached_InvalidKeys(t *testing.T) {
+	backend, _ := newTestMemcached(t)
+	defer backend.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{"", "light:has space", "light:\n", indexKey, strings.Repeat("k", 250)} {
+		if err := backend.Set(ctx, key, []byte("v"), 0); !errors.Is(err, cache.ErrInvalidKey) {
+			t.Errorf("Set(%q) error = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestMemcached_HashLongKeys(t *testing.T) {
+	server := newFakeMemcache()
+	config := DefaultMemcachedConfig()
+	config.HashLongKeys = true
+	backend := newMemcached(server, config)
+	defer backend.Close()
+
+	ctx := context.Background()
+	long := "light:" + strings.Repeat("a", 300)
+	spaced := "light:has space"
+	for _, key := range []string{long, spaced, "light:1"} {
+		if err := backend.Set(ctx, key, []byte(key), 0); err != nil {
+			t.Fatalf("Set(%.20q) failed: %v", key, err)
+		}
+		entry, err := backend.Get(ctx, key)
+		if err != nil || string(entry.Value) != key || entry.Key != key {
+			t.Errorf("Get(%.20q) = %v, %v; want the stored entry", key, entry, err)
+		}
+	}
+
+	// Every item fits memcached's limits, and valid keys aren't hashed
+	server.mu.Lock()
+	for name := range server.items {
+		if len(name) > maxMemcachedKey || strings.ContainsAny(name, " \n") {
+			t.Errorf("stored under illegal memcached key %.40q", name)
+		}
+	}
+	_, plain := server.items[config.KeyPrefix+"light:1"]
+	server.mu.Unlock()
+	if !plain {
+		t.Error("light:1 was not stored under its own key")
+	}
+
+	// Listing reports the original keys
+	keys, err := backend.Keys(ctx, "light:*")
+	if err != nil {
+		t.Fatalf("Keys() failed: %v", err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 3 || keys[0] != "light:1" || keys[1] != long || keys[2] != spaced {
+		t.Errorf("Keys() = %d keys, want the 3 original keys", len(keys))
+	}
+	if keys, _ := backend.Keys(ctx, "room:*"); len(keys) != 0 {
+		t.Errorf("Keys(room:*) = %v, want none", keys)
+	}
+
+	if applied, err := backend.SetIf(ctx, long, []byte("new"), 0, func(*cache.Entry) bool { return true }); err != nil || !applied {
+		t.Errorf("SetIf() = %v, %v; want applied", applied, err)
+	}
+	if err := backend.Touch(ctx, long, time.Hour); err != nil {
+		t.Errorf("Touch() failed: %v", err)
+	}
+	if err := backend.Delete(ctx, long); err != nil {
+		t.Errorf("Delete() failed: %v", err)
+	}
+	if _, err := backend.Get(ctx, long); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+
+	// Empty keys and the index stay invalid
+	for _, key := range []string{"", indexKey} {
+		if err := backend.Set(ctx, key, []byte("v"), 0); !errors.Is(err, cache.ErrInvalidKey) {
+			t.Errorf("Set(%q) error = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestMemcachedExpiration(t *testing.T) {
+	if got := memcachedExpiration(0); got != 0 {
+		t.Errorf("memcachedExpiration(0) = %d, want 0", got)
+	}
+	if got := memcachedExpiration(1500 * time.Millisecond); got != 2 {
+		t.Errorf("memcachedExpiration(1.5s) = %d, want 2 (rounded up)", got)
+	}
+	if got := memcachedExpiration(60 * 24 * time.Hour); int64(got) < time.Now().Unix() {
+		t.Errorf("memcachedExpiration(60d) = %d, want a Unix time", got)
+	}
+}
+
+func TestMemcached_DisableKeyIndex(t *testing.T) {
+	server := newFakeMemcache()
+	config := DefaultMemcachedConfig()
+	config.DisableKeyIndex = true
+	backend := newMemcached(server, config)
+	defer backend.Close()
+
+	ctx := context.Background()
+	if err := backend.Set(ctx, "light:1", []byte("one"), 0); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	if _, ok := server.items["hue-cache:"+indexKey]; ok {
+		t.Error("Set() wrote the key index")
+	}
+	if entry, err := backend.Get(ctx, "light:1"); err != nil || string(entry.Value) != "one" {
+		t.Errorf("Get() = %v, %v", entry, err)
+	}
+
+	if _, err := backend.Keys(ctx, "*"); !errors.Is(err, cache.ErrUnsupported) {
+		t.Errorf("Keys() error = %v, want ErrUnsupported", err)
+	}
+	if _, err := cache.ListEntries(ctx, backend, "*"); !errors.Is(err, cache.ErrUnsupported) {
+		t.Errorf("ListEntries() error = %v, want ErrUnsupported", err)
+	}
+	if err := backend.Clear(ctx); !errors.Is(err, cache.ErrUnsupported) {
+		t.Errorf("Clear() error = %v, want ErrUnsupported", err)
+	}
+	if stats, err := backend.Stats(ctx); err != nil || stats.Entries != 0 || stats.Hits != 1 {
+		t.Errorf("Stats() = %+v, %v; want hits but no entry count", stats, err)
+	}
+}
+
+func TestMemcached_Peek(t *testing.T) {
+	backend, _ := newTestMemcached(t)
+	defer backend.Close()
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("value"), 0)
+
+	entry, err := backend.Peek(ctx, "light:1")
+	if err != nil {
+		t.Fatalf("Peek() failed: %v", err)
+	}
+	if string(entry.Value) != "value" {
+		t.Errorf("Peek() value = %q, want value", entry.Value)
+	}
+	if _, err := backend.Peek(ctx, "light:missing"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Peek() of missing key error = %v, want ErrNotFound", err)
+	}
+
+	stats, _ := backend.Stats(ctx)
+	if stats.Hits != 0 || stats.Misses != 0 {
+		t.Errorf("Stats = %d hits, %d misses; want 0, 0", stats.Hits, stats.Misses)
+	}
+}
+
+func TestMemcached_Ping(t *testing.T) {
+	backend, server := newTestMemcached(t)
+	defer backend.Close()
+
+	ctx := context.Background()
+	if err := backend.Ping(ctx); err != nil {
+		t.Errorf("Ping() failed: %v", err)
+	}
+
+	server.mu.Lock()
+	server.pingErr = memcache.ErrNoServers
+	server.mu.Unlock()
+	if err := backend.Ping(ctx); !errors.Is(err, memcache.ErrNoServers) {
+		t.Errorf("Ping() error = %v, want ErrNoServers", err)
+	}
+}
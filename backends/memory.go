@@ -2,55 +2,371 @@ package backends
 
 import (
 	"context"
-	"strings"
+	"errors"
+	"runtime"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unsafe"
 
 	cache "github.com/rmrfslashbin/hue-cache"
 )
 
-// Memory implements an in-memory cache backend using sync.Map.
+// Memory implements an in-memory cache backend.
 // It supports TTL expiration, memory limits, and LRU eviction.
+//
+// Entries are spread across independently locked shards by key hash, so
+// concurrent operations on different keys rarely contend for a lock.
 type Memory struct {
-	// data stores cache entries
-	data sync.Map
-
-	// stats tracks cache statistics
-	stats *cache.StatsCollector
+	// shards stores cache entries and statistics, partitioned by key hash
+	shards []*memoryShard
 
 	// config holds configuration
 	config *MemoryConfig
 
+	// logger receives eviction diagnostics
+	logger cache.Logger
+
+	// changes notifies OnChange listeners
+	changes *cache.ChangeFeed
+
+	// keyBuilder parses keys for type priorities and per-type statistics,
+	// relative to MemoryConfig.KeyPrefix
+	keyBuilder *cache.KeyBuilder
+
+	// sketch estimates access frequencies for TinyLFU admission
+	// (nil unless EvictionPolicy is EvictionTinyLFU)
+	sketch *frequencySketch
+
 	// cleanup manages background cleanup
 	cleanupTicker *time.Ticker
 	cleanupDone   chan struct{}
 
-	// mu protects size tracking and eviction
-	mu         sync.RWMutex
-	totalSize  int64
-	entryCount int64
+	// total sums the shards' sizes and entry counts, so writes can check
+	// the limits without locking every shard
+	total memoryTotals
+
+	// evictMu serializes eviction across shards
+	evictMu sync.Mutex
+
+	// cleanupMu protects cleanupStats
+	cleanupMu    sync.Mutex
+	cleanupStats CleanupStats
+
+	// latency records operation latencies
+	// (nil unless MemoryConfig.TrackLatency is set)
+	latency *latencyTracker
 
 	// closed tracks if backend is closed
-	closed bool
+	closed atomic.Bool
+}
+
+// memoryShard is one partition of the memory backend's entries.
+type memoryShard struct {
+	// mu protects data, size, and entries
+	mu      sync.Mutex
+	data    map[string]*cache.Entry
+	size    int64
+	entries int64
+
+	// stats tracks hits, misses, and evictions for this shard
+	// (nil if MemoryConfig.DisableStats is set)
+	stats *cache.StatsCollector
+
+	// byType holds per-resource-type counters, protected by mu
+	// (nil unless MemoryConfig.StatsByType is set)
+	byType map[string]*cache.Stats
+
+	// tags indexes the keys of tagged entries by tag, protected by mu
+	tags map[string]map[string]struct{}
+
+	// keyBuilder parses keys for byType (the Memory's keyBuilder)
+	keyBuilder *cache.KeyBuilder
+
+	// total is the Memory's running total this shard's size and entries
+	// are counted in (nil for shards staged by ReplaceAll)
+	total *memoryTotals
+}
+
+// memoryTotals is the size and entry count of all of a Memory's shards.
+// The counters are atomic, so they can be read without locking a shard.
+type memoryTotals struct {
+	size    atomic.Int64
+	entries atomic.Int64
+}
+
+// add adjusts the totals. A nil memoryTotals is a no-op.
+func (t *memoryTotals) add(size, entries int64) {
+	if t == nil {
+		return
+	}
+	t.size.Add(size)
+	t.entries.Add(entries)
+}
+
+// add adjusts the shard's size and entry count, and those of key's
+// resource type. Must be called with mu held.
+func (s *memoryShard) add(key string, size, entries int64) {
+	s.size += size
+	s.entries += entries
+	s.total.add(size, entries)
+
+	if ts := s.typeStats(key); ts != nil {
+		ts.Size += size
+		ts.Entries += entries
+	}
+}
+
+// resize sets the shard's size and entry count, keeping the totals in
+// step. Must be called with mu held.
+func (s *memoryShard) resize(size, entries int64) {
+	s.total.add(size-s.size, entries-s.entries)
+	s.size = size
+	s.entries = entries
+}
+
+// put stores entry under key, replacing any existing entry. Must be
+// called with mu held.
+func (s *memoryShard) put(key string, entry *cache.Entry) {
+	if oldEntry, exists := s.data[key]; exists {
+		s.add(key, entry.Size-oldEntry.Size, 0)
+		s.untag(key, oldEntry)
+	} else {
+		s.add(key, entry.Size, 1)
+	}
+	s.data[key] = entry
+	s.tag(key, entry)
+}
+
+// remove deletes entry, stored under key. Must be called with mu held.
+func (s *memoryShard) remove(key string, entry *cache.Entry) {
+	delete(s.data, key)
+	s.add(key, -entry.Size, -1)
+	s.untag(key, entry)
+}
+
+// tag indexes key under each of entry's tags. Must be called with mu held.
+func (s *memoryShard) tag(key string, entry *cache.Entry) {
+	for _, tag := range entry.Tags {
+		if s.tags == nil {
+			s.tags = make(map[string]map[string]struct{})
+		}
+		keys, ok := s.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			s.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// untag removes key from the index of each of entry's tags. Must be
+// called with mu held.
+func (s *memoryShard) untag(key string, entry *cache.Entry) {
+	for _, tag := range entry.Tags {
+		delete(s.tags[tag], key)
+		if len(s.tags[tag]) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
+
+// allows reports whether SetIf may replace key: it is absent or expired,
+// or cond approves the current entry. Must be called with mu held.
+func (s *memoryShard) allows(key string, cond func(*cache.Entry) bool) bool {
+	existing, ok := s.data[key]
+	return !ok || existing.IsExpired() || cond(existing)
+}
+
+// recordHit counts a hit, unless statistics are disabled.
+func (s *memoryShard) recordHit() {
+	if s.stats != nil {
+		s.stats.RecordHit()
+	}
+}
+
+// recordMiss counts a miss, unless statistics are disabled.
+func (s *memoryShard) recordMiss() {
+	if s.stats != nil {
+		s.stats.RecordMiss()
+	}
+}
+
+// recordEviction counts an eviction, unless statistics are disabled.
+func (s *memoryShard) recordEviction() {
+	if s.stats != nil {
+		s.stats.RecordEviction()
+	}
+}
+
+// typeStats returns the counters for key's resource type, or nil if
+// per-type statistics are disabled. Keys without a resource type prefix,
+// or outside MemoryConfig.KeyPrefix, are counted under "". Must be called
+// with mu held.
+func (s *memoryShard) typeStats(key string) *cache.Stats {
+	if s.byType == nil {
+		return nil
+	}
+
+	resourceType, _, _ := s.keyBuilder.ParseKey(key)
+	ts, ok := s.byType[resourceType]
+	if !ok {
+		ts = &cache.Stats{}
+		s.byType[resourceType] = ts
+	}
+	return ts
 }
 
 // MemoryConfig contains configuration options for the memory backend.
 type MemoryConfig struct {
 	// MaxMemory is the maximum memory in bytes (0 = unlimited).
+	// Limits apply to the whole backend, not to each shard. Under
+	// concurrent writes they are enforced approximately: each writer makes
+	// room before storing, so the cache may briefly exceed a limit by the
+	// entries being written at that moment. Writes are only serialized
+	// once a limit is reached, while they evict.
 	MaxMemory int64
 
 	// MaxEntries is the maximum number of entries (0 = unlimited).
+	// Enforced like MaxMemory.
 	MaxEntries int64
 
+	// MaxEntrySize is the maximum size in bytes of a single value
+	// (0 = unlimited). Set rejects larger values with cache.ErrValueTooLarge
+	// before evicting anything, so one oversized resource cannot flush the
+	// rest of the cache to make room for itself.
+	MaxEntrySize int64
+
 	// CleanupInterval is how often to run TTL cleanup.
 	// Default: 1 minute
 	CleanupInterval time.Duration
 
+	// CleanupBatchSize is the maximum number of expired entries cleanup
+	// removes while holding a shard's lock. Between batches cleanup
+	// releases the lock and yields, so removing a large number of expired
+	// entries doesn't stall concurrent operations.
+	// Default: 0 (remove each shard's expired entries in one batch)
+	CleanupBatchSize int
+
+	// CleanupBatchDelay throttles cleanup by pausing between batches,
+	// spreading the work of a large expiry over time.
+	// Default: 0 (yield without pausing)
+	CleanupBatchDelay time.Duration
+
 	// EvictionPolicy determines how to evict entries when limits are reached.
 	// Default: LRU
 	EvictionPolicy EvictionPolicy
+
+	// Logger receives eviction diagnostics.
+	// Default: no-op logger
+	Logger cache.Logger
+
+	// SlidingTTL resets an entry's expiration on every hit, extending it
+	// by the entry's original TTL. Frequently read entries stay cached
+	// while unread ones expire. Entries without a TTL are unaffected.
+	// Default: false
+	SlidingTTL bool
+
+	// ChangeBufferSize is the number of change events buffered for each
+	// OnChange listener. Events for a listener whose buffer is full are
+	// dropped, so a slow listener never stalls cache writes.
+	// Default: 256
+	ChangeBufferSize int
+
+	// TypePriorities ranks resource types (the key prefix, e.g. "light")
+	// for eviction. Entries of lower-priority types are evicted before any
+	// entry of a higher-priority type; the EvictionPolicy chooses among
+	// entries of equal priority. Unlisted types have priority 0.
+	//
+	// Example: {"light": 10, "grouped_light": 10, "room": 5, "scene": -1}
+	// Default: nil (all types equal)
+	TypePriorities map[string]int
+
+	// KeyPrefix is the namespace of the keys stored in the backend (see
+	// cache.NewKeyBuilderWithPrefix). TypePriorities and StatsByType read
+	// a key's resource type after it; keys outside the namespace have no
+	// type. Set it to the KeyPrefix of the clients and sync engine
+	// writing to the backend.
+	// Default: "" (no namespace)
+	KeyPrefix string
+
+	// Shards is the number of independently locked partitions entries are
+	// spread across by key hash. More shards reduce lock contention under
+	// concurrent access; 1 puts every entry behind a single lock.
+	// Default: 16
+	Shards int
+
+	// StatsByType additionally tracks statistics per resource type (the
+	// key prefix, e.g. "light"), reported by StatsByType. It costs a key
+	// parse and map lookup on every operation.
+	// Default: false
+	StatsByType bool
+
+	// DisableStats skips hit, miss, and eviction counting, removing the
+	// atomic increments from every Get for maximum throughput. Stats then
+	// reports zero for those counters; Entries and Size are still
+	// tracked, since memory limits depend on them. StatsByType is
+	// unaffected.
+	// Default: false
+	DisableStats bool
+
+	// TrackLatency records the latency of every Get, Set, Delete, and
+	// Keys, reported by LatencyStats. Use it to tell whether slow cached
+	// reads come from the backend (e.g. lock contention) or the bridge.
+	// It costs two clock reads and a few atomic updates per operation.
+	// Default: false
+	TrackLatency bool
+
+	// OnEvict is called with each entry the backend removes on its own:
+	// to make room under MaxEntries or MaxMemory, or because its TTL
+	// expired (found by cleanup or by a Get). Explicit deletes and clears
+	// are not evictions. It runs on the goroutine that caused the
+	// eviction, after the backend's locks are released, so it may call
+	// back into the backend; keep it fast, since that goroutine's Set or
+	// Get waits for it.
+	// Default: nil
+	OnEvict func(key string, entry *cache.Entry, reason EvictReason)
+}
+
+// EvictReason describes why the memory backend evicted an entry.
+type EvictReason int
+
+const (
+	// EvictCapacity means the entry was evicted to stay within MaxEntries.
+	EvictCapacity EvictReason = iota
+
+	// EvictMemory means the entry was evicted to stay within MaxMemory.
+	EvictMemory
+
+	// EvictExpired means the entry's TTL expired.
+	EvictExpired
+)
+
+// String returns the reason's name.
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictMemory:
+		return "memory"
+	case EvictExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// eviction records an entry evicted while locks were held, so OnEvict
+// can be called once they are released.
+type eviction struct {
+	entry  *cache.Entry
+	reason EvictReason
 }
 
+// defaultShards is the shard count used when MemoryConfig.Shards is 0.
+const defaultShards = 16
+
 // EvictionPolicy determines how entries are evicted when limits are reached.
 type EvictionPolicy int
 
@@ -63,8 +379,23 @@ const (
 
 	// EvictionFIFO evicts oldest entries first.
 	EvictionFIFO
+
+	// EvictionTinyLFU evicts least recently used entries, but only admits
+	// a new entry if it has been accessed more often recently than the
+	// entry it would evict; otherwise Set drops the new entry and keeps
+	// the cache as it is. Access frequencies are estimated with a
+	// count-min sketch that ages, so one-off scans can't flush entries
+	// that are read repeatedly. Updates of cached keys are always stored.
+	EvictionTinyLFU
 )
 
+// errAdmissionRejected is returned by evictOne when the TinyLFU admission
+// policy keeps the victim instead of admitting the new entry.
+var errAdmissionRejected = errors.New("rejected by admission policy")
+
+// defaultSketchCapacity sizes the TinyLFU sketch when MaxEntries is unset.
+const defaultSketchCapacity = 1024
+
 // DefaultMemoryConfig returns default configuration.
 func DefaultMemoryConfig() *MemoryConfig {
 	return &MemoryConfig{
@@ -72,6 +403,7 @@ func DefaultMemoryConfig() *MemoryConfig {
 		MaxEntries:      0, // Unlimited
 		CleanupInterval: 1 * time.Minute,
 		EvictionPolicy:  EvictionLRU,
+		Shards:          defaultShards,
 	}
 }
 
@@ -82,10 +414,44 @@ func NewMemory(config ...*MemoryConfig) *Memory {
 		cfg = config[0]
 	}
 
+	logger := cfg.Logger
+	if logger == nil {
+		logger = cache.NopLogger()
+	}
+
+	shards := cfg.Shards
+	if shards <= 0 {
+		shards = defaultShards
+	}
+
 	m := &Memory{
-		stats:       cache.NewStatsCollector(),
+		shards:      make([]*memoryShard, shards),
 		config:      cfg,
+		logger:      logger,
+		changes:     cache.NewChangeFeed(cfg.ChangeBufferSize),
+		keyBuilder:  cache.NewKeyBuilderWithPrefix(cfg.KeyPrefix),
 		cleanupDone: make(chan struct{}),
+		latency:     newLatencyTracker(cfg.TrackLatency),
+	}
+	if cfg.EvictionPolicy == EvictionTinyLFU {
+		capacity := defaultSketchCapacity
+		if cfg.MaxEntries > 0 {
+			capacity = int(cfg.MaxEntries)
+		}
+		m.sketch = newFrequencySketch(capacity)
+	}
+	for i := range m.shards {
+		m.shards[i] = &memoryShard{
+			data:       make(map[string]*cache.Entry),
+			keyBuilder: m.keyBuilder,
+			total:      &m.total,
+		}
+		if !cfg.DisableStats {
+			m.shards[i].stats = cache.NewStatsCollector()
+		}
+		if cfg.StatsByType {
+			m.shards[i].byType = make(map[string]*cache.Stats)
+		}
 	}
 
 	// Start background cleanup if interval is set
@@ -99,7 +465,11 @@ func NewMemory(config ...*MemoryConfig) *Memory {
 
 // Get retrieves a value from the cache.
 func (m *Memory) Get(ctx context.Context, key string) (*cache.Entry, error) {
-	if m.closed {
+	if m.latency != nil {
+		defer m.latency.get.record(time.Now())
+	}
+
+	if m.closed.Load() {
 		return nil, cache.NewError("Get", key, cache.ErrBackendClosed)
 	}
 
@@ -107,20 +477,37 @@ func (m *Memory) Get(ctx context.Context, key string) (*cache.Entry, error) {
 		return nil, cache.NewError("Get", key, cache.ErrInvalidKey)
 	}
 
-	value, ok := m.data.Load(key)
+	// Misses count too: a key that keeps missing is worth admitting
+	if m.sketch != nil {
+		m.sketch.increment(key)
+	}
+
+	shard := m.shard(key)
+	shard.mu.Lock()
+
+	entry, ok := shard.data[key]
 	if !ok {
-		m.stats.RecordMiss()
+		if ts := shard.typeStats(key); ts != nil {
+			ts.Misses++
+		}
+		shard.mu.Unlock()
+		shard.recordMiss()
 		return nil, cache.NewError("Get", key, cache.ErrNotFound)
 	}
 
-	entry := value.(*cache.Entry)
-
 	// Check expiration
 	if entry.IsExpired() {
-		m.stats.RecordMiss()
-		m.data.Delete(key)
-		m.updateSize(-entry.Size)
-		m.stats.RecordEviction()
+		shard.remove(key, entry)
+		if ts := shard.typeStats(key); ts != nil {
+			ts.Misses++
+			ts.Evictions++
+		}
+		shard.mu.Unlock()
+
+		shard.recordMiss()
+		shard.recordEviction()
+		m.changes.Publish(cache.ChangeEvent{Key: key, Op: cache.ChangeExpire})
+		m.evicted(entry, EvictExpired)
 		return nil, cache.NewError("Get", key, cache.ErrExpired)
 	}
 
@@ -128,13 +515,93 @@ func (m *Memory) Get(ctx context.Context, key string) (*cache.Entry, error) {
 	entry.Hits++
 	entry.UpdatedAt = time.Now()
 
-	m.stats.RecordHit()
-	return entry.Clone(), nil
+	// Sliding expiration extends the entry by its original TTL
+	if m.config.SlidingTTL && entry.TTL > 0 {
+		entry.ExpiresAt = entry.UpdatedAt.Add(entry.TTL)
+	}
+
+	if ts := shard.typeStats(key); ts != nil {
+		ts.Hits++
+	}
+
+	result := entry.Clone()
+	shard.mu.Unlock()
+
+	shard.recordHit()
+	return result, nil
+}
+
+// GetMeta returns metadata for the entry under key without copying its
+// value. Unlike Get it doesn't count as a hit, update the entry, or
+// extend a sliding TTL. See cache.MetaGetter.
+func (m *Memory) GetMeta(ctx context.Context, key string) (*cache.EntryMeta, error) {
+	if m.closed.Load() {
+		return nil, cache.NewError("GetMeta", key, cache.ErrBackendClosed)
+	}
+
+	if key == "" {
+		return nil, cache.NewError("GetMeta", key, cache.ErrInvalidKey)
+	}
+
+	shard := m.shard(key)
+	shard.mu.Lock()
+	entry, ok := shard.data[key]
+	if !ok {
+		shard.mu.Unlock()
+		return nil, cache.NewError("GetMeta", key, cache.ErrNotFound)
+	}
+	meta := entry.Meta()
+	expired := entry.IsExpired()
+	shard.mu.Unlock()
+
+	if expired {
+		return nil, cache.NewError("GetMeta", key, cache.ErrExpired)
+	}
+	return meta, nil
+}
+
+// Peek returns the entry under key without side effects: unlike Get it
+// doesn't count as a hit or miss, update the entry, extend a sliding TTL,
+// or evict an expired entry. See cache.Peeker.
+func (m *Memory) Peek(ctx context.Context, key string) (*cache.Entry, error) {
+	if m.closed.Load() {
+		return nil, cache.NewError("Peek", key, cache.ErrBackendClosed)
+	}
+
+	if key == "" {
+		return nil, cache.NewError("Peek", key, cache.ErrInvalidKey)
+	}
+
+	shard := m.shard(key)
+	shard.mu.Lock()
+	entry, ok := shard.data[key]
+	if !ok {
+		shard.mu.Unlock()
+		return nil, cache.NewError("Peek", key, cache.ErrNotFound)
+	}
+	expired := entry.IsExpired()
+	result := entry.Clone()
+	shard.mu.Unlock()
+
+	if expired {
+		return nil, cache.NewError("Peek", key, cache.ErrExpired)
+	}
+	return result, nil
 }
 
 // Set stores a value in the cache.
 func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
-	if m.closed {
+	return m.SetWithTags(ctx, key, value, ttl, nil)
+}
+
+// SetWithTags stores a value like Set, tagged with tags for DeleteTag.
+// See cache.Tagger.
+func (m *Memory) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	if m.latency != nil {
+		defer m.latency.set.record(time.Now())
+	}
+
+	if m.closed.Load() {
 		return cache.NewError("Set", key, cache.ErrBackendClosed)
 	}
 
@@ -142,38 +609,162 @@ func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Dur
 		return cache.NewError("Set", key, cache.ErrInvalidKey)
 	}
 
-	if value == nil {
-		return cache.NewError("Set", key, cache.ErrInvalidValue)
+	// Reject oversized values before evicting anything to fit them
+	if err := cache.ValidateValue(value, m.config.MaxEntrySize); err != nil {
+		return cache.NewError("Set", key, err)
 	}
 
 	entry := cache.NewEntry(key, value, ttl)
+	entry.Tags = slices.Clone(tags)
+
+	if m.sketch != nil {
+		m.sketch.increment(key)
+	}
 
 	// Check if we need to evict
-	if err := m.makeRoom(entry.Size); err != nil {
+	if err := m.makeRoom(key, entry.Size); err != nil {
+		if errors.Is(err, errAdmissionRejected) {
+			m.logger.Debug("entry not admitted", "key", key, "policy", m.config.EvictionPolicy)
+			return nil
+		}
 		return cache.NewError("Set", key, err)
 	}
 
-	// Check if key already exists
-	if oldValue, ok := m.data.Load(key); ok {
-		oldEntry := oldValue.(*cache.Entry)
-		m.updateSize(-oldEntry.Size)
-	}
+	shard := m.shard(key)
+	shard.mu.Lock()
+	shard.put(key, entry)
+	shard.mu.Unlock()
 
-	m.data.Store(key, entry)
-	m.updateSize(entry.Size)
+	m.changes.Publish(cache.ChangeEvent{Key: key, Op: cache.ChangeSet, Value: value})
 
 	return nil
 }
 
+// SetIf stores a value if the key is absent or expired, or cond approves
+// the current entry. cond is evaluated under the shard lock, in the same
+// critical section as the write. It is also checked once before making
+// room, so a rejected write doesn't evict anything. See
+// cache.ConditionalSetter.
+func (m *Memory) SetIf(ctx context.Context, key string, value []byte, ttl time.Duration, cond func(*cache.Entry) bool) (bool, error) {
+	return m.setIf(cache.NewEntry(key, value, ttl), cond)
+}
+
+// load stores an entry read back from persistent storage, to expire after
+// ttl. It keeps the entry's CreatedAt, so its age carries across restarts,
+// and its TTL, which sliding expiration extends it by. Unless overwrite is
+// set, an entry written since the load started is kept instead.
+func (m *Memory) load(saved *cache.Entry, ttl time.Duration, overwrite bool) {
+	entry := cache.NewEntry(saved.Key, saved.Value, ttl)
+	entry.Tags = slices.Clone(saved.Tags)
+	if !saved.CreatedAt.IsZero() {
+		entry.CreatedAt = saved.CreatedAt
+	}
+	if ttl > 0 && saved.TTL > 0 {
+		entry.TTL = saved.TTL
+	}
+	_, _ = m.setIf(entry, func(*cache.Entry) bool { return overwrite })
+}
+
+// setIf is SetIf for a prepared entry.
+func (m *Memory) setIf(entry *cache.Entry, cond func(*cache.Entry) bool) (bool, error) {
+	key, value := entry.Key, entry.Value
+	if m.closed.Load() {
+		return false, cache.NewError("SetIf", key, cache.ErrBackendClosed)
+	}
+
+	if key == "" {
+		return false, cache.NewError("SetIf", key, cache.ErrInvalidKey)
+	}
+
+	if err := cache.ValidateValue(value, m.config.MaxEntrySize); err != nil {
+		return false, cache.NewError("SetIf", key, err)
+	}
+
+	shard := m.shard(key)
+	shard.mu.Lock()
+	allowed := shard.allows(key, cond)
+	shard.mu.Unlock()
+	if !allowed {
+		return false, nil
+	}
+
+	if m.sketch != nil {
+		m.sketch.increment(key)
+	}
+
+	if err := m.makeRoom(key, entry.Size); err != nil {
+		if errors.Is(err, errAdmissionRejected) {
+			m.logger.Debug("entry not admitted", "key", key, "policy", m.config.EvictionPolicy)
+			return false, nil
+		}
+		return false, cache.NewError("SetIf", key, err)
+	}
+
+	// Check again: another write may have landed while making room
+	shard.mu.Lock()
+	if !shard.allows(key, cond) {
+		shard.mu.Unlock()
+		return false, nil
+	}
+	shard.put(key, entry)
+	shard.mu.Unlock()
+
+	m.changes.Publish(cache.ChangeEvent{Key: key, Op: cache.ChangeSet, Value: value})
+
+	return true, nil
+}
+
 // Delete removes a key from the cache.
 func (m *Memory) Delete(ctx context.Context, key string) error {
-	if m.closed {
+	if m.latency != nil {
+		defer m.latency.delete.record(time.Now())
+	}
+
+	if m.closed.Load() {
 		return cache.NewError("Delete", key, cache.ErrBackendClosed)
 	}
 
-	if value, ok := m.data.LoadAndDelete(key); ok {
-		entry := value.(*cache.Entry)
-		m.updateSize(-entry.Size)
+	shard := m.shard(key)
+	shard.mu.Lock()
+	entry, ok := shard.data[key]
+	if ok {
+		shard.remove(key, entry)
+	}
+	shard.mu.Unlock()
+
+	if ok {
+		m.changes.Publish(cache.ChangeEvent{Key: key, Op: cache.ChangeDelete})
+	}
+
+	return nil
+}
+
+// Touch resets the TTL of an unexpired entry in place, without copying
+// its value or counting a hit. Expired entries are left for cleanup. See
+// cache.Toucher.
+func (m *Memory) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if m.closed.Load() {
+		return cache.NewError("Touch", key, cache.ErrBackendClosed)
+	}
+
+	if key == "" {
+		return cache.NewError("Touch", key, cache.ErrInvalidKey)
+	}
+
+	shard := m.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	entry, ok := shard.data[key]
+	if !ok || entry.IsExpired() {
+		return cache.NewError("Touch", key, cache.ErrNotFound)
+	}
+
+	entry.TTL = ttl
+	if ttl > 0 {
+		entry.ExpiresAt = time.Now().Add(ttl)
+	} else {
+		entry.ExpiresAt = time.Time{}
 	}
 
 	return nil
@@ -181,73 +772,451 @@ func (m *Memory) Delete(ctx context.Context, key string) error {
 
 // Clear removes all entries from the cache.
 func (m *Memory) Clear(ctx context.Context) error {
-	if m.closed {
+	if m.closed.Load() {
 		return cache.NewError("Clear", "", cache.ErrBackendClosed)
 	}
 
-	m.data.Range(func(key, value interface{}) bool {
-		m.data.Delete(key)
-		return true
-	})
+	m.clear()
+	return nil
+}
+
+// clear empties every shard. It is shared by Clear and Close.
+func (m *Memory) clear() {
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+		removed := shard.data
+		shard.data = make(map[string]*cache.Entry)
+		shard.tags = nil
+		shard.resize(0, 0)
+		for _, ts := range shard.byType {
+			ts.Size = 0
+			ts.Entries = 0
+		}
+		shard.mu.Unlock()
+
+		for key := range removed {
+			m.changes.Publish(cache.ChangeEvent{Key: key, Op: cache.ChangeDelete})
+		}
+	}
+}
+
+// ReplaceAll atomically replaces the cache's contents with items, each
+// stored with ttl. The new entries are built before any shard is locked
+// and then swapped in with every shard locked at once, so concurrent
+// readers see either the old contents or the new ones, never an empty
+// cache. Items that exceed MaxEntries or MaxMemory fail with
+// cache.ErrMemoryLimit, and values over MaxEntrySize with
+// cache.ErrValueTooLarge; either way the cache is left unchanged. OnChange
+// listeners see a delete for each key dropped and a set for each key
+// stored.
+func (m *Memory) ReplaceAll(ctx context.Context, items map[string][]byte, ttl time.Duration) error {
+	if m.closed.Load() {
+		return cache.NewError("ReplaceAll", "", cache.ErrBackendClosed)
+	}
+
+	// Stage the new contents of each shard, counting sizes as put does
+	staged := make([]*memoryShard, len(m.shards))
+	for i := range staged {
+		staged[i] = &memoryShard{data: make(map[string]*cache.Entry), keyBuilder: m.keyBuilder}
+		if m.config.StatsByType {
+			staged[i].byType = make(map[string]*cache.Stats)
+		}
+	}
+	var size int64
+	for key, value := range items {
+		if key == "" {
+			return cache.NewError("ReplaceAll", key, cache.ErrInvalidKey)
+		}
+		if err := cache.ValidateValue(value, m.config.MaxEntrySize); err != nil {
+			return cache.NewError("ReplaceAll", key, err)
+		}
+		entry := cache.NewEntry(key, value, ttl)
+		staged[keyHash(key)%uint32(len(m.shards))].put(key, entry)
+		size += entry.Size
+	}
+	if (m.config.MaxEntries > 0 && int64(len(items)) > m.config.MaxEntries) ||
+		(m.config.MaxMemory > 0 && size > m.config.MaxMemory) {
+		return cache.NewError("ReplaceAll", "", cache.ErrMemoryLimit)
+	}
 
-	m.mu.Lock()
-	m.totalSize = 0
-	m.entryCount = 0
-	m.mu.Unlock()
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+	}
+	var dropped []string
+	for i, shard := range m.shards {
+		for key := range shard.data {
+			if _, kept := items[key]; !kept {
+				dropped = append(dropped, key)
+			}
+		}
+		shard.data = staged[i].data
+		shard.tags = nil
+		shard.resize(staged[i].size, staged[i].entries)
+
+		// Keep the per-type hit and miss counters; replace only the totals
+		for _, ts := range shard.byType {
+			ts.Size = 0
+			ts.Entries = 0
+		}
+		for resourceType, ts := range staged[i].byType {
+			current, ok := shard.byType[resourceType]
+			if !ok {
+				current = &cache.Stats{}
+				shard.byType[resourceType] = current
+			}
+			current.Size = ts.Size
+			current.Entries = ts.Entries
+		}
+	}
+	for _, shard := range m.shards {
+		shard.mu.Unlock()
+	}
 
-	m.stats.SetSize(0)
-	m.stats.SetEntries(0)
+	for _, key := range dropped {
+		m.changes.Publish(cache.ChangeEvent{Key: key, Op: cache.ChangeDelete})
+	}
+	for key, value := range items {
+		m.changes.Publish(cache.ChangeEvent{Key: key, Op: cache.ChangeSet, Value: value})
+	}
 
 	return nil
 }
 
+// DeletePattern deletes all keys matching the pattern, expired or not,
+// with every shard locked at once, so concurrent readers see either all
+// the matching entries or none of them. See cache.PatternDeleter.
+func (m *Memory) DeletePattern(ctx context.Context, pattern string) (int, error) {
+	if m.closed.Load() {
+		return 0, cache.NewError("DeletePattern", "", cache.ErrBackendClosed)
+	}
+
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+	}
+	var deleted []string
+	for _, shard := range m.shards {
+		for key, entry := range shard.data {
+			if matchPattern(key, pattern) {
+				shard.remove(key, entry)
+				deleted = append(deleted, key)
+			}
+		}
+	}
+	for _, shard := range m.shards {
+		shard.mu.Unlock()
+	}
+
+	for _, key := range deleted {
+		m.changes.Publish(cache.ChangeEvent{Key: key, Op: cache.ChangeDelete})
+	}
+
+	return len(deleted), nil
+}
+
+// DeleteMany deletes keys, expired or not, with every shard locked at
+// once, like DeletePattern. Absent keys are skipped. See
+// cache.BatchDeleter.
+func (m *Memory) DeleteMany(ctx context.Context, keys []string) (int, error) {
+	if m.closed.Load() {
+		return 0, cache.NewError("DeleteMany", "", cache.ErrBackendClosed)
+	}
+
+	return len(m.deleteMany(keys)), nil
+}
+
+// deleteMany deletes keys with every shard locked at once and returns the
+// keys that were deleted.
+func (m *Memory) deleteMany(keys []string) []string {
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+	}
+	var deleted []string
+	for _, key := range keys {
+		shard := m.shard(key)
+		if entry, ok := shard.data[key]; ok {
+			shard.remove(key, entry)
+			deleted = append(deleted, key)
+		}
+	}
+	for _, shard := range m.shards {
+		shard.mu.Unlock()
+	}
+
+	for _, key := range deleted {
+		m.changes.Publish(cache.ChangeEvent{Key: key, Op: cache.ChangeDelete})
+	}
+
+	return deleted
+}
+
+// DeleteTag deletes all entries tagged with tag, expired or not, using
+// the tag index and with every shard locked at once, like DeletePattern.
+// See cache.TagDeleter.
+func (m *Memory) DeleteTag(ctx context.Context, tag string) (int, error) {
+	if m.closed.Load() {
+		return 0, cache.NewError("DeleteTag", "", cache.ErrBackendClosed)
+	}
+
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+	}
+	var deleted []string
+	for _, shard := range m.shards {
+		for key := range shard.tags[tag] {
+			shard.remove(key, shard.data[key])
+			deleted = append(deleted, key)
+		}
+	}
+	for _, shard := range m.shards {
+		shard.mu.Unlock()
+	}
+
+	for _, key := range deleted {
+		m.changes.Publish(cache.ChangeEvent{Key: key, Op: cache.ChangeDelete})
+	}
+
+	return len(deleted), nil
+}
+
 // Keys returns all keys matching the pattern.
 func (m *Memory) Keys(ctx context.Context, pattern string) ([]string, error) {
-	if m.closed {
+	if m.latency != nil {
+		defer m.latency.keys.record(time.Now())
+	}
+
+	if m.closed.Load() {
 		return nil, cache.NewError("Keys", "", cache.ErrBackendClosed)
 	}
 
 	var keys []string
 
-	m.data.Range(func(key, value interface{}) bool {
-		k := key.(string)
-		if matchPattern(k, pattern) {
-			entry := value.(*cache.Entry)
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+		for key, entry := range shard.data {
 			// Skip expired entries
-			if !entry.IsExpired() {
-				keys = append(keys, k)
+			if matchPattern(key, pattern) && !entry.IsExpired() {
+				keys = append(keys, key)
 			}
 		}
-		return true
-	})
+		shard.mu.Unlock()
+	}
 
 	return keys, nil
 }
 
-// Stats returns cache statistics.
+// Entries returns the metadata of unexpired entries matching pattern, in
+// one pass over the shards and without copying values. See
+// cache.EntryLister.
+func (m *Memory) Entries(ctx context.Context, pattern string) ([]*cache.EntryInfo, error) {
+	if m.closed.Load() {
+		return nil, cache.NewError("Entries", "", cache.ErrBackendClosed)
+	}
+
+	var infos []*cache.EntryInfo
+
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+		for key, entry := range shard.data {
+			if matchPattern(key, pattern) && !entry.IsExpired() {
+				infos = append(infos, entry.Info())
+			}
+		}
+		shard.mu.Unlock()
+	}
+
+	return infos, nil
+}
+
+// Snapshot returns copies of all unexpired entries as of a single moment.
+// It holds every shard's lock while it collects the entries, so a
+// concurrent write is either entirely in the snapshot or entirely absent,
+// and no write to one key lands between the reads of two others. Unlike
+// Get, it doesn't count hits or touch the entries.
+func (m *Memory) Snapshot(ctx context.Context) ([]*cache.Entry, error) {
+	if m.closed.Load() {
+		return nil, cache.NewError("Snapshot", "", cache.ErrBackendClosed)
+	}
+
+	// Copy only the entry structs under the locks, keeping writers out
+	// briefly; values are never modified in place, so they are cloned
+	// after the locks are released
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+	}
+	var count int64
+	for _, shard := range m.shards {
+		count += shard.entries
+	}
+	copies := make([]cache.Entry, 0, count)
+	for _, shard := range m.shards {
+		for _, entry := range shard.data {
+			if !entry.IsExpired() {
+				copies = append(copies, *entry)
+			}
+		}
+	}
+	for _, shard := range m.shards {
+		shard.mu.Unlock()
+	}
+
+	entries := make([]*cache.Entry, len(copies))
+	for i := range copies {
+		entries[i] = copies[i].Clone()
+	}
+	return entries, nil
+}
+
+// OnChange registers listener to be notified of every Set, Delete,
+// eviction, and expiration, and returns a function that unregisters it.
+// Listeners run asynchronously; see MemoryConfig.ChangeBufferSize.
+func (m *Memory) OnChange(listener func(cache.ChangeEvent)) (unsubscribe func()) {
+	return m.changes.OnChange(listener)
+}
+
+// Stats returns cache statistics. With MemoryConfig.DisableStats set,
+// Hits, Misses, and Evictions are always zero.
 func (m *Memory) Stats(ctx context.Context) (*cache.Stats, error) {
-	if m.closed {
+	if m.closed.Load() {
 		return nil, cache.NewError("Stats", "", cache.ErrBackendClosed)
 	}
 
-	stats := m.stats.Stats()
+	all := make([]*cache.Stats, len(m.shards))
+	for i, shard := range m.shards {
+		stats := &cache.Stats{}
+		if shard.stats != nil {
+			stats = shard.stats.Stats()
+		}
+
+		shard.mu.Lock()
+		stats.Entries = shard.entries
+		stats.Size = shard.size
+		shard.mu.Unlock()
+
+		all[i] = stats
+	}
+
+	return all[0].Merge(all[1:]...), nil
+}
+
+// ResetStats zeroes hit, miss, and eviction counters, including per-type
+// counters, and the latency statistics, while leaving entries intact.
+// Entries and Size continue to reflect the cached data. See
+// cache.StatsResetter.
+func (m *Memory) ResetStats(ctx context.Context) error {
+	if m.closed.Load() {
+		return cache.NewError("ResetStats", "", cache.ErrBackendClosed)
+	}
+
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+		if shard.stats != nil {
+			shard.stats.Reset()
+		}
+		for _, ts := range shard.byType {
+			*ts = cache.Stats{Entries: ts.Entries, Size: ts.Size}
+		}
+		shard.mu.Unlock()
+	}
+	m.latency.reset()
 
-	m.mu.RLock()
-	stats.Entries = m.entryCount
-	stats.Size = m.totalSize
-	m.mu.RUnlock()
+	return nil
+}
 
-	return stats, nil
+// LatencyStats returns the latencies of Get, Set, Delete, and Keys since
+// the backend was created or ResetStats was last called. It is all zeros
+// unless MemoryConfig.TrackLatency is set.
+func (m *Memory) LatencyStats() LatencyStats {
+	return m.latency.stats()
+}
+
+// StatsByType returns statistics per resource type (the key prefix, e.g.
+// "light"), merged across shards. Keys without a type prefix are reported
+// under "". The map is empty unless MemoryConfig.StatsByType is set.
+// Entries and Size are current; Hits, Misses, and Evictions are counted
+// since the backend was created.
+func (m *Memory) StatsByType(ctx context.Context) (map[string]*cache.Stats, error) {
+	if m.closed.Load() {
+		return nil, cache.NewError("StatsByType", "", cache.ErrBackendClosed)
+	}
+
+	byType := make(map[string]*cache.Stats)
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+		for resourceType, ts := range shard.byType {
+			if merged, ok := byType[resourceType]; ok {
+				byType[resourceType] = merged.Merge(ts)
+			} else {
+				byType[resourceType] = ts.Clone()
+			}
+		}
+		shard.mu.Unlock()
+	}
+
+	return byType, nil
+}
+
+// DetailedStats summarizes the sizes and ages of the unexpired entries,
+// in one pass over the shards.
+func (m *Memory) DetailedStats(ctx context.Context) (*cache.DetailedStats, error) {
+	if m.closed.Load() {
+		return nil, cache.NewError("DetailedStats", "", cache.ErrBackendClosed)
+	}
+
+	var sizes []int64
+	var ages []time.Duration
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+		for _, entry := range shard.data {
+			if !entry.IsExpired() {
+				sizes = append(sizes, int64(len(entry.Value)))
+				ages = append(ages, entry.Age())
+			}
+		}
+		shard.mu.Unlock()
+	}
+
+	return cache.NewDetailedStats(sizes, ages), nil
+}
+
+// mapEntryOverhead approximates the per-entry cost of a Go map: the key
+// string header and entry pointer plus a share of the bucket array.
+const mapEntryOverhead = 64
+
+// EstimateFootprint approximates the process memory held by the cache in
+// bytes. Unlike Stats.Size, which only counts value bytes, it includes the
+// Entry structs, key strings, and map bookkeeping. Use it to choose a
+// realistic MaxMemory; it is an estimate, not an exact measurement.
+func (m *Memory) EstimateFootprint() int64 {
+	entrySize := int64(unsafe.Sizeof(cache.Entry{}))
+
+	var total int64
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+		for key, entry := range shard.data {
+			total += entrySize + mapEntryOverhead
+			total += int64(len(key))
+			total += int64(cap(entry.Value))
+		}
+		shard.mu.Unlock()
+	}
+
+	return total
+}
+
+// Ping returns nil unless the backend is closed. See cache.Pinger.
+func (m *Memory) Ping(ctx context.Context) error {
+	if m.closed.Load() {
+		return cache.NewError("Ping", "", cache.ErrBackendClosed)
+	}
+	return nil
 }
 
 // Close releases resources held by the backend.
 func (m *Memory) Close() error {
-	if m.closed {
+	if !m.closed.CompareAndSwap(false, true) {
 		return nil
 	}
 
-	m.closed = true
-
 	// Stop cleanup goroutine
 	if m.cleanupTicker != nil {
 		m.cleanupTicker.Stop()
@@ -255,7 +1224,8 @@ func (m *Memory) Close() error {
 	}
 
 	// Clear all data
-	m.Clear(context.Background())
+	m.clear()
+	m.changes.Close()
 
 	return nil
 }
@@ -272,142 +1242,324 @@ func (m *Memory) cleanupLoop() {
 	}
 }
 
-// cleanupExpired removes expired entries.
+// CleanupStats describes the background removal of expired entries.
+type CleanupStats struct {
+	// Runs is the number of cleanup runs completed.
+	Runs int64
+
+	// LastRemoved is the number of entries removed by the last run.
+	LastRemoved int64
+
+	// TotalRemoved is the number of entries removed by all runs.
+	TotalRemoved int64
+
+	// LastRun is when the last run finished.
+	LastRun time.Time
+
+	// LastDuration is how long the last run took.
+	LastDuration time.Duration
+}
+
+// CleanupStats returns statistics for TTL cleanup runs.
+func (m *Memory) CleanupStats() CleanupStats {
+	m.cleanupMu.Lock()
+	defer m.cleanupMu.Unlock()
+	return m.cleanupStats
+}
+
+// cleanupExpired removes expired entries, one shard at a time. Each
+// shard's expired keys are found in one scan and removed in batches of
+// CleanupBatchSize, yielding between batches. Entries are re-checked
+// before removal, so ones replaced or deleted since the scan are left
+// alone; ones that expire after the scan are removed by the next run.
 func (m *Memory) cleanupExpired() {
-	var toDelete []string
+	start := time.Now()
+	var removed int64
+
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+		var keys []string
+		for key, entry := range shard.data {
+			if entry.IsExpired() {
+				keys = append(keys, key)
+			}
+		}
+		shard.mu.Unlock()
+
+		batchSize := m.config.CleanupBatchSize
+		if batchSize <= 0 {
+			batchSize = len(keys)
+		}
 
-	m.data.Range(func(key, value interface{}) bool {
-		entry := value.(*cache.Entry)
-		if entry.IsExpired() {
-			toDelete = append(toDelete, key.(string))
+		for len(keys) > 0 {
+			n := min(batchSize, len(keys))
+			removed += m.cleanupBatch(shard, keys[:n])
+			keys = keys[n:]
+
+			if len(keys) > 0 && !m.pauseCleanup() {
+				return
+			}
 		}
-		return true
-	})
+	}
 
-	for _, key := range toDelete {
-		if value, ok := m.data.LoadAndDelete(key); ok {
-			entry := value.(*cache.Entry)
-			m.updateSize(-entry.Size)
-			m.stats.RecordEviction()
+	m.cleanupMu.Lock()
+	m.cleanupStats.Runs++
+	m.cleanupStats.LastRemoved = removed
+	m.cleanupStats.TotalRemoved += removed
+	m.cleanupStats.LastRun = time.Now()
+	m.cleanupStats.LastDuration = time.Since(start)
+	m.cleanupMu.Unlock()
+
+	if removed > 0 {
+		m.logger.Debug("cleaned up expired entries", "removed", removed, "duration", time.Since(start))
+	}
+}
+
+// cleanupBatch removes the entries under keys that are still expired,
+// returning how many were removed.
+func (m *Memory) cleanupBatch(shard *memoryShard, keys []string) int64 {
+	var expired []*cache.Entry
+
+	shard.mu.Lock()
+	for _, key := range keys {
+		entry, ok := shard.data[key]
+		if !ok || !entry.IsExpired() {
+			continue
+		}
+		shard.remove(key, entry)
+		if ts := shard.typeStats(key); ts != nil {
+			ts.Evictions++
 		}
+		expired = append(expired, entry)
 	}
+	shard.mu.Unlock()
+
+	for _, entry := range expired {
+		shard.recordEviction()
+		m.changes.Publish(cache.ChangeEvent{Key: entry.Key, Op: cache.ChangeExpire})
+		m.logger.Debug("evicted expired entry", "key", entry.Key)
+		m.evicted(entry, EvictExpired)
+	}
+
+	return int64(len(expired))
 }
 
-// makeRoom evicts entries if necessary to make room for new entry.
-func (m *Memory) makeRoom(newSize int64) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// pauseCleanup yields between cleanup batches, sleeping for
+// CleanupBatchDelay if set. It returns false if the backend was closed
+// meanwhile.
+func (m *Memory) pauseCleanup() bool {
+	if m.config.CleanupBatchDelay <= 0 {
+		runtime.Gosched()
+		return !m.closed.Load()
+	}
+
+	timer := time.NewTimer(m.config.CleanupBatchDelay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return !m.closed.Load()
+	case <-m.cleanupDone:
+		return false
+	}
+}
+
+// makeRoom evicts entries if necessary to make room for a new entry
+// under key. With TinyLFU, it returns errAdmissionRejected if the entry
+// should not be stored.
+func (m *Memory) makeRoom(key string, newSize int64) error {
+	// Most writes fit; only take evictMu once a limit is reached
+	if !m.full(newSize) {
+		return nil
+	}
+
+	m.evictMu.Lock()
+	evictions, err := m.evictFor(key, newSize)
+	m.evictMu.Unlock()
+
+	for _, e := range evictions {
+		m.evicted(e.entry, e.reason)
+	}
+	return err
+}
+
+// full reports whether a new entry of newSize would exceed MaxEntries or
+// MaxMemory. It reads the running totals without locking the shards.
+func (m *Memory) full(newSize int64) bool {
+	size, entries := m.totals()
+	return (m.config.MaxEntries > 0 && entries >= m.config.MaxEntries) ||
+		(m.config.MaxMemory > 0 && size+newSize > m.config.MaxMemory)
+}
+
+// evictFor evicts entries until a new entry of newSize under key fits,
+// returning the entries evicted. Must be called with evictMu held.
+func (m *Memory) evictFor(key string, newSize int64) ([]eviction, error) {
+	var evictions []eviction
+
+	// Only new keys are subject to admission
+	candidate := ""
+	if m.sketch != nil {
+		shard := m.shard(key)
+		shard.mu.Lock()
+		if _, exists := shard.data[key]; !exists {
+			candidate = key
+		}
+		shard.mu.Unlock()
+	}
 
 	// Check entry count limit
-	if m.config.MaxEntries > 0 && m.entryCount >= m.config.MaxEntries {
-		if err := m.evictOne(); err != nil {
-			return err
+	if _, entries := m.totals(); m.config.MaxEntries > 0 && entries >= m.config.MaxEntries {
+		victim, err := m.evictOne(candidate)
+		if err != nil {
+			return evictions, err
+		}
+		if victim != nil {
+			evictions = append(evictions, eviction{victim, EvictCapacity})
 		}
 	}
 
 	// Check memory limit
 	if m.config.MaxMemory > 0 {
-		for m.totalSize+newSize > m.config.MaxMemory {
-			if err := m.evictOne(); err != nil {
-				return err
+		for {
+			if size, _ := m.totals(); size+newSize <= m.config.MaxMemory {
+				break
+			}
+			victim, err := m.evictOne(candidate)
+			if err != nil {
+				return evictions, err
+			}
+			if victim != nil {
+				evictions = append(evictions, eviction{victim, EvictMemory})
 			}
 		}
 	}
 
-	return nil
+	return evictions, nil
 }
 
-// evictOne evicts a single entry based on the eviction policy.
-// Must be called with mu held.
-func (m *Memory) evictOne() error {
-	var evictKey string
-	var evictEntry *cache.Entry
+// evicted reports an evicted entry to OnEvict, if set.
+func (m *Memory) evicted(entry *cache.Entry, reason EvictReason) {
+	if m.config.OnEvict != nil {
+		m.config.OnEvict(entry.Key, entry, reason)
+	}
+}
 
-	switch m.config.EvictionPolicy {
-	case EvictionLRU:
-		// Find least recently used
-		var oldestTime time.Time
-		m.data.Range(func(key, value interface{}) bool {
-			entry := value.(*cache.Entry)
-			if oldestTime.IsZero() || entry.UpdatedAt.Before(oldestTime) {
-				oldestTime = entry.UpdatedAt
-				evictKey = key.(string)
-				evictEntry = entry
+// evictOne evicts a single entry based on the eviction policy.
+// If TypePriorities is set, entries of the lowest-priority type present
+// are evicted first, and the policy chooses among them. The victim is
+// chosen across all shards, so limits behave as if unsharded. With
+// TinyLFU, a non-empty candidate key must be accessed more often than the
+// victim, or nothing is evicted and errAdmissionRejected is returned.
+// It returns the evicted entry, or nil if the victim changed before it
+// could be removed. Must be called with evictMu held.
+func (m *Memory) evictOne(candidate string) (*cache.Entry, error) {
+	var victimShard *memoryShard
+	var victim *cache.Entry
+	var victimPriority int
+
+	// best is a copy of the victim, since its shard is unlocked while the
+	// remaining shards are scanned and concurrent hits may update it
+	var best cache.Entry
+
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+		for key, entry := range shard.data {
+			priority := m.priority(key)
+			if victim == nil || priority < victimPriority ||
+				(priority == victimPriority && m.preferEviction(entry, &best)) {
+				victimShard = shard
+				victim = entry
+				victimPriority = priority
+				best = *entry
 			}
-			return true
-		})
+		}
+		shard.mu.Unlock()
+	}
 
-	case EvictionLFU:
-		// Find least frequently used
-		var lowestHits int64 = -1
-		m.data.Range(func(key, value interface{}) bool {
-			entry := value.(*cache.Entry)
-			if lowestHits == -1 || entry.Hits < lowestHits {
-				lowestHits = entry.Hits
-				evictKey = key.(string)
-				evictEntry = entry
-			}
-			return true
-		})
+	if victim == nil {
+		return nil, cache.ErrMemoryLimit
+	}
 
-	case EvictionFIFO:
-		// Find oldest created
-		var oldestTime time.Time
-		m.data.Range(func(key, value interface{}) bool {
-			entry := value.(*cache.Entry)
-			if oldestTime.IsZero() || entry.CreatedAt.Before(oldestTime) {
-				oldestTime = entry.CreatedAt
-				evictKey = key.(string)
-				evictEntry = entry
-			}
-			return true
-		})
+	if m.sketch != nil && candidate != "" && m.sketch.estimate(candidate) <= m.sketch.estimate(victim.Key) {
+		return nil, errAdmissionRejected
 	}
 
-	if evictKey == "" {
-		return cache.ErrMemoryLimit
+	// Only remove the victim if it was not replaced or deleted meanwhile
+	victimShard.mu.Lock()
+	current, ok := victimShard.data[victim.Key]
+	removed := ok && current == victim
+	if removed {
+		victimShard.remove(victim.Key, victim)
+		if ts := victimShard.typeStats(victim.Key); ts != nil {
+			ts.Evictions++
+		}
 	}
+	victimShard.mu.Unlock()
 
-	m.data.Delete(evictKey)
-	m.totalSize -= evictEntry.Size
-	m.entryCount--
-	m.stats.RecordEviction()
+	if !removed {
+		return nil, nil
+	}
 
-	return nil
-}
+	victimShard.recordEviction()
+	m.changes.Publish(cache.ChangeEvent{Key: victim.Key, Op: cache.ChangeEvict})
+	m.logger.Debug("evicted entry to make room", "key", victim.Key, "size", victim.Size, "policy", m.config.EvictionPolicy)
 
-// updateSize updates the total size and entry count.
-func (m *Memory) updateSize(delta int64) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	return victim, nil
+}
 
-	m.totalSize += delta
-	if delta > 0 {
-		m.entryCount++
-	} else if delta < 0 {
-		m.entryCount--
+// preferEviction reports whether the eviction policy would evict
+// candidate before current.
+func (m *Memory) preferEviction(candidate, current *cache.Entry) bool {
+	switch m.config.EvictionPolicy {
+	case EvictionLRU, EvictionTinyLFU:
+		// Least recently used
+		return candidate.UpdatedAt.Before(current.UpdatedAt)
+	case EvictionLFU:
+		// Least frequently used
+		return candidate.Hits < current.Hits
+	case EvictionFIFO:
+		// Oldest created
+		return candidate.CreatedAt.Before(current.CreatedAt)
+	default:
+		return false
 	}
-
-	m.stats.SetSize(m.totalSize)
-	m.stats.SetEntries(m.entryCount)
 }
 
-// matchPattern matches a key against a pattern.
-// Supports: "*" (all), "prefix:*", "*:suffix", "exact"
-func matchPattern(key, pattern string) bool {
-	if pattern == "*" {
-		return true
+// priority returns the eviction priority of key's resource type.
+func (m *Memory) priority(key string) int {
+	if len(m.config.TypePriorities) == 0 {
+		return 0
 	}
+	resourceType, _, _ := m.keyBuilder.ParseKey(key)
+	return m.config.TypePriorities[resourceType]
+}
 
-	if strings.HasSuffix(pattern, "*") {
-		prefix := strings.TrimSuffix(pattern, "*")
-		return strings.HasPrefix(key, prefix)
+// shard returns the shard that owns key, chosen by FNV-1a hash.
+func (m *Memory) shard(key string) *memoryShard {
+	if len(m.shards) == 1 {
+		return m.shards[0]
 	}
 
-	if strings.HasPrefix(pattern, "*") {
-		suffix := strings.TrimPrefix(pattern, "*")
-		return strings.HasSuffix(key, suffix)
+	return m.shards[keyHash(key)%uint32(len(m.shards))]
+}
+
+// keyHash returns the FNV-1a hash of key.
+func keyHash(key string) uint32 {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
 	}
+	return hash
+}
 
-	return key == pattern
+// totals returns the size and entry count summed across all shards.
+func (m *Memory) totals() (size, entries int64) {
+	return m.total.size.Load(), m.total.entries.Load()
+}
+
+// matchPattern matches a key against a glob pattern.
+// See cache.MatchPattern for the supported syntax.
+func matchPattern(key, pattern string) bool {
+	return cache.MatchPattern(pattern, key)
 }
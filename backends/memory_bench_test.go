@@ -2,6 +2,9 @@ package backends
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -44,6 +47,41 @@ func BenchmarkMemory_Get(b *testing.B) {
 	}
 }
 
+// BenchmarkMemory_GetStats compares Get throughput with hit/miss
+// statistics enabled and disabled.
+func BenchmarkMemory_GetStats(b *testing.B) {
+	for _, disabled := range []bool{false, true} {
+		name := "enabled"
+		if disabled {
+			name = "disabled"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			config := DefaultMemoryConfig()
+			config.DisableStats = disabled
+			backend := NewMemory(config)
+			defer backend.Close()
+
+			ctx := context.Background()
+			value := []byte("test value")
+			for i := 0; i < 100; i++ {
+				backend.Set(ctx, "light:"+string(rune('0'+i)), value, 0)
+			}
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					_, _ = backend.Get(ctx, "light:"+string(rune('0'+(i%100))))
+					i++
+				}
+			})
+		})
+	}
+}
+
 func BenchmarkMemory_GetMiss(b *testing.B) {
 	backend := NewMemory()
 	defer backend.Close()
@@ -179,6 +217,41 @@ func BenchmarkMemory_ParallelSet(b *testing.B) {
 	})
 }
 
+// BenchmarkMemory_ParallelSetMaxEntries measures parallel writes with a
+// MaxEntries limit, both below it (rewriting keys) and at it (every new
+// key evicts).
+func BenchmarkMemory_ParallelSetMaxEntries(b *testing.B) {
+	for _, tc := range []struct {
+		name       string
+		maxEntries int64
+		keys       int
+	}{
+		{"below-limit", 10000, 1000},
+		{"evicting", 1000, 1 << 20},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			backend := NewMemory(&MemoryConfig{MaxEntries: tc.maxEntries})
+			defer backend.Close()
+
+			ctx := context.Background()
+			value := []byte("test value")
+			var worker atomic.Int64
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			b.RunParallel(func(pb *testing.PB) {
+				prefix := fmt.Sprintf("light:%d-", worker.Add(1))
+				i := 0
+				for pb.Next() {
+					_ = backend.Set(ctx, prefix+strconv.Itoa(i%tc.keys), value, 0)
+					i++
+				}
+			})
+		})
+	}
+}
+
 func BenchmarkMemory_ParallelMixed(b *testing.B) {
 	backend := NewMemory()
 	defer backend.Close()
@@ -209,6 +282,41 @@ func BenchmarkMemory_ParallelMixed(b *testing.B) {
 	})
 }
 
+// BenchmarkMemory_ParallelMixedSingleShard is ParallelMixed with every
+// entry behind one lock, for comparison with the default shard count.
+func BenchmarkMemory_ParallelMixedSingleShard(b *testing.B) {
+	config := DefaultMemoryConfig()
+	config.Shards = 1
+
+	backend := NewMemory(config)
+	defer backend.Close()
+
+	ctx := context.Background()
+	value := []byte("test value")
+
+	// Pre-populate
+	for i := 0; i < 100; i++ {
+		key := "light:" + string(rune('0'+i))
+		backend.Set(ctx, key, value, 0)
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := "light:" + string(rune('0'+(i%100)))
+			if i%2 == 0 {
+				_, _ = backend.Get(ctx, key)
+			} else {
+				_ = backend.Set(ctx, key, value, 0)
+			}
+			i++
+		}
+	})
+}
+
 func BenchmarkMemory_EvictionLRU(b *testing.B) {
 	config := &MemoryConfig{
 		MaxEntries:     100,
@@ -271,3 +379,23 @@ func BenchmarkMemory_EvictionFIFO(b *testing.B) {
 		_ = backend.Set(ctx, key, value, 0)
 	}
 }
+
+// BenchmarkMemory_ZipfHitRate compares the hit rate of LRU and TinyLFU
+// eviction on a Zipfian read-through workload, reported as hit-rate.
+func BenchmarkMemory_ZipfHitRate(b *testing.B) {
+	for _, policy := range []struct {
+		name   string
+		policy EvictionPolicy
+	}{
+		{"LRU", EvictionLRU},
+		{"TinyLFU", EvictionTinyLFU},
+	} {
+		b.Run(policy.name, func(b *testing.B) {
+			var rate float64
+			for i := 0; i < b.N; i++ {
+				rate = zipfHitRate(policy.policy, 20000)
+			}
+			b.ReportMetric(rate, "hit-rate")
+		})
+	}
+}
@@ -2,6 +2,12 @@ package backends
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
@@ -97,6 +103,49 @@ func TestMemory_TTLCleanup(t *testing.T) {
 	}
 }
 
+func TestMemory_SlidingTTL(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{SlidingTTL: true})
+	defer backend.Close()
+
+	ctx := context.Background()
+	ttl := 100 * time.Millisecond
+	if err := backend.Set(ctx, "test:sliding", []byte("value"), ttl); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+
+	// Keep reading well past the original expiry
+	deadline := time.Now().Add(3 * ttl)
+	for time.Now().Before(deadline) {
+		time.Sleep(ttl / 4)
+		if _, err := backend.Get(ctx, "test:sliding"); err != nil {
+			t.Fatalf("Get() failed while entry was being read: %v", err)
+		}
+	}
+
+	// Stop reading; the entry should now expire
+	time.Sleep(ttl + 50*time.Millisecond)
+	if _, err := backend.Get(ctx, "test:sliding"); err == nil {
+		t.Error("Get() should fail after the entry went unread for its TTL")
+	}
+}
+
+func TestMemory_SlidingTTL_Disabled(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{})
+	defer backend.Close()
+
+	ctx := context.Background()
+	ttl := 100 * time.Millisecond
+	backend.Set(ctx, "test:fixed", []byte("value"), ttl)
+
+	time.Sleep(ttl / 2)
+	backend.Get(ctx, "test:fixed")
+	time.Sleep(ttl/2 + 20*time.Millisecond)
+
+	if _, err := backend.Get(ctx, "test:fixed"); err == nil {
+		t.Error("Reads should not extend expiration without SlidingTTL")
+	}
+}
+
 func TestMemory_MaxEntries(t *testing.T) {
 	config := &MemoryConfig{
 		MaxEntries:     5,
@@ -232,6 +281,94 @@ func TestMemory_EvictionLRU(t *testing.T) {
 	}
 }
 
+func TestMemory_EvictionTypePriorities(t *testing.T) {
+	config := &MemoryConfig{
+		MaxEntries:     4,
+		EvictionPolicy: EvictionLRU,
+		TypePriorities: map[string]int{"light": 10, "room": 5, "scene": -1},
+	}
+
+	backend := NewMemory(config)
+	defer backend.Close()
+
+	ctx := context.Background()
+
+	// Scenes are written last, so plain LRU would evict lights first
+	backend.Set(ctx, "light:1", []byte("value"), 0)
+	time.Sleep(5 * time.Millisecond)
+	backend.Set(ctx, "room:1", []byte("value"), 0)
+	time.Sleep(5 * time.Millisecond)
+	backend.Set(ctx, "scene:1", []byte("value"), 0)
+	time.Sleep(5 * time.Millisecond)
+	backend.Set(ctx, "scene:2", []byte("value"), 0)
+	time.Sleep(5 * time.Millisecond)
+
+	// At capacity: each new light evicts a scene, oldest first
+	backend.Set(ctx, "light:2", []byte("value"), 0)
+	if _, err := backend.Get(ctx, "scene:1"); err == nil {
+		t.Error("scene:1 should be evicted first (lowest priority, least recent)")
+	}
+	if _, err := backend.Get(ctx, "scene:2"); err != nil {
+		t.Errorf("scene:2 should remain after one eviction: %v", err)
+	}
+
+	backend.Set(ctx, "light:3", []byte("value"), 0)
+	if _, err := backend.Get(ctx, "scene:2"); err == nil {
+		t.Error("scene:2 should be evicted second")
+	}
+
+	// With no scenes left, rooms go before lights
+	backend.Set(ctx, "light:4", []byte("value"), 0)
+	if _, err := backend.Get(ctx, "room:1"); err == nil {
+		t.Error("room:1 should be evicted before any light")
+	}
+
+	for _, key := range []string{"light:1", "light:2", "light:3", "light:4"} {
+		if _, err := backend.Get(ctx, key); err != nil {
+			t.Errorf("%s should not be evicted: %v", key, err)
+		}
+	}
+}
+
+func TestMemory_KeyPrefix(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{
+		MaxEntries:     2,
+		EvictionPolicy: EvictionLRU,
+		TypePriorities: map[string]int{"light": 10, "scene": -1},
+		StatsByType:    true,
+		KeyPrefix:      "bridge-A",
+	})
+	defer backend.Close()
+
+	ctx := context.Background()
+	kb := cache.NewKeyBuilderWithPrefix("bridge-A")
+
+	// Plain LRU would evict the older light; the scene has lower priority
+	backend.Set(ctx, kb.Light("1"), []byte("value"), 0)
+	time.Sleep(5 * time.Millisecond)
+	backend.Set(ctx, kb.Scene("1"), []byte("value"), 0)
+	time.Sleep(5 * time.Millisecond)
+	backend.Set(ctx, kb.Light("2"), []byte("value"), 0)
+
+	if _, err := backend.Get(ctx, kb.Scene("1")); err == nil {
+		t.Error("scene should be evicted before a light")
+	}
+	if _, err := backend.Get(ctx, kb.Light("1")); err != nil {
+		t.Errorf("light:1 should not be evicted: %v", err)
+	}
+
+	byType, _ := backend.StatsByType(ctx)
+	if light := byType["light"]; light == nil || light.Entries != 2 || light.Hits != 1 {
+		t.Errorf("light stats = %+v, want 2 entries and 1 hit", light)
+	}
+	if scene := byType["scene"]; scene == nil || scene.Misses != 1 || scene.Evictions != 1 {
+		t.Errorf("scene stats = %+v, want 1 miss and 1 eviction", scene)
+	}
+	if _, ok := byType["bridge-A"]; ok {
+		t.Errorf("StatsByType() reported the namespace as a type: %v", byType)
+	}
+}
+
 func TestMemory_EvictionLFU(t *testing.T) {
 	config := &MemoryConfig{
 		MaxEntries:     3,
@@ -274,6 +411,83 @@ func TestMemory_EvictionLFU(t *testing.T) {
 	}
 }
 
+func TestMemory_EvictionTinyLFU(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{
+		MaxEntries:     3,
+		EvictionPolicy: EvictionTinyLFU,
+	})
+	defer backend.Close()
+
+	ctx := context.Background()
+
+	// Three hot entries, each read repeatedly
+	for _, key := range []string{"test:1", "test:2", "test:3"} {
+		backend.Set(ctx, key, []byte("value"), 0)
+		for i := 0; i < 3; i++ {
+			backend.Get(ctx, key)
+		}
+	}
+
+	// A one-off scan is not admitted, so it can't flush them
+	for i := 0; i < 10; i++ {
+		if err := backend.Set(ctx, fmt.Sprintf("scan:%d", i), []byte("value"), 0); err != nil {
+			t.Fatalf("Set() failed: %v", err)
+		}
+	}
+	for _, key := range []string{"test:1", "test:2", "test:3"} {
+		if _, err := backend.Get(ctx, key); err != nil {
+			t.Errorf("%s should survive a scan: %v", key, err)
+		}
+	}
+	if _, err := backend.Get(ctx, "scan:9"); err == nil {
+		t.Error("scan:9 should not have been admitted")
+	}
+
+	// A key that keeps being requested is eventually admitted
+	for i := 0; i < 10; i++ {
+		backend.Get(ctx, "test:4")
+	}
+	backend.Set(ctx, "test:4", []byte("value"), 0)
+	if _, err := backend.Get(ctx, "test:4"); err != nil {
+		t.Errorf("test:4 should have been admitted: %v", err)
+	}
+
+	// Updates of cached keys are always stored
+	backend.Set(ctx, "test:4", []byte("updated"), 0)
+	if entry, err := backend.Get(ctx, "test:4"); err != nil || string(entry.Value) != "updated" {
+		t.Errorf("Get(test:4) = %v, %v; want the updated value", entry, err)
+	}
+}
+
+// zipfHitRate replays a Zipfian read-through workload against a memory
+// backend with room for 5% of the keys and returns its hit rate.
+func zipfHitRate(policy EvictionPolicy, accesses int) float64 {
+	backend := NewMemory(&MemoryConfig{MaxEntries: 50, EvictionPolicy: policy})
+	defer backend.Close()
+
+	ctx := context.Background()
+	value := []byte("value")
+	hits := 0
+	for _, access := range zipfWorkload(accesses, 1000, 100) {
+		if _, err := backend.Get(ctx, access.Key); err == nil {
+			hits++
+			continue
+		}
+		backend.Set(ctx, access.Key, value, 0)
+	}
+	return float64(hits) / float64(accesses)
+}
+
+func TestMemory_TinyLFUZipfHitRate(t *testing.T) {
+	lru := zipfHitRate(EvictionLRU, 20000)
+	tinyLFU := zipfHitRate(EvictionTinyLFU, 20000)
+	t.Logf("LRU=%.3f TinyLFU=%.3f", lru, tinyLFU)
+
+	if tinyLFU <= lru {
+		t.Errorf("TinyLFU hit rate %.3f should beat LRU %.3f on a skewed workload", tinyLFU, lru)
+	}
+}
+
 func TestMemory_EvictionFIFO(t *testing.T) {
 	config := &MemoryConfig{
 		MaxEntries:     3,
@@ -309,6 +523,59 @@ func TestMemory_EvictionFIFO(t *testing.T) {
 	}
 }
 
+func TestMemory_OnEvict(t *testing.T) {
+	ctx := context.Background()
+	spill := NewMemory()
+	defer spill.Close()
+
+	var backend *Memory
+	reasons := make(map[string]EvictReason)
+	backend = NewMemory(&MemoryConfig{
+		MaxEntries:     2,
+		MaxMemory:      1000,
+		EvictionPolicy: EvictionFIFO,
+		OnEvict: func(key string, entry *cache.Entry, reason EvictReason) {
+			reasons[key] = reason
+			// Re-entering the backend must not deadlock
+			if _, err := backend.Keys(ctx, "*"); err != nil {
+				t.Errorf("Keys() in OnEvict failed: %v", err)
+			}
+			spill.Set(ctx, key, entry.Value, 0)
+		},
+	})
+	defer backend.Close()
+
+	backend.Set(ctx, "test:1", []byte("value1"), 0)
+	time.Sleep(10 * time.Millisecond)
+	backend.Set(ctx, "test:2", []byte("value2"), time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	// Over MaxEntries: evicts test:1
+	backend.Set(ctx, "test:3", []byte("value3"), 0)
+	if reasons["test:1"] != EvictCapacity {
+		t.Errorf("test:1 reason = %v, want capacity", reasons["test:1"])
+	}
+
+	// Expired on read
+	backend.Get(ctx, "test:2")
+	if reason, ok := reasons["test:2"]; !ok || reason != EvictExpired {
+		t.Errorf("test:2 reason = %v (evicted %v), want expired", reason, ok)
+	}
+
+	// Over MaxMemory: evicts test:3
+	backend.Set(ctx, "test:4", make([]byte, 995), 0)
+	if reason, ok := reasons["test:3"]; !ok || reason != EvictMemory {
+		t.Errorf("test:3 reason = %v (evicted %v), want memory", reason, ok)
+	}
+
+	// Evicted entries were spilled
+	for _, key := range []string{"test:1", "test:2", "test:3"} {
+		if _, err := spill.Get(ctx, key); err != nil {
+			t.Errorf("spill Get(%s) failed: %v", key, err)
+		}
+	}
+}
+
 func TestMemory_Close(t *testing.T) {
 	backend := NewMemory()
 	ctx := context.Background()
@@ -438,3 +705,1067 @@ func TestMemory_UpdateExistingKey(t *testing.T) {
 		t.Errorf("Value = %q, want %q", entry.Value, "longer value")
 	}
 }
+
+func TestMemory_EstimateFootprint(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{})
+	defer backend.Close()
+
+	if got := backend.EstimateFootprint(); got != 0 {
+		t.Errorf("EstimateFootprint() on empty cache = %d, want 0", got)
+	}
+
+	const entries = 10000
+	ctx := context.Background()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	for i := 0; i < entries; i++ {
+		key := fmt.Sprintf("light:%08d-0000-0000-0000", i)
+		if err := backend.Set(ctx, key, make([]byte, 256), 0); err != nil {
+			t.Fatalf("Set() failed: %v", err)
+		}
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	actual := int64(after.HeapAlloc) - int64(before.HeapAlloc)
+	estimate := backend.EstimateFootprint()
+
+	stats, _ := backend.Stats(ctx)
+	if estimate <= stats.Size {
+		t.Errorf("EstimateFootprint() = %d, should exceed value bytes %d", estimate, stats.Size)
+	}
+
+	// The estimate should be within a factor of 2 of the measured heap growth
+	if estimate < actual/2 || estimate > actual*2 {
+		t.Errorf("EstimateFootprint() = %d, measured heap growth = %d", estimate, actual)
+	}
+	t.Logf("estimate=%d measured=%d", estimate, actual)
+}
+
+func TestMemory_OnChange(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{MaxEntries: 2})
+	defer backend.Close()
+
+	var _ cache.ChangeNotifier = backend
+
+	events := make(chan cache.ChangeEvent, 16)
+	unsubscribe := backend.OnChange(func(event cache.ChangeEvent) {
+		events <- event
+	})
+	defer unsubscribe()
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("one"), 0)
+	backend.Set(ctx, "light:2", []byte("two"), 0)
+	backend.Set(ctx, "light:3", []byte("three"), 0) // evicts one entry
+	backend.Delete(ctx, "light:3")
+	backend.Delete(ctx, "light:missing") // no-op, no event
+	backend.Set(ctx, "light:4", []byte("four"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	backend.Get(ctx, "light:4") // expires on read
+
+	want := []cache.ChangeOp{
+		cache.ChangeSet, cache.ChangeSet,
+		cache.ChangeEvict, cache.ChangeSet,
+		cache.ChangeDelete,
+		cache.ChangeSet, cache.ChangeExpire,
+	}
+
+	var got []cache.ChangeEvent
+	timeout := time.After(time.Second)
+	for len(got) < len(want) {
+		select {
+		case event := <-events:
+			got = append(got, event)
+		case <-timeout:
+			t.Fatalf("Timed out with %d of %d events: %+v", len(got), len(want), got)
+		}
+	}
+
+	for i, op := range want {
+		if got[i].Op != op {
+			t.Errorf("Event %d op = %v, want %v", i, got[i].Op, op)
+		}
+	}
+	if got[0].Key != "light:1" || string(got[0].Value) != "one" {
+		t.Errorf("First event = %+v, want set light:1 = one", got[0])
+	}
+	if got[4].Key != "light:3" || got[4].Value != nil {
+		t.Errorf("Delete event = %+v, want light:3 with no value", got[4])
+	}
+}
+
+func TestMemory_OnChange_SlowListenerDoesNotBlock(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{ChangeBufferSize: 1})
+	defer backend.Close()
+
+	release := make(chan struct{})
+	unsubscribe := backend.OnChange(func(event cache.ChangeEvent) {
+		<-release
+	})
+	defer unsubscribe()
+	defer close(release)
+
+	ctx := context.Background()
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			backend.Set(ctx, fmt.Sprintf("light:%d", i), []byte("value"), 0)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Set blocked on a slow change listener")
+	}
+}
+
+func TestMemory_Shards(t *testing.T) {
+	for _, shards := range []int{1, 4, 16} {
+		t.Run(fmt.Sprintf("shards=%d", shards), func(t *testing.T) {
+			backend := NewMemory(&MemoryConfig{Shards: shards})
+			defer backend.Close()
+
+			if len(backend.shards) != shards {
+				t.Fatalf("len(shards) = %d, want %d", len(backend.shards), shards)
+			}
+
+			ctx := context.Background()
+			for i := 0; i < 100; i++ {
+				backend.Set(ctx, fmt.Sprintf("light:%d", i), []byte("value"), 0)
+			}
+
+			// Every shard should hold some keys
+			for i, shard := range backend.shards {
+				if len(shard.data) == 0 {
+					t.Errorf("shard %d is empty", i)
+				}
+			}
+
+			keys, _ := backend.Keys(ctx, "light:*")
+			if len(keys) != 100 {
+				t.Errorf("Keys returned %d keys, want 100", len(keys))
+			}
+
+			stats, _ := backend.Stats(ctx)
+			if stats.Entries != 100 || stats.Size != 500 {
+				t.Errorf("Stats = %d entries, %d bytes; want 100 entries, 500 bytes", stats.Entries, stats.Size)
+			}
+
+			backend.Clear(ctx)
+			stats, _ = backend.Stats(ctx)
+			if stats.Entries != 0 || stats.Size != 0 {
+				t.Errorf("after Clear, Stats = %d entries, %d bytes; want 0", stats.Entries, stats.Size)
+			}
+		})
+	}
+}
+
+func TestMemory_DefaultShards(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{})
+	defer backend.Close()
+
+	if len(backend.shards) != defaultShards {
+		t.Errorf("len(shards) = %d, want %d", len(backend.shards), defaultShards)
+	}
+}
+
+func TestMemory_ConcurrentLimit(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{MaxEntries: 50})
+	defer backend.Close()
+
+	ctx := context.Background()
+
+	const writers = 8
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				key := fmt.Sprintf("light:%d-%d", w, i)
+				backend.Set(ctx, key, []byte("value"), 0)
+				backend.Get(ctx, key)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// Limits are approximate under concurrency: at most one extra entry
+	// per writer racing past the check
+	stats, _ := backend.Stats(ctx)
+	if stats.Entries > 50+writers {
+		t.Errorf("Entries = %d, want at most %d", stats.Entries, 50+writers)
+	}
+
+	keys, _ := backend.Keys(ctx, "*")
+	if int64(len(keys)) != stats.Entries {
+		t.Errorf("Keys returned %d keys but Stats reports %d entries", len(keys), stats.Entries)
+	}
+}
+
+func TestMemory_MaxEntrySize(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{MaxMemory: 100, MaxEntrySize: 40})
+	defer backend.Close()
+
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		backend.Set(ctx, fmt.Sprintf("light:%d", i), make([]byte, 20), 0)
+	}
+
+	// Rejected without evicting anything
+	err := backend.Set(ctx, "scene:huge", make([]byte, 41), 0)
+	if !errors.Is(err, cache.ErrValueTooLarge) {
+		t.Fatalf("Set() error = %v, want ErrValueTooLarge", err)
+	}
+	if !errors.Is(err, cache.ErrInvalidValue) {
+		t.Errorf("Set() error = %v, should also match ErrInvalidValue", err)
+	}
+
+	stats, _ := backend.Stats(ctx)
+	if stats.Entries != 3 || stats.Evictions != 0 {
+		t.Errorf("Stats = %d entries, %d evictions; want 3 entries, 0 evictions", stats.Entries, stats.Evictions)
+	}
+
+	// Values at the limit are accepted
+	if err := backend.Set(ctx, "scene:ok", make([]byte, 40), 0); err != nil {
+		t.Errorf("Set() at limit failed: %v", err)
+	}
+}
+
+func TestMemory_GetMeta(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{SlidingTTL: true})
+	defer backend.Close()
+
+	var _ cache.MetaGetter = backend
+	var _ cache.MetaGetter = (*File)(nil)
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("value"), time.Minute)
+	backend.Get(ctx, "light:1")
+	backend.Get(ctx, "light:1")
+
+	var meta *cache.EntryMeta
+	for i := 0; i < 3; i++ {
+		var err error
+		meta, err = backend.GetMeta(ctx, "light:1")
+		if err != nil {
+			t.Fatalf("GetMeta() failed: %v", err)
+		}
+	}
+
+	// Metadata reads are not hits and leave the entry untouched
+	if meta.Size != 5 || meta.TTL != time.Minute || meta.Hits != 2 || !meta.Cached {
+		t.Errorf("GetMeta() = size %d, ttl %v, hits %d, cached %v; want 5, 1m, 2, true",
+			meta.Size, meta.TTL, meta.Hits, meta.Cached)
+	}
+	if stats, _ := backend.Stats(ctx); stats.Hits != 2 {
+		t.Errorf("Stats.Hits = %d, want 2", stats.Hits)
+	}
+
+	if _, err := backend.GetMeta(ctx, "light:missing"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("GetMeta() of missing key error = %v, want ErrNotFound", err)
+	}
+
+	backend.Set(ctx, "light:2", []byte("value"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, err := backend.GetMeta(ctx, "light:2"); !errors.Is(err, cache.ErrExpired) {
+		t.Errorf("GetMeta() of expired key error = %v, want ErrExpired", err)
+	}
+}
+
+func TestMemory_Touch(t *testing.T) {
+	backend := NewMemory()
+	defer backend.Close()
+
+	var _ cache.Toucher = backend
+	var _ cache.Toucher = (*File)(nil)
+	var _ cache.Toucher = (*Bolt)(nil)
+	var _ cache.Toucher = (*Memcached)(nil)
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("value"), time.Minute)
+
+	if err := backend.Touch(ctx, "light:1", 0); err != nil {
+		t.Fatalf("Touch() failed: %v", err)
+	}
+
+	meta, err := backend.GetMeta(ctx, "light:1")
+	if err != nil {
+		t.Fatalf("GetMeta() failed: %v", err)
+	}
+
+	// A zero TTL removes the expiry
+	if !meta.ExpiresAt.IsZero() || meta.TTL != 0 {
+		t.Errorf("After Touch(0), expires %v, ttl %v; want no expiry", meta.ExpiresAt, meta.TTL)
+	}
+
+	// Touches are neither hits nor misses
+	backend.Touch(ctx, "light:missing", time.Minute)
+	stats, _ := backend.Stats(ctx)
+	if stats.Hits != 0 || stats.Misses != 0 {
+		t.Errorf("Stats = %d hits, %d misses; want 0, 0", stats.Hits, stats.Misses)
+	}
+
+	backend.Close()
+	if err := backend.Touch(ctx, "light:1", time.Minute); !errors.Is(err, cache.ErrBackendClosed) {
+		t.Errorf("Touch() after Close() error = %v, want ErrBackendClosed", err)
+	}
+}
+
+func TestMemory_Peek(t *testing.T) {
+	config := DefaultMemoryConfig()
+	config.SlidingTTL = true
+	backend := NewMemory(config)
+	defer backend.Close()
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("value"), time.Minute)
+	before, _ := backend.GetMeta(ctx, "light:1")
+
+	entry, err := backend.Peek(ctx, "light:1")
+	if err != nil {
+		t.Fatalf("Peek() failed: %v", err)
+	}
+	if string(entry.Value) != "value" {
+		t.Errorf("Peek() value = %q, want value", entry.Value)
+	}
+	if _, err := backend.Peek(ctx, "light:missing"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Peek() of missing key error = %v, want ErrNotFound", err)
+	}
+
+	// Neither the entry nor the statistics changed
+	after, _ := backend.GetMeta(ctx, "light:1")
+	if after.Hits != 0 || !after.ExpiresAt.Equal(before.ExpiresAt) {
+		t.Errorf("after Peek() hits = %d, expires %v; want 0, %v", after.Hits, after.ExpiresAt, before.ExpiresAt)
+	}
+	stats, _ := backend.Stats(ctx)
+	if stats.Hits != 0 || stats.Misses != 0 {
+		t.Errorf("Stats = %d hits, %d misses; want 0, 0", stats.Hits, stats.Misses)
+	}
+}
+
+func TestMemory_SetIfConcurrent(t *testing.T) {
+	backend := NewMemory()
+	defer backend.Close()
+
+	var _ cache.ConditionalSetter = backend
+	var _ cache.ConditionalSetter = (*File)(nil)
+	var _ cache.ConditionalSetter = (*Bolt)(nil)
+	var _ cache.ConditionalSetter = (*Memcached)(nil)
+
+	ctx := context.Background()
+	backend.Set(ctx, "counter", []byte("0"), 0)
+
+	// Each goroutine increments the counter with compare-and-swap retries;
+	// no increment may be lost
+	const workers, increments = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				for {
+					entry, err := backend.Get(ctx, "counter")
+					if err != nil {
+						t.Errorf("Get() failed: %v", err)
+						return
+					}
+					var n int
+					fmt.Sscan(string(entry.Value), &n)
+					next := []byte(fmt.Sprint(n + 1))
+					applied, err := backend.SetIf(ctx, "counter", next, 0, func(existing *cache.Entry) bool {
+						return string(existing.Value) == string(entry.Value)
+					})
+					if err != nil {
+						t.Errorf("SetIf() failed: %v", err)
+						return
+					}
+					if applied {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	entry, _ := backend.Get(ctx, "counter")
+	if want := fmt.Sprint(workers * increments); string(entry.Value) != want {
+		t.Errorf("counter = %s, want %s", entry.Value, want)
+	}
+
+	backend.Close()
+	if _, err := backend.SetIf(ctx, "counter", []byte("x"), 0, nil); !errors.Is(err, cache.ErrBackendClosed) {
+		t.Errorf("SetIf() after Close() error = %v, want ErrBackendClosed", err)
+	}
+}
+
+func TestMemory_DisableStats(t *testing.T) {
+	config := DefaultMemoryConfig()
+	config.DisableStats = true
+	config.MaxEntries = 1
+	backend := NewMemory(config)
+	defer backend.Close()
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("value"), 0)
+	backend.Get(ctx, "light:1")
+	backend.Get(ctx, "light:missing")
+	backend.Set(ctx, "light:2", []byte("value"), 0) // evicts light:1
+
+	stats, err := backend.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() failed: %v", err)
+	}
+	if stats.Hits != 0 || stats.Misses != 0 || stats.Evictions != 0 {
+		t.Errorf("Stats = %d hits, %d misses, %d evictions; want all 0",
+			stats.Hits, stats.Misses, stats.Evictions)
+	}
+
+	// Entries and Size are still tracked
+	if stats.Entries != 1 || stats.Size != 5 {
+		t.Errorf("Stats = %d entries, %d bytes; want 1, 5", stats.Entries, stats.Size)
+	}
+}
+
+func TestMemory_CleanupBatches(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{
+		Shards:            1,
+		CleanupBatchSize:  3,
+		CleanupBatchDelay: 5 * time.Millisecond,
+	})
+	defer backend.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		backend.Set(ctx, fmt.Sprintf("light:%d", i), []byte("value"), time.Millisecond)
+	}
+	backend.Set(ctx, "room:1", []byte("value"), 0)
+	time.Sleep(5 * time.Millisecond)
+
+	backend.cleanupExpired()
+
+	cleanup := backend.CleanupStats()
+	if cleanup.Runs != 1 || cleanup.LastRemoved != 10 || cleanup.TotalRemoved != 10 {
+		t.Errorf("CleanupStats = %+v, want 1 run removing 10", cleanup)
+	}
+
+	// Four batches of at most 3, with a pause between each
+	if cleanup.LastDuration < 15*time.Millisecond {
+		t.Errorf("LastDuration = %v, want at least 3 batch delays", cleanup.LastDuration)
+	}
+
+	stats, _ := backend.Stats(ctx)
+	if stats.Entries != 1 || stats.Evictions != 10 {
+		t.Errorf("Stats = %d entries, %d evictions; want 1, 10", stats.Entries, stats.Evictions)
+	}
+}
+
+func TestMemory_CleanupBatch_RechecksEntries(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{Shards: 1})
+	defer backend.Close()
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("value"), time.Millisecond)
+	backend.Set(ctx, "light:2", []byte("value"), time.Millisecond)
+	backend.Set(ctx, "light:3", []byte("value"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	// Between the scan and the batch, one entry is rewritten and one is
+	// deleted
+	keys := []string{"light:1", "light:2", "light:3"}
+	backend.Set(ctx, "light:2", []byte("fresh"), time.Hour)
+	backend.Delete(ctx, "light:3")
+
+	if removed := backend.cleanupBatch(backend.shards[0], keys); removed != 1 {
+		t.Errorf("cleanupBatch() removed %d, want 1", removed)
+	}
+	if _, err := backend.Get(ctx, "light:2"); err != nil {
+		t.Errorf("Rewritten entry was cleaned up: %v", err)
+	}
+}
+
+func TestMemory_ResetStats(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{StatsByType: true})
+	defer backend.Close()
+
+	var _ cache.StatsResetter = backend
+	var _ cache.StatsResetter = (*File)(nil)
+	var _ cache.StatsResetter = (*Bolt)(nil)
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("value"), 0)
+	backend.Get(ctx, "light:1")
+	backend.Get(ctx, "light:missing")
+
+	if err := cache.ResetStats(ctx, backend); err != nil {
+		t.Fatalf("ResetStats() failed: %v", err)
+	}
+
+	stats, _ := backend.Stats(ctx)
+	if stats.Hits != 0 || stats.Misses != 0 {
+		t.Errorf("Stats after reset = %d hits, %d misses; want 0, 0", stats.Hits, stats.Misses)
+	}
+	if stats.Entries != 1 || stats.Size != 5 {
+		t.Errorf("Stats after reset = %d entries, %d bytes; want 1, 5", stats.Entries, stats.Size)
+	}
+
+	byType, _ := backend.StatsByType(ctx)
+	if light := byType["light"]; light.Hits != 0 || light.Misses != 0 || light.Entries != 1 {
+		t.Errorf("Light stats after reset = %+v, want no hits or misses and 1 entry", *light)
+	}
+
+	// Data remains
+	if _, err := backend.Get(ctx, "light:1"); err != nil {
+		t.Errorf("Get() after reset failed: %v", err)
+	}
+	if stats, _ := backend.Stats(ctx); stats.Hits != 1 {
+		t.Errorf("Hits after reset and Get = %d, want 1", stats.Hits)
+	}
+}
+
+func TestMemory_LatencyStats(t *testing.T) {
+	ctx := context.Background()
+
+	untracked := NewMemory()
+	defer untracked.Close()
+	untracked.Set(ctx, "light:1", []byte("value"), 0)
+	if stats := untracked.LatencyStats(); stats != (LatencyStats{}) {
+		t.Errorf("LatencyStats() without TrackLatency = %+v, want zeros", stats)
+	}
+
+	backend := NewMemory(&MemoryConfig{TrackLatency: true})
+	defer backend.Close()
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := fmt.Sprintf("light:%d", i)
+			for range 50 {
+				backend.Set(ctx, key, []byte("value"), 0)
+				backend.Get(ctx, key)
+				backend.Get(ctx, "light:missing")
+			}
+			backend.Delete(ctx, key)
+		}()
+	}
+	wg.Wait()
+	backend.Keys(ctx, "light:*")
+
+	stats := backend.LatencyStats()
+	for name, op := range map[string]struct {
+		got   OpLatency
+		count int64
+	}{
+		"Get":    {stats.Get, 800},
+		"Set":    {stats.Set, 400},
+		"Delete": {stats.Delete, 8},
+		"Keys":   {stats.Keys, 1},
+	} {
+		if op.got.Count != op.count {
+			t.Errorf("%s count = %d, want %d", name, op.got.Count, op.count)
+		}
+		if op.got.Min < 0 || op.got.Min > op.got.Avg || op.got.Avg > op.got.Max {
+			t.Errorf("%s latency = %+v, want 0 <= Min <= Avg <= Max", name, op.got)
+		}
+	}
+
+	if err := backend.ResetStats(ctx); err != nil {
+		t.Fatalf("ResetStats() failed: %v", err)
+	}
+	if stats := backend.LatencyStats(); stats != (LatencyStats{}) {
+		t.Errorf("LatencyStats() after reset = %+v, want zeros", stats)
+	}
+
+	backend.Get(ctx, "light:missing")
+	if get := backend.LatencyStats().Get; get.Count != 1 || get.Min != get.Max {
+		t.Errorf("Get latency after reset and Get = %+v, want 1 sample", get)
+	}
+}
+
+func TestMemory_StatsByType(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{StatsByType: true, MaxEntries: 4})
+	defer backend.Close()
+
+	var _ cache.TypeStatsProvider = backend
+	var _ cache.TypeStatsProvider = (*File)(nil)
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("aaaa"), 0)
+	backend.Set(ctx, "light:2", []byte("bb"), 0)
+	backend.Set(ctx, "scene:1", []byte("cccccc"), 0)
+
+	backend.Get(ctx, "light:1")
+	backend.Get(ctx, "light:2")
+	backend.Get(ctx, "light:3")
+	backend.Get(ctx, "scene:2")
+	backend.Get(ctx, "scene:3")
+	backend.Delete(ctx, "light:2")
+
+	byType, err := backend.StatsByType(ctx)
+	if err != nil {
+		t.Fatalf("StatsByType() failed: %v", err)
+	}
+
+	light, scene := byType["light"], byType["scene"]
+	if light == nil || scene == nil {
+		t.Fatalf("StatsByType() = %v, want light and scene", byType)
+	}
+	if light.Hits != 2 || light.Misses != 1 || light.Entries != 1 || light.Size != 4 {
+		t.Errorf("light = %+v, want 2 hits, 1 miss, 1 entry, 4 bytes", light)
+	}
+	if scene.Hits != 0 || scene.Misses != 2 || scene.Entries != 1 || scene.Size != 6 {
+		t.Errorf("scene = %+v, want 0 hits, 2 misses, 1 entry, 6 bytes", scene)
+	}
+
+	// Evictions are charged to the evicted entry's type
+	backend.Set(ctx, "room:1", []byte("r"), 0)
+	backend.Set(ctx, "room:2", []byte("r"), 0)
+	backend.Set(ctx, "room:3", []byte("r"), 0)
+	byType, _ = backend.StatsByType(ctx)
+	var evictions int64
+	for _, ts := range byType {
+		evictions += ts.Evictions
+	}
+	if evictions != 1 {
+		t.Errorf("per-type evictions = %d, want 1", evictions)
+	}
+
+	// Per-type totals agree with the global stats
+	stats, _ := backend.Stats(ctx)
+	merged := &cache.Stats{}
+	for _, ts := range byType {
+		merged = merged.Merge(ts)
+	}
+	if merged.Hits != stats.Hits || merged.Misses != stats.Misses || merged.Entries != stats.Entries || merged.Size != stats.Size {
+		t.Errorf("merged per-type stats %+v disagree with Stats %+v", merged, stats)
+	}
+}
+
+func TestMemory_StatsByTypeDisabled(t *testing.T) {
+	backend := NewMemory()
+	defer backend.Close()
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("value"), 0)
+	backend.Get(ctx, "light:1")
+
+	byType, err := backend.StatsByType(ctx)
+	if err != nil {
+		t.Fatalf("StatsByType() failed: %v", err)
+	}
+	if len(byType) != 0 {
+		t.Errorf("StatsByType() = %v, want empty when disabled", byType)
+	}
+}
+
+func TestMemory_DetailedStats(t *testing.T) {
+	backend := NewMemory(DefaultMemoryConfig())
+	defer backend.Close()
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("1234"), 0)
+	backend.Set(ctx, "light:2", []byte("12345678"), 0)
+	backend.Set(ctx, "light:3", []byte("123456"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	stats, err := cache.GetDetailedStats(ctx, backend)
+	if err != nil {
+		t.Fatalf("DetailedStats() failed: %v", err)
+	}
+	if stats.Entries != 2 || stats.AvgSize != 6 || stats.MaxSize != 8 {
+		t.Errorf("DetailedStats() = %+v, want 2 unexpired entries, avg 6, max 8", stats)
+	}
+	if stats.OldestEntryAge <= 0 || stats.AvgAge > stats.OldestEntryAge {
+		t.Errorf("AvgAge = %v, OldestEntryAge = %v", stats.AvgAge, stats.OldestEntryAge)
+	}
+
+	backend.Close()
+	if _, err := backend.DetailedStats(ctx); !errors.Is(err, cache.ErrBackendClosed) {
+		t.Errorf("DetailedStats() after Close error = %v, want ErrBackendClosed", err)
+	}
+}
+
+func TestMemory_ReplaceAll(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{MaxEntries: 3, StatsByType: true})
+	defer backend.Close()
+	ctx := context.Background()
+
+	backend.Set(ctx, "light:1", []byte("old"), 0)
+	backend.Set(ctx, "light:2", []byte("old"), 0)
+
+	err := backend.ReplaceAll(ctx, map[string][]byte{
+		"light:2": []byte("new"),
+		"room:1":  []byte("new"),
+	}, time.Hour)
+	if err != nil {
+		t.Fatalf("ReplaceAll() failed: %v", err)
+	}
+
+	if _, err := backend.Get(ctx, "light:1"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Get(light:1) error = %v, want ErrNotFound", err)
+	}
+	for _, key := range []string{"light:2", "room:1"} {
+		entry, err := backend.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get(%s) failed: %v", key, err)
+		}
+		if string(entry.Value) != "new" || entry.TTL != time.Hour {
+			t.Errorf("Get(%s) = %q with TTL %v", key, entry.Value, entry.TTL)
+		}
+	}
+
+	stats, _ := backend.Stats(ctx)
+	if stats.Entries != 2 || stats.Size != 6 {
+		t.Errorf("Stats = %d entries, %d bytes; want 2, 6", stats.Entries, stats.Size)
+	}
+	byType, _ := backend.StatsByType(ctx)
+	if byType["light"].Entries != 1 || byType["room"].Entries != 1 {
+		t.Errorf("StatsByType entries = light %d, room %d; want 1, 1", byType["light"].Entries, byType["room"].Entries)
+	}
+
+	// Over the limit: rejected, cache unchanged
+	err = backend.ReplaceAll(ctx, map[string][]byte{"a:1": {1}, "a:2": {2}, "a:3": {3}, "a:4": {4}}, 0)
+	if !errors.Is(err, cache.ErrMemoryLimit) {
+		t.Errorf("ReplaceAll() over MaxEntries error = %v, want ErrMemoryLimit", err)
+	}
+	if _, err := backend.Get(ctx, "room:1"); err != nil {
+		t.Errorf("Get(room:1) after rejected ReplaceAll failed: %v", err)
+	}
+}
+
+func TestMemory_Snapshot(t *testing.T) {
+	backend := NewMemory()
+	ctx := context.Background()
+
+	backend.SetWithTags(ctx, "light:1", []byte("light1"), 0, []string{"room:1"})
+	backend.Set(ctx, "light:2", []byte("light2"), time.Hour)
+	backend.Set(ctx, "light:expired", []byte("old"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	entries, err := backend.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot() failed: %v", err)
+	}
+	got := make(map[string]*cache.Entry)
+	for _, entry := range entries {
+		got[entry.Key] = entry
+	}
+	if len(got) != 2 || string(got["light:1"].Value) != "light1" || string(got["light:2"].Value) != "light2" {
+		t.Fatalf("Snapshot() = %v, want light:1 and light:2", got)
+	}
+	if !slices.Equal(got["light:1"].Tags, []string{"room:1"}) || got["light:2"].TTL != time.Hour {
+		t.Errorf("Snapshot() lost metadata: %+v, %+v", got["light:1"], got["light:2"])
+	}
+
+	// Snapshots are copies and aren't hits
+	got["light:1"].Value[0] = 'X'
+	entry, _ := backend.Get(ctx, "light:1")
+	if string(entry.Value) != "light1" {
+		t.Errorf("Get() after modifying snapshot = %q, want light1", entry.Value)
+	}
+	if stats, _ := backend.Stats(ctx); stats.Hits != 1 {
+		t.Errorf("Hits = %d, want 1 (from Get only)", stats.Hits)
+	}
+
+	backend.Close()
+	if _, err := backend.Snapshot(ctx); !errors.Is(err, cache.ErrBackendClosed) {
+		t.Errorf("Snapshot() after Close error = %v, want ErrBackendClosed", err)
+	}
+}
+
+// TestMemory_SnapshotConcurrentWrites checks snapshots are point-in-time.
+// A single writer stores keys 0, 1, 2, ... in order, so at any moment the
+// stored keys are 0 through n with no gaps. Keys hash to different shards,
+// so a snapshot that read the shards at different times could see a key
+// without an earlier one.
+func TestMemory_SnapshotConcurrentWrites(t *testing.T) {
+	backend := NewMemory()
+	defer backend.Close()
+	ctx := context.Background()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 20000 {
+			backend.Set(ctx, fmt.Sprintf("light:%d", i), []byte("value"), 0)
+		}
+	}()
+
+	for snapshots := 0; ; snapshots++ {
+		select {
+		case <-done:
+			if snapshots == 0 {
+				t.Log("writer finished before the first snapshot")
+			}
+			return
+		default:
+		}
+
+		entries, err := backend.Snapshot(ctx)
+		if err != nil {
+			t.Fatalf("Snapshot() failed: %v", err)
+		}
+		if gap := firstGap(entries); gap >= 0 {
+			t.Fatalf("snapshot of %d entries is missing light:%d", len(entries), gap)
+		}
+	}
+}
+
+// firstGap returns the lowest n missing from entries keyed "light:0"
+// through "light:<len(entries)-1>", or -1 if none is missing.
+func firstGap(entries []*cache.Entry) int {
+	present := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		present[entry.Key] = true
+	}
+	for n := range entries {
+		if !present[fmt.Sprintf("light:%d", n)] {
+			return n
+		}
+	}
+	return -1
+}
+
+func TestMemory_ReplaceAllConcurrentReaders(t *testing.T) {
+	backend := NewMemory()
+	defer backend.Close()
+	ctx := context.Background()
+
+	items := make(map[string][]byte)
+	for i := range 100 {
+		items[fmt.Sprintf("light:%d", i)] = []byte("value")
+	}
+	backend.ReplaceAll(ctx, items, 0)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// Every key is in both the old and new contents
+				if _, err := backend.Get(ctx, "light:42"); err != nil {
+					t.Errorf("Get() during ReplaceAll failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for range 50 {
+		if err := backend.ReplaceAll(ctx, items, 0); err != nil {
+			t.Fatalf("ReplaceAll() failed: %v", err)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestMemory_DeleteMany(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{Shards: 4, StatsByType: true})
+	defer backend.Close()
+	ctx := context.Background()
+
+	backend.Set(ctx, "light:1", []byte("one"), 0)
+	backend.Set(ctx, "light:2", []byte("two"), 0)
+	backend.Set(ctx, "room:1", []byte("room"), 0)
+
+	deleted, err := backend.DeleteMany(ctx, []string{"light:1", "room:1", "light:missing"})
+	if err != nil || deleted != 2 {
+		t.Fatalf("DeleteMany() = %d, %v; want 2", deleted, err)
+	}
+	if keys, _ := backend.Keys(ctx, "*"); len(keys) != 1 || keys[0] != "light:2" {
+		t.Errorf("Keys() = %v, want [light:2]", keys)
+	}
+	stats, _ := backend.Stats(ctx)
+	if stats.Entries != 1 || stats.Size != 3 {
+		t.Errorf("Stats = %d entries, %d bytes; want 1, 3", stats.Entries, stats.Size)
+	}
+	byType, _ := backend.StatsByType(ctx)
+	if byType["room"].Entries != 0 || byType["light"].Entries != 1 {
+		t.Errorf("StatsByType entries = light %d, room %d; want 1, 0", byType["light"].Entries, byType["room"].Entries)
+	}
+}
+
+func TestMemory_DeleteManyConcurrent(t *testing.T) {
+	backend := NewMemory(&MemoryConfig{Shards: 8})
+	defer backend.Close()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				keys := make([]string, 5)
+				for j := range keys {
+					keys[j] = fmt.Sprintf("light:%d", (w*7+i+j)%50)
+					backend.Set(ctx, keys[j], make([]byte, j+1), 0)
+				}
+				backend.DeleteMany(ctx, keys[:3])
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Size and count agree with what is actually stored
+	keys, _ := backend.Keys(ctx, "*")
+	var size int64
+	for _, key := range keys {
+		entry, err := backend.Peek(ctx, key)
+		if err != nil {
+			t.Fatalf("Peek(%s) failed: %v", key, err)
+		}
+		size += entry.Size
+	}
+	stats, _ := backend.Stats(ctx)
+	if stats.Entries != int64(len(keys)) || stats.Size != size {
+		t.Errorf("Stats = %d entries, %d bytes; stored %d entries, %d bytes", stats.Entries, stats.Size, len(keys), size)
+	}
+}
+
+func TestMemory_DeletePattern(t *testing.T) {
+	backend := NewMemory()
+	defer backend.Close()
+
+	ctx := context.Background()
+	for _, key := range []string{"light:1", "light:2", "room:1", "lights"} {
+		backend.Set(ctx, key, []byte("value"), 0)
+	}
+	backend.Set(ctx, "light:3", []byte("value"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	// Expired matches are removed and counted too
+	deleted, err := backend.DeletePattern(ctx, "light:*")
+	if err != nil {
+		t.Fatalf("DeletePattern() failed: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("DeletePattern() deleted %d keys, want 3", deleted)
+	}
+
+	keys, _ := backend.Keys(ctx, "*")
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "lights" || keys[1] != "room:1" {
+		t.Errorf("Keys() after DeletePattern() = %v, want [lights room:1]", keys)
+	}
+	if stats, _ := backend.Stats(ctx); stats.Entries != 2 || stats.Size != 10 {
+		t.Errorf("Stats() = %d entries, %d bytes; want 2, 10", stats.Entries, stats.Size)
+	}
+
+	// The manager clears through it
+	for _, key := range []string{"light:1", "light:2"} {
+		backend.Set(ctx, key, []byte("value"), 0)
+	}
+	if err := cache.NewCacheManager(backend, nil).ClearLights(ctx); err != nil {
+		t.Fatalf("ClearLights() failed: %v", err)
+	}
+	if keys, _ := backend.Keys(ctx, "light:*"); len(keys) != 0 {
+		t.Errorf("Keys(light:*) after ClearLights() = %v, want none", keys)
+	}
+
+	backend.Close()
+	if _, err := backend.DeletePattern(ctx, "*"); !errors.Is(err, cache.ErrBackendClosed) {
+		t.Errorf("DeletePattern() after Close() error = %v, want ErrBackendClosed", err)
+	}
+}
+
+func TestMemory_Tags(t *testing.T) {
+	backend := NewMemory()
+	defer backend.Close()
+
+	// indexed returns the keys the tag index holds for tag
+	indexed := func(backend *Memory, tag string) []string {
+		var keys []string
+		for _, shard := range backend.shards {
+			shard.mu.Lock()
+			for key := range shard.tags[tag] {
+				keys = append(keys, key)
+			}
+			shard.mu.Unlock()
+		}
+		sort.Strings(keys)
+		return keys
+	}
+
+	ctx := context.Background()
+	backend.SetWithTags(ctx, "light:1", []byte("value"), 0, []string{"floor-2"})
+	backend.SetWithTags(ctx, "room:1", []byte("value"), 0, []string{"floor-2", "holiday"})
+	backend.SetWithTags(ctx, "light:2", []byte("value"), 0, []string{"floor-2"})
+	backend.Set(ctx, "light:3", []byte("value"), 0)
+
+	if entry, _ := backend.Get(ctx, "room:1"); !entry.HasTag("holiday") || !entry.HasTag("floor-2") {
+		t.Errorf("Get(room:1) tags = %v, want [floor-2 holiday]", entry.Tags)
+	}
+	if keys := indexed(backend, "floor-2"); len(keys) != 3 {
+		t.Errorf("index(floor-2) = %v, want 3 keys", keys)
+	}
+
+	// Overwriting and deleting keep the index in step
+	backend.Set(ctx, "light:2", []byte("value"), 0)
+	backend.Delete(ctx, "light:1")
+	if keys := indexed(backend, "floor-2"); len(keys) != 1 || keys[0] != "room:1" {
+		t.Errorf("index(floor-2) = %v, want [room:1]", keys)
+	}
+
+	// So does eviction
+	small := NewMemory(&MemoryConfig{MaxEntries: 1, EvictionPolicy: EvictionLRU})
+	defer small.Close()
+	small.SetWithTags(ctx, "light:1", []byte("value"), 0, []string{"floor-2"})
+	small.Set(ctx, "light:2", []byte("value"), 0)
+	if keys := indexed(small, "floor-2"); len(keys) != 0 {
+		t.Errorf("index(floor-2) after eviction = %v, want none", keys)
+	}
+
+	backend.SetWithTags(ctx, "light:4", []byte("value"), 0, []string{"holiday"})
+
+	deleted, err := backend.DeleteTag(ctx, "holiday")
+	if err != nil {
+		t.Fatalf("DeleteTag() failed: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("DeleteTag() deleted %d keys, want 2", deleted)
+	}
+	keys, _ := backend.Keys(ctx, "*")
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "light:2" || keys[1] != "light:3" {
+		t.Errorf("Keys() after DeleteTag() = %v, want [light:2 light:3]", keys)
+	}
+	if keys := indexed(backend, "holiday"); len(keys) != 0 {
+		t.Errorf("index(holiday) after DeleteTag() = %v, want none", keys)
+	}
+
+	// The manager clears through it, across resource types
+	backend.SetWithTags(ctx, "light:1", []byte("value"), 0, []string{"floor-2"})
+	backend.SetWithTags(ctx, "room:1", []byte("value"), 0, []string{"floor-2"})
+	if err := cache.NewCacheManager(backend, nil).ClearTag(ctx, "floor-2"); err != nil {
+		t.Fatalf("ClearTag() failed: %v", err)
+	}
+	if keys, _ := backend.Keys(ctx, "*"); len(keys) != 2 {
+		t.Errorf("Keys() after ClearTag() = %v, want 2 untagged keys", keys)
+	}
+
+	backend.Close()
+	if _, err := backend.DeleteTag(ctx, "floor-2"); !errors.Is(err, cache.ErrBackendClosed) {
+		t.Errorf("DeleteTag() after Close() error = %v, want ErrBackendClosed", err)
+	}
+}
@@ -0,0 +1,64 @@
+package backends
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+
+	cache "github.com/rmrfslashbin/hue-cache"
+)
+
+// MigrateFile rewrites a cache file saved with an older, incompatible
+// Entry schema in the current format, so an upgrade doesn't discard the
+// cache. T is the old entry type: a struct with the fields (names and
+// types) of Entry as that version saved it. Each old entry is passed to
+// migrate; return nil to drop it. Keep old entry types around for as long
+// as files in their schema may exist.
+//
+// oldPath and newPath may be the same file; the new file is written to a
+// temp file and renamed into place, so a failed migration leaves oldPath
+// intact. Expired entries are kept and skipped by Load as usual.
+//
+// Example:
+//
+//	// Entry as saved by v0.3, before TTL became a time.Duration
+//	type entryV03 struct {
+//	    Key        string
+//	    Value      []byte
+//	    CreatedAt  time.Time
+//	    ExpiresAt  time.Time
+//	    TTLSeconds int64
+//	}
+//
+//	err := backends.MigrateFile(path, path, func(old entryV03) (*cache.Entry, error) {
+//	    entry := cache.NewEntry(old.Key, old.Value, 0)
+//	    entry.CreatedAt = old.CreatedAt
+//	    entry.ExpiresAt = old.ExpiresAt
+//	    entry.TTL = time.Duration(old.TTLSeconds) * time.Second
+//	    return entry, nil
+//	})
+func MigrateFile[T any](oldPath, newPath string, migrate func(old T) (*cache.Entry, error)) error {
+	file, err := os.Open(oldPath)
+	if err != nil {
+		return fmt.Errorf("opening cache file: %w", err)
+	}
+	defer file.Close()
+
+	var old []T
+	if err := gob.NewDecoder(file).Decode(&old); err != nil {
+		return fmt.Errorf("decoding cache in old schema: %w", err)
+	}
+
+	entries := make([]*cache.Entry, 0, len(old))
+	for i, o := range old {
+		entry, err := migrate(o)
+		if err != nil {
+			return fmt.Errorf("migrating entry %d: %w", i, err)
+		}
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	return writeCacheFile(newPath, entries, defaultWriteBufferSize, (*os.File).Sync, nil)
+}
@@ -0,0 +1,113 @@
+package backends
+
+import (
+	"context"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cache "github.com/rmrfslashbin/hue-cache"
+)
+
+// entryV0 simulates a prior Entry schema that the current one can't
+// decode: TTL is stored in seconds and the value as a string.
+type entryV0 struct {
+	Key        string
+	Value      string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	TTLSeconds int64
+}
+
+// writeV0File saves entries in the prior schema, as an older release did.
+func writeV0File(t *testing.T, path string, entries []entryV0) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("creating v0 file: %v", err)
+	}
+	defer file.Close()
+
+	if err := gob.NewEncoder(file).Encode(entries); err != nil {
+		t.Fatalf("encoding v0 file: %v", err)
+	}
+}
+
+func TestMigrateFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "cache.gob")
+	now := time.Now()
+
+	writeV0File(t, path, []entryV0{
+		{Key: "light:1", Value: `{"id":"1"}`, CreatedAt: now, ExpiresAt: now.Add(time.Hour), TTLSeconds: 3600},
+		{Key: "room:1", Value: `{"id":"1"}`, CreatedAt: now},
+		{Key: "legacy:1", Value: "dropped", CreatedAt: now},
+	})
+
+	migrate := func(old entryV0) (*cache.Entry, error) {
+		if old.Key == "legacy:1" {
+			return nil, nil
+		}
+		entry := cache.NewEntry(old.Key, []byte(old.Value), 0)
+		entry.CreatedAt = old.CreatedAt
+		entry.ExpiresAt = old.ExpiresAt
+		entry.TTL = time.Duration(old.TTLSeconds) * time.Second
+		return entry, nil
+	}
+	newPath := filepath.Join(tmpDir, "migrated.gob")
+	if err := MigrateFile(path, newPath, migrate); err != nil {
+		t.Fatalf("MigrateFile() failed: %v", err)
+	}
+
+	// The current schema can't load the old file
+	old, err := NewFile(&FileConfig{FilePath: path})
+	if err != nil {
+		t.Fatalf("NewFile() failed: %v", err)
+	}
+	if err := old.Load(); err == nil {
+		t.Error("Load() of old schema should fail")
+	}
+	old.Close()
+
+	migrated, err := NewFile(&FileConfig{FilePath: newPath, LoadOnStart: true})
+	if err != nil {
+		t.Fatalf("NewFile() after migration failed: %v", err)
+	}
+	defer migrated.Close()
+
+	ctx := context.Background()
+	entry, err := migrated.Get(ctx, "light:1")
+	if err != nil {
+		t.Fatalf("Get() of migrated entry failed: %v", err)
+	}
+	if string(entry.Value) != `{"id":"1"}` || entry.TimeUntilExpiry() < 59*time.Minute {
+		t.Errorf("Migrated light = %s expiring in %v, want value kept and ~1h left",
+			entry.Value, entry.TimeUntilExpiry())
+	}
+
+	if _, err := migrated.Get(ctx, "room:1"); err != nil {
+		t.Errorf("Get() of migrated room failed: %v", err)
+	}
+	if keys, _ := migrated.Keys(ctx, "*"); len(keys) != 2 {
+		t.Errorf("Migrated keys = %v, want 2 (legacy entry dropped)", keys)
+	}
+}
+
+func TestMigrateFile_LeavesOldFileOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+	os.WriteFile(path, []byte("not gob"), 0644)
+
+	err := MigrateFile(path, path, func(old entryV0) (*cache.Entry, error) {
+		return nil, nil
+	})
+	if err == nil {
+		t.Fatal("MigrateFile() of corrupt file should fail")
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "not gob" {
+		t.Error("MigrateFile() modified the old file after failing")
+	}
+}
@@ -0,0 +1,195 @@
+package backends
+
+// WorkloadAccess is one cache lookup in a sample workload.
+type WorkloadAccess struct {
+	// Key is the cache key looked up.
+	Key string
+
+	// Size is the size of the key's value in bytes.
+	Size int64
+}
+
+// Workload is a representative sequence of cache lookups used to tune a
+// memory backend offline.
+type Workload struct {
+	// Accesses is the sequence of lookups, in order. A lookup of a key
+	// that isn't cached is treated as a miss followed by a Set.
+	Accesses []WorkloadAccess
+
+	// MaxMemory is the memory budget in bytes (0 = unlimited).
+	MaxMemory int64
+}
+
+// PolicyResult is the simulated outcome of one eviction policy.
+type PolicyResult struct {
+	// Policy is the eviction policy simulated.
+	Policy EvictionPolicy
+
+	// HitRate is the fraction of accesses served from the cache (0.0-1.0).
+	HitRate float64
+
+	// Evictions is the number of entries evicted to stay within budget.
+	Evictions int64
+
+	// PeakEntries is the largest number of entries held at once.
+	PeakEntries int64
+}
+
+// Recommendation is the output of Recommend.
+type Recommendation struct {
+	// Config is the suggested memory backend configuration.
+	Config *MemoryConfig
+
+	// HitRate is the simulated hit rate of Config.
+	HitRate float64
+
+	// Results holds the simulated outcome of every policy considered.
+	Results []PolicyResult
+}
+
+// Recommend simulates each eviction policy against a sample workload and
+// suggests the memory backend configuration with the best hit rate within
+// the workload's memory budget. MaxEntries is set to the most entries the
+// winning policy held at once, so the entry limit never evicts before the
+// memory budget does.
+//
+// This is an offline tuning aid: it replays the workload in a simple
+// model of the backend's eviction and does not touch a real cache. TinyLFU
+// is modeled with the backend's frequency sketch, sized as it is without
+// MaxEntries, so its result is approximate. Ties are broken in favor of
+// LRU, then LFU, then FIFO, then TinyLFU.
+//
+// Example:
+//
+//	rec := backends.Recommend(&backends.Workload{
+//	    Accesses:  sampledAccesses,
+//	    MaxMemory: 16 * 1024 * 1024,
+//	})
+//	backend := backends.NewMemory(rec.Config)
+func Recommend(workload *Workload) *Recommendation {
+	rec := &Recommendation{}
+
+	best := -1
+	for _, policy := range []EvictionPolicy{EvictionLRU, EvictionLFU, EvictionFIFO, EvictionTinyLFU} {
+		result := simulatePolicy(workload, policy)
+		rec.Results = append(rec.Results, result)
+
+		if best < 0 || result.HitRate > rec.Results[best].HitRate {
+			best = len(rec.Results) - 1
+		}
+	}
+
+	winner := rec.Results[best]
+	rec.HitRate = winner.HitRate
+	rec.Config = DefaultMemoryConfig()
+	rec.Config.EvictionPolicy = winner.Policy
+	rec.Config.MaxMemory = workload.MaxMemory
+	rec.Config.MaxEntries = winner.PeakEntries
+
+	return rec
+}
+
+// simEntry is the simulated state of one cached key.
+type simEntry struct {
+	size     int64
+	created  int64
+	lastUsed int64
+	hits     int64
+}
+
+// simulatePolicy replays a workload against a model of the memory backend
+// using the given eviction policy.
+func simulatePolicy(workload *Workload, policy EvictionPolicy) PolicyResult {
+	result := PolicyResult{Policy: policy}
+	if len(workload.Accesses) == 0 {
+		return result
+	}
+
+	entries := make(map[string]*simEntry)
+	var totalSize, hits int64
+
+	// TinyLFU counts every Get, hit or miss, and every Set
+	var sketch *frequencySketch
+	if policy == EvictionTinyLFU {
+		sketch = newFrequencySketch(defaultSketchCapacity)
+	}
+
+	for tick, access := range workload.Accesses {
+		now := int64(tick)
+		if sketch != nil {
+			sketch.increment(access.Key)
+		}
+
+		if entry, ok := entries[access.Key]; ok {
+			hits++
+			entry.hits++
+			entry.lastUsed = now
+			continue
+		}
+
+		// Miss - too large to ever fit, so it's never cached
+		if workload.MaxMemory > 0 && access.Size > workload.MaxMemory {
+			continue
+		}
+
+		if sketch != nil {
+			sketch.increment(access.Key)
+		}
+
+		// Make room, then cache the value
+		admitted := true
+		for workload.MaxMemory > 0 && totalSize+access.Size > workload.MaxMemory {
+			victim := simVictim(entries, policy)
+
+			// Admission, as in evictOne: the new key must be more
+			// frequent than the victim, or it isn't cached
+			if sketch != nil && sketch.estimate(access.Key) <= sketch.estimate(victim) {
+				admitted = false
+				break
+			}
+
+			totalSize -= entries[victim].size
+			delete(entries, victim)
+			result.Evictions++
+		}
+		if !admitted {
+			continue
+		}
+
+		entries[access.Key] = &simEntry{size: access.Size, created: now, lastUsed: now}
+		totalSize += access.Size
+		if n := int64(len(entries)); n > result.PeakEntries {
+			result.PeakEntries = n
+		}
+	}
+
+	result.HitRate = float64(hits) / float64(len(workload.Accesses))
+	return result
+}
+
+// simVictim chooses the entry the policy would evict, mirroring evictOne.
+func simVictim(entries map[string]*simEntry, policy EvictionPolicy) string {
+	var victim string
+	var victimEntry *simEntry
+
+	for key, entry := range entries {
+		if victimEntry == nil || simPrefer(policy, entry, victimEntry) ||
+			(!simPrefer(policy, victimEntry, entry) && key < victim) {
+			victim, victimEntry = key, entry
+		}
+	}
+
+	return victim
+}
+
+// simPrefer reports whether policy would evict candidate before current.
+func simPrefer(policy EvictionPolicy, candidate, current *simEntry) bool {
+	switch policy {
+	case EvictionLFU:
+		return candidate.hits < current.hits
+	case EvictionFIFO:
+		return candidate.created < current.created
+	default:
+		return candidate.lastUsed < current.lastUsed
+	}
+}
@@ -0,0 +1,87 @@
+package backends
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// zipfWorkload builds a skewed workload where a few keys are very hot.
+func zipfWorkload(accesses, keys int, size int64) []WorkloadAccess {
+	rng := rand.New(rand.NewSource(1))
+	zipf := rand.NewZipf(rng, 1.2, 1, uint64(keys-1))
+
+	workload := make([]WorkloadAccess, accesses)
+	for i := range workload {
+		workload[i] = WorkloadAccess{Key: fmt.Sprintf("light:%d", zipf.Uint64()), Size: size}
+	}
+	return workload
+}
+
+func TestRecommend_ZipfPrefersFrequency(t *testing.T) {
+	rec := Recommend(&Workload{
+		Accesses:  zipfWorkload(20000, 1000, 100),
+		MaxMemory: 50 * 100, // room for 5% of the keys
+	})
+
+	results := make(map[EvictionPolicy]PolicyResult)
+	for _, result := range rec.Results {
+		results[result.Policy] = result
+	}
+	t.Logf("LRU=%.3f LFU=%.3f FIFO=%.3f TinyLFU=%.3f", results[EvictionLRU].HitRate, results[EvictionLFU].HitRate,
+		results[EvictionFIFO].HitRate, results[EvictionTinyLFU].HitRate)
+
+	// Admission keeps one-off keys from displacing hot ones, so TinyLFU
+	// wins; both frequency-based policies beat the recency-based ones
+	if rec.Config.EvictionPolicy != EvictionTinyLFU {
+		t.Errorf("Recommended policy = %v, want TinyLFU", rec.Config.EvictionPolicy)
+	}
+	for _, policy := range []EvictionPolicy{EvictionLFU, EvictionTinyLFU} {
+		for _, other := range []EvictionPolicy{EvictionLRU, EvictionFIFO} {
+			if results[policy].HitRate <= results[other].HitRate {
+				t.Errorf("policy %v hit rate %.3f should beat policy %v's %.3f on a skewed workload",
+					policy, results[policy].HitRate, other, results[other].HitRate)
+			}
+		}
+	}
+	if results[EvictionTinyLFU].Evictions >= results[EvictionLRU].Evictions {
+		t.Errorf("TinyLFU evicted %d entries, want fewer than LRU's %d, since it rejects cold keys",
+			results[EvictionTinyLFU].Evictions, results[EvictionLRU].Evictions)
+	}
+	if rec.Config.MaxMemory != 5000 {
+		t.Errorf("MaxMemory = %d, want the workload budget", rec.Config.MaxMemory)
+	}
+	if rec.Config.MaxEntries != 50 {
+		t.Errorf("MaxEntries = %d, want 50", rec.Config.MaxEntries)
+	}
+}
+
+func TestRecommend_EverythingFits(t *testing.T) {
+	accesses := []WorkloadAccess{
+		{Key: "light:1", Size: 10},
+		{Key: "light:2", Size: 10},
+		{Key: "light:1", Size: 10},
+		{Key: "light:2", Size: 10},
+	}
+
+	rec := Recommend(&Workload{Accesses: accesses})
+
+	if rec.HitRate != 0.5 {
+		t.Errorf("HitRate = %v, want 0.5", rec.HitRate)
+	}
+	if rec.Config.MaxEntries != 2 {
+		t.Errorf("MaxEntries = %d, want 2", rec.Config.MaxEntries)
+	}
+	for _, result := range rec.Results {
+		if result.Evictions != 0 {
+			t.Errorf("%v evicted %d entries with no budget", result.Policy, result.Evictions)
+		}
+	}
+}
+
+func TestRecommend_EmptyWorkload(t *testing.T) {
+	rec := Recommend(&Workload{})
+	if rec.Config == nil || rec.HitRate != 0 {
+		t.Errorf("Recommend() on empty workload = %+v", rec)
+	}
+}
@@ -0,0 +1,104 @@
+package backends
+
+import (
+	"sync"
+)
+
+// sketchDepth is the number of counter rows in a frequencySketch.
+const sketchDepth = 4
+
+// sketchMaxCount caps each counter, as in TinyLFU's 4-bit counters. Only
+// relative frequencies matter for admission, and a low cap lets aging
+// forget old popularity quickly.
+const sketchMaxCount = 15
+
+// frequencySketch estimates how often keys were accessed recently, for
+// the TinyLFU admission policy. It is a count-min sketch: each key
+// increments one counter per row, and its estimate is the smallest of
+// them, so collisions can only overestimate. After sampleSize increments
+// every counter is halved, so the estimates follow changes in popularity
+// instead of counting forever.
+type frequencySketch struct {
+	mu         sync.Mutex
+	rows       [sketchDepth][]uint8
+	mask       uint64
+	additions  int
+	sampleSize int
+}
+
+// newFrequencySketch creates a sketch sized for a cache holding about
+// capacity entries.
+func newFrequencySketch(capacity int) *frequencySketch {
+	width := 64
+	for width < capacity {
+		width <<= 1
+	}
+
+	s := &frequencySketch{
+		mask:       uint64(width - 1),
+		sampleSize: 10 * width,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// increment records an access to key.
+func (s *frequencySketch) increment(key string) {
+	h1, h2 := sketchHashes(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.rows {
+		idx := (h1 + uint64(i)*h2) & s.mask
+		if s.rows[i][idx] < sketchMaxCount {
+			s.rows[i][idx]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.sampleSize {
+		s.age()
+	}
+}
+
+// estimate returns the estimated recent access count of key.
+func (s *frequencySketch) estimate(key string) uint8 {
+	h1, h2 := sketchHashes(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lowest := uint8(sketchMaxCount)
+	for i := range s.rows {
+		if count := s.rows[i][(h1+uint64(i)*h2)&s.mask]; count < lowest {
+			lowest = count
+		}
+	}
+	return lowest
+}
+
+// age halves every counter. Must be called with mu held.
+func (s *frequencySketch) age() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
+
+// sketchHashes returns two independent hashes of key for double hashing:
+// row i uses h1 + i*h2. h2 is odd, so the rows never share a column
+// sequence.
+func sketchHashes(key string) (h1, h2 uint64) {
+	// FNV-1a, 64-bit
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h, (h>>32 | h<<32) | 1
+}
@@ -1,9 +1,14 @@
 package cache
 
 import (
+	"context"
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/rmrfslashbin/hue-sdk"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 )
 
 // CachedClient wraps an SDK client with caching for all resource types.
@@ -13,7 +18,9 @@ import (
 //
 //	backend := backends.NewMemory()
 //	sdkClient, _ := hue.NewClient(...)
-//	cachedClient := cache.NewCachedClient(backend, sdkClient, 5*time.Minute)
+//	cachedClient := cache.NewCachedClient(backend, sdkClient, nil)
+//	cachedClient.Start()
+//	defer cachedClient.Close()
 //
 //	// Use just like the SDK client
 //	lights, err := cachedClient.Lights().List(ctx)
@@ -21,6 +28,17 @@ type CachedClient struct {
 	backend   Backend
 	sdkClient *hue.Client
 	ttl       time.Duration
+	config    *CachedClientConfig
+
+	// syncEngine is owned by the client when EnableSync is true
+	syncEngine *SyncEngine
+
+	// limiter throttles SDK calls across all resource clients
+	// (nil if SDKRateLimit is unset)
+	limiter *rate.Limiter
+
+	closeOnce sync.Once
+	closeErr  error
 
 	// Cached resource clients
 	lights        *CachedLightClient
@@ -28,6 +46,8 @@ type CachedClient struct {
 	zones         *CachedZoneClient
 	scenes        *CachedSceneClient
 	groupedLights *CachedGroupedLightClient
+	bridge        *CachedBridgeClient
+	bridgeHome    *CachedBridgeHomeClient
 }
 
 // CachedClientConfig contains configuration for the cached client.
@@ -37,21 +57,208 @@ type CachedClientConfig struct {
 	// Default: 0 (no expiration, rely on SSE)
 	TTL time.Duration
 
-	// EnableSync enables automatic SSE synchronization.
-	// If false, you must manually sync or rely on TTL expiration.
+	// EnableSync gives the client its own sync engine, started by
+	// CachedClient.Start and stopped by CachedClient.Close. If false,
+	// you must manually sync or rely on TTL expiration.
 	// Default: true
 	EnableSync bool
 
-	// SyncConfig is passed to the sync engine if EnableSync is true.
+	// SyncConfig configures the client's sync engine if EnableSync is
+	// true. So that the engine writes what the clients read, its KeyPrefix,
+	// Codec, Projection, and CacheRaw are replaced by this config's, and
+	// LastWriteWins is turned on if it is set here. SyncConfig itself is
+	// not modified.
+	// Default: DefaultSyncConfig()
 	SyncConfig *SyncConfig
+
+	// KeyPrefix namespaces every cache key the clients read and write
+	// (see NewKeyBuilderWithPrefix), so several bridges or environments
+	// can share one backend. Use the same prefix for a SyncEngine you run
+	// yourself (SyncConfig.KeyPrefix; the client's own engine gets it) and
+	// the CacheManager (NewCacheManagerWithPrefix) of the same bridge.
+	// Default: "" (no namespace)
+	KeyPrefix string
+
+	// LastWriteWins orders SDK writes against SSE events. Update and
+	// Delete claim the resource's version (see LastWriteWins) as of when
+	// the SDK call started, so a sync engine with SyncConfig.LastWriteWins
+	// rejects events created before the write, which reflect the state
+	// the write replaced. The write's own event, created after it, is
+	// accepted and repopulates the cache. Event versions come from the
+	// bridge's clock and write versions from this process's, so keep
+	// them in sync.
+	// Default: false
+	LastWriteWins bool
+
+	// Logger receives diagnostics for errors that don't fail the caller,
+	// such as cache population failures.
+	// Default: no-op logger
+	Logger Logger
+
+	// TracerProvider enables OpenTelemetry spans around cached client
+	// operations (e.g. "hue-cache.Lights.Get"), recording the cache key,
+	// whether the lookup was a hit, and whether the SDK was called.
+	// Default: nil (tracing disabled)
+	TracerProvider trace.TracerProvider
+
+	// NegativeTTL enables negative caching. When the SDK reports that a
+	// resource doesn't exist, a tombstone entry is cached for NegativeTTL
+	// and subsequent Gets return ErrNotFound without calling the SDK.
+	// SSE add/update events and cached Update/Delete calls replace or
+	// remove the tombstone, so a recreated resource is not masked.
+	// Keep this short; it bounds how long a newly created resource can be
+	// reported missing if its SSE event is lost.
+	// Default: 0 (disabled)
+	NegativeTTL time.Duration
+
+	// IsNotFound reports whether an SDK error means the resource doesn't
+	// exist. Only such errors are negatively cached.
+	// Default: errors.Is(err, ErrNotFound)
+	IsNotFound func(error) bool
+
+	// WriteBehind enables asynchronous light updates. Update returns as
+	// soon as the change is queued and the cached light is patched; a
+	// background worker sends it to the SDK. Call CachedClient.Close to
+	// flush pending updates on shutdown.
+	// Default: nil (synchronous write-through)
+	WriteBehind *WriteBehindConfig
+
+	// TTLJitter randomizes each entry's TTL by up to this fraction in
+	// either direction (0.1 = ±10%), so entries cached together don't all
+	// expire - and hit the bridge - at the same moment. Jitter only
+	// applies when TTL > 0.
+	// Default: 0 (no jitter)
+	TTLJitter float64
+
+	// TTLJitterSeed makes jitter deterministic: with a non-zero seed, a
+	// given key always gets the same TTL. Useful for reproducible tests.
+	// Default: 0 (random jitter)
+	TTLJitterSeed uint64
+
+	// BackendTimeout bounds each backend operation made without a caller
+	// deadline, so a wedged backend can't hang reads and writes. Callers'
+	// own deadlines always take precedence. The backend must honor context
+	// cancellation. Set to 0 to disable.
+	// Default: DefaultBackendTimeout (5 seconds)
+	BackendTimeout time.Duration
+
+	// CacheRaw also caches each resource's JSON under a separate raw key
+	// (see KeyBuilder.Raw), so GetRaw can serve it verbatim without
+	// re-marshaling. If the SDK client implements RawGetter, the raw entry
+	// holds the bridge's bytes exactly as received; otherwise it holds the
+	// resource as the SDK decoded it. Raw entries are written and
+	// invalidated together with the parsed entries.
+	// Default: false
+	CacheRaw bool
+
+	// Validation checks each resource before it is cached. Rejected
+	// resources are still returned to the caller but not cached, and a
+	// warning is logged.
+	// Default: nil (no validation)
+	Validation *Validation
+
+	// FailClosed controls what reads do when the backend fails, as
+	// opposed to missing the key. If false, the failure is logged and the
+	// read falls back to the SDK, as if the cache were empty. If true,
+	// the backend error is returned, so callers can detect a broken cache
+	// instead of silently loading the bridge. Misses always fall back.
+	// Default: false (fail open)
+	FailClosed bool
+
+	// CoalesceWindow collapses Get misses for different IDs into one SDK
+	// List call. The first miss waits up to CoalesceWindow for others to
+	// join, then a single List answers them all, so a UI loading many
+	// items at once costs one bridge round-trip. It trades that delay on
+	// every miss for fewer requests; keep it to a few milliseconds. Has no
+	// effect on misses fetched through CacheRaw's RawGetter.
+	// Default: 0 (disabled)
+	CoalesceWindow time.Duration
+
+	// Codec serializes cached resources. MsgpackCodec allocates less than
+	// JSON when caching many resources. Use the same codec for a SyncEngine
+	// you run yourself (SyncConfig.Codec; the client's own engine gets it)
+	// and the CacheManager (SetCodec) sharing the backend; values written
+	// by another codec are treated as misses.
+	// Default: JSONCodec()
+	Codec Codec
+
+	// StaleWhileRevalidate keeps Get serving a cached resource after its
+	// TTL elapses, so a bridge hiccup doesn't fail reads that a slightly
+	// stale value would answer. A Get past the TTL returns the stale value
+	// at once (flagged Stale by GetWithMeta) and refreshes it from the SDK
+	// in the background. If the refresh fails, the stale value keeps being
+	// served until StaleIfError past the TTL. List and ListPage serve
+	// stale entries the same way. Entries are stored with a
+	// TTL of TTL+StaleIfError, which EntryMeta reports, and tagged with
+	// the window (e.g. "swr:5m0s"). Entries without the tag, such as those
+	// written by sync or to a backend that can't store tags, are never
+	// served stale. Only applies when TTL > 0.
+	// Default: false
+	StaleWhileRevalidate bool
+
+	// StaleIfError is how long past its TTL a resource may be served
+	// stale with StaleWhileRevalidate.
+	// Default: DefaultStaleIfError
+	StaleIfError time.Duration
+
+	// ListRepairThreshold lets List repair a mostly intact cache instead
+	// of discarding it. When at most this many cached entries are expired,
+	// stale, or can't be decoded, List returns the readable ones and
+	// fetches only the others, with one SDK Get each. Above the threshold,
+	// List makes a single SDK List call as usual. One List is cheaper than
+	// a handful of Gets on the bridge, so keep it small. Only entries the
+	// backend still lists can be repaired; a resource with no cache key
+	// at all is invisible to List until the next full fetch or sync.
+	// Default: 0 (any unreadable entry refetches everything)
+	ListRepairThreshold int
+
+	// CacheOnCreate makes Create on the room, zone, and scene clients
+	// fetch the new resource with an SDK Get and cache it. Without a
+	// running sync engine nothing else caches created resources, so
+	// enable it when EnableSync is false; with sync, the SSE add event
+	// caches them without the extra bridge round-trip. A failed fetch is
+	// logged and doesn't fail the Create.
+	// Default: false
+	CacheOnCreate bool
+
+	// Projection strips cached resources to an allowlist of fields per
+	// resource type, shrinking the cache. Get and List then return partial
+	// resources; see Projection.
+	// Default: nil (cache full resources)
+	Projection *Projection
+
+	// SDKRateLimit caps the calls per second the cached clients make to
+	// the SDK: cache misses, List refetches, refreshes, and writes. The
+	// resource clients of a CachedClient share one token bucket, so a cold
+	// cache under bursty traffic can't flood the bridge, which answers
+	// floods with 429s. A call that can't get a token before its context's
+	// deadline fails at once with ErrRateLimited. Coalesced Gets each take
+	// a token.
+	// Default: 0 (unlimited)
+	SDKRateLimit rate.Limit
+
+	// SDKRateBurst is how many SDK calls may be made back to back before
+	// SDKRateLimit applies.
+	// Default: 1 when SDKRateLimit is set
+	SDKRateBurst int
+}
+
+// newSDKLimiter returns the SDK rate limiter described by config, or nil
+// if SDK calls are unlimited.
+func newSDKLimiter(config *CachedClientConfig) *rate.Limiter {
+	if config.SDKRateLimit <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(config.SDKRateLimit, max(config.SDKRateBurst, 1))
 }
 
 // DefaultCachedClientConfig returns default configuration.
 func DefaultCachedClientConfig() *CachedClientConfig {
 	return &CachedClientConfig{
-		TTL:        0, // No expiration by default
-		EnableSync: true,
-		SyncConfig: DefaultSyncConfig(),
+		TTL:            0, // No expiration by default
+		EnableSync:     true,
+		SyncConfig:     DefaultSyncConfig(),
+		BackendTimeout: DefaultBackendTimeout,
 	}
 }
 
@@ -78,17 +285,61 @@ func NewCachedClient(backend Backend, sdkClient *hue.Client, config *CachedClien
 		config = DefaultCachedClientConfig()
 	}
 
-	return &CachedClient{
+	c := &CachedClient{
 		backend:   backend,
 		sdkClient: sdkClient,
 		ttl:       config.TTL,
+		config:    config,
+		limiter:   newSDKLimiter(config),
+	}
+	if config.EnableSync {
+		c.syncEngine = NewSyncEngine(backend, sdkClient, ownedSyncConfig(config))
+	}
+	return c
+}
+
+// ownedSyncConfig returns the configuration of the client's own sync
+// engine: a copy of config.SyncConfig with the settings the engine must
+// share with the clients taken from config. See
+// CachedClientConfig.SyncConfig.
+func ownedSyncConfig(config *CachedClientConfig) *SyncConfig {
+	syncConfig := DefaultSyncConfig()
+	if config.SyncConfig != nil {
+		copied := *config.SyncConfig
+		syncConfig = &copied
 	}
+
+	syncConfig.KeyPrefix = config.KeyPrefix
+	syncConfig.Codec = config.Codec
+	syncConfig.Projection = config.Projection
+	syncConfig.CacheRaw = config.CacheRaw
+	// Client writes claim versions for the engine to check; the engine
+	// can also check versions on its own
+	syncConfig.LastWriteWins = syncConfig.LastWriteWins || config.LastWriteWins
+	return syncConfig
+}
+
+// Start starts the client's sync engine. It is a no-op when EnableSync
+// is false.
+func (c *CachedClient) Start() error {
+	if c.syncEngine == nil {
+		return nil
+	}
+	return c.syncEngine.Start()
+}
+
+// SyncEngine returns the client's sync engine, or nil when EnableSync is
+// false. Useful for sync statistics.
+func (c *CachedClient) SyncEngine() *SyncEngine {
+	return c.syncEngine
 }
 
 // Lights returns a cached light client.
 func (c *CachedClient) Lights() hue.LightClient {
 	if c.lights == nil {
 		c.lights = NewCachedLightClient(c.backend, c.sdkClient.Lights(), c.ttl)
+		c.lights.configure(c.config)
+		c.lights.limiter = c.limiter
 	}
 	return c.lights
 }
@@ -97,6 +348,8 @@ func (c *CachedClient) Lights() hue.LightClient {
 func (c *CachedClient) Rooms() hue.RoomClient {
 	if c.rooms == nil {
 		c.rooms = NewCachedRoomClient(c.backend, c.sdkClient.Rooms(), c.ttl)
+		c.rooms.configure(c.config)
+		c.rooms.limiter = c.limiter
 	}
 	return c.rooms
 }
@@ -105,6 +358,8 @@ func (c *CachedClient) Rooms() hue.RoomClient {
 func (c *CachedClient) Zones() hue.ZoneClient {
 	if c.zones == nil {
 		c.zones = NewCachedZoneClient(c.backend, c.sdkClient.Zones(), c.ttl)
+		c.zones.configure(c.config)
+		c.zones.limiter = c.limiter
 	}
 	return c.zones
 }
@@ -113,6 +368,8 @@ func (c *CachedClient) Zones() hue.ZoneClient {
 func (c *CachedClient) Scenes() hue.SceneClient {
 	if c.scenes == nil {
 		c.scenes = NewCachedSceneClient(c.backend, c.sdkClient.Scenes(), c.ttl)
+		c.scenes.configure(c.config)
+		c.scenes.limiter = c.limiter
 	}
 	return c.scenes
 }
@@ -121,10 +378,69 @@ func (c *CachedClient) Scenes() hue.SceneClient {
 func (c *CachedClient) GroupedLights() hue.GroupedLightClient {
 	if c.groupedLights == nil {
 		c.groupedLights = NewCachedGroupedLightClient(c.backend, c.sdkClient.GroupedLights(), c.ttl)
+		c.groupedLights.configure(c.config)
+		c.groupedLights.limiter = c.limiter
 	}
 	return c.groupedLights
 }
 
+// Bridge returns a cached bridge client.
+func (c *CachedClient) Bridge() hue.BridgeClient {
+	if c.bridge == nil {
+		c.bridge = NewCachedBridgeClient(c.backend, c.sdkClient.Bridge(), c.ttl)
+		c.bridge.configure(c.config)
+		c.bridge.limiter = c.limiter
+	}
+	return c.bridge
+}
+
+// BridgeHome returns a cached bridge home client.
+func (c *CachedClient) BridgeHome() hue.BridgeHomeClient {
+	if c.bridgeHome == nil {
+		c.bridgeHome = NewCachedBridgeHomeClient(c.backend, c.sdkClient.BridgeHome(), c.ttl)
+		c.bridgeHome.configure(c.config)
+		c.bridgeHome.limiter = c.limiter
+	}
+	return c.bridgeHome
+}
+
+// Close shuts down what the client owns: it stops the sync engine, so no
+// more events are applied, then flushes any pending write-behind updates.
+// The backend and SDK client belong to the caller, who may share them
+// with a CacheManager or other clients, so neither is closed; close the
+// backend after the client. Only the first call has any effect; later
+// calls return its result.
+func (c *CachedClient) Close() error {
+	c.closeOnce.Do(func() {
+		var errs []error
+		if c.syncEngine != nil {
+			errs = append(errs, c.syncEngine.Stop())
+		}
+		if c.lights != nil {
+			errs = append(errs, c.lights.Close())
+		}
+		c.closeErr = errors.Join(errs...)
+	})
+	return c.closeErr
+}
+
+// Subscribe returns a channel receiving changes to cached entries whose
+// keys match pattern (e.g. "light:*"), such as those the sync engine
+// applies from SSE events, so a live view can be pushed updates instead of
+// polling List. Patterns and event keys are relative to KeyPrefix. The
+// channel is closed when ctx is done. It requires a backend implementing
+// ChangeNotifier and otherwise returns ErrUnsupported. See Subscribe.
+func (c *CachedClient) Subscribe(ctx context.Context, pattern string) (<-chan ChangeEvent, error) {
+	return subscribe(ctx, c.backend, pattern, NewKeyBuilderWithPrefix(c.config.KeyPrefix))
+}
+
+// Ping reports whether the cache backend is functioning, for readiness
+// probes such as an HTTP /healthz handler. It doesn't contact the bridge.
+// See Pinger.
+func (c *CachedClient) Ping(ctx context.Context) error {
+	return Ping(ctx, c.backend)
+}
+
 // Backend returns the underlying cache backend.
 // Useful for accessing cache statistics or performing manual operations.
 func (c *CachedClient) Backend() Backend {
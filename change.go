@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChangeOp identifies how a cache entry changed.
+type ChangeOp int
+
+const (
+	// ChangeSet means the entry was created or overwritten.
+	ChangeSet ChangeOp = iota
+
+	// ChangeDelete means the entry was deleted (including by Clear).
+	ChangeDelete
+
+	// ChangeEvict means the entry was evicted to make room.
+	ChangeEvict
+
+	// ChangeExpire means the entry was removed because its TTL elapsed.
+	ChangeExpire
+)
+
+// String returns the operation name.
+func (op ChangeOp) String() string {
+	switch op {
+	case ChangeSet:
+		return "set"
+	case ChangeDelete:
+		return "delete"
+	case ChangeEvict:
+		return "evict"
+	case ChangeExpire:
+		return "expire"
+	default:
+		return "unknown"
+	}
+}
+
+// ChangeEvent describes a change to a single cache entry.
+type ChangeEvent struct {
+	// Key is the cache key that changed.
+	Key string
+
+	// Op is how the entry changed.
+	Op ChangeOp
+
+	// Value is the new value for ChangeSet, and nil otherwise.
+	// It may be shared with the cache and must not be modified.
+	Value []byte
+
+	// Time is when the change happened.
+	Time time.Time
+}
+
+// ChangeNotifier is implemented by backends that can report entry changes,
+// e.g. to keep derived indexes up to date.
+type ChangeNotifier interface {
+	// OnChange registers listener to be called for every entry change and
+	// returns a function that unregisters it. Listeners are called
+	// asynchronously and in order; a listener that falls too far behind
+	// misses events rather than stalling cache writes.
+	OnChange(listener func(ChangeEvent)) (unsubscribe func())
+}
+
+// Subscribe returns a channel receiving changes to entries of backend
+// whose keys match pattern (see MatchPattern), including those the sync
+// engine applies, so callers can push updates instead of polling. Events
+// arrive in order. A subscriber that falls more than the channel's buffer
+// (256 events) plus the backend's listener buffer behind misses events
+// rather than stalling cache writes. When ctx is done the subscription
+// ends and the channel is closed. Backends that don't implement
+// ChangeNotifier return ErrUnsupported.
+func Subscribe(ctx context.Context, backend Backend, pattern string) (<-chan ChangeEvent, error) {
+	return subscribe(ctx, backend, pattern, NewKeyBuilder())
+}
+
+// subscribe is Subscribe within kb's namespace: pattern is matched inside
+// it, and event keys are delivered without the prefix.
+func subscribe(ctx context.Context, backend Backend, pattern string, kb *KeyBuilder) (<-chan ChangeEvent, error) {
+	notifier, ok := backend.(ChangeNotifier)
+	if !ok {
+		return nil, NewError("Subscribe", "", ErrUnsupported)
+	}
+
+	pattern = kb.pattern(pattern)
+	events := make(chan ChangeEvent, defaultChangeBufferSize)
+
+	// mu orders sends against closing events
+	var mu sync.Mutex
+	closed := false
+
+	unsubscribe := notifier.OnChange(func(event ChangeEvent) {
+		if !MatchPattern(pattern, event.Key) {
+			return
+		}
+		event.Key = strings.TrimPrefix(event.Key, kb.prefix)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	})
+
+	go func() {
+		<-ctx.Done()
+		unsubscribe()
+
+		mu.Lock()
+		defer mu.Unlock()
+		closed = true
+		close(events)
+	}()
+
+	return events, nil
+}
+
+// ChangeFeed fans change events out to listeners without blocking the
+// publisher. Each listener has its own buffer and goroutine; when a
+// listener's buffer is full, events for it are dropped and counted.
+// Backends embed a ChangeFeed to implement ChangeNotifier.
+type ChangeFeed struct {
+	listeners *fanout[ChangeEvent]
+}
+
+// defaultChangeBufferSize is the per-listener buffer used when
+// NewChangeFeed is given a size <= 0.
+const defaultChangeBufferSize = 256
+
+// NewChangeFeed creates a feed that buffers up to bufferSize events per
+// listener. If bufferSize <= 0, a default of 256 is used.
+func NewChangeFeed(bufferSize int) *ChangeFeed {
+	if bufferSize <= 0 {
+		bufferSize = defaultChangeBufferSize
+	}
+	return &ChangeFeed{listeners: newFanout[ChangeEvent](bufferSize)}
+}
+
+// OnChange registers a listener. See ChangeNotifier.
+func (f *ChangeFeed) OnChange(listener func(ChangeEvent)) (unsubscribe func()) {
+	return f.listeners.subscribe(nil, listener)
+}
+
+// Publish delivers an event to every listener without blocking.
+func (f *ChangeFeed) Publish(event ChangeEvent) {
+	if !f.listeners.hasSubscribers() {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	f.listeners.publish(event)
+}
+
+// Dropped returns how many events were dropped because a listener's
+// buffer was full.
+func (f *ChangeFeed) Dropped() int64 {
+	return f.listeners.dropped.Load()
+}
+
+// Close unregisters all listeners. Events already buffered are still
+// delivered. Listeners registered after Close are never called.
+func (f *ChangeFeed) Close() {
+	f.listeners.close()
+}
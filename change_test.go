@@ -0,0 +1,202 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestChangeFeed_DeliversInOrder(t *testing.T) {
+	feed := NewChangeFeed(0)
+	defer feed.Close()
+
+	var mu sync.Mutex
+	var keys []string
+	done := make(chan struct{})
+	feed.OnChange(func(event ChangeEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, event.Key)
+		if len(keys) == 3 {
+			close(done)
+		}
+	})
+
+	for _, key := range []string{"a", "b", "c"} {
+		feed.Publish(ChangeEvent{Key: key, Op: ChangeSet})
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for events")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if keys[0] != "a" || keys[1] != "b" || keys[2] != "c" {
+		t.Errorf("Events delivered as %v, want [a b c]", keys)
+	}
+}
+
+func TestChangeFeed_DropsWhenFull(t *testing.T) {
+	feed := NewChangeFeed(1)
+	defer feed.Close()
+
+	release := make(chan struct{})
+	feed.OnChange(func(event ChangeEvent) {
+		<-release
+	})
+	defer close(release)
+
+	for i := 0; i < 10; i++ {
+		feed.Publish(ChangeEvent{Key: "a"})
+	}
+
+	// One event is being handled and one is buffered; the rest are dropped
+	if dropped := feed.Dropped(); dropped < 8 {
+		t.Errorf("Dropped() = %d, want at least 8", dropped)
+	}
+}
+
+func TestChangeFeed_Unsubscribe(t *testing.T) {
+	feed := NewChangeFeed(0)
+	defer feed.Close()
+
+	calls := make(chan ChangeEvent, 10)
+	unsubscribe := feed.OnChange(func(event ChangeEvent) {
+		calls <- event
+	})
+	unsubscribe()
+	unsubscribe() // idempotent
+
+	feed.Publish(ChangeEvent{Key: "a"})
+	time.Sleep(10 * time.Millisecond)
+
+	if len(calls) != 0 {
+		t.Error("Listener called after unsubscribe")
+	}
+}
+
+func TestChangeOp_String(t *testing.T) {
+	ops := map[ChangeOp]string{
+		ChangeSet:    "set",
+		ChangeDelete: "delete",
+		ChangeEvict:  "evict",
+		ChangeExpire: "expire",
+		ChangeOp(99): "unknown",
+	}
+	for op, want := range ops {
+		if got := op.String(); got != want {
+			t.Errorf("ChangeOp(%d).String() = %q, want %q", op, got, want)
+		}
+	}
+}
+
+// notifyingBackend is a mockBackend that reports sets and deletes.
+type notifyingBackend struct {
+	*mockBackend
+	changes *ChangeFeed
+}
+
+func newNotifyingBackend() *notifyingBackend {
+	return &notifyingBackend{mockBackend: newMockBackend(), changes: NewChangeFeed(0)}
+}
+
+func (b *notifyingBackend) OnChange(listener func(ChangeEvent)) (unsubscribe func()) {
+	return b.changes.OnChange(listener)
+}
+
+func (b *notifyingBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := b.mockBackend.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	b.changes.Publish(ChangeEvent{Key: key, Op: ChangeSet, Value: value})
+	return nil
+}
+
+func (b *notifyingBackend) Delete(ctx context.Context, key string) error {
+	if err := b.mockBackend.Delete(ctx, key); err != nil {
+		return err
+	}
+	b.changes.Publish(ChangeEvent{Key: key, Op: ChangeDelete})
+	return nil
+}
+
+// receive returns the next event from events, failing the test after a
+// second.
+func receive(t *testing.T, events <-chan ChangeEvent) ChangeEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("events closed")
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for an event")
+	}
+	return ChangeEvent{}
+}
+
+func TestSubscribe(t *testing.T) {
+	backend := newNotifyingBackend()
+	defer backend.changes.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := Subscribe(ctx, backend, "light:*")
+	if err != nil {
+		t.Fatalf("Subscribe() failed: %v", err)
+	}
+
+	backend.Set(ctx, "room:1", []byte("room"), 0)
+	backend.Set(ctx, "light:1", []byte("on"), 0)
+	backend.Delete(ctx, "light:1")
+
+	// Only matching keys are delivered, in order
+	if event := receive(t, events); event.Key != "light:1" || event.Op != ChangeSet || string(event.Value) != "on" {
+		t.Errorf("first event = %+v, want set light:1", event)
+	}
+	if event := receive(t, events); event.Key != "light:1" || event.Op != ChangeDelete {
+		t.Errorf("second event = %+v, want delete light:1", event)
+	}
+
+	// Cancelling closes the channel and unsubscribes
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("received an event after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("events not closed after cancel")
+	}
+	backend.Set(context.Background(), "light:2", []byte("on"), 0) // Must not panic
+
+	if _, err := Subscribe(context.Background(), newMockBackend(), "*"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Subscribe() without ChangeNotifier error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestCachedClient_Subscribe(t *testing.T) {
+	backend := newNotifyingBackend()
+	defer backend.changes.Close()
+
+	client := NewCachedClient(backend, nil, &CachedClientConfig{KeyPrefix: "home"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := client.Subscribe(ctx, "light:*")
+	if err != nil {
+		t.Fatalf("Subscribe() failed: %v", err)
+	}
+
+	// Keys are relative to the client's namespace
+	backend.Set(ctx, "office:light:1", []byte("other"), 0)
+	backend.Set(ctx, "home:light:1", []byte("on"), 0)
+	if event := receive(t, events); event.Key != "light:1" || string(event.Value) != "on" {
+		t.Errorf("event = %+v, want set light:1 from the home namespace", event)
+	}
+}
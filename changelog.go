@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ChangeRecord is one mutation applied to the cache by the sync engine.
+type ChangeRecord struct {
+	// Time is when the mutation was applied.
+	Time time.Time `json:"t"`
+
+	// EventType is the SSE event type ("add", "update", "delete").
+	EventType string `json:"e"`
+
+	// Type is the resource type (e.g. "light").
+	Type string `json:"type"`
+
+	// ID is the resource ID.
+	ID string `json:"id"`
+}
+
+// ChangeLogConfig contains configuration for a change-log.
+type ChangeLogConfig struct {
+	// Path is the change-log file. The previous generation is kept at
+	// Path + ".1" after rotation.
+	Path string
+
+	// MaxEntries is how many records a file holds before it is rotated.
+	// Between MaxEntries and 2*MaxEntries records are retained.
+	// Default: 10000
+	MaxEntries int
+}
+
+// DefaultChangeLogConfig returns default change-log configuration.
+func DefaultChangeLogConfig(path string) *ChangeLogConfig {
+	return &ChangeLogConfig{
+		Path:       path,
+		MaxEntries: 10000,
+	}
+}
+
+// ChangeLog is a compact, durable record of cache mutations applied by
+// sync, stored as one JSON object per line. It is bounded by rotating
+// into a single previous-generation file, so it behaves like a ring.
+//
+// Records are appended without fsync: a crash may lose the most recent
+// records but never corrupts earlier ones.
+type ChangeLog struct {
+	path       string
+	maxEntries int
+
+	mu      sync.Mutex
+	file    *os.File
+	entries int
+}
+
+// OpenChangeLog opens or creates the change-log described by config.
+func OpenChangeLog(config *ChangeLogConfig) (*ChangeLog, error) {
+	if config == nil || config.Path == "" {
+		return nil, fmt.Errorf("change-log path is required")
+	}
+
+	maxEntries := config.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+
+	// Count existing records so rotation resumes where it left off
+	existing, err := readChangeRecords(config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening change-log: %w", err)
+	}
+
+	return &ChangeLog{
+		path:       config.Path,
+		maxEntries: maxEntries,
+		file:       file,
+		entries:    len(existing),
+	}, nil
+}
+
+// Append writes a record to the change-log, rotating first if the
+// current file is full.
+func (l *ChangeLog) Append(record ChangeRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encoding change record: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return ErrClientClosed
+	}
+
+	if l.entries >= l.maxEntries {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := l.file.Write(line); err != nil {
+		return fmt.Errorf("writing change-log: %w", err)
+	}
+	l.entries++
+
+	return nil
+}
+
+// Tail returns up to the n most recent records, oldest first.
+// If n <= 0, every retained record is returned.
+func (l *ChangeLog) Tail(n int) ([]ChangeRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	previous, err := readChangeRecords(l.path + ".1")
+	if err != nil {
+		return nil, err
+	}
+	current, err := readChangeRecords(l.path)
+	if err != nil {
+		return nil, err
+	}
+
+	records := append(previous, current...)
+	if n > 0 && len(records) > n {
+		records = records[len(records)-n:]
+	}
+	return records, nil
+}
+
+// Close closes the change-log file.
+func (l *ChangeLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// rotate moves the current file to the previous generation and starts a
+// new one. Must be called with mu held.
+func (l *ChangeLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("closing change-log: %w", err)
+	}
+	l.file = nil
+
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return fmt.Errorf("rotating change-log: %w", err)
+	}
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("opening change-log: %w", err)
+	}
+	l.file = file
+	l.entries = 0
+
+	return nil
+}
+
+// readChangeRecords reads every record from a change-log file.
+// A missing file has no records; a torn final line (from a crash
+// mid-write) is ignored.
+func readChangeRecords(path string) ([]ChangeRecord, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening change-log: %w", err)
+	}
+	defer file.Close()
+
+	var records []ChangeRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record ChangeRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading change-log: %w", err)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+func TestSyncEngine_ChangeLog(t *testing.T) {
+	changeLog, err := OpenChangeLog(DefaultChangeLogConfig(filepath.Join(t.TempDir(), "changes.log")))
+	if err != nil {
+		t.Fatalf("OpenChangeLog() failed: %v", err)
+	}
+	defer changeLog.Close()
+
+	config := DefaultSyncConfig()
+	config.ChangeLog = changeLog
+	engine := NewSyncEngine(newMockBackend(), nil, config)
+
+	ctx := context.Background()
+	raw, _ := json.Marshal(map[string]interface{}{"id": "light-1", "type": "light"})
+	events := []struct {
+		eventType string
+		data      resources.EventData
+	}{
+		{resources.EventTypeAdd, resources.EventData{ID: "light-1", Type: "light", RawData: raw}},
+		{resources.EventTypeUpdate, resources.EventData{ID: "light-1", Type: "light", RawData: raw}},
+		{resources.EventTypeDelete, resources.EventData{ID: "room-1", Type: "room"}},
+	}
+
+	before := time.Now()
+	for _, event := range events {
+		if err := engine.processEventData(ctx, event.eventType, &event.data); err != nil {
+			t.Fatalf("processEventData() failed: %v", err)
+		}
+	}
+
+	// Unknown event types aren't applied, so aren't recorded
+	_ = engine.processEventData(ctx, "bogus", &resources.EventData{ID: "light-2", Type: "light"})
+
+	records, err := changeLog.Tail(0)
+	if err != nil {
+		t.Fatalf("Tail() failed: %v", err)
+	}
+	if len(records) != len(events) {
+		t.Fatalf("Got %d records, want %d: %+v", len(records), len(events), records)
+	}
+
+	for i, event := range events {
+		record := records[i]
+		if record.EventType != event.eventType || record.Type != event.data.Type || record.ID != event.data.ID {
+			t.Errorf("Record %d = %+v, want %s %s/%s", i, record, event.eventType, event.data.Type, event.data.ID)
+		}
+		if record.Time.Before(before.Truncate(time.Second)) {
+			t.Errorf("Record %d time %v is before events were applied", i, record.Time)
+		}
+	}
+}
+
+func TestChangeLog_TailAndRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changes.log")
+	changeLog, err := OpenChangeLog(&ChangeLogConfig{Path: path, MaxEntries: 3})
+	if err != nil {
+		t.Fatalf("OpenChangeLog() failed: %v", err)
+	}
+
+	for i := 0; i < 8; i++ {
+		record := ChangeRecord{Time: time.Now(), EventType: "update", Type: "light", ID: fmt.Sprintf("light-%d", i)}
+		if err := changeLog.Append(record); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+
+	// Files hold [3,4,5] and [6,7]; the oldest generation was dropped
+	records, err := changeLog.Tail(0)
+	if err != nil {
+		t.Fatalf("Tail() failed: %v", err)
+	}
+	if len(records) != 5 || records[0].ID != "light-3" || records[4].ID != "light-7" {
+		t.Errorf("Tail(0) = %+v, want light-3 through light-7", records)
+	}
+
+	records, _ = changeLog.Tail(2)
+	if len(records) != 2 || records[0].ID != "light-6" || records[1].ID != "light-7" {
+		t.Errorf("Tail(2) = %+v, want light-6 and light-7", records)
+	}
+
+	if err := changeLog.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	// Reopening resumes the count, so the next append rotates on schedule
+	changeLog, err = OpenChangeLog(&ChangeLogConfig{Path: path, MaxEntries: 3})
+	if err != nil {
+		t.Fatalf("OpenChangeLog() failed: %v", err)
+	}
+	defer changeLog.Close()
+
+	changeLog.Append(ChangeRecord{EventType: "add", Type: "light", ID: "light-8"})
+	changeLog.Append(ChangeRecord{EventType: "add", Type: "light", ID: "light-9"})
+
+	records, _ = changeLog.Tail(0)
+	if len(records) != 4 || records[0].ID != "light-6" || records[3].ID != "light-9" {
+		t.Errorf("Tail(0) after reopen = %+v, want light-6 through light-9", records)
+	}
+}
+
+func TestChangeLog_AppendAfterClose(t *testing.T) {
+	changeLog, err := OpenChangeLog(DefaultChangeLogConfig(filepath.Join(t.TempDir(), "changes.log")))
+	if err != nil {
+		t.Fatalf("OpenChangeLog() failed: %v", err)
+	}
+	changeLog.Close()
+
+	if err := changeLog.Append(ChangeRecord{ID: "light-1"}); err == nil {
+		t.Error("Expected Append() after Close to fail")
+	}
+}
@@ -2,8 +2,6 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"time"
 
 	"github.com/rmrfslashbin/hue-sdk"
@@ -13,656 +11,798 @@ import (
 // CachedLightClient wraps the SDK LightClient with caching.
 // It implements the same interface as hue.LightClient for drop-in replacement.
 type CachedLightClient struct {
-	backend    Backend
-	client     hue.LightClient
-	keyBuilder *KeyBuilder
-	ttl        time.Duration
+	resourceCache
+	client hue.LightClient
+
+	// writer queues updates when write-behind mode is enabled (nil otherwise)
+	writer *writeBehind[resources.LightUpdate]
 }
 
 // NewCachedLightClient creates a new cached light client.
 // If ttl is 0, cached entries never expire (rely on SSE updates).
 func NewCachedLightClient(backend Backend, client hue.LightClient, ttl time.Duration) *CachedLightClient {
 	return &CachedLightClient{
-		backend:    backend,
-		client:     client,
-		keyBuilder: NewKeyBuilder(),
-		ttl:        ttl,
+		resourceCache: newResourceCache("Lights", backend, ttl, client),
+		client:        client,
 	}
 }
 
 // List returns all lights, using cache when possible.
 // On cache miss, fetches from SDK and populates cache.
 func (c *CachedLightClient) List(ctx context.Context) ([]resources.Light, error) {
-	// Try to get all lights from cache using pattern
-	pattern := c.keyBuilder.AllLights()
-	keys, err := c.backend.Keys(ctx, pattern)
-	if err == nil && len(keys) > 0 {
-		// Attempt to get all lights from cache
-		var lights []resources.Light
-		allFound := true
-
-		for _, key := range keys {
-			entry, err := c.backend.Get(ctx, key)
-			if err != nil {
-				allFound = false
-				break
-			}
-
-			var light resources.Light
-			if err := json.Unmarshal(entry.Value, &light); err != nil {
-				allFound = false
-				break
-			}
-
-			lights = append(lights, light)
-		}
+	keyOf := func(light *resources.Light) string {
+		return c.keyBuilder.Light(light.ID)
+	}
+	return listThrough(ctx, &c.resourceCache, c.keyBuilder.AllLights(), keyOf, c.client.List, c.client.Get)
+}
 
-		if allFound {
-			return lights, nil
-		}
+// ListSorted returns all lights like List, sorted by less. The sort is
+// stable, so lights that compare equal keep their List order.
+func (c *CachedLightClient) ListSorted(ctx context.Context, less func(a, b resources.Light) bool) ([]resources.Light, error) {
+	return listSorted(ctx, c.List, less)
+}
+
+// ListPage returns one page of lights, ordered by ID, and the total number of
+// lights. Pages served from the cache only unmarshal the lights on the page.
+func (c *CachedLightClient) ListPage(ctx context.Context, offset, limit int) ([]resources.Light, int, error) {
+	keyOf := func(light *resources.Light) string {
+		return c.keyBuilder.Light(light.ID)
 	}
+	return listPage(ctx, &c.resourceCache, c.keyBuilder.AllLights(), offset, limit, keyOf, c.client.List, c.client.Get)
+}
 
-	// Cache miss - fetch from SDK
-	lights, err := c.client.List(ctx)
-	if err != nil {
+// Get returns a single light by ID, using cache when possible.
+// On cache miss, fetches from SDK and populates cache.
+func (c *CachedLightClient) Get(ctx context.Context, id string) (*resources.Light, error) {
+	if err := checkID("light", id); err != nil {
 		return nil, err
 	}
 
-	// Populate cache
-	for _, light := range lights {
-		key := c.keyBuilder.Light(light.ID)
-		data, err := json.Marshal(light)
-		if err != nil {
-			continue // Skip this light, don't fail entire operation
-		}
-		_ = c.backend.Set(ctx, key, data, c.ttl)
+	fetch := coalescedFetch(&c.resourceCache, id, func(r *resources.Light) string { return r.ID }, c.client.List,
+		func(ctx context.Context) (*resources.Light, error) {
+			return c.client.Get(ctx, id)
+		})
+	return getThrough(ctx, &c.resourceCache, c.keyBuilder.Light(id), fetch)
+}
+
+// GetWithMeta returns a single light by ID like Get, along with metadata
+// describing how fresh the cached copy is.
+func (c *CachedLightClient) GetWithMeta(ctx context.Context, id string) (*resources.Light, *EntryMeta, error) {
+	if err := checkID("light", id); err != nil {
+		return nil, nil, err
 	}
 
-	return lights, nil
+	fetch := coalescedFetch(&c.resourceCache, id, func(r *resources.Light) string { return r.ID }, c.client.List,
+		func(ctx context.Context) (*resources.Light, error) {
+			return c.client.Get(ctx, id)
+		})
+	return getWithMetaThrough(ctx, &c.resourceCache, c.keyBuilder.Light(id), fetch)
 }
 
-// Get returns a single light by ID, using cache when possible.
-// On cache miss, fetches from SDK and populates cache.
-func (c *CachedLightClient) Get(ctx context.Context, id string) (*resources.Light, error) {
-	if id == "" {
-		return nil, fmt.Errorf("invalid light ID")
+// GetRaw returns the JSON for a light. With CacheRaw enabled it is
+// served verbatim from the raw cache entry; otherwise the light is
+// loaded like Get and serialized.
+func (c *CachedLightClient) GetRaw(ctx context.Context, id string) ([]byte, error) {
+	if err := checkID("light", id); err != nil {
+		return nil, err
 	}
 
-	// Try cache first
-	key := c.keyBuilder.Light(id)
-	entry, err := c.backend.Get(ctx, key)
-	if err == nil {
-		var light resources.Light
-		if err := json.Unmarshal(entry.Value, &light); err == nil {
-			return &light, nil
-		}
+	get := func(ctx context.Context) (*resources.Light, error) {
+		return c.Get(ctx, id)
 	}
+	return rawThrough(ctx, &c.resourceCache, c.keyBuilder.Light(id), get)
+}
 
-	// Cache miss - fetch from SDK
-	light, err := c.client.Get(ctx, id)
-	if err != nil {
+// Refresh fetches a light from the SDK, bypassing the cache, and
+// overwrites its cache entry with the result.
+// Use this when the cached copy is known to be stale.
+func (c *CachedLightClient) Refresh(ctx context.Context, id string) (*resources.Light, error) {
+	if err := checkID("light", id); err != nil {
 		return nil, err
 	}
 
-	// Populate cache
-	data, err := json.Marshal(light)
-	if err == nil {
-		_ = c.backend.Set(ctx, key, data, c.ttl)
+	fetch := func(ctx context.Context) (*resources.Light, error) {
+		return c.client.Get(ctx, id)
 	}
+	return refreshThrough(ctx, &c.resourceCache, c.keyBuilder.Light(id), fetch)
+}
 
-	return light, nil
+// RefreshAll re-lists all lights from the SDK and replaces every cached
+// light, removing entries for lights that no longer exist.
+func (c *CachedLightClient) RefreshAll(ctx context.Context) ([]resources.Light, error) {
+	keyOf := func(light *resources.Light) string {
+		return c.keyBuilder.Light(light.ID)
+	}
+	return refreshAllThrough(ctx, &c.resourceCache, c.keyBuilder.AllLights(), keyOf, c.client.List)
 }
 
-// Update updates a light's state in both SDK and cache.
+// Update updates a light in the SDK and invalidates its cache entry.
 // This is write-through caching - update SDK first, then invalidate cache.
+//
+// In write-behind mode, the cached light is patched immediately so
+// subsequent reads reflect it, and the update is queued for the SDK. If it
+// can't be queued, the cached light is invalidated.
 func (c *CachedLightClient) Update(ctx context.Context, id string, update resources.LightUpdate) error {
-	if id == "" {
-		return fmt.Errorf("invalid light ID")
+	if err := checkID("light", id); err != nil {
+		return err
 	}
 
-	// Update SDK first
-	if err := c.client.Update(ctx, id, update); err != nil {
-		return err
+	if c.writer != nil {
+		// Patch before queueing, so a failed flush's invalidation can't
+		// land before the patch and be overwritten by it
+		key := c.keyBuilder.Light(id)
+		c.patch(ctx, key, update)
+		if err := c.writer.enqueue(ctx, id, update); err != nil {
+			// The update won't be sent; drop the optimistic entry
+			c.invalidate(ctx, key)
+			return err
+		}
+		return nil
 	}
 
-	// Invalidate cache entry (SSE event will repopulate it)
-	key := c.keyBuilder.Light(id)
-	_ = c.backend.Delete(ctx, key)
+	return c.writeThrough(ctx, "Update", c.keyBuilder.Light(id), func(ctx context.Context) error {
+		return c.client.Update(ctx, id, update)
+	})
+}
+
+// configure applies CachedClientConfig settings, including write-behind mode.
+func (c *CachedLightClient) configure(config *CachedClientConfig) {
+	c.resourceCache.configure(config)
+	if config == nil || config.WriteBehind == nil || c.writer != nil {
+		return
+	}
+
+	c.writer = newWriteBehind(config.WriteBehind, c.logger, func(ctx context.Context, id string, update resources.LightUpdate) error {
+		if err := c.throttle(ctx, "Update", c.keyBuilder.Light(id)); err != nil {
+			return err
+		}
+		if err := c.client.Update(ctx, id, update); err != nil {
+			// The optimistic cache entry is wrong; let the next read fetch the truth
+			c.invalidate(ctx, c.keyBuilder.Light(id))
+			return err
+		}
+		return nil
+	})
+}
+
+// Flush sends all queued write-behind updates to the SDK and waits for them
+// to complete. It is a no-op when write-behind mode is disabled.
+func (c *CachedLightClient) Flush(ctx context.Context) error {
+	if c.writer == nil {
+		return nil
+	}
+	return c.writer.flush(ctx)
+}
 
+// Close flushes any queued write-behind updates and stops the background
+// worker. Updates after Close return ErrClientClosed in write-behind mode.
+func (c *CachedLightClient) Close() error {
+	if c.writer != nil {
+		c.writer.close()
+	}
 	return nil
 }
 
 // CachedRoomClient wraps the SDK RoomClient with caching.
 // It implements the same interface as hue.RoomClient for drop-in replacement.
 type CachedRoomClient struct {
-	backend    Backend
-	client     hue.RoomClient
-	keyBuilder *KeyBuilder
-	ttl        time.Duration
+	resourceCache
+	client hue.RoomClient
 }
 
 // NewCachedRoomClient creates a new cached room client.
 func NewCachedRoomClient(backend Backend, client hue.RoomClient, ttl time.Duration) *CachedRoomClient {
 	return &CachedRoomClient{
-		backend:    backend,
-		client:     client,
-		keyBuilder: NewKeyBuilder(),
-		ttl:        ttl,
+		resourceCache: newResourceCache("Rooms", backend, ttl, client),
+		client:        client,
 	}
 }
 
 // List returns all rooms, using cache when possible.
+// On cache miss, fetches from SDK and populates cache.
 func (c *CachedRoomClient) List(ctx context.Context) ([]resources.Room, error) {
-	pattern := c.keyBuilder.AllRooms()
-	keys, err := c.backend.Keys(ctx, pattern)
-	if err == nil && len(keys) > 0 {
-		var rooms []resources.Room
-		allFound := true
-
-		for _, key := range keys {
-			entry, err := c.backend.Get(ctx, key)
-			if err != nil {
-				allFound = false
-				break
-			}
-
-			var room resources.Room
-			if err := json.Unmarshal(entry.Value, &room); err != nil {
-				allFound = false
-				break
-			}
-
-			rooms = append(rooms, room)
-		}
+	keyOf := func(room *resources.Room) string {
+		return c.keyBuilder.Room(room.ID)
+	}
+	return listThrough(ctx, &c.resourceCache, c.keyBuilder.AllRooms(), keyOf, c.client.List, c.client.Get)
+}
 
-		if allFound {
-			return rooms, nil
-		}
+// ListSorted returns all rooms like List, sorted by less. The sort is
+// stable, so rooms that compare equal keep their List order.
+func (c *CachedRoomClient) ListSorted(ctx context.Context, less func(a, b resources.Room) bool) ([]resources.Room, error) {
+	return listSorted(ctx, c.List, less)
+}
+
+// ListPage returns one page of rooms, ordered by ID, and the total number of
+// rooms. Pages served from the cache only unmarshal the rooms on the page.
+func (c *CachedRoomClient) ListPage(ctx context.Context, offset, limit int) ([]resources.Room, int, error) {
+	keyOf := func(room *resources.Room) string {
+		return c.keyBuilder.Room(room.ID)
 	}
+	return listPage(ctx, &c.resourceCache, c.keyBuilder.AllRooms(), offset, limit, keyOf, c.client.List, c.client.Get)
+}
 
-	// Cache miss - fetch from SDK
-	rooms, err := c.client.List(ctx)
-	if err != nil {
+// Get returns a single room by ID, using cache when possible.
+// On cache miss, fetches from SDK and populates cache.
+func (c *CachedRoomClient) Get(ctx context.Context, id string) (*resources.Room, error) {
+	if err := checkID("room", id); err != nil {
 		return nil, err
 	}
 
-	// Populate cache
-	for _, room := range rooms {
-		key := c.keyBuilder.Room(room.ID)
-		data, err := json.Marshal(room)
-		if err != nil {
-			continue
-		}
-		_ = c.backend.Set(ctx, key, data, c.ttl)
+	fetch := coalescedFetch(&c.resourceCache, id, func(r *resources.Room) string { return r.ID }, c.client.List,
+		func(ctx context.Context) (*resources.Room, error) {
+			return c.client.Get(ctx, id)
+		})
+	return getThrough(ctx, &c.resourceCache, c.keyBuilder.Room(id), fetch)
+}
+
+// GetWithMeta returns a single room by ID like Get, along with metadata
+// describing how fresh the cached copy is.
+func (c *CachedRoomClient) GetWithMeta(ctx context.Context, id string) (*resources.Room, *EntryMeta, error) {
+	if err := checkID("room", id); err != nil {
+		return nil, nil, err
 	}
 
-	return rooms, nil
+	fetch := coalescedFetch(&c.resourceCache, id, func(r *resources.Room) string { return r.ID }, c.client.List,
+		func(ctx context.Context) (*resources.Room, error) {
+			return c.client.Get(ctx, id)
+		})
+	return getWithMetaThrough(ctx, &c.resourceCache, c.keyBuilder.Room(id), fetch)
 }
 
-// Get returns a single room by ID, using cache when possible.
-func (c *CachedRoomClient) Get(ctx context.Context, id string) (*resources.Room, error) {
-	if id == "" {
-		return nil, fmt.Errorf("invalid room ID")
+// GetRaw returns the JSON for a room. With CacheRaw enabled it is
+// served verbatim from the raw cache entry; otherwise the room is
+// loaded like Get and serialized.
+func (c *CachedRoomClient) GetRaw(ctx context.Context, id string) ([]byte, error) {
+	if err := checkID("room", id); err != nil {
+		return nil, err
 	}
 
-	// Try cache first
-	key := c.keyBuilder.Room(id)
-	entry, err := c.backend.Get(ctx, key)
-	if err == nil {
-		var room resources.Room
-		if err := json.Unmarshal(entry.Value, &room); err == nil {
-			return &room, nil
-		}
+	get := func(ctx context.Context) (*resources.Room, error) {
+		return c.Get(ctx, id)
 	}
+	return rawThrough(ctx, &c.resourceCache, c.keyBuilder.Room(id), get)
+}
 
-	// Cache miss - fetch from SDK
-	room, err := c.client.Get(ctx, id)
-	if err != nil {
+// Refresh fetches a room from the SDK, bypassing the cache, and
+// overwrites its cache entry with the result.
+// Use this when the cached copy is known to be stale.
+func (c *CachedRoomClient) Refresh(ctx context.Context, id string) (*resources.Room, error) {
+	if err := checkID("room", id); err != nil {
 		return nil, err
 	}
 
-	// Populate cache
-	data, err := json.Marshal(room)
-	if err == nil {
-		_ = c.backend.Set(ctx, key, data, c.ttl)
+	fetch := func(ctx context.Context) (*resources.Room, error) {
+		return c.client.Get(ctx, id)
 	}
+	return refreshThrough(ctx, &c.resourceCache, c.keyBuilder.Room(id), fetch)
+}
 
-	return room, nil
+// RefreshAll re-lists all rooms from the SDK and replaces every cached
+// room, removing entries for rooms that no longer exist.
+func (c *CachedRoomClient) RefreshAll(ctx context.Context) ([]resources.Room, error) {
+	keyOf := func(room *resources.Room) string {
+		return c.keyBuilder.Room(room.ID)
+	}
+	return refreshAllThrough(ctx, &c.resourceCache, c.keyBuilder.AllRooms(), keyOf, c.client.List)
 }
 
-// Create creates a new room in the SDK and invalidates cache.
+// Create creates a new room in the SDK.
+// The new room is cached by the SSE add event, or right away with
+// CachedClientConfig.CacheOnCreate.
 func (c *CachedRoomClient) Create(ctx context.Context, room resources.RoomCreate) (string, error) {
-	// Create in SDK
-	id, err := c.client.Create(ctx, room)
-	if err != nil {
-		return "", err
+	create := func(ctx context.Context) (string, error) {
+		return c.client.Create(ctx, room)
 	}
-
-	// Don't cache yet - SSE event will populate it
-	return id, nil
+	return createThrough(ctx, &c.resourceCache, create, c.keyBuilder.Room, c.client.Get)
 }
 
-// Update updates a room in SDK and invalidates cache.
+// Update updates a room in the SDK and invalidates its cache entry.
+// This is write-through caching - update SDK first, then invalidate cache.
 func (c *CachedRoomClient) Update(ctx context.Context, id string, update resources.RoomUpdate) error {
-	if id == "" {
-		return fmt.Errorf("invalid room ID")
-	}
-
-	// Update SDK first
-	if err := c.client.Update(ctx, id, update); err != nil {
+	if err := checkID("room", id); err != nil {
 		return err
 	}
 
-	// Invalidate cache entry
-	key := c.keyBuilder.Room(id)
-	_ = c.backend.Delete(ctx, key)
-
-	return nil
+	return c.writeThrough(ctx, "Update", c.keyBuilder.Room(id), func(ctx context.Context) error {
+		return c.client.Update(ctx, id, update)
+	})
 }
 
-// Delete deletes a room from SDK and cache.
+// Delete deletes a room from the SDK and cache.
 func (c *CachedRoomClient) Delete(ctx context.Context, id string) error {
-	if id == "" {
-		return fmt.Errorf("invalid room ID")
-	}
-
-	// Delete from SDK first
-	if err := c.client.Delete(ctx, id); err != nil {
+	if err := checkID("room", id); err != nil {
 		return err
 	}
 
-	// Remove from cache
-	key := c.keyBuilder.Room(id)
-	_ = c.backend.Delete(ctx, key)
-
-	return nil
+	return c.writeThrough(ctx, "Delete", c.keyBuilder.Room(id), func(ctx context.Context) error {
+		return c.client.Delete(ctx, id)
+	})
 }
 
 // CachedZoneClient wraps the SDK ZoneClient with caching.
 type CachedZoneClient struct {
-	backend    Backend
-	client     hue.ZoneClient
-	keyBuilder *KeyBuilder
-	ttl        time.Duration
+	resourceCache
+	client hue.ZoneClient
 }
 
 // NewCachedZoneClient creates a new cached zone client.
 func NewCachedZoneClient(backend Backend, client hue.ZoneClient, ttl time.Duration) *CachedZoneClient {
 	return &CachedZoneClient{
-		backend:    backend,
-		client:     client,
-		keyBuilder: NewKeyBuilder(),
-		ttl:        ttl,
+		resourceCache: newResourceCache("Zones", backend, ttl, client),
+		client:        client,
 	}
 }
 
 // List returns all zones, using cache when possible.
+// On cache miss, fetches from SDK and populates cache.
 func (c *CachedZoneClient) List(ctx context.Context) ([]resources.Zone, error) {
-	pattern := c.keyBuilder.AllZones()
-	keys, err := c.backend.Keys(ctx, pattern)
-	if err == nil && len(keys) > 0 {
-		var zones []resources.Zone
-		allFound := true
-
-		for _, key := range keys {
-			entry, err := c.backend.Get(ctx, key)
-			if err != nil {
-				allFound = false
-				break
-			}
-
-			var zone resources.Zone
-			if err := json.Unmarshal(entry.Value, &zone); err != nil {
-				allFound = false
-				break
-			}
-
-			zones = append(zones, zone)
-		}
+	keyOf := func(zone *resources.Zone) string {
+		return c.keyBuilder.Zone(zone.ID)
+	}
+	return listThrough(ctx, &c.resourceCache, c.keyBuilder.AllZones(), keyOf, c.client.List, c.client.Get)
+}
 
-		if allFound {
-			return zones, nil
-		}
+// ListSorted returns all zones like List, sorted by less. The sort is
+// stable, so zones that compare equal keep their List order.
+func (c *CachedZoneClient) ListSorted(ctx context.Context, less func(a, b resources.Zone) bool) ([]resources.Zone, error) {
+	return listSorted(ctx, c.List, less)
+}
+
+// ListPage returns one page of zones, ordered by ID, and the total number of
+// zones. Pages served from the cache only unmarshal the zones on the page.
+func (c *CachedZoneClient) ListPage(ctx context.Context, offset, limit int) ([]resources.Zone, int, error) {
+	keyOf := func(zone *resources.Zone) string {
+		return c.keyBuilder.Zone(zone.ID)
 	}
+	return listPage(ctx, &c.resourceCache, c.keyBuilder.AllZones(), offset, limit, keyOf, c.client.List, c.client.Get)
+}
 
-	// Cache miss - fetch from SDK
-	zones, err := c.client.List(ctx)
-	if err != nil {
+// Get returns a single zone by ID, using cache when possible.
+// On cache miss, fetches from SDK and populates cache.
+func (c *CachedZoneClient) Get(ctx context.Context, id string) (*resources.Zone, error) {
+	if err := checkID("zone", id); err != nil {
 		return nil, err
 	}
 
-	// Populate cache
-	for _, zone := range zones {
-		key := c.keyBuilder.Zone(zone.ID)
-		data, err := json.Marshal(zone)
-		if err != nil {
-			continue
-		}
-		_ = c.backend.Set(ctx, key, data, c.ttl)
+	fetch := coalescedFetch(&c.resourceCache, id, func(r *resources.Zone) string { return r.ID }, c.client.List,
+		func(ctx context.Context) (*resources.Zone, error) {
+			return c.client.Get(ctx, id)
+		})
+	return getThrough(ctx, &c.resourceCache, c.keyBuilder.Zone(id), fetch)
+}
+
+// GetWithMeta returns a single zone by ID like Get, along with metadata
+// describing how fresh the cached copy is.
+func (c *CachedZoneClient) GetWithMeta(ctx context.Context, id string) (*resources.Zone, *EntryMeta, error) {
+	if err := checkID("zone", id); err != nil {
+		return nil, nil, err
 	}
 
-	return zones, nil
+	fetch := coalescedFetch(&c.resourceCache, id, func(r *resources.Zone) string { return r.ID }, c.client.List,
+		func(ctx context.Context) (*resources.Zone, error) {
+			return c.client.Get(ctx, id)
+		})
+	return getWithMetaThrough(ctx, &c.resourceCache, c.keyBuilder.Zone(id), fetch)
 }
 
-// Get returns a single zone by ID, using cache when possible.
-func (c *CachedZoneClient) Get(ctx context.Context, id string) (*resources.Zone, error) {
-	if id == "" {
-		return nil, fmt.Errorf("invalid zone ID")
+// GetRaw returns the JSON for a zone. With CacheRaw enabled it is
+// served verbatim from the raw cache entry; otherwise the zone is
+// loaded like Get and serialized.
+func (c *CachedZoneClient) GetRaw(ctx context.Context, id string) ([]byte, error) {
+	if err := checkID("zone", id); err != nil {
+		return nil, err
 	}
 
-	// Try cache first
-	key := c.keyBuilder.Zone(id)
-	entry, err := c.backend.Get(ctx, key)
-	if err == nil {
-		var zone resources.Zone
-		if err := json.Unmarshal(entry.Value, &zone); err == nil {
-			return &zone, nil
-		}
+	get := func(ctx context.Context) (*resources.Zone, error) {
+		return c.Get(ctx, id)
 	}
+	return rawThrough(ctx, &c.resourceCache, c.keyBuilder.Zone(id), get)
+}
 
-	// Cache miss - fetch from SDK
-	zone, err := c.client.Get(ctx, id)
-	if err != nil {
+// Refresh fetches a zone from the SDK, bypassing the cache, and
+// overwrites its cache entry with the result.
+// Use this when the cached copy is known to be stale.
+func (c *CachedZoneClient) Refresh(ctx context.Context, id string) (*resources.Zone, error) {
+	if err := checkID("zone", id); err != nil {
 		return nil, err
 	}
 
-	// Populate cache
-	data, err := json.Marshal(zone)
-	if err == nil {
-		_ = c.backend.Set(ctx, key, data, c.ttl)
+	fetch := func(ctx context.Context) (*resources.Zone, error) {
+		return c.client.Get(ctx, id)
 	}
+	return refreshThrough(ctx, &c.resourceCache, c.keyBuilder.Zone(id), fetch)
+}
 
-	return zone, nil
+// RefreshAll re-lists all zones from the SDK and replaces every cached
+// zone, removing entries for zones that no longer exist.
+func (c *CachedZoneClient) RefreshAll(ctx context.Context) ([]resources.Zone, error) {
+	keyOf := func(zone *resources.Zone) string {
+		return c.keyBuilder.Zone(zone.ID)
+	}
+	return refreshAllThrough(ctx, &c.resourceCache, c.keyBuilder.AllZones(), keyOf, c.client.List)
 }
 
-// Create creates a new zone in SDK and invalidates cache.
+// Create creates a new zone in the SDK.
+// The new zone is cached by the SSE add event, or right away with
+// CachedClientConfig.CacheOnCreate.
 func (c *CachedZoneClient) Create(ctx context.Context, zone resources.ZoneCreate) (string, error) {
-	id, err := c.client.Create(ctx, zone)
-	if err != nil {
-		return "", err
+	create := func(ctx context.Context) (string, error) {
+		return c.client.Create(ctx, zone)
 	}
-	return id, nil
+	return createThrough(ctx, &c.resourceCache, create, c.keyBuilder.Zone, c.client.Get)
 }
 
-// Update updates a zone in SDK and invalidates cache.
+// Update updates a zone in the SDK and invalidates its cache entry.
+// This is write-through caching - update SDK first, then invalidate cache.
 func (c *CachedZoneClient) Update(ctx context.Context, id string, update resources.ZoneUpdate) error {
-	if id == "" {
-		return fmt.Errorf("invalid zone ID")
-	}
-
-	if err := c.client.Update(ctx, id, update); err != nil {
+	if err := checkID("zone", id); err != nil {
 		return err
 	}
 
-	key := c.keyBuilder.Zone(id)
-	_ = c.backend.Delete(ctx, key)
-
-	return nil
+	return c.writeThrough(ctx, "Update", c.keyBuilder.Zone(id), func(ctx context.Context) error {
+		return c.client.Update(ctx, id, update)
+	})
 }
 
-// Delete deletes a zone from SDK and cache.
+// Delete deletes a zone from the SDK and cache.
 func (c *CachedZoneClient) Delete(ctx context.Context, id string) error {
-	if id == "" {
-		return fmt.Errorf("invalid zone ID")
-	}
-
-	if err := c.client.Delete(ctx, id); err != nil {
+	if err := checkID("zone", id); err != nil {
 		return err
 	}
 
-	key := c.keyBuilder.Zone(id)
-	_ = c.backend.Delete(ctx, key)
-
-	return nil
+	return c.writeThrough(ctx, "Delete", c.keyBuilder.Zone(id), func(ctx context.Context) error {
+		return c.client.Delete(ctx, id)
+	})
 }
 
 // CachedSceneClient wraps the SDK SceneClient with caching.
 type CachedSceneClient struct {
-	backend    Backend
-	client     hue.SceneClient
-	keyBuilder *KeyBuilder
-	ttl        time.Duration
+	resourceCache
+	client hue.SceneClient
 }
 
 // NewCachedSceneClient creates a new cached scene client.
 func NewCachedSceneClient(backend Backend, client hue.SceneClient, ttl time.Duration) *CachedSceneClient {
 	return &CachedSceneClient{
-		backend:    backend,
-		client:     client,
-		keyBuilder: NewKeyBuilder(),
-		ttl:        ttl,
+		resourceCache: newResourceCache("Scenes", backend, ttl, client),
+		client:        client,
 	}
 }
 
 // List returns all scenes, using cache when possible.
+// On cache miss, fetches from SDK and populates cache.
 func (c *CachedSceneClient) List(ctx context.Context) ([]resources.Scene, error) {
-	pattern := c.keyBuilder.AllScenes()
-	keys, err := c.backend.Keys(ctx, pattern)
-	if err == nil && len(keys) > 0 {
-		var scenes []resources.Scene
-		allFound := true
-
-		for _, key := range keys {
-			entry, err := c.backend.Get(ctx, key)
-			if err != nil {
-				allFound = false
-				break
-			}
-
-			var scene resources.Scene
-			if err := json.Unmarshal(entry.Value, &scene); err != nil {
-				allFound = false
-				break
-			}
-
-			scenes = append(scenes, scene)
-		}
+	keyOf := func(scene *resources.Scene) string {
+		return c.keyBuilder.Scene(scene.ID)
+	}
+	return listThrough(ctx, &c.resourceCache, c.keyBuilder.AllScenes(), keyOf, c.client.List, c.client.Get)
+}
 
-		if allFound {
-			return scenes, nil
-		}
+// ListSorted returns all scenes like List, sorted by less. The sort is
+// stable, so scenes that compare equal keep their List order.
+func (c *CachedSceneClient) ListSorted(ctx context.Context, less func(a, b resources.Scene) bool) ([]resources.Scene, error) {
+	return listSorted(ctx, c.List, less)
+}
+
+// ListPage returns one page of scenes, ordered by ID, and the total number of
+// scenes. Pages served from the cache only unmarshal the scenes on the page.
+func (c *CachedSceneClient) ListPage(ctx context.Context, offset, limit int) ([]resources.Scene, int, error) {
+	keyOf := func(scene *resources.Scene) string {
+		return c.keyBuilder.Scene(scene.ID)
 	}
+	return listPage(ctx, &c.resourceCache, c.keyBuilder.AllScenes(), offset, limit, keyOf, c.client.List, c.client.Get)
+}
 
-	// Cache miss - fetch from SDK
-	scenes, err := c.client.List(ctx)
-	if err != nil {
+// Get returns a single scene by ID, using cache when possible.
+// On cache miss, fetches from SDK and populates cache.
+func (c *CachedSceneClient) Get(ctx context.Context, id string) (*resources.Scene, error) {
+	if err := checkID("scene", id); err != nil {
 		return nil, err
 	}
 
-	// Populate cache
-	for _, scene := range scenes {
-		key := c.keyBuilder.Scene(scene.ID)
-		data, err := json.Marshal(scene)
-		if err != nil {
-			continue
-		}
-		_ = c.backend.Set(ctx, key, data, c.ttl)
+	fetch := coalescedFetch(&c.resourceCache, id, func(r *resources.Scene) string { return r.ID }, c.client.List,
+		func(ctx context.Context) (*resources.Scene, error) {
+			return c.client.Get(ctx, id)
+		})
+	return getThrough(ctx, &c.resourceCache, c.keyBuilder.Scene(id), fetch)
+}
+
+// GetWithMeta returns a single scene by ID like Get, along with metadata
+// describing how fresh the cached copy is.
+func (c *CachedSceneClient) GetWithMeta(ctx context.Context, id string) (*resources.Scene, *EntryMeta, error) {
+	if err := checkID("scene", id); err != nil {
+		return nil, nil, err
 	}
 
-	return scenes, nil
+	fetch := coalescedFetch(&c.resourceCache, id, func(r *resources.Scene) string { return r.ID }, c.client.List,
+		func(ctx context.Context) (*resources.Scene, error) {
+			return c.client.Get(ctx, id)
+		})
+	return getWithMetaThrough(ctx, &c.resourceCache, c.keyBuilder.Scene(id), fetch)
 }
 
-// Get returns a single scene by ID, using cache when possible.
-func (c *CachedSceneClient) Get(ctx context.Context, id string) (*resources.Scene, error) {
-	if id == "" {
-		return nil, fmt.Errorf("invalid scene ID")
+// GetRaw returns the JSON for a scene. With CacheRaw enabled it is
+// served verbatim from the raw cache entry; otherwise the scene is
+// loaded like Get and serialized.
+func (c *CachedSceneClient) GetRaw(ctx context.Context, id string) ([]byte, error) {
+	if err := checkID("scene", id); err != nil {
+		return nil, err
 	}
 
-	// Try cache first
-	key := c.keyBuilder.Scene(id)
-	entry, err := c.backend.Get(ctx, key)
-	if err == nil {
-		var scene resources.Scene
-		if err := json.Unmarshal(entry.Value, &scene); err == nil {
-			return &scene, nil
-		}
+	get := func(ctx context.Context) (*resources.Scene, error) {
+		return c.Get(ctx, id)
 	}
+	return rawThrough(ctx, &c.resourceCache, c.keyBuilder.Scene(id), get)
+}
 
-	// Cache miss - fetch from SDK
-	scene, err := c.client.Get(ctx, id)
-	if err != nil {
+// Refresh fetches a scene from the SDK, bypassing the cache, and
+// overwrites its cache entry with the result.
+// Use this when the cached copy is known to be stale.
+func (c *CachedSceneClient) Refresh(ctx context.Context, id string) (*resources.Scene, error) {
+	if err := checkID("scene", id); err != nil {
 		return nil, err
 	}
 
-	// Populate cache
-	data, err := json.Marshal(scene)
-	if err == nil {
-		_ = c.backend.Set(ctx, key, data, c.ttl)
+	fetch := func(ctx context.Context) (*resources.Scene, error) {
+		return c.client.Get(ctx, id)
 	}
+	return refreshThrough(ctx, &c.resourceCache, c.keyBuilder.Scene(id), fetch)
+}
 
-	return scene, nil
+// RefreshAll re-lists all scenes from the SDK and replaces every cached
+// scene, removing entries for scenes that no longer exist.
+func (c *CachedSceneClient) RefreshAll(ctx context.Context) ([]resources.Scene, error) {
+	keyOf := func(scene *resources.Scene) string {
+		return c.keyBuilder.Scene(scene.ID)
+	}
+	return refreshAllThrough(ctx, &c.resourceCache, c.keyBuilder.AllScenes(), keyOf, c.client.List)
 }
 
-// Create creates a new scene in SDK.
+// Create creates a new scene in the SDK.
+// The new scene is cached by the SSE add event, or right away with
+// CachedClientConfig.CacheOnCreate.
 func (c *CachedSceneClient) Create(ctx context.Context, scene resources.SceneCreate) (string, error) {
-	id, err := c.client.Create(ctx, scene)
-	if err != nil {
-		return "", err
+	create := func(ctx context.Context) (string, error) {
+		return c.client.Create(ctx, scene)
 	}
-	return id, nil
+	return createThrough(ctx, &c.resourceCache, create, c.keyBuilder.Scene, c.client.Get)
 }
 
-// Update updates a scene in SDK and invalidates cache.
+// Update updates a scene in the SDK and invalidates its cache entry.
+// This is write-through caching - update SDK first, then invalidate cache.
 func (c *CachedSceneClient) Update(ctx context.Context, id string, update resources.SceneUpdate) error {
-	if id == "" {
-		return fmt.Errorf("invalid scene ID")
-	}
-
-	if err := c.client.Update(ctx, id, update); err != nil {
+	if err := checkID("scene", id); err != nil {
 		return err
 	}
 
-	key := c.keyBuilder.Scene(id)
-	_ = c.backend.Delete(ctx, key)
-
-	return nil
+	return c.writeThrough(ctx, "Update", c.keyBuilder.Scene(id), func(ctx context.Context) error {
+		return c.client.Update(ctx, id, update)
+	})
 }
 
-// Delete deletes a scene from SDK and cache.
+// Delete deletes a scene from the SDK and cache.
 func (c *CachedSceneClient) Delete(ctx context.Context, id string) error {
-	if id == "" {
-		return fmt.Errorf("invalid scene ID")
-	}
-
-	if err := c.client.Delete(ctx, id); err != nil {
+	if err := checkID("scene", id); err != nil {
 		return err
 	}
 
-	key := c.keyBuilder.Scene(id)
-	_ = c.backend.Delete(ctx, key)
-
-	return nil
+	return c.writeThrough(ctx, "Delete", c.keyBuilder.Scene(id), func(ctx context.Context) error {
+		return c.client.Delete(ctx, id)
+	})
 }
 
 // CachedGroupedLightClient wraps the SDK GroupedLightClient with caching.
 type CachedGroupedLightClient struct {
-	backend    Backend
-	client     hue.GroupedLightClient
-	keyBuilder *KeyBuilder
-	ttl        time.Duration
+	resourceCache
+	client hue.GroupedLightClient
 }
 
 // NewCachedGroupedLightClient creates a new cached grouped light client.
 func NewCachedGroupedLightClient(backend Backend, client hue.GroupedLightClient, ttl time.Duration) *CachedGroupedLightClient {
 	return &CachedGroupedLightClient{
-		backend:    backend,
-		client:     client,
-		keyBuilder: NewKeyBuilder(),
-		ttl:        ttl,
+		resourceCache: newResourceCache("GroupedLights", backend, ttl, client),
+		client:        client,
 	}
 }
 
 // List returns all grouped lights, using cache when possible.
+// On cache miss, fetches from SDK and populates cache.
 func (c *CachedGroupedLightClient) List(ctx context.Context) ([]resources.GroupedLight, error) {
-	pattern := c.keyBuilder.AllGroupedLights()
-	keys, err := c.backend.Keys(ctx, pattern)
-	if err == nil && len(keys) > 0 {
-		var groupedLights []resources.GroupedLight
-		allFound := true
-
-		for _, key := range keys {
-			entry, err := c.backend.Get(ctx, key)
-			if err != nil {
-				allFound = false
-				break
-			}
-
-			var gl resources.GroupedLight
-			if err := json.Unmarshal(entry.Value, &gl); err != nil {
-				allFound = false
-				break
-			}
-
-			groupedLights = append(groupedLights, gl)
-		}
+	keyOf := func(gl *resources.GroupedLight) string {
+		return c.keyBuilder.GroupedLight(gl.ID)
+	}
+	return listThrough(ctx, &c.resourceCache, c.keyBuilder.AllGroupedLights(), keyOf, c.client.List, c.client.Get)
+}
 
-		if allFound {
-			return groupedLights, nil
-		}
+// ListSorted returns all grouped lights like List, sorted by less. The sort is
+// stable, so grouped lights that compare equal keep their List order.
+func (c *CachedGroupedLightClient) ListSorted(ctx context.Context, less func(a, b resources.GroupedLight) bool) ([]resources.GroupedLight, error) {
+	return listSorted(ctx, c.List, less)
+}
+
+// ListPage returns one page of grouped lights, ordered by ID, and the total number of
+// grouped lights. Pages served from the cache only unmarshal the grouped lights on the page.
+func (c *CachedGroupedLightClient) ListPage(ctx context.Context, offset, limit int) ([]resources.GroupedLight, int, error) {
+	keyOf := func(gl *resources.GroupedLight) string {
+		return c.keyBuilder.GroupedLight(gl.ID)
 	}
+	return listPage(ctx, &c.resourceCache, c.keyBuilder.AllGroupedLights(), offset, limit, keyOf, c.client.List, c.client.Get)
+}
 
-	// Cache miss - fetch from SDK
-	groupedLights, err := c.client.List(ctx)
-	if err != nil {
+// Get returns a single grouped light by ID, using cache when possible.
+// On cache miss, fetches from SDK and populates cache.
+func (c *CachedGroupedLightClient) Get(ctx context.Context, id string) (*resources.GroupedLight, error) {
+	if err := checkID("grouped light", id); err != nil {
 		return nil, err
 	}
 
-	// Populate cache
-	for _, gl := range groupedLights {
-		key := c.keyBuilder.GroupedLight(gl.ID)
-		data, err := json.Marshal(gl)
-		if err != nil {
-			continue
-		}
-		_ = c.backend.Set(ctx, key, data, c.ttl)
+	fetch := coalescedFetch(&c.resourceCache, id, func(r *resources.GroupedLight) string { return r.ID }, c.client.List,
+		func(ctx context.Context) (*resources.GroupedLight, error) {
+			return c.client.Get(ctx, id)
+		})
+	return getThrough(ctx, &c.resourceCache, c.keyBuilder.GroupedLight(id), fetch)
+}
+
+// GetWithMeta returns a single grouped light by ID like Get, along with metadata
+// describing how fresh the cached copy is.
+func (c *CachedGroupedLightClient) GetWithMeta(ctx context.Context, id string) (*resources.GroupedLight, *EntryMeta, error) {
+	if err := checkID("grouped light", id); err != nil {
+		return nil, nil, err
 	}
 
-	return groupedLights, nil
+	fetch := coalescedFetch(&c.resourceCache, id, func(r *resources.GroupedLight) string { return r.ID }, c.client.List,
+		func(ctx context.Context) (*resources.GroupedLight, error) {
+			return c.client.Get(ctx, id)
+		})
+	return getWithMetaThrough(ctx, &c.resourceCache, c.keyBuilder.GroupedLight(id), fetch)
 }
 
-// Get returns a single grouped light by ID, using cache when possible.
-func (c *CachedGroupedLightClient) Get(ctx context.Context, id string) (*resources.GroupedLight, error) {
-	if id == "" {
-		return nil, fmt.Errorf("invalid grouped light ID")
+// GetRaw returns the JSON for a grouped light. With CacheRaw enabled it is
+// served verbatim from the raw cache entry; otherwise the grouped light is
+// loaded like Get and serialized.
+func (c *CachedGroupedLightClient) GetRaw(ctx context.Context, id string) ([]byte, error) {
+	if err := checkID("grouped light", id); err != nil {
+		return nil, err
 	}
 
-	// Try cache first
-	key := c.keyBuilder.GroupedLight(id)
-	entry, err := c.backend.Get(ctx, key)
-	if err == nil {
-		var gl resources.GroupedLight
-		if err := json.Unmarshal(entry.Value, &gl); err == nil {
-			return &gl, nil
-		}
+	get := func(ctx context.Context) (*resources.GroupedLight, error) {
+		return c.Get(ctx, id)
 	}
+	return rawThrough(ctx, &c.resourceCache, c.keyBuilder.GroupedLight(id), get)
+}
 
-	// Cache miss - fetch from SDK
-	gl, err := c.client.Get(ctx, id)
-	if err != nil {
+// Refresh fetches a grouped light from the SDK, bypassing the cache, and
+// overwrites its cache entry with the result.
+// Use this when the cached copy is known to be stale.
+func (c *CachedGroupedLightClient) Refresh(ctx context.Context, id string) (*resources.GroupedLight, error) {
+	if err := checkID("grouped light", id); err != nil {
 		return nil, err
 	}
 
-	// Populate cache
-	data, err := json.Marshal(gl)
-	if err == nil {
-		_ = c.backend.Set(ctx, key, data, c.ttl)
+	fetch := func(ctx context.Context) (*resources.GroupedLight, error) {
+		return c.client.Get(ctx, id)
 	}
+	return refreshThrough(ctx, &c.resourceCache, c.keyBuilder.GroupedLight(id), fetch)
+}
 
-	return gl, nil
+// RefreshAll re-lists all grouped lights from the SDK and replaces every cached
+// grouped light, removing entries for grouped lights that no longer exist.
+func (c *CachedGroupedLightClient) RefreshAll(ctx context.Context) ([]resources.GroupedLight, error) {
+	keyOf := func(gl *resources.GroupedLight) string {
+		return c.keyBuilder.GroupedLight(gl.ID)
+	}
+	return refreshAllThrough(ctx, &c.resourceCache, c.keyBuilder.AllGroupedLights(), keyOf, c.client.List)
 }
 
-// Update updates a grouped light in SDK and invalidates cache.
+// Update updates a grouped light in the SDK and invalidates its cache entry.
+// This is write-through caching - update SDK first, then invalidate cache.
 func (c *CachedGroupedLightClient) Update(ctx context.Context, id string, update resources.GroupedLightUpdate) error {
-	if id == "" {
-		return fmt.Errorf("invalid grouped light ID")
+	if err := checkID("grouped light", id); err != nil {
+		return err
 	}
 
-	if err := c.client.Update(ctx, id, update); err != nil {
-		return err
+	return c.writeThrough(ctx, "Update", c.keyBuilder.GroupedLight(id), func(ctx context.Context) error {
+		return c.client.Update(ctx, id, update)
+	})
+}
+
+// CachedBridgeClient wraps the SDK BridgeClient with caching. Bridge
+// metadata (name, software version, time zone) rarely changes, so caching
+// it lets apps check it repeatedly without a bridge round-trip.
+type CachedBridgeClient struct {
+	resourceCache
+	client hue.BridgeClient
+}
+
+// NewCachedBridgeClient creates a new cached bridge client.
+func NewCachedBridgeClient(backend Backend, client hue.BridgeClient, ttl time.Duration) *CachedBridgeClient {
+	return &CachedBridgeClient{
+		resourceCache: newResourceCache("Bridge", backend, ttl, client),
+		client:        client,
+	}
+}
+
+// List returns all bridges, using cache when possible.
+// On cache miss, fetches from SDK and populates cache.
+func (c *CachedBridgeClient) List(ctx context.Context) ([]resources.Bridge, error) {
+	keyOf := func(bridge *resources.Bridge) string {
+		return c.keyBuilder.Bridge(bridge.ID)
+	}
+	return listThrough(ctx, &c.resourceCache, c.keyBuilder.AllBridges(), keyOf, c.client.List, c.client.Get)
+}
+
+// Get returns a single bridge by ID, using cache when possible.
+// On cache miss, fetches from SDK and populates cache.
+func (c *CachedBridgeClient) Get(ctx context.Context, id string) (*resources.Bridge, error) {
+	if err := checkID("bridge", id); err != nil {
+		return nil, err
 	}
 
-	key := c.keyBuilder.GroupedLight(id)
-	_ = c.backend.Delete(ctx, key)
+	fetch := func(ctx context.Context) (*resources.Bridge, error) {
+		return c.client.Get(ctx, id)
+	}
+	return getThrough(ctx, &c.resourceCache, c.keyBuilder.Bridge(id), fetch)
+}
 
-	return nil
+// Refresh fetches a bridge from the SDK, bypassing the cache, and
+// overwrites its cache entry with the result.
+func (c *CachedBridgeClient) Refresh(ctx context.Context, id string) (*resources.Bridge, error) {
+	if err := checkID("bridge", id); err != nil {
+		return nil, err
+	}
+
+	fetch := func(ctx context.Context) (*resources.Bridge, error) {
+		return c.client.Get(ctx, id)
+	}
+	return refreshThrough(ctx, &c.resourceCache, c.keyBuilder.Bridge(id), fetch)
+}
+
+// CachedBridgeHomeClient wraps the SDK BridgeHomeClient with caching.
+type CachedBridgeHomeClient struct {
+	resourceCache
+	client hue.BridgeHomeClient
+}
+
+// NewCachedBridgeHomeClient creates a new cached bridge home client.
+func NewCachedBridgeHomeClient(backend Backend, client hue.BridgeHomeClient, ttl time.Duration) *CachedBridgeHomeClient {
+	return &CachedBridgeHomeClient{
+		resourceCache: newResourceCache("BridgeHome", backend, ttl, client),
+		client:        client,
+	}
+}
+
+// List returns all bridge homes, using cache when possible.
+// On cache miss, fetches from SDK and populates cache.
+func (c *CachedBridgeHomeClient) List(ctx context.Context) ([]resources.BridgeHome, error) {
+	keyOf := func(home *resources.BridgeHome) string {
+		return c.keyBuilder.BridgeHome(home.ID)
+	}
+	return listThrough(ctx, &c.resourceCache, c.keyBuilder.AllBridgeHomes(), keyOf, c.client.List, c.client.Get)
+}
+
+// Get returns a single bridge home by ID, using cache when possible.
+// On cache miss, fetches from SDK and populates cache.
+func (c *CachedBridgeHomeClient) Get(ctx context.Context, id string) (*resources.BridgeHome, error) {
+	if err := checkID("bridge home", id); err != nil {
+		return nil, err
+	}
+
+	fetch := func(ctx context.Context) (*resources.BridgeHome, error) {
+		return c.client.Get(ctx, id)
+	}
+	return getThrough(ctx, &c.resourceCache, c.keyBuilder.BridgeHome(id), fetch)
+}
+
+// Refresh fetches a bridge home from the SDK, bypassing the cache, and
+// overwrites its cache entry with the result.
+func (c *CachedBridgeHomeClient) Refresh(ctx context.Context, id string) (*resources.BridgeHome, error) {
+	if err := checkID("bridge home", id); err != nil {
+		return nil, err
+	}
+
+	fetch := func(ctx context.Context) (*resources.BridgeHome, error) {
+		return c.client.Get(ctx, id)
+	}
+	return refreshThrough(ctx, &c.resourceCache, c.keyBuilder.BridgeHome(id), fetch)
 }
@@ -3,10 +3,16 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	hue "github.com/rmrfslashbin/hue-sdk"
 	"github.com/rmrfslashbin/hue-sdk/resources"
+	"golang.org/x/time/rate"
 )
 
 // mockLightClient implements hue.LightClient for testing
@@ -173,6 +179,201 @@ func TestCachedLightClient_List_CacheHit(t *testing.T) {
 	}
 }
 
+// racingDeleteBackend deletes a key right after Keys returns it,
+// simulating an SSE delete racing with List.
+type racingDeleteBackend struct {
+	*mockBackend
+	deleteKey string
+}
+
+func (b *racingDeleteBackend) Keys(ctx context.Context, pattern string) ([]string, error) {
+	keys, err := b.mockBackend.Keys(ctx, pattern)
+	b.mockBackend.Delete(ctx, b.deleteKey)
+	return keys, err
+}
+
+func TestCachedLightClient_List_KeyDeletedBeforeGet(t *testing.T) {
+	backend := &racingDeleteBackend{mockBackend: newMockBackend(), deleteKey: "light:light-2"}
+	mockSDK := newMockLightClient()
+	ctx := context.Background()
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	for _, id := range []string{"light-1", "light-2"} {
+		client.store(ctx, "light:"+id, &resources.Light{ID: id, Type: "light"})
+	}
+
+	lights, err := client.List(ctx)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+
+	if len(lights) != 1 || lights[0].ID != "light-1" {
+		t.Errorf("List() = %+v, want only light-1", lights)
+	}
+	if mockSDK.calls["List"] != 0 {
+		t.Errorf("Expected no SDK List for a concurrently deleted key, got %d", mockSDK.calls["List"])
+	}
+}
+
+func TestCachedLightClient_List_RepairThreshold(t *testing.T) {
+	ctx := context.Background()
+	setup := func(threshold int) (*CachedLightClient, *mockLightClient) {
+		backend := newMockBackend()
+		mockSDK := newMockLightClient()
+		for _, id := range []string{"light-1", "light-2", "light-3", "light-4"} {
+			mockSDK.lights[id] = &resources.Light{ID: id, Type: "light"}
+		}
+
+		client := NewCachedLightClient(backend, mockSDK, 0)
+		client.configure(&CachedClientConfig{ListRepairThreshold: threshold})
+		for _, id := range []string{"light-1", "light-2", "light-3"} {
+			client.store(ctx, "light:"+id, mockSDK.lights[id])
+		}
+		backend.Set(ctx, "light:light-4", []byte("{corrupt"), 0)
+		return client, mockSDK
+	}
+
+	t.Run("within threshold", func(t *testing.T) {
+		client, mockSDK := setup(1)
+
+		lights, err := client.List(ctx)
+		if err != nil {
+			t.Fatalf("List() failed: %v", err)
+		}
+		if len(lights) != 4 {
+			t.Errorf("got %d lights, want 4", len(lights))
+		}
+		if mockSDK.calls["List"] != 0 || mockSDK.calls["Get"] != 1 {
+			t.Errorf("SDK calls = %v, want one Get", mockSDK.calls)
+		}
+
+		// The repaired entry is cached again
+		if _, err := client.List(ctx); err != nil {
+			t.Fatalf("List() failed: %v", err)
+		}
+		if mockSDK.calls["List"] != 0 || mockSDK.calls["Get"] != 1 {
+			t.Errorf("SDK calls = %v, want no new calls", mockSDK.calls)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		client, mockSDK := setup(0)
+
+		if _, err := client.List(ctx); err != nil {
+			t.Fatalf("List() failed: %v", err)
+		}
+		if mockSDK.calls["List"] != 1 || mockSDK.calls["Get"] != 0 {
+			t.Errorf("SDK calls = %v, want one List", mockSDK.calls)
+		}
+	})
+
+	t.Run("deleted on bridge", func(t *testing.T) {
+		client, mockSDK := setup(1)
+		delete(mockSDK.lights, "light-4")
+
+		lights, err := client.List(ctx)
+		if err != nil {
+			t.Fatalf("List() failed: %v", err)
+		}
+		if len(lights) != 3 {
+			t.Errorf("got %d lights, want 3", len(lights))
+		}
+		if mockSDK.calls["List"] != 0 {
+			t.Errorf("SDK List called %d times, want 0", mockSDK.calls["List"])
+		}
+		if _, err := client.backend.Get(ctx, "light:light-4"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(light-4) error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestCachedLightClient_ListSorted(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	ctx := context.Background()
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	for id, name := range map[string]string{"light-1": "Kitchen", "light-2": "Bedroom", "light-3": "Office"} {
+		client.store(ctx, "light:"+id, &resources.Light{ID: id, Type: "light", Metadata: resources.Metadata{Name: name}})
+	}
+
+	byName := func(a, b resources.Light) bool {
+		return a.Metadata.Name < b.Metadata.Name
+	}
+	lights, err := client.ListSorted(ctx, byName)
+	if err != nil {
+		t.Fatalf("ListSorted() failed: %v", err)
+	}
+
+	var names []string
+	for _, light := range lights {
+		names = append(names, light.Metadata.Name)
+	}
+	if got := strings.Join(names, ","); got != "Bedroom,Kitchen,Office" {
+		t.Errorf("ListSorted() names = %s, want Bedroom,Kitchen,Office", got)
+	}
+
+	// Served from cache
+	if mockSDK.calls["List"] != 0 {
+		t.Errorf("Expected no SDK List calls, got %d", mockSDK.calls["List"])
+	}
+}
+
+func TestCachedLightClient_ListPage(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	ctx := context.Background()
+
+	for _, id := range []string{"light-3", "light-1", "light-5", "light-2", "light-4"} {
+		mockSDK.lights[id] = &resources.Light{ID: id, Type: "light"}
+	}
+	client := NewCachedLightClient(backend, mockSDK, 0)
+
+	ids := func(lights []resources.Light) string {
+		var ids []string
+		for _, light := range lights {
+			ids = append(ids, light.ID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	// First page comes from the SDK and populates the cache
+	page, total, err := client.ListPage(ctx, 0, 2)
+	if err != nil {
+		t.Fatalf("ListPage() failed: %v", err)
+	}
+	if got := ids(page); got != "light-1,light-2" || total != 5 {
+		t.Errorf("ListPage(0, 2) = %s (total %d), want light-1,light-2 (total 5)", got, total)
+	}
+
+	// Later pages are served from the cache in the same order
+	page, total, err = client.ListPage(ctx, 2, 2)
+	if err != nil {
+		t.Fatalf("ListPage() failed: %v", err)
+	}
+	if got := ids(page); got != "light-3,light-4" || total != 5 {
+		t.Errorf("ListPage(2, 2) = %s (total %d), want light-3,light-4 (total 5)", got, total)
+	}
+	page, _, _ = client.ListPage(ctx, 4, 2)
+	if got := ids(page); got != "light-5" {
+		t.Errorf("ListPage(4, 2) = %s, want light-5", got)
+	}
+	page, total, _ = client.ListPage(ctx, 10, 2)
+	if len(page) != 0 || total != 5 {
+		t.Errorf("ListPage(10, 2) = %d lights (total %d), want 0 (total 5)", len(page), total)
+	}
+	if mockSDK.calls["List"] != 1 {
+		t.Errorf("Expected 1 SDK List call, got %d", mockSDK.calls["List"])
+	}
+
+	// Invalid bounds
+	for _, bounds := range [][2]int{{-1, 2}, {0, 0}} {
+		if _, _, err := client.ListPage(ctx, bounds[0], bounds[1]); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("ListPage(%d, %d) error = %v, want ErrInvalidValue", bounds[0], bounds[1], err)
+		}
+	}
+}
+
 func TestCachedLightClient_Update_InvalidatesCache(t *testing.T) {
 	backend := newMockBackend()
 	mockSDK := newMockLightClient()
@@ -238,6 +439,28 @@ func TestCachedLightClient_EmptyID(t *testing.T) {
 	}
 }
 
+func TestCachedLightClient_ReservedID(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	client := NewCachedLightClient(backend, mockSDK, 5*time.Minute)
+	ctx := context.Background()
+
+	for _, id := range []string{"", "light:1", "light-*", "light-?", `light\1`} {
+		if _, err := client.Get(ctx, id); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Get(%q) error = %v, want ErrInvalidKey", id, err)
+		}
+		if err := client.Update(ctx, id, resources.LightUpdate{}); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Update(%q) error = %v, want ErrInvalidKey", id, err)
+		}
+		if _, err := client.Refresh(ctx, id); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Refresh(%q) error = %v, want ErrInvalidKey", id, err)
+		}
+	}
+	if len(mockSDK.calls) != 0 {
+		t.Errorf("SDK calls = %v, want none", mockSDK.calls)
+	}
+}
+
 // mockRoomClient implements hue.RoomClient for testing
 type mockRoomClient struct {
 	rooms map[string]*resources.Room
@@ -315,6 +538,34 @@ func TestCachedRoomClient_Create(t *testing.T) {
 	}
 }
 
+func TestCachedRoomClient_CreateCacheOnCreate(t *testing.T) {
+	for _, cacheOnCreate := range []bool{false, true} {
+		backend := newMockBackend()
+		mockSDK := newMockRoomClient()
+
+		client := NewCachedRoomClient(backend, mockSDK, 0)
+		client.configure(&CachedClientConfig{CacheOnCreate: cacheOnCreate})
+
+		id, err := client.Create(context.Background(), resources.RoomCreate{})
+		if err != nil {
+			t.Fatalf("Create() failed: %v", err)
+		}
+
+		_, err = backend.Get(context.Background(), "room:"+id)
+		if cached := err == nil; cached != cacheOnCreate {
+			t.Errorf("CacheOnCreate %v: cached = %v", cacheOnCreate, cached)
+		}
+
+		// The Get after Create is served from the cache when enabled
+		if _, err := client.Get(context.Background(), id); err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		if mockSDK.calls["Get"] != 1 {
+			t.Errorf("CacheOnCreate %v: SDK Get called %d times, want 1", cacheOnCreate, mockSDK.calls["Get"])
+		}
+	}
+}
+
 func TestCachedRoomClient_Delete(t *testing.T) {
 	backend := newMockBackend()
 	mockSDK := newMockRoomClient()
@@ -349,6 +600,47 @@ func TestCachedRoomClient_Delete(t *testing.T) {
 	}
 }
 
+func TestCachedLightClient_SDKRateLimit(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+	mockSDK.lights["light-2"] = &resources.Light{ID: "light-2", Type: "light"}
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	client.configure(&CachedClientConfig{SDKRateLimit: rate.Every(time.Hour), SDKRateBurst: 1})
+
+	// The burst allows one SDK call
+	if _, err := client.Get(context.Background(), "light-1"); err != nil {
+		t.Fatalf("Get(light-1) failed: %v", err)
+	}
+
+	// Cache hits don't need a token
+	if _, err := client.Get(context.Background(), "light-1"); err != nil {
+		t.Fatalf("cached Get(light-1) failed: %v", err)
+	}
+
+	// The next token is an hour away, past the deadline: fail at once
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.Get(ctx, "light-2"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Get(light-2) error = %v, want ErrRateLimited", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Get(light-2) waited %v, want immediate failure", elapsed)
+	}
+
+	// Writes share the bucket
+	update := resources.LightUpdate{On: &resources.OnState{On: true}}
+	if err := client.Update(ctx, "light-1", update); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Update() error = %v, want ErrRateLimited", err)
+	}
+
+	if mockSDK.calls["Get"] != 1 || mockSDK.calls["Update"] != 0 {
+		t.Errorf("SDK calls = %v, want one Get", mockSDK.calls)
+	}
+}
+
 func TestCachedClient_LazyInitialization(t *testing.T) {
 	backend := newMockBackend()
 
@@ -378,3 +670,470 @@ func TestKeyBuilder_AllGroupedLights(t *testing.T) {
 		t.Errorf("AllGroupedLights() = %q, want \"grouped_light:*\"", pattern)
 	}
 }
+
+func TestCachedLightClient_Refresh(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{
+		ID:   "light-1",
+		Type: "light",
+		On:   resources.OnState{On: false},
+	}
+
+	client := NewCachedLightClient(backend, mockSDK, 5*time.Minute)
+	ctx := context.Background()
+
+	// Populate cache
+	if _, err := client.Get(ctx, "light-1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	// Bridge state changes without an SSE event
+	mockSDK.lights["light-1"].On = resources.OnState{On: true}
+
+	light, err := client.Refresh(ctx, "light-1")
+	if err != nil {
+		t.Fatalf("Refresh() failed: %v", err)
+	}
+	if !light.On.On {
+		t.Error("Refresh() should return fresh SDK data")
+	}
+	if mockSDK.calls["Get"] != 2 {
+		t.Errorf("Expected Refresh to call SDK, got %d Get calls", mockSDK.calls["Get"])
+	}
+
+	// Cache now holds the fresh value
+	cached, err := client.Get(ctx, "light-1")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if !cached.On.On {
+		t.Error("Expected cache to be overwritten by Refresh")
+	}
+	if mockSDK.calls["Get"] != 2 {
+		t.Errorf("Expected Get after Refresh to hit cache, got %d Get calls", mockSDK.calls["Get"])
+	}
+
+	if _, err := client.Refresh(ctx, ""); err == nil {
+		t.Error("Expected error for empty ID")
+	}
+}
+
+func TestCachedLightClient_Refresh_RemovesMissing(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	ctx := context.Background()
+
+	if _, err := client.Get(ctx, "light-1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	delete(mockSDK.lights, "light-1")
+
+	if _, err := client.Refresh(ctx, "light-1"); err == nil {
+		t.Fatal("Expected Refresh() of deleted light to fail")
+	}
+	if _, err := backend.Get(ctx, "light:light-1"); err == nil {
+		t.Error("Expected stale entry to be removed")
+	}
+}
+
+func TestCachedRoomClient_RefreshAll(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockRoomClient()
+	mockSDK.rooms["room-1"] = &resources.Room{ID: "room-1", Type: "room"}
+	mockSDK.rooms["room-2"] = &resources.Room{ID: "room-2", Type: "room"}
+
+	client := NewCachedRoomClient(backend, mockSDK, 0)
+	ctx := context.Background()
+
+	if _, err := client.List(ctx); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+
+	// room-2 is removed and room-3 added on the bridge
+	delete(mockSDK.rooms, "room-2")
+	mockSDK.rooms["room-3"] = &resources.Room{ID: "room-3", Type: "room"}
+
+	rooms, err := client.RefreshAll(ctx)
+	if err != nil {
+		t.Fatalf("RefreshAll() failed: %v", err)
+	}
+	if len(rooms) != 2 {
+		t.Errorf("RefreshAll() returned %d rooms, want 2", len(rooms))
+	}
+
+	keys, _ := backend.Keys(ctx, "room:*")
+	got := make(map[string]bool)
+	for _, key := range keys {
+		got[key] = true
+	}
+	if len(got) != 2 || !got["room:room-1"] || !got["room:room-3"] {
+		t.Errorf("Cached room keys = %v, want room:room-1 and room:room-3", keys)
+	}
+}
+
+func TestCachedLightClient_GetWithMeta(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	client := NewCachedLightClient(backend, mockSDK, time.Hour)
+	ctx := context.Background()
+
+	// Miss: fetched from the SDK and cached
+	light, meta, err := client.GetWithMeta(ctx, "light-1")
+	if err != nil {
+		t.Fatalf("GetWithMeta() failed: %v", err)
+	}
+	if light.ID != "light-1" {
+		t.Errorf("GetWithMeta() light = %q, want light-1", light.ID)
+	}
+	if meta.Cached {
+		t.Error("Expected Cached = false on a miss")
+	}
+	if meta.TimeUntilExpiry <= 59*time.Minute || meta.ExpiresAt.IsZero() {
+		t.Errorf("Expected fresh entry to expire in ~1h, got %v", meta.TimeUntilExpiry)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	// Hit: metadata comes from the cached entry
+	_, meta, err = client.GetWithMeta(ctx, "light-1")
+	if err != nil {
+		t.Fatalf("GetWithMeta() failed: %v", err)
+	}
+	if !meta.Cached {
+		t.Error("Expected Cached = true on a hit")
+	}
+	if meta.Age < 10*time.Millisecond {
+		t.Errorf("Age = %v, want at least 10ms", meta.Age)
+	}
+	if meta.CreatedAt.IsZero() || meta.UpdatedAt.IsZero() {
+		t.Error("Expected CreatedAt and UpdatedAt to be set")
+	}
+	if mockSDK.calls["Get"] != 1 {
+		t.Errorf("Expected 1 SDK Get, got %d", mockSDK.calls["Get"])
+	}
+
+	if _, _, err := client.GetWithMeta(ctx, ""); err == nil {
+		t.Error("Expected error for empty ID")
+	}
+}
+
+// mockBridgeClient implements hue.BridgeClient for testing
+type mockBridgeClient struct {
+	bridges map[string]*resources.Bridge
+	calls   map[string]int
+}
+
+func newMockBridgeClient() *mockBridgeClient {
+	return &mockBridgeClient{
+		bridges: make(map[string]*resources.Bridge),
+		calls:   make(map[string]int),
+	}
+}
+
+func (m *mockBridgeClient) List(ctx context.Context) ([]resources.Bridge, error) {
+	m.calls["List"]++
+	var bridges []resources.Bridge
+	for _, bridge := range m.bridges {
+		bridges = append(bridges, *bridge)
+	}
+	return bridges, nil
+}
+
+func (m *mockBridgeClient) Get(ctx context.Context, id string) (*resources.Bridge, error) {
+	m.calls["Get"]++
+	bridge, ok := m.bridges[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return bridge, nil
+}
+
+func TestCachedBridgeClient_Get(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockBridgeClient()
+	mockSDK.bridges["bridge-1"] = &resources.Bridge{ID: "bridge-1", Type: "bridge", BridgeID: "001788fffe000001"}
+
+	client := NewCachedBridgeClient(backend, mockSDK, 0)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		bridge, err := client.Get(ctx, "bridge-1")
+		if err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		if bridge.BridgeID != "001788fffe000001" {
+			t.Errorf("BridgeID = %q, want 001788fffe000001", bridge.BridgeID)
+		}
+	}
+	if mockSDK.calls["Get"] != 1 {
+		t.Errorf("Expected 1 SDK Get call, got %d", mockSDK.calls["Get"])
+	}
+
+	if _, err := backend.Get(ctx, NewKeyBuilder().Bridge("bridge-1")); err != nil {
+		t.Errorf("bridge not cached under its bridge key: %v", err)
+	}
+
+	bridges, err := client.List(ctx)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(bridges) != 1 || bridges[0].ID != "bridge-1" {
+		t.Errorf("List() = %v, want bridge-1", bridges)
+	}
+}
+
+func TestCachedBridgeClient_SyncedEvent(t *testing.T) {
+	backend := newMockBackend()
+	engine := &SyncEngine{
+		backend:    backend,
+		keyBuilder: NewKeyBuilder(),
+		stats:      &SyncStats{},
+		config:     DefaultSyncConfig(),
+	}
+
+	// The bridge's metadata arrives over SSE...
+	raw := json.RawMessage(`{"id": "bridge-1", "type": "bridge", "bridge_id": "001788fffe000001"}`)
+	data := &resources.EventData{ID: "bridge-1", Type: "bridge", RawData: raw}
+	if err := engine.processEventData(context.Background(), resources.EventTypeAdd, data); err != nil {
+		t.Fatalf("processEventData() failed: %v", err)
+	}
+
+	// ...and capability checks read it without calling the bridge
+	mockSDK := newMockBridgeClient()
+	client := NewCachedBridgeClient(backend, mockSDK, 0)
+	bridge, err := client.Get(context.Background(), "bridge-1")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if bridge.BridgeID != "001788fffe000001" {
+		t.Errorf("BridgeID = %q, want 001788fffe000001", bridge.BridgeID)
+	}
+	if mockSDK.calls["Get"] != 0 {
+		t.Errorf("Expected a cache hit, got %d SDK Get calls", mockSDK.calls["Get"])
+	}
+}
+
+// The cached clients are drop-in replacements for the SDK clients.
+var (
+	_ hue.LightClient        = (*CachedLightClient)(nil)
+	_ hue.RoomClient         = (*CachedRoomClient)(nil)
+	_ hue.ZoneClient         = (*CachedZoneClient)(nil)
+	_ hue.SceneClient        = (*CachedSceneClient)(nil)
+	_ hue.GroupedLightClient = (*CachedGroupedLightClient)(nil)
+	_ hue.BridgeClient       = (*CachedBridgeClient)(nil)
+	_ hue.BridgeHomeClient   = (*CachedBridgeHomeClient)(nil)
+)
+
+// TestCachedClients_SDKCoverage lists, per SDK client, the methods that
+// aren't served from the cache. They are forwarded to the SDK; writes also
+// invalidate the resource. A method the SDK adds fails the test until it
+// is either cached or listed here, so gaps stay visible.
+func TestCachedClients_SDKCoverage(t *testing.T) {
+	cached := map[string]bool{"List": true, "Get": true}
+
+	tests := []struct {
+		sdk      reflect.Type
+		uncached []string
+	}{
+		{reflect.TypeOf((*hue.LightClient)(nil)).Elem(), []string{"Update"}},
+		{reflect.TypeOf((*hue.RoomClient)(nil)).Elem(), []string{"Create", "Delete", "Update"}},
+		{reflect.TypeOf((*hue.ZoneClient)(nil)).Elem(), []string{"Create", "Delete", "Update"}},
+		{reflect.TypeOf((*hue.SceneClient)(nil)).Elem(), []string{"Create", "Delete", "Update"}},
+		{reflect.TypeOf((*hue.GroupedLightClient)(nil)).Elem(), []string{"Update"}},
+		{reflect.TypeOf((*hue.BridgeClient)(nil)).Elem(), nil},
+		{reflect.TypeOf((*hue.BridgeHomeClient)(nil)).Elem(), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sdk.Name(), func(t *testing.T) {
+			var got []string
+			for i := 0; i < tt.sdk.NumMethod(); i++ {
+				if name := tt.sdk.Method(i).Name; !cached[name] {
+					got = append(got, name)
+				}
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tt.uncached) {
+				t.Errorf("uncached methods of hue.%s = %v, want %v", tt.sdk.Name(), got, tt.uncached)
+			}
+		})
+	}
+}
+
+// unlistableBackend is a backend that can't list keys.
+type unlistableBackend struct {
+	*mockBackend
+}
+
+func (b *unlistableBackend) Keys(ctx context.Context, pattern string) ([]string, error) {
+	return nil, NewError("Keys", "", ErrUnsupported)
+}
+
+func TestCachedLightClient_List_KeysUnsupported(t *testing.T) {
+	backend := &unlistableBackend{newMockBackend()}
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	client := NewCachedLightClient(backend, mockSDK, 5*time.Minute)
+	client.configure(&CachedClientConfig{FailClosed: true})
+	ctx := context.Background()
+
+	// Lists go to the SDK without failing, even with FailClosed set
+	for i := 0; i < 2; i++ {
+		lights, err := client.List(ctx)
+		if err != nil {
+			t.Fatalf("List() failed: %v", err)
+		}
+		if len(lights) != 1 {
+			t.Errorf("List() returned %d lights, want 1", len(lights))
+		}
+	}
+	if mockSDK.calls["List"] != 2 {
+		t.Errorf("SDK List calls = %d, want 2", mockSDK.calls["List"])
+	}
+
+	page, total, err := client.ListPage(ctx, 0, 10)
+	if err != nil || total != 1 || len(page) != 1 {
+		t.Errorf("ListPage() = %d lights of %d, %v; want 1 of 1", len(page), total, err)
+	}
+
+	// The listed lights were still cached
+	if _, err := client.Get(ctx, "light-1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if mockSDK.calls["Get"] != 0 {
+		t.Errorf("SDK Get calls = %d, want 0 (cached by List)", mockSDK.calls["Get"])
+	}
+}
+
+// closeRecordingBackend records the state of its owner when it is closed.
+type closeRecordingBackend struct {
+	*mockBackend
+	closes int
+}
+
+func (b *closeRecordingBackend) Close() error {
+	b.closes++
+	return nil
+}
+
+func TestCachedClient_Close(t *testing.T) {
+	backend := &closeRecordingBackend{mockBackend: newMockBackend()}
+	source := &fakeEventSource{events: make(chan resources.Event)}
+	client := NewCachedClient(backend, nil, &CachedClientConfig{
+		EnableSync:  true,
+		SyncConfig:  &SyncConfig{EnableAutoSync: true, EventSource: source},
+		WriteBehind: &WriteBehindConfig{FlushInterval: time.Hour},
+	})
+	if client.SyncEngine() == nil {
+		t.Fatal("SyncEngine() = nil with EnableSync")
+	}
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+	client.lights = NewCachedLightClient(backend, mockSDK, 0)
+	client.lights.configure(client.config)
+
+	ctx := context.Background()
+	if err := client.lights.Update(ctx, "light-1", resources.LightUpdate{On: &resources.OnState{On: true}}); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := client.Close(); err != nil {
+			t.Fatalf("Close() failed: %v", err)
+		}
+	}
+
+	select {
+	case <-client.SyncEngine().done:
+	default:
+		t.Error("sync engine still running after Close()")
+	}
+	if mockSDK.calls["Update"] != 1 {
+		t.Errorf("Close() flushed %d updates, want 1", mockSDK.calls["Update"])
+	}
+
+	// The caller's backend may be shared, so it stays open
+	if backend.closes != 0 {
+		t.Errorf("backend closed %d times, want 0", backend.closes)
+	}
+}
+
+func TestCachedClient_Close_WithoutAutoSync(t *testing.T) {
+	backend := &closeRecordingBackend{mockBackend: newMockBackend()}
+	client := NewCachedClient(backend, nil, &CachedClientConfig{
+		EnableSync: true,
+		SyncConfig: &SyncConfig{EnableAutoSync: false},
+	})
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	// No sync loop runs, so Close mustn't wait for one
+	closed := make(chan error, 1)
+	go func() { closed <- client.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil || backend.closes != 0 {
+			t.Errorf("Close() = %v with %d backend closes, want nil and 0", err, backend.closes)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close() blocked without EnableAutoSync")
+	}
+}
+
+func TestCachedClient_SyncConfigMatchesClient(t *testing.T) {
+	projection := NewProjection(map[string][]string{"light": {"on"}})
+	syncConfig := &SyncConfig{KeyPrefix: "other", SyncTimeout: time.Minute}
+	client := NewCachedClient(newMockBackend(), nil, &CachedClientConfig{
+		EnableSync:    true,
+		SyncConfig:    syncConfig,
+		KeyPrefix:     "home",
+		Codec:         MsgpackCodec(),
+		Projection:    projection,
+		CacheRaw:      true,
+		LastWriteWins: true,
+	})
+
+	engine := client.SyncEngine()
+	got := engine.config
+	if engine.keyBuilder.Prefix() != "home" || got.KeyPrefix != "home" {
+		t.Errorf("engine key prefix = %q, want the client's home", engine.keyBuilder.Prefix())
+	}
+	if got.Codec != MsgpackCodec() || got.Projection != projection || !got.CacheRaw || !got.LastWriteWins {
+		t.Errorf("engine config = %+v, want the client's codec, projection, CacheRaw, and LastWriteWins", got)
+	}
+	if got.SyncTimeout != time.Minute {
+		t.Errorf("engine SyncTimeout = %v, want the SyncConfig's 1m", got.SyncTimeout)
+	}
+	if syncConfig.KeyPrefix != "other" || syncConfig.CacheRaw {
+		t.Errorf("caller's SyncConfig was modified: %+v", syncConfig)
+	}
+}
+
+func TestCachedClient_WithoutSync(t *testing.T) {
+	backend := &closeRecordingBackend{mockBackend: newMockBackend()}
+	client := NewCachedClient(backend, nil, &CachedClientConfig{EnableSync: false})
+
+	if client.SyncEngine() != nil {
+		t.Error("SyncEngine() != nil without EnableSync")
+	}
+	if err := client.Start(); err != nil {
+		t.Errorf("Start() failed: %v", err)
+	}
+	if err := client.Close(); err != nil || backend.closes != 0 {
+		t.Errorf("Close() = %v with %d backend closes, want nil and 0", err, backend.closes)
+	}
+}
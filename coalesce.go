@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// listCoalescer collapses a burst of Get misses for different IDs into a
+// single SDK List call. The first miss opens a batch; misses that arrive
+// within the window join it, and when the window closes one List answers
+// them all. A UI that loads many items at once then costs one bridge
+// round-trip instead of one per item.
+type listCoalescer struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	pending *listBatch
+}
+
+// listBatch is one coalesced List call. found maps IDs to resources
+// (as *T for the client's resource type T); it and err are set before
+// done is closed.
+type listBatch struct {
+	done  chan struct{}
+	found map[string]any
+	err   error
+}
+
+// newListCoalescer creates a coalescer with the given window, or returns
+// nil (coalescing disabled) if window <= 0.
+func newListCoalescer(window time.Duration) *listCoalescer {
+	if window <= 0 {
+		return nil
+	}
+	return &listCoalescer{window: window}
+}
+
+// join returns the open batch, opening one that runs list after the
+// window if there is none. The List call outlives the caller that opened
+// the batch, so it runs without the caller's cancellation.
+func (c *listCoalescer) join(ctx context.Context, list func(context.Context) (map[string]any, error)) *listBatch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending != nil {
+		return c.pending
+	}
+
+	batch := &listBatch{done: make(chan struct{})}
+	c.pending = batch
+	listCtx := context.WithoutCancel(ctx)
+	time.AfterFunc(c.window, func() {
+		c.mu.Lock()
+		c.pending = nil
+		c.mu.Unlock()
+
+		batch.found, batch.err = list(listCtx)
+		close(batch.done)
+	})
+	return batch
+}
+
+// coalescedFetch returns the fetch function for a Get of id. With
+// coalescing enabled (CachedClientConfig.CoalesceWindow), the resource is
+// taken from a shared List call, and an ID missing from the list is
+// reported as ErrNotFound; otherwise get is returned unchanged.
+func coalescedFetch[T any](r *resourceCache, id string, idOf func(*T) string, list func(context.Context) ([]T, error), get func(context.Context) (*T, error)) func(context.Context) (*T, error) {
+	if r.coalescer == nil {
+		return get
+	}
+
+	return func(ctx context.Context) (*T, error) {
+		batch := r.coalescer.join(ctx, func(ctx context.Context) (map[string]any, error) {
+			resources, err := list(ctx)
+			if err != nil {
+				return nil, err
+			}
+			found := make(map[string]any, len(resources))
+			for i := range resources {
+				found[idOf(&resources[i])] = &resources[i]
+			}
+			return found, nil
+		})
+
+		select {
+		case <-batch.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if batch.err != nil {
+			return nil, batch.err
+		}
+		resource, ok := batch.found[id]
+		if !ok {
+			return nil, NewError("Get", id, ErrNotFound)
+		}
+
+		// Every waiter shares the batch; hand each its own copy
+		copied := *resource.(*T)
+		return &copied, nil
+	}
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+func TestCachedLightClient_CoalesceWindow(t *testing.T) {
+	mockSDK := newMockLightClient()
+	for i := 1; i <= 10; i++ {
+		id := fmt.Sprintf("light-%d", i)
+		mockSDK.lights[id] = &resources.Light{ID: id, Type: "light"}
+	}
+
+	client := NewCachedLightClient(newMockBackend(), mockSDK, 0)
+	client.configure(&CachedClientConfig{CoalesceWindow: 50 * time.Millisecond})
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("light-%d", i+1)
+			light, err := client.Get(ctx, id)
+			if err == nil && light.ID != id {
+				err = fmt.Errorf("got %s", light.ID)
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Get(light-%d) failed: %v", i+1, err)
+		}
+	}
+	if mockSDK.calls["List"] != 1 || mockSDK.calls["Get"] != 0 {
+		t.Errorf("SDK calls = %v, want 1 List and no Gets", mockSDK.calls)
+	}
+
+	// The results were cached like ordinary misses
+	if _, err := client.Get(ctx, "light-3"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if mockSDK.calls["List"] != 1 {
+		t.Errorf("Expected cached Get, got %d List calls", mockSDK.calls["List"])
+	}
+}
+
+func TestCachedLightClient_CoalesceWindow_NotFound(t *testing.T) {
+	mockSDK := newMockLightClient()
+	client := NewCachedLightClient(newMockBackend(), mockSDK, 0)
+	client.configure(&CachedClientConfig{CoalesceWindow: time.Millisecond})
+
+	if _, err := client.Get(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCachedLightClient_CoalesceWindow_Cancel(t *testing.T) {
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+	client := NewCachedLightClient(newMockBackend(), mockSDK, 0)
+	client.configure(&CachedClientConfig{CoalesceWindow: 50 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := client.Get(ctx, "light-1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() error = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec serializes the resources cached by the cached clients, the sync
+// engine, and the cache manager. Every component sharing a backend must
+// use the same codec; a value written by another codec is reported as
+// ErrCodecMismatch, and readers treat it as a miss.
+//
+// Raw entries (see CachedClientConfig.CacheRaw) always hold JSON, and
+// validators always receive a resource's JSON form, whatever the codec.
+type Codec interface {
+	// Marshal encodes v.
+	Marshal(v any) ([]byte, error)
+
+	// Unmarshal decodes data into v. It returns ErrCodecMismatch if data
+	// was encoded by a different codec.
+	Unmarshal(data []byte, v any) error
+}
+
+// msgpackMarker prefixes every value MsgpackCodec encodes. 0xc1 is never
+// used by MessagePack and can't start a JSON document, so each codec can
+// tell the other's values from its own.
+const msgpackMarker = 0xc1
+
+// JSONCodec returns the default codec, encoding/json. It reads values
+// written before codecs were configurable.
+func JSONCodec() Codec {
+	return jsonCodec{}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	if len(data) > 0 && data[0] == msgpackMarker {
+		return ErrCodecMismatch
+	}
+	return json.Unmarshal(data, v)
+}
+
+// MsgpackCodec returns a codec that encodes resources as MessagePack,
+// using their JSON field names. It allocates less than JSONCodec when
+// caching many resources (e.g. warming), and its values are smaller.
+func MsgpackCodec() Codec {
+	return msgpackCodec{}
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(msgpackMarker)
+
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	if len(data) == 0 || data[0] != msgpackMarker {
+		return ErrCodecMismatch
+	}
+
+	dec := msgpack.NewDecoder(bytes.NewReader(data[1:]))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// codecOrJSON returns codec, or JSONCodec if it is nil.
+func codecOrJSON(codec Codec) Codec {
+	if codec == nil {
+		return JSONCodec()
+	}
+	return codec
+}
+
+// isJSONCodec reports whether codec encodes values as JSON.
+func isJSONCodec(codec Codec) bool {
+	_, ok := codec.(jsonCodec)
+	return ok
+}
+
+// transcodeJSON re-encodes a JSON document with codec.
+func transcodeJSON(codec Codec, data []byte) ([]byte, error) {
+	if isJSONCodec(codec) {
+		return data, nil
+	}
+
+	value, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return codec.Marshal(value)
+}
+
+// decodeJSON decodes a JSON document into generic values. Integers are
+// kept as int64 rather than float64, so codecs that tell them apart
+// encode them as integers and they decode into integer fields.
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return normalizeNumbers(value), nil
+}
+
+// normalizeNumbers replaces the json.Numbers in a decoded JSON value with
+// int64s or float64s.
+func normalizeNumbers(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for k, v := range value {
+			value[k] = normalizeNumbers(v)
+		}
+	case []any:
+		for i, v := range value {
+			value[i] = normalizeNumbers(v)
+		}
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return n
+		}
+		f, _ := value.Float64()
+		return f
+	}
+	return value
+}
+
+// validateResource runs validation on resource's JSON form. data is the
+// resource as encoded by codec, reused when codec is JSON.
+func validateResource(validation *Validation, codec Codec, key string, resource any, data []byte) error {
+	if validation == nil {
+		return nil
+	}
+	if !isJSONCodec(codec) {
+		var err error
+		if data, err = json.Marshal(resource); err != nil {
+			return fmt.Errorf("encoding resource for validation: %w", err)
+		}
+	}
+	return validation.Validate(key, data)
+}
@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+func TestCodecs_RoundTrip(t *testing.T) {
+	light := resources.Light{
+		ID:       "light-1",
+		Type:     "light",
+		On:       resources.OnState{On: true},
+		Metadata: resources.Metadata{Name: "Desk"},
+	}
+
+	for name, codec := range map[string]Codec{"json": JSONCodec(), "msgpack": MsgpackCodec()} {
+		t.Run(name, func(t *testing.T) {
+			data, err := codec.Marshal(&light)
+			if err != nil {
+				t.Fatalf("Marshal() failed: %v", err)
+			}
+
+			var decoded resources.Light
+			if err := codec.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("Unmarshal() failed: %v", err)
+			}
+			if decoded != light {
+				t.Errorf("Unmarshal() = %+v, want %+v", decoded, light)
+			}
+		})
+	}
+}
+
+func TestCodecs_DetectMismatch(t *testing.T) {
+	jsonData, _ := JSONCodec().Marshal(map[string]string{"id": "light-1"})
+	msgpackData, _ := MsgpackCodec().Marshal(map[string]string{"id": "light-1"})
+
+	var v map[string]string
+	if err := MsgpackCodec().Unmarshal(jsonData, &v); !errors.Is(err, ErrCodecMismatch) {
+		t.Errorf("msgpack decoding JSON: got %v, want ErrCodecMismatch", err)
+	}
+	if err := JSONCodec().Unmarshal(msgpackData, &v); !errors.Is(err, ErrCodecMismatch) {
+		t.Errorf("JSON decoding msgpack: got %v, want ErrCodecMismatch", err)
+	}
+}
+
+func TestDecodeJSON_KeepsIntegers(t *testing.T) {
+	value, err := decodeJSON([]byte(`{"brightness": 80, "mirek": 366.5, "points": [1, 2]}`))
+	if err != nil {
+		t.Fatalf("decodeJSON() failed: %v", err)
+	}
+
+	m := value.(map[string]any)
+	if _, ok := m["brightness"].(int64); !ok {
+		t.Errorf("brightness = %T, want int64", m["brightness"])
+	}
+	if _, ok := m["mirek"].(float64); !ok {
+		t.Errorf("mirek = %T, want float64", m["mirek"])
+	}
+	if _, ok := m["points"].([]any)[0].(int64); !ok {
+		t.Errorf("points[0] = %T, want int64", m["points"].([]any)[0])
+	}
+}
+
+func TestCachedClient_Msgpack(t *testing.T) {
+	backend := newMockBackend()
+	ctx := context.Background()
+
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light", On: resources.OnState{On: true}}
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	client.configure(&CachedClientConfig{Codec: MsgpackCodec()})
+
+	for i := 0; i < 2; i++ {
+		light, err := client.Get(ctx, "light-1")
+		if err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		if !light.On.On {
+			t.Error("Expected light to be on")
+		}
+	}
+	if mockSDK.calls["Get"] != 1 {
+		t.Errorf("Expected 1 SDK Get call, got %d", mockSDK.calls["Get"])
+	}
+
+	entry, err := backend.Get(ctx, "light:light-1")
+	if err != nil {
+		t.Fatalf("light not cached: %v", err)
+	}
+	if entry.Value[0] != msgpackMarker {
+		t.Errorf("Expected a msgpack value, got %q", entry.Value)
+	}
+}
+
+func TestCachedClient_CodecMismatchRefetches(t *testing.T) {
+	backend := newMockBackend()
+	ctx := context.Background()
+
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	// Written as JSON, read with msgpack
+	_ = backend.Set(ctx, "light:light-1", []byte(`{"id": "light-1", "type": "light"}`), 0)
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	client.configure(&CachedClientConfig{Codec: MsgpackCodec()})
+
+	if _, err := client.Get(ctx, "light-1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if mockSDK.calls["Get"] != 1 {
+		t.Errorf("Expected the mismatched entry to be refetched, got %d SDK Get calls", mockSDK.calls["Get"])
+	}
+
+	// The entry is rewritten with the client's codec
+	entry, _ := backend.Get(ctx, "light:light-1")
+	var light resources.Light
+	if err := MsgpackCodec().Unmarshal(entry.Value, &light); err != nil {
+		t.Errorf("Expected entry rewritten as msgpack: %v", err)
+	}
+}
+
+func TestSyncEngine_Codec(t *testing.T) {
+	backend := newMockBackend()
+	config := DefaultSyncConfig()
+	config.Codec = MsgpackCodec()
+	config.CacheRaw = true
+	engine := &SyncEngine{
+		backend:    backend,
+		keyBuilder: NewKeyBuilder(),
+		stats:      &SyncStats{},
+		config:     config,
+	}
+
+	raw := json.RawMessage(`{"id": "light-1", "type": "light", "on": {"on": true}}`)
+	data := &resources.EventData{ID: "light-1", Type: "light", RawData: raw}
+	if err := engine.processEventData(context.Background(), resources.EventTypeAdd, data); err != nil {
+		t.Fatalf("processEventData() failed: %v", err)
+	}
+
+	// Clients with the same codec read the synced resource
+	mockSDK := newMockLightClient()
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	client.configure(&CachedClientConfig{Codec: MsgpackCodec(), CacheRaw: true})
+
+	light, err := client.Get(context.Background(), "light-1")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if !light.On.On || mockSDK.calls["Get"] != 0 {
+		t.Errorf("Expected a cache hit for the synced light, got %+v with %d SDK calls", light, mockSDK.calls["Get"])
+	}
+
+	// Raw entries stay JSON
+	rawEntry, err := backend.Get(context.Background(), NewKeyBuilder().Raw("light:light-1"))
+	if err != nil {
+		t.Fatalf("raw entry not cached: %v", err)
+	}
+	if !json.Valid(rawEntry.Value) {
+		t.Errorf("Expected raw entry to be JSON, got %q", rawEntry.Value)
+	}
+}
+
+// BenchmarkWarmLights compares the codecs for caching 1000 lights, as
+// warming does.
+func BenchmarkWarmLights(b *testing.B) {
+	mockSDK := newMockLightClient()
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("light-%d", i)
+		mockSDK.lights[id] = &resources.Light{
+			ID:       id,
+			Type:     "light",
+			On:       resources.OnState{On: i%2 == 0},
+			Metadata: resources.Metadata{Name: "Light " + id},
+		}
+	}
+
+	for name, codec := range map[string]Codec{"json": JSONCodec(), "msgpack": MsgpackCodec()} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				client := NewCachedLightClient(newMockBackend(), mockSDK, 0)
+				client.configure(&CachedClientConfig{Codec: codec})
+				if _, err := client.List(context.Background()); err != nil {
+					b.Fatalf("List() failed: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+func TestLastWriteWins(t *testing.T) {
+	ctx := context.Background()
+	backend := newMockBackend()
+	w := NewLastWriteWins(backend)
+
+	older := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Second)
+
+	if applied, err := w.Set(ctx, "light:1", []byte("new"), 0, newer); err != nil || !applied {
+		t.Fatalf("Set(newer) = %v, %v; want applied", applied, err)
+	}
+	if applied, err := w.Set(ctx, "light:1", []byte("old"), 0, older); err != nil || applied {
+		t.Errorf("Set(older) = %v, %v; want rejected", applied, err)
+	}
+	if entry, _ := backend.Get(ctx, "light:1"); string(entry.Value) != "new" {
+		t.Errorf("value = %s, want new", entry.Value)
+	}
+
+	// Retrying the same version is applied
+	if applied, _ := w.Set(ctx, "light:1", []byte("new"), 0, newer); !applied {
+		t.Error("Set() with the stored version should be applied")
+	}
+
+	if version, _ := w.Version(ctx, "light:1"); !version.Equal(newer) {
+		t.Errorf("Version() = %v, want %v", version, newer)
+	}
+	if version, _ := w.Version(ctx, "light:unknown"); !version.IsZero() {
+		t.Errorf("Version() of unknown key = %v, want zero", version)
+	}
+
+	// A delete keeps the version, so a late write can't resurrect the key
+	later := newer.Add(time.Second)
+	if applied, err := w.Delete(ctx, "light:1", later); err != nil || !applied {
+		t.Fatalf("Delete() = %v, %v; want applied", applied, err)
+	}
+	if applied, _ := w.Set(ctx, "light:1", []byte("new"), 0, newer); applied {
+		t.Error("Set() older than the delete should be rejected")
+	}
+	if _, err := backend.Get(ctx, "light:1"); err == nil {
+		t.Error("light:1 should stay deleted")
+	}
+}
+
+func TestSyncEngine_LastWriteWins_OutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	backend := newMockBackend()
+
+	// Two processes syncing the same bridge into one backend
+	newWriter := func() *SyncEngine {
+		return &SyncEngine{
+			backend:    backend,
+			keyBuilder: NewKeyBuilder(),
+			stats:      &SyncStats{},
+			config:     &SyncConfig{LastWriteWins: true},
+		}
+	}
+	fast, slow := newWriter(), newWriter()
+
+	event := func(eventType string, created time.Time, name string) *resources.Event {
+		return &resources.Event{
+			Type:         eventType,
+			CreationTime: created.Format(time.RFC3339),
+			Data: []resources.EventData{{
+				ID:      "light-1",
+				Type:    "light",
+				RawData: json.RawMessage(`{"id":"light-1","metadata":{"name":"` + name + `"}}`),
+			}},
+		}
+	}
+
+	t1 := time.Now().Add(-time.Minute).Truncate(time.Second)
+	t2 := t1.Add(time.Second)
+
+	fast.processEvent(event(resources.EventTypeAdd, t1.Add(-time.Second), "Initial"))
+
+	// The fast process applies the newer event; the slow one delivers the
+	// older event afterwards
+	fast.processEvent(event(resources.EventTypeUpdate, t2, "Fresh"))
+	slow.processEvent(event(resources.EventTypeUpdate, t1, "Stale"))
+
+	entry, err := backend.Get(ctx, "light:light-1")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	var light resources.Light
+	if err := json.Unmarshal(entry.Value, &light); err != nil {
+		t.Fatalf("decoding cached light: %v", err)
+	}
+	if light.Metadata.Name != "Fresh" {
+		t.Errorf("cached name = %q, want Fresh", light.Metadata.Name)
+	}
+	if stale := slow.Stats().StaleEvents; stale != 1 {
+		t.Errorf("StaleEvents = %d, want 1", stale)
+	}
+	if errs := slow.Stats().SyncErrors; errs != 0 {
+		t.Errorf("SyncErrors = %d, want 0 (stale events aren't errors)", errs)
+	}
+
+	// A delayed add can't resurrect a resource deleted later
+	fast.processEvent(event(resources.EventTypeDelete, t2.Add(time.Second), ""))
+	slow.processEvent(event(resources.EventTypeAdd, t2, "Zombie"))
+	if _, err := backend.Get(ctx, "light:light-1"); err == nil {
+		t.Error("light-1 should stay deleted")
+	}
+}
+
+func TestCachedClient_LastWriteWins_UpdateBeforeStaleEvent(t *testing.T) {
+	ctx := context.Background()
+	backend := newMockBackend()
+	engine := &SyncEngine{
+		backend:    backend,
+		keyBuilder: NewKeyBuilder(),
+		stats:      &SyncStats{},
+		config:     &SyncConfig{LastWriteWins: true},
+	}
+
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	client.configure(&CachedClientConfig{LastWriteWins: true})
+
+	event := func(created time.Time, on bool) *resources.Event {
+		raw, _ := json.Marshal(resources.Light{ID: "light-1", Type: "light", On: resources.OnState{On: on}})
+		return &resources.Event{
+			Type:         resources.EventTypeUpdate,
+			CreationTime: created.Format(time.RFC3339Nano),
+			Data:         []resources.EventData{{ID: "light-1", Type: "light", RawData: raw}},
+		}
+	}
+	cachedOn := func() bool {
+		t.Helper()
+		light, err := client.Get(ctx, "light-1")
+		if err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		return light.On.On
+	}
+
+	if cachedOn() {
+		t.Fatal("light-1 should start off")
+	}
+
+	// The bridge emitted an event for the old state just before the
+	// update, but it is delivered after the update completes
+	beforeUpdate := time.Now()
+	time.Sleep(time.Millisecond)
+	if err := client.Update(ctx, "light-1", resources.LightUpdate{On: &resources.OnState{On: true}}); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	engine.processEvent(event(beforeUpdate, false))
+
+	if stale := engine.Stats().StaleEvents; stale != 1 {
+		t.Errorf("StaleEvents = %d, want 1", stale)
+	}
+	if !cachedOn() {
+		t.Error("stale event resurrected the pre-update state")
+	}
+
+	// The update's own event is newer, so it is applied
+	mockSDK.calls["Get"] = 0
+	engine.processEvent(event(time.Now(), true))
+	if !cachedOn() || mockSDK.calls["Get"] != 0 {
+		t.Errorf("update's event not cached (SDK Gets = %d)", mockSDK.calls["Get"])
+	}
+}
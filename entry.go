@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"slices"
 	"time"
 )
 
@@ -29,14 +30,30 @@ type Entry struct {
 
 	// Size is the size of the value in bytes.
 	Size int64
+
+	// Tags group the entry with others for DeleteTag (see SetWithTags).
+	Tags []string
 }
 
 // IsExpired returns true if the entry has expired.
 func (e *Entry) IsExpired() bool {
+	return e.IsExpiredWithGrace(0)
+}
+
+// IsExpiredWithGrace returns true if the entry expired more than grace
+// ago. Backends shared across hosts use it to tolerate clock skew: an
+// entry written on a host whose clock runs behind looks expired early to
+// the others, by as much as the skew.
+func (e *Entry) IsExpiredWithGrace(grace time.Duration) bool {
 	if e.ExpiresAt.IsZero() {
 		return false
 	}
-	return time.Now().After(e.ExpiresAt)
+	return time.Now().After(e.ExpiresAt.Add(grace))
+}
+
+// HasTag reports whether the entry is tagged with tag.
+func (e *Entry) HasTag(tag string) bool {
+	return slices.Contains(e.Tags, tag)
 }
 
 // Age returns how long the entry has existed.
@@ -57,6 +74,81 @@ func (e *Entry) TimeUntilExpiry() time.Duration {
 	return remaining
 }
 
+// EntryMeta describes the freshness of a cached value, without exposing
+// the cached bytes. Age and TimeUntilExpiry are computed when the value
+// is returned.
+type EntryMeta struct {
+	// Cached is true if the value was served from the cache, and false
+	// if it was just fetched from the bridge.
+	Cached bool
+
+	// CreatedAt is when the entry was written to the cache.
+	CreatedAt time.Time
+
+	// UpdatedAt is when the entry was last written or read.
+	UpdatedAt time.Time
+
+	// ExpiresAt is when the entry expires (zero means no expiration).
+	ExpiresAt time.Time
+
+	// Age is how long the entry has existed.
+	Age time.Duration
+
+	// TimeUntilExpiry is how long until the entry expires
+	// (0 if it has no TTL).
+	TimeUntilExpiry time.Duration
+
+	// Hits is the number of times the entry has been retrieved.
+	Hits int64
+
+	// TTL is the time-to-live the entry was written with (0 = none).
+	TTL time.Duration
+
+	// Size is the size of the cached value in bytes.
+	Size int64
+
+	// Stale is true if the value outlived its TTL and was served while
+	// being refreshed in the background (see
+	// CachedClientConfig.StaleWhileRevalidate).
+	Stale bool
+}
+
+// EntryInfo is the metadata of one cached entry, with its key, as
+// returned by ListEntries.
+type EntryInfo struct {
+	Key string
+	EntryMeta
+}
+
+// Info returns the entry's key and metadata, for implementing
+// EntryLister.
+func (e *Entry) Info() *EntryInfo {
+	return &EntryInfo{Key: e.Key, EntryMeta: *e.Meta()}
+}
+
+// newEntryMeta copies the freshness metadata from an entry.
+func newEntryMeta(e *Entry, cached bool) *EntryMeta {
+	meta := e.Meta()
+	meta.Cached = cached
+	return meta
+}
+
+// Meta returns the entry's metadata, as read from the cache, for
+// implementing MetaGetter.
+func (e *Entry) Meta() *EntryMeta {
+	return &EntryMeta{
+		Cached:          true,
+		CreatedAt:       e.CreatedAt,
+		UpdatedAt:       e.UpdatedAt,
+		ExpiresAt:       e.ExpiresAt,
+		Age:             e.Age(),
+		TimeUntilExpiry: e.TimeUntilExpiry(),
+		Hits:            e.Hits,
+		TTL:             e.TTL,
+		Size:            e.Size,
+	}
+}
+
 // Clone creates a deep copy of the entry.
 func (e *Entry) Clone() *Entry {
 	valueCopy := make([]byte, len(e.Value))
@@ -71,6 +163,7 @@ func (e *Entry) Clone() *Entry {
 		TTL:       e.TTL,
 		Hits:      e.Hits,
 		Size:      e.Size,
+		Tags:      slices.Clone(e.Tags),
 	}
 }
 
@@ -145,6 +145,20 @@ func TestEntry_IsExpired(t *testing.T) {
 	}
 }
 
+func TestEntry_IsExpiredWithGrace(t *testing.T) {
+	entry := &Entry{Key: "test:1", ExpiresAt: time.Now().Add(-5 * time.Second)}
+
+	if !entry.IsExpiredWithGrace(0) {
+		t.Error("IsExpiredWithGrace(0) = false, want true")
+	}
+	if entry.IsExpiredWithGrace(10 * time.Second) {
+		t.Error("IsExpiredWithGrace(10s) = true, want false within grace")
+	}
+	if (&Entry{}).IsExpiredWithGrace(-time.Hour) {
+		t.Error("entry with no TTL should never expire")
+	}
+}
+
 func TestEntry_Age(t *testing.T) {
 	now := time.Now()
 	entry := &Entry{
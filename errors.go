@@ -24,6 +24,33 @@ var (
 
 	// ErrMemoryLimit is returned when an operation would exceed memory limits.
 	ErrMemoryLimit = errors.New("cache: memory limit exceeded")
+
+	// ErrClientClosed is returned when operating on a closed cached client.
+	ErrClientClosed = errors.New("cache: client closed")
+
+	// ErrValueTooLarge is returned when a value exceeds a backend's
+	// per-entry size limit. It wraps ErrInvalidValue.
+	ErrValueTooLarge = fmt.Errorf("%w: value too large", ErrInvalidValue)
+
+	// ErrLoading is returned by reads from a backend that is still loading
+	// its persisted state and was configured not to wait for it. It wraps
+	// ErrNotFound, so callers that fetch from the source on a miss fall
+	// back to it automatically.
+	ErrLoading = fmt.Errorf("%w: backend loading", ErrNotFound)
+
+	// ErrUnsupported is returned by a backend for an operation its store
+	// can't perform, such as Keys on a store that can't list keys. It
+	// wraps errors.ErrUnsupported. Callers that can do without the
+	// operation should fall back instead of failing.
+	ErrUnsupported = fmt.Errorf("cache: %w", errors.ErrUnsupported)
+
+	// ErrCodecMismatch is returned when decoding a cached value that was
+	// encoded by a different Codec than the one reading it.
+	ErrCodecMismatch = errors.New("cache: value encoded by a different codec")
+
+	// ErrRateLimited is returned by a cached client when an SDK call
+	// can't get a token from the SDK rate limiter before its context ends.
+	ErrRateLimited = errors.New("cache: SDK rate limit exceeded")
 )
 
 // Error wraps cache errors with additional context.
@@ -56,6 +83,21 @@ func (e *Error) Is(target error) bool {
 	return errors.Is(e.Err, target)
 }
 
+// ValidateValue checks a value before a backend stores it. It returns
+// ErrInvalidValue for a nil value and ErrValueTooLarge if the value is
+// longer than maxSize bytes. A maxSize of 0 or less means unlimited.
+// Backends should call it before making room for the value, so an
+// oversized value never evicts other entries.
+func ValidateValue(value []byte, maxSize int64) error {
+	if value == nil {
+		return ErrInvalidValue
+	}
+	if maxSize > 0 && int64(len(value)) > maxSize {
+		return ErrValueTooLarge
+	}
+	return nil
+}
+
 // NewError creates a new cache error.
 func NewError(op, key string, err error) *Error {
 	return &Error{
@@ -64,3 +106,61 @@ func NewError(op, key string, err error) *Error {
 		Err: err,
 	}
 }
+
+// SubscribeError is reported to SyncConfig.ErrorHandler when the sync
+// engine can't subscribe to the bridge's event stream at all, e.g.
+// because the bridge is unreachable or rejects the app key.
+type SubscribeError struct {
+	// Err is the error returned by the event source.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *SubscribeError) Error() string {
+	return fmt.Sprintf("sync: subscribing to events: %v", e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *SubscribeError) Unwrap() error {
+	return e.Err
+}
+
+// StreamClosedError is reported to SyncConfig.ErrorHandler when the event
+// stream closes while the sync engine is running, such as a connection
+// dropped mid-stream. Events after the close are missed until the engine
+// is restarted.
+type StreamClosedError struct {
+	// Events is the number of events received before the stream closed.
+	Events int64
+}
+
+// Error implements the error interface.
+func (e *StreamClosedError) Error() string {
+	return fmt.Sprintf("sync: event stream closed after %d events", e.Events)
+}
+
+// ProcessError is reported to SyncConfig.ErrorHandler when an event was
+// received but couldn't be applied to the cache.
+type ProcessError struct {
+	// EventID is the ID of the SSE event.
+	EventID string
+
+	// EventType is the event type ("add", "update", or "delete").
+	EventType string
+
+	// Key is the cache key of the resource the event describes.
+	Key string
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ProcessError) Error() string {
+	return fmt.Sprintf("sync: processing %s event %s for %q: %v", e.EventType, e.EventID, e.Key, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ProcessError) Unwrap() error {
+	return e.Err
+}
@@ -129,6 +129,9 @@ func TestSentinelErrors(t *testing.T) {
 		ErrInvalidValue,
 		ErrBackendClosed,
 		ErrMemoryLimit,
+		ErrClientClosed,
+		ErrValueTooLarge,
+		ErrUnsupported,
 	}
 
 	// Check that all sentinel errors are defined and unique
@@ -146,3 +149,38 @@ func TestSentinelErrors(t *testing.T) {
 		seen[msg] = true
 	}
 }
+
+func TestValidateValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   []byte
+		maxSize int64
+		want    error
+	}{
+		{name: "nil", value: nil, maxSize: 0, want: ErrInvalidValue},
+		{name: "empty", value: []byte{}, maxSize: 0, want: nil},
+		{name: "unlimited", value: make([]byte, 1<<20), maxSize: 0, want: nil},
+		{name: "at limit", value: make([]byte, 10), maxSize: 10, want: nil},
+		{name: "over limit", value: make([]byte, 11), maxSize: 10, want: ErrValueTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateValue(tt.value, tt.maxSize); err != tt.want {
+				t.Errorf("ValidateValue() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Oversized values are also invalid values
+	if !errors.Is(ErrValueTooLarge, ErrInvalidValue) {
+		t.Error("ErrValueTooLarge should wrap ErrInvalidValue")
+	}
+}
+
+func TestErrUnsupported(t *testing.T) {
+	err := NewError("Keys", "", ErrUnsupported)
+	if !errors.Is(err, ErrUnsupported) || !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("%v should match ErrUnsupported and errors.ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// ResourceEvent is a single resource change reported by the bridge.
+type ResourceEvent struct {
+	// EventType is the SSE event type ("add", "update", "delete").
+	EventType string
+
+	// Type is the resource type (e.g. "light").
+	Type string
+
+	// ID is the resource ID.
+	ID string
+
+	// Data is the raw resource payload from the event. For updates it
+	// holds only the changed fields.
+	Data json.RawMessage
+
+	// Time is when the event was received.
+	Time time.Time
+}
+
+// EventFilter selects which events a subscriber receives. Each non-empty
+// field restricts delivery to events matching one of its values; an empty
+// filter matches every event.
+type EventFilter struct {
+	// ResourceTypes limits delivery to these resource types (e.g. "light").
+	ResourceTypes []string
+
+	// EventTypes limits delivery to these event types (e.g. "update").
+	EventTypes []string
+
+	// IDs limits delivery to these resource IDs.
+	IDs []string
+}
+
+// Matches reports whether event passes the filter.
+func (f EventFilter) Matches(event ResourceEvent) bool {
+	if len(f.ResourceTypes) > 0 && !slices.Contains(f.ResourceTypes, event.Type) {
+		return false
+	}
+	if len(f.EventTypes) > 0 && !slices.Contains(f.EventTypes, event.EventType) {
+		return false
+	}
+	if len(f.IDs) > 0 && !slices.Contains(f.IDs, event.ID) {
+		return false
+	}
+	return true
+}
+
+// EventBusConfig contains configuration for an EventBus.
+type EventBusConfig struct {
+	// BufferSize is the number of events buffered for each subscriber.
+	// Events for a subscriber whose buffer is full are dropped, so a slow
+	// subscriber never stalls the sync engine.
+	// Default: 256
+	BufferSize int
+}
+
+// DefaultEventBusConfig returns default event bus configuration.
+func DefaultEventBusConfig() *EventBusConfig {
+	return &EventBusConfig{
+		BufferSize: 256,
+	}
+}
+
+// EventBus notifies application code of resource changes, independently
+// of the cache. Set SyncConfig.EventBus and the sync engine publishes every
+// event it receives, whether or not it changes the cache.
+//
+// Example:
+//
+//	bus := cache.NewEventBus()
+//	defer bus.Close()
+//
+//	bus.Subscribe(cache.EventFilter{ResourceTypes: []string{"light"}}, func(e cache.ResourceEvent) {
+//	    log.Printf("light %s: %s", e.ID, e.EventType)
+//	})
+//
+//	syncConfig := cache.DefaultSyncConfig()
+//	syncConfig.EventBus = bus
+type EventBus struct {
+	subscribers *fanout[ResourceEvent]
+}
+
+// NewEventBus creates an event bus. If config is nil, defaults are used.
+func NewEventBus(config ...*EventBusConfig) *EventBus {
+	cfg := DefaultEventBusConfig()
+	if len(config) > 0 && config[0] != nil {
+		cfg = config[0]
+	}
+
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = DefaultEventBusConfig().BufferSize
+	}
+
+	return &EventBus{subscribers: newFanout[ResourceEvent](bufferSize)}
+}
+
+// Subscribe registers handler for events matching filter and returns a
+// function that unregisters it. Handlers run asynchronously, in event
+// order.
+func (b *EventBus) Subscribe(filter EventFilter, handler func(ResourceEvent)) (unsubscribe func()) {
+	return b.subscribers.subscribe(filter.Matches, handler)
+}
+
+// Publish delivers an event to every matching subscriber without blocking.
+func (b *EventBus) Publish(event ResourceEvent) {
+	if !b.subscribers.hasSubscribers() {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	b.subscribers.publish(event)
+}
+
+// Dropped returns how many events were dropped because a subscriber's
+// buffer was full.
+func (b *EventBus) Dropped() int64 {
+	return b.subscribers.dropped.Load()
+}
+
+// Close unregisters all subscribers. Events already buffered are still
+// delivered.
+func (b *EventBus) Close() {
+	b.subscribers.close()
+}
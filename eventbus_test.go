@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+// eventRecorder collects events delivered to a subscriber.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []ResourceEvent
+}
+
+func (r *eventRecorder) record(event ResourceEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// waitFor waits until n events have been recorded and returns them.
+func (r *eventRecorder) waitFor(t *testing.T, n int) []ResourceEvent {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		if len(r.events) >= n {
+			events := append([]ResourceEvent(nil), r.events...)
+			r.mu.Unlock()
+			return events
+		}
+		r.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %d events", n)
+	return nil
+}
+
+func TestEventBus_Filters(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+
+	var all, lights, deletes, oneRoom, lightUpdates eventRecorder
+	bus.Subscribe(EventFilter{}, all.record)
+	bus.Subscribe(EventFilter{ResourceTypes: []string{"light"}}, lights.record)
+	bus.Subscribe(EventFilter{EventTypes: []string{resources.EventTypeDelete}}, deletes.record)
+	bus.Subscribe(EventFilter{IDs: []string{"room-1"}}, oneRoom.record)
+	bus.Subscribe(EventFilter{
+		ResourceTypes: []string{"light"},
+		EventTypes:    []string{resources.EventTypeUpdate},
+	}, lightUpdates.record)
+
+	events := []ResourceEvent{
+		{EventType: resources.EventTypeAdd, Type: "light", ID: "light-1"},
+		{EventType: resources.EventTypeUpdate, Type: "light", ID: "light-1"},
+		{EventType: resources.EventTypeUpdate, Type: "room", ID: "room-1"},
+		{EventType: resources.EventTypeDelete, Type: "room", ID: "room-2"},
+		{EventType: resources.EventTypeDelete, Type: "light", ID: "light-2"},
+	}
+	for _, event := range events {
+		bus.Publish(event)
+	}
+
+	all.waitFor(t, 5)
+
+	ids := func(events []ResourceEvent) []string {
+		var out []string
+		for _, e := range events {
+			out = append(out, e.EventType+":"+e.ID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		recorder *eventRecorder
+		want     []string
+	}{
+		{"lights", &lights, []string{"add:light-1", "update:light-1", "delete:light-2"}},
+		{"deletes", &deletes, []string{"delete:room-2", "delete:light-2"}},
+		{"room-1", &oneRoom, []string{"update:room-1"}},
+		{"light updates", &lightUpdates, []string{"update:light-1"}},
+	}
+	for _, tt := range tests {
+		got := ids(tt.recorder.waitFor(t, len(tt.want)))
+		if len(got) != len(tt.want) {
+			t.Errorf("%s received %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s received %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestEventBus_Unsubscribe(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+
+	var before, after eventRecorder
+	unsubscribe := bus.Subscribe(EventFilter{}, before.record)
+	bus.Subscribe(EventFilter{}, after.record)
+
+	bus.Publish(ResourceEvent{Type: "light", ID: "light-1"})
+	before.waitFor(t, 1)
+	unsubscribe()
+
+	bus.Publish(ResourceEvent{Type: "light", ID: "light-2"})
+	after.waitFor(t, 2)
+
+	if events := before.waitFor(t, 1); len(events) != 1 {
+		t.Errorf("Unsubscribed handler received %d events, want 1", len(events))
+	}
+}
+
+func TestSyncEngine_PublishesToEventBus(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+
+	var received eventRecorder
+	bus.Subscribe(EventFilter{ResourceTypes: []string{"light"}}, received.record)
+
+	config := DefaultSyncConfig()
+	config.EventBus = bus
+	// A wedged backend must not stop notifications
+	config.BackendTimeout = time.Millisecond
+	engine := NewSyncEngine(&blockingBackend{newMockBackend()}, nil, config)
+
+	raw, _ := json.Marshal(map[string]interface{}{"id": "light-1", "on": map[string]bool{"on": true}})
+	engine.processEvent(&resources.Event{
+		ID:   "event-1",
+		Type: resources.EventTypeUpdate,
+		Data: []resources.EventData{
+			{ID: "light-1", Type: "light", RawData: raw},
+			{ID: "room-1", Type: "room", RawData: raw},
+		},
+	})
+
+	events := received.waitFor(t, 1)
+	event := events[0]
+	if event.EventType != resources.EventTypeUpdate || event.Type != "light" || event.ID != "light-1" {
+		t.Errorf("Received %+v, want update light/light-1", event)
+	}
+	if string(event.Data) != string(raw) {
+		t.Errorf("Data = %s, want %s", event.Data, raw)
+	}
+	if event.Time.IsZero() {
+		t.Error("Expected event time to be set")
+	}
+}
@@ -26,20 +26,18 @@ func main() {
 	backend := backends.NewMemory(backends.DefaultMemoryConfig())
 	defer backend.Close()
 
-	// Create sync engine for automatic cache updates
-	syncEngine := cache.NewSyncEngine(backend, sdkClient, nil)
-	if err := syncEngine.Start(); err != nil {
-		log.Fatal(err)
-	}
-	defer syncEngine.Stop()
-
-	// Create cached client with same interface as SDK client
+	// Create cached client with same interface as SDK client. With
+	// EnableSync it owns a sync engine for automatic cache updates.
 	config := &cache.CachedClientConfig{
 		TTL:        0, // No expiration, rely on SSE sync
 		EnableSync: true,
 		SyncConfig: cache.DefaultSyncConfig(),
 	}
 	cachedClient := cache.NewCachedClient(backend, sdkClient, config)
+	if err := cachedClient.Start(); err != nil {
+		log.Fatal(err)
+	}
+	defer cachedClient.Close() // Stops sync before the backend closes
 
 	ctx := context.Background()
 
@@ -104,11 +102,12 @@ func main() {
 	fmt.Printf("  Hit Rate: %.2f%%\n", stats.HitRate())
 
 	// Check sync statistics
-	syncStats := syncEngine.Stats()
+	syncStats := cachedClient.SyncEngine().Stats()
 	fmt.Printf("\nSync Statistics:\n")
 	fmt.Printf("  Events Processed: %d\n", syncStats.EventsProcessed)
 	fmt.Printf("  Add Events: %d\n", syncStats.AddEvents)
 	fmt.Printf("  Update Events: %d\n", syncStats.UpdateEvents)
 	fmt.Printf("  Delete Events: %d\n", syncStats.DeleteEvents)
 	fmt.Printf("  Avg Latency: %v\n", syncStats.AvgLatency)
+	fmt.Printf("  p95/p99 Latency: %v / %v\n", syncStats.P95Latency, syncStats.P99Latency)
 }
@@ -61,18 +61,16 @@ func main() {
 	fmt.Printf("  GroupedLights: %d\n", warmStats.GroupedLightsWarmed)
 	fmt.Printf("  Total: %d entries\n", warmStats.TotalWarmed)
 
-	// Start SSE sync engine for automatic updates
-	syncEngine := cache.NewSyncEngine(backend, sdkClient, nil)
-	if err := syncEngine.Start(); err != nil {
-		log.Fatal(err)
-	}
-	defer syncEngine.Stop()
-
-	// Create cached client
+	// Create cached client, with its own sync engine for automatic updates
 	cachedClient := cache.NewCachedClient(backend, sdkClient, &cache.CachedClientConfig{
 		TTL:        0, // No expiration, rely on SSE sync
 		EnableSync: true,
 	})
+	if err := cachedClient.Start(); err != nil {
+		log.Fatal(err)
+	}
+	// Stops sync before the backend closes and saves
+	defer cachedClient.Close()
 
 	// Use cached client - first access after warmup is instant!
 	fmt.Println("\nGetting lights from cache...")
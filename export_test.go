@@ -0,0 +1,7 @@
+package cache
+
+// NeedsInitialSync exposes needsInitialSync to the tests in package
+// cache_test, which can use the backends package without an import cycle.
+func (s *SyncEngine) NeedsInitialSync() bool {
+	return s.needsInitialSync()
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+// errBackendDown is returned by failingReadBackend.
+var errBackendDown = errors.New("backend unavailable")
+
+// failingReadBackend is a mockBackend whose Get and Keys always fail.
+type failingReadBackend struct {
+	*mockBackend
+}
+
+func (b *failingReadBackend) Get(ctx context.Context, key string) (*Entry, error) {
+	return nil, errBackendDown
+}
+
+func (b *failingReadBackend) Keys(ctx context.Context, pattern string) ([]string, error) {
+	return nil, errBackendDown
+}
+
+func TestCachedLightClient_FailOpen(t *testing.T) {
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	logger := &recordingLogger{}
+	client := NewCachedLightClient(&failingReadBackend{newMockBackend()}, mockSDK, 0)
+	client.configure(&CachedClientConfig{Logger: logger})
+
+	ctx := context.Background()
+	if _, err := client.Get(ctx, "light-1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if _, err := client.List(ctx); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+
+	if mockSDK.calls["Get"] != 1 || mockSDK.calls["List"] != 1 {
+		t.Errorf("SDK calls = %v, want 1 Get and 1 List", mockSDK.calls)
+	}
+	if logger.count("warn") != 2 {
+		t.Errorf("Expected 2 warnings for backend failures, got %d", logger.count("warn"))
+	}
+}
+
+func TestCachedLightClient_FailClosed(t *testing.T) {
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	client := NewCachedLightClient(&failingReadBackend{newMockBackend()}, mockSDK, 0)
+	client.configure(&CachedClientConfig{FailClosed: true})
+
+	ctx := context.Background()
+	if _, err := client.Get(ctx, "light-1"); !errors.Is(err, errBackendDown) {
+		t.Errorf("Get() error = %v, want backend error", err)
+	}
+	if _, err := client.List(ctx); !errors.Is(err, errBackendDown) {
+		t.Errorf("List() error = %v, want backend error", err)
+	}
+
+	if len(mockSDK.calls) != 0 {
+		t.Errorf("SDK calls = %v, want none with a broken cache", mockSDK.calls)
+	}
+}
+
+func TestCachedLightClient_FailClosed_MissFallsBack(t *testing.T) {
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	client := NewCachedLightClient(newMockBackend(), mockSDK, 0)
+	client.configure(&CachedClientConfig{FailClosed: true})
+
+	// A miss is not a backend failure
+	if _, err := client.Get(context.Background(), "light-1"); err != nil {
+		t.Fatalf("Get() on a miss failed: %v", err)
+	}
+	if mockSDK.calls["Get"] != 1 {
+		t.Errorf("SDK Get calls = %d, want 1", mockSDK.calls["Get"])
+	}
+}
+
+func TestCachedClientConfig_FailOpenByDefault(t *testing.T) {
+	// A config literal that doesn't mention FailClosed fails open, like
+	// DefaultCachedClientConfig
+	for name, config := range map[string]*CachedClientConfig{
+		"literal": {},
+		"default": DefaultCachedClientConfig(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockSDK := newMockLightClient()
+			mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+			client := NewCachedLightClient(&failingReadBackend{newMockBackend()}, mockSDK, 0)
+			client.configure(config)
+
+			if _, err := client.Get(context.Background(), "light-1"); err != nil {
+				t.Fatalf("Get() with a failing backend = %v, want SDK fallback", err)
+			}
+			if mockSDK.calls["Get"] != 1 {
+				t.Errorf("SDK Get calls = %d, want 1", mockSDK.calls["Get"])
+			}
+		})
+	}
+}
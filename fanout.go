@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// fanout delivers published values to subscribers without blocking the
+// publisher. Each subscriber has its own buffer and goroutine, so values
+// reach it in order; when its buffer is full, values for it are dropped
+// and counted.
+type fanout[E any] struct {
+	bufferSize int
+
+	mu          sync.RWMutex
+	subscribers map[*subscriber[E]]struct{}
+	closed      bool
+
+	// active is the subscriber count, so publish is free with none.
+	active  atomic.Int32
+	dropped atomic.Int64
+}
+
+// subscriber is one registered handler and its buffer.
+type subscriber[E any] struct {
+	values chan E
+	accept func(E) bool
+}
+
+// newFanout creates a fanout buffering up to bufferSize values per
+// subscriber.
+func newFanout[E any](bufferSize int) *fanout[E] {
+	return &fanout[E]{
+		bufferSize:  bufferSize,
+		subscribers: make(map[*subscriber[E]]struct{}),
+	}
+}
+
+// subscribe registers handler for values accepted by accept (all values
+// if accept is nil) and returns a function that unregisters it.
+func (f *fanout[E]) subscribe(accept func(E) bool, handler func(E)) (unsubscribe func()) {
+	s := &subscriber[E]{values: make(chan E, f.bufferSize), accept: accept}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return func() {}
+	}
+	f.subscribers[s] = struct{}{}
+	f.active.Add(1)
+
+	go func() {
+		for value := range s.values {
+			handler(value)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.remove(s)
+		})
+	}
+}
+
+// hasSubscribers reports whether publish would deliver to anyone.
+func (f *fanout[E]) hasSubscribers() bool {
+	return f.active.Load() > 0
+}
+
+// publish delivers value to every accepting subscriber without blocking.
+func (f *fanout[E]) publish(value E) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for s := range f.subscribers {
+		if s.accept != nil && !s.accept(value) {
+			continue
+		}
+		select {
+		case s.values <- value:
+		default:
+			f.dropped.Add(1)
+		}
+	}
+}
+
+// close unregisters all subscribers. Buffered values are still delivered.
+func (f *fanout[E]) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return
+	}
+	f.closed = true
+	for s := range f.subscribers {
+		f.remove(s)
+	}
+}
+
+// remove unregisters a subscriber. Must be called with mu held.
+func (f *fanout[E]) remove(s *subscriber[E]) {
+	if _, ok := f.subscribers[s]; !ok {
+		return
+	}
+	delete(f.subscribers, s)
+	f.active.Add(-1)
+	close(s.values)
+}
@@ -0,0 +1,265 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AsFS exposes backend as a read-only file system, so debugging tools can
+// browse the cache with the standard fs functions (fs.WalkDir, fs.ReadFile,
+// http.FS, ...). Each key is a file holding the cached value, at the path
+// "type/id" (e.g. "light:abc-123" is "light/abc-123"). Path elements are
+// escaped like URL path segments, so IDs containing "/" stay one element.
+// Keys without a type are files at the root.
+//
+// Entries are read with Peek, so browsing doesn't count as hits or
+// misses. The directory tree is rebuilt from Keys on every call, which
+// makes it always current but costs a full key listing per call; it is
+// meant for inspection, not for serving traffic.
+func AsFS(backend Backend) fs.FS {
+	return &backendFS{backend: backend, keyBuilder: NewKeyBuilder()}
+}
+
+// backendFS implements fs.FS for AsFS.
+type backendFS struct {
+	backend    Backend
+	keyBuilder *KeyBuilder
+}
+
+var (
+	_ fs.ReadDirFS  = (*backendFS)(nil)
+	_ fs.ReadFileFS = (*backendFS)(nil)
+)
+
+// Open opens the file or directory at name.
+func (f *backendFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	paths, err := f.paths()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	if key, ok := paths[name]; ok {
+		entry, err := f.peek(key)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &fsFile{info: entryInfo(path.Base(name), entry), Reader: bytes.NewReader(entry.Value)}, nil
+	}
+
+	entries, ok := f.readDir(paths, name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &fsDir{info: dirInfo(path.Base(name)), path: name, entries: entries}, nil
+}
+
+// ReadFile returns the cached value of the key at name.
+func (f *backendFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+
+	paths, err := f.paths()
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+
+	key, ok := paths[name]
+	if !ok {
+		if _, isDir := f.readDir(paths, name); isDir {
+			return nil, &fs.PathError{Op: "read", Path: name, Err: errIsDir}
+		}
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entry, err := f.peek(key)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return entry.Value, nil
+}
+
+// ReadDir lists the directory at name, sorted by file name.
+func (f *backendFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	paths, err := f.paths()
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	entries, ok := f.readDir(paths, name)
+	if !ok {
+		if _, isFile := paths[name]; isFile {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+		}
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return entries, nil
+}
+
+// errIsDir and errNotDir report reading a directory as a file and vice
+// versa.
+var (
+	errIsDir  = errors.New("is a directory")
+	errNotDir = errors.New("not a directory")
+)
+
+// paths maps the file path of every key in the backend to its key.
+func (f *backendFS) paths() (map[string]string, error) {
+	keys, err := f.backend.Keys(context.Background(), "*")
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if p := f.keyPath(key); fs.ValidPath(p) {
+			paths[p] = key
+		}
+	}
+	return paths, nil
+}
+
+// keyPath returns the file path of key.
+func (f *backendFS) keyPath(key string) string {
+	resourceType, id, ok := f.keyBuilder.ParseKey(key)
+	if !ok {
+		return url.PathEscape(key)
+	}
+	return url.PathEscape(resourceType) + "/" + url.PathEscape(id)
+}
+
+// peek reads the entry under key, reporting misses as fs.ErrNotExist.
+func (f *backendFS) peek(key string) (*Entry, error) {
+	entry, err := Peek(context.Background(), f.backend, key)
+	if isMiss(err) {
+		return nil, fs.ErrNotExist
+	}
+	return entry, err
+}
+
+// readDir lists the files and directories directly under dir, sorted by
+// name. ok is false if dir isn't a directory: it isn't the root and no
+// path is under it. Keys that expire while listing are left out.
+func (f *backendFS) readDir(paths map[string]string, dir string) (entries []fs.DirEntry, ok bool) {
+	prefix := dir + "/"
+	if dir == "." {
+		prefix = ""
+	}
+
+	children := make(map[string]fs.DirEntry)
+	for p, key := range paths {
+		rest, under := strings.CutPrefix(p, prefix)
+		if !under {
+			continue
+		}
+		ok = true
+
+		child, _, nested := strings.Cut(rest, "/")
+		if nested {
+			children[child] = fs.FileInfoToDirEntry(dirInfo(child))
+			continue
+		}
+		if _, isDir := children[child]; isDir {
+			continue
+		}
+		if entry, err := f.peek(key); err == nil {
+			children[child] = fs.FileInfoToDirEntry(entryInfo(child, entry))
+		}
+	}
+
+	entries = make([]fs.DirEntry, 0, len(children))
+	for _, entry := range children {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, ok || dir == "."
+}
+
+// fsInfo describes a file or directory of a backendFS.
+type fsInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+// entryInfo describes the file holding entry.
+func entryInfo(name string, entry *Entry) *fsInfo {
+	return &fsInfo{name: name, size: int64(len(entry.Value)), modTime: entry.UpdatedAt}
+}
+
+// dirInfo describes a directory.
+func dirInfo(name string) *fsInfo {
+	return &fsInfo{name: name, dir: true}
+}
+
+func (i *fsInfo) Name() string       { return i.name }
+func (i *fsInfo) Size() int64        { return i.size }
+func (i *fsInfo) ModTime() time.Time { return i.modTime }
+func (i *fsInfo) IsDir() bool        { return i.dir }
+func (i *fsInfo) Sys() any           { return nil }
+
+func (i *fsInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+// fsFile is an open cache entry.
+type fsFile struct {
+	info *fsInfo
+	*bytes.Reader
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *fsFile) Close() error               { return nil }
+
+// fsDir is an open directory. Its entries are listed when it is opened.
+type fsDir struct {
+	info    *fsInfo
+	path    string
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *fsDir) Close() error               { return nil }
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: errIsDir}
+}
+
+// ReadDir returns the next n entries, or all remaining entries if n <= 0,
+// following fs.ReadDirFile.
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
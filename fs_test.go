@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+// peekingBackend is a mockBackend with a Peeker implementation that
+// counts calls.
+type peekingBackend struct {
+	*mockBackend
+	peeks int
+}
+
+func (p *peekingBackend) Peek(ctx context.Context, key string) (*Entry, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.peeks++
+	entry, ok := p.data[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return entry.Clone(), nil
+}
+
+func TestAsFS(t *testing.T) {
+	backend := &peekingBackend{mockBackend: newMockBackend()}
+	ctx := context.Background()
+	backend.Set(ctx, "light:abc-123", []byte(`{"id":"abc-123"}`), 0)
+	backend.Set(ctx, "light:def-456", []byte(`{"id":"def-456"}`), 0)
+	backend.Set(ctx, "room:a/b", []byte(`{"id":"a/b"}`), 0)
+	backend.Set(ctx, "raw:light:abc-123", []byte(`{}`), 0)
+	backend.Set(ctx, "bare", []byte("x"), 0)
+
+	fsys := AsFS(backend)
+
+	// The file system behaves like any other, including for fs.WalkDir
+	err := fstest.TestFS(fsys, "light/abc-123", "light/def-456", "room/a%2Fb", "raw/light:abc-123", "bare")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var files []string
+	err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDir() failed: %v", err)
+	}
+	if len(files) != 5 {
+		t.Errorf("WalkDir() found %v, want 5 files", files)
+	}
+
+	data, err := fs.ReadFile(fsys, "light/abc-123")
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+	if string(data) != `{"id":"abc-123"}` {
+		t.Errorf("ReadFile() = %s, want the cached value", data)
+	}
+
+	if _, err := fs.ReadFile(fsys, "light/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadFile() of missing key error = %v, want fs.ErrNotExist", err)
+	}
+
+	// Browsing reads with Peek and leaves the statistics alone
+	if backend.peeks == 0 {
+		t.Error("Expected reads through Peek")
+	}
+	if backend.hits != 0 || backend.misses != 0 {
+		t.Errorf("browsing counted %d hits, %d misses; want none", backend.hits, backend.misses)
+	}
+}
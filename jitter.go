@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand/v2"
+	"time"
+)
+
+// ttlJitter randomizes entry TTLs so entries written together don't all
+// expire at the same instant.
+type ttlJitter struct {
+	// fraction is the maximum relative deviation (0.1 = ±10%).
+	fraction float64
+
+	// seed makes the jitter deterministic per key when non-zero.
+	seed uint64
+}
+
+// apply returns ttl adjusted by a random factor in [1-fraction, 1+fraction].
+// A ttl of 0 (no expiration) is returned unchanged, and the result is
+// never less than 1ns, so jitter can't turn an expiring entry into a
+// permanent one.
+func (j ttlJitter) apply(key string, ttl time.Duration) time.Duration {
+	if ttl <= 0 || j.fraction <= 0 {
+		return ttl
+	}
+
+	fraction := j.fraction
+	if fraction > 1 {
+		fraction = 1
+	}
+
+	// r is uniform in [-1, 1)
+	r := 2*j.random(key) - 1
+	jittered := time.Duration(float64(ttl) * (1 + r*fraction))
+	if jittered <= 0 {
+		return 1
+	}
+	return jittered
+}
+
+// random returns a value in [0, 1): derived from the seed and key when a
+// seed is set, otherwise drawn from the global source.
+func (j ttlJitter) random(key string) float64 {
+	if j.seed == 0 {
+		return rand.Float64()
+	}
+
+	h := fnv.New64a()
+	var seed [8]byte
+	binary.LittleEndian.PutUint64(seed[:], j.seed)
+	h.Write(seed[:])
+	h.Write([]byte(key))
+
+	// Use the top 53 bits for a uniformly distributed float64
+	return float64(h.Sum64()>>11) / (1 << 53)
+}
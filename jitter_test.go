@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+func TestTTLJitter_Bounds(t *testing.T) {
+	jitter := ttlJitter{fraction: 0.1}
+	ttl := 10 * time.Minute
+
+	for i := 0; i < 1000; i++ {
+		got := jitter.apply(fmt.Sprintf("light:%d", i), ttl)
+		if got < 9*time.Minute || got > 11*time.Minute {
+			t.Fatalf("apply() = %v, want within ±10%% of %v", got, ttl)
+		}
+	}
+}
+
+func TestTTLJitter_NoTTL(t *testing.T) {
+	jitter := ttlJitter{fraction: 0.5, seed: 42}
+	if got := jitter.apply("light:1", 0); got != 0 {
+		t.Errorf("apply() with no TTL = %v, want 0", got)
+	}
+}
+
+func TestTTLJitter_Disabled(t *testing.T) {
+	jitter := ttlJitter{}
+	if got := jitter.apply("light:1", time.Minute); got != time.Minute {
+		t.Errorf("apply() without jitter = %v, want %v", got, time.Minute)
+	}
+}
+
+func TestTTLJitter_Seeded(t *testing.T) {
+	a := ttlJitter{fraction: 0.2, seed: 42}
+	b := ttlJitter{fraction: 0.2, seed: 42}
+	other := ttlJitter{fraction: 0.2, seed: 7}
+
+	ttl := time.Hour
+	if a.apply("light:1", ttl) != b.apply("light:1", ttl) {
+		t.Error("Same seed and key should give the same TTL")
+	}
+	if a.apply("light:1", ttl) == a.apply("light:2", ttl) {
+		t.Error("Different keys should get different TTLs")
+	}
+	if a.apply("light:1", ttl) == other.apply("light:1", ttl) {
+		t.Error("Different seeds should give different TTLs")
+	}
+}
+
+func TestCachedClient_TTLJitter(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("light-%d", i)
+		mockSDK.lights[id] = &resources.Light{ID: id, Type: "light"}
+	}
+
+	ttl := time.Hour
+	client := NewCachedLightClient(backend, mockSDK, ttl)
+	client.configure(&CachedClientConfig{TTLJitter: 0.1, TTLJitterSeed: 1})
+
+	if _, err := client.List(context.Background()); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+
+	ttls := make(map[time.Duration]bool)
+	for key, entry := range backend.data {
+		if entry.TTL < 54*time.Minute || entry.TTL > 66*time.Minute {
+			t.Errorf("%s TTL = %v, want within ±10%% of %v", key, entry.TTL, ttl)
+		}
+		ttls[entry.TTL] = true
+	}
+	if len(ttls) < 2 {
+		t.Error("Expected jitter to spread entry TTLs")
+	}
+}
+
+func TestWarmConfig_ClientConfigJitter(t *testing.T) {
+	config := &WarmConfig{TTL: time.Hour, TTLJitter: 0.1, TTLJitterSeed: 7}
+
+	clientConfig := config.clientConfig()
+	if clientConfig.TTL != time.Hour || clientConfig.TTLJitter != 0.1 || clientConfig.TTLJitterSeed != 7 {
+		t.Errorf("clientConfig() = %+v, want TTL and jitter settings carried over", clientConfig)
+	}
+}
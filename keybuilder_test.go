@@ -1,7 +1,10 @@
 package cache
 
 import (
+	"context"
 	"testing"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
 )
 
 func TestKeyBuilder_Light(t *testing.T) {
@@ -158,3 +161,164 @@ func TestKeyBuilder_AllResources(t *testing.T) {
 		t.Errorf("AllResources() = %v, want %v", got, want)
 	}
 }
+
+func TestKeyBuilder_ParseKey(t *testing.T) {
+	kb := NewKeyBuilder()
+
+	tests := []struct {
+		key          string
+		resourceType string
+		id           string
+		ok           bool
+	}{
+		{key: "light:abc-123", resourceType: "light", id: "abc-123", ok: true},
+		{key: "grouped_light:gl-1", resourceType: "grouped_light", id: "gl-1", ok: true},
+		{key: "smart_scene:ss-1", resourceType: "smart_scene", id: "ss-1", ok: true},
+		{key: "bridge_home:bh-1", resourceType: "bridge_home", id: "bh-1", ok: true},
+		{key: "light:a:b", resourceType: "light", id: "a:b", ok: true},
+		{key: "", ok: false},
+		{key: "light", ok: false},
+		{key: "light:", ok: false},
+		{key: ":abc", ok: false},
+		{key: ":", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			resourceType, id, ok := kb.ParseKey(tt.key)
+			if resourceType != tt.resourceType || id != tt.id || ok != tt.ok {
+				t.Errorf("ParseKey(%q) = (%q, %q, %v), want (%q, %q, %v)",
+					tt.key, resourceType, id, ok, tt.resourceType, tt.id, tt.ok)
+			}
+		})
+	}
+}
+
+func TestKeyBuilder_ParseKey_RoundTrip(t *testing.T) {
+	kb := NewKeyBuilder()
+
+	keys := []string{
+		kb.GroupedLight("gl-1"),
+		kb.SmartScene("ss-1"),
+		kb.BridgeHome("bh-1"),
+		kb.Resource("device_power", "dp-1"),
+	}
+
+	for _, key := range keys {
+		resourceType, id, ok := kb.ParseKey(key)
+		if !ok {
+			t.Errorf("ParseKey(%q) failed", key)
+			continue
+		}
+		if got := kb.Resource(resourceType, id); got != key {
+			t.Errorf("Resource(ParseKey(%q)) = %q", key, got)
+		}
+	}
+}
+
+func TestKeyBuilder_Prefix(t *testing.T) {
+	kb := NewKeyBuilderWithPrefix("bridge-A")
+
+	tests := []struct {
+		got, want string
+	}{
+		{kb.Light("123"), "bridge-A:light:123"},
+		{kb.Resource("device", "d-1"), "bridge-A:device:d-1"},
+		{kb.Raw(kb.Light("123")), "bridge-A:raw:light:123"},
+		{kb.Version(kb.Light("123")), "bridge-A:version:light:123"},
+		{kb.AllLights(), "bridge-A:light:*"},
+		{kb.All(), "bridge-A:*"},
+		{kb.Prefix(), "bridge-A"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+
+	// ParseKey strips the namespace, and rejects keys outside it
+	resourceType, id, ok := kb.ParseKey("bridge-A:light:123")
+	if resourceType != "light" || id != "123" || !ok {
+		t.Errorf("ParseKey() = (%q, %q, %v), want (light, 123, true)", resourceType, id, ok)
+	}
+	if _, _, ok := kb.ParseKey("bridge-B:light:123"); ok {
+		t.Error("ParseKey() accepted a key from another namespace")
+	}
+
+	// Glob characters in the namespace match literally
+	starred := NewKeyBuilderWithPrefix("env*")
+	if !MatchPattern(starred.AllLights(), starred.Light("1")) {
+		t.Errorf("%q doesn't match %q", starred.AllLights(), starred.Light("1"))
+	}
+	if MatchPattern(starred.AllLights(), "envX:light:1") {
+		t.Errorf("%q matches another namespace", starred.AllLights())
+	}
+
+	if NewKeyBuilderWithPrefix("").Light("1") != NewKeyBuilder().Light("1") {
+		t.Error("an empty prefix changed the keys")
+	}
+}
+
+func TestKeyPrefix_SharedBackend(t *testing.T) {
+	backend := newMockBackend()
+	ctx := context.Background()
+
+	clients := make(map[string]*CachedLightClient)
+	for _, prefix := range []string{"bridge-A", "bridge-B"} {
+		mockSDK := newMockLightClient()
+		mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light", Metadata: resources.Metadata{Name: prefix}}
+		client := NewCachedLightClient(backend, mockSDK, 0)
+		client.configure(&CachedClientConfig{KeyPrefix: prefix})
+		clients[prefix] = client
+	}
+
+	// The same light ID on two bridges doesn't collide
+	for prefix, client := range clients {
+		light, err := client.Get(ctx, "light-1")
+		if err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		if light.Metadata.Name != prefix {
+			t.Errorf("%s client got %s's light", prefix, light.Metadata.Name)
+		}
+	}
+	for prefix, client := range clients {
+		light, _ := client.Get(ctx, "light-1")
+		if light.Metadata.Name != prefix {
+			t.Errorf("%s client got %s's cached light", prefix, light.Metadata.Name)
+		}
+	}
+
+	// Clearing one namespace leaves the other
+	manager := NewCacheManagerWithPrefix(backend, nil, "bridge-A")
+	if err := manager.ClearAll(ctx); err != nil {
+		t.Fatalf("ClearAll() failed: %v", err)
+	}
+	if _, err := backend.Get(ctx, "bridge-A:light:light-1"); err == nil {
+		t.Error("ClearAll() left an entry in its namespace")
+	}
+	if _, err := backend.Get(ctx, "bridge-B:light:light-1"); err != nil {
+		t.Errorf("ClearAll() removed another namespace's entry: %v", err)
+	}
+
+	counts, _ := NewCacheManagerWithPrefix(backend, nil, "bridge-B").CountByType(ctx)
+	if counts.Lights != 1 {
+		t.Errorf("CountByType() in bridge-B = %d lights, want 1", counts.Lights)
+	}
+}
+
+func TestKeyPrefix_Sync(t *testing.T) {
+	backend := newMockBackend()
+	config := DefaultSyncConfig()
+	config.KeyPrefix = "bridge-A"
+	engine := NewSyncEngine(backend, nil, config)
+
+	data := &resources.EventData{ID: "light-1", Type: "light", RawData: []byte(`{"id":"light-1"}`)}
+	if err := engine.processEventData(context.Background(), resources.EventTypeAdd, data); err != nil {
+		t.Fatalf("processEventData() failed: %v", err)
+	}
+
+	if _, err := backend.Get(context.Background(), "bridge-A:light:light-1"); err != nil {
+		t.Errorf("synced resource not under the namespace: %v", err)
+	}
+}
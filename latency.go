@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// latencyWindowSize is the number of recent samples a latencyWindow keeps.
+const latencyWindowSize = 1024
+
+// latencyWindow is a fixed-size ring buffer of the most recent latency
+// samples, used to compute percentiles in bounded memory. The zero value
+// is ready to use. It is not safe for concurrent use.
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+}
+
+// add records a latency sample, overwriting the oldest once full.
+func (w *latencyWindow) add(latency time.Duration) {
+	if len(w.samples) < latencyWindowSize {
+		w.samples = append(w.samples, latency)
+		return
+	}
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % latencyWindowSize
+}
+
+// percentiles returns the nearest-rank percentile of the recorded samples
+// for each p in (0, 1], e.g. 0.95 for p95. All results are 0 when no
+// samples have been recorded.
+func (w *latencyWindow) percentiles(ps ...float64) []time.Duration {
+	results := make([]time.Duration, len(ps))
+	if len(w.samples) == 0 {
+		return results
+	}
+
+	sorted := slices.Clone(w.samples)
+	slices.Sort(sorted)
+
+	for i, p := range ps {
+		rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+		results[i] = sorted[max(0, min(rank, len(sorted)-1))]
+	}
+	return results
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyWindow_Percentiles(t *testing.T) {
+	var w latencyWindow
+
+	if got := w.percentiles(0.5); got[0] != 0 {
+		t.Errorf("empty window p50 = %v, want 0", got[0])
+	}
+
+	// 1ms..100ms, added out of order
+	for i := 100; i >= 1; i-- {
+		w.add(time.Duration(i) * time.Millisecond)
+	}
+
+	got := w.percentiles(0.50, 0.95, 0.99, 1)
+	want := []time.Duration{50 * time.Millisecond, 95 * time.Millisecond, 99 * time.Millisecond, 100 * time.Millisecond}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("percentiles()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLatencyWindow_Bounded(t *testing.T) {
+	var w latencyWindow
+
+	// A burst of slow events followed by a full window of fast ones
+	for i := 0; i < latencyWindowSize; i++ {
+		w.add(time.Second)
+	}
+	for i := 0; i < latencyWindowSize; i++ {
+		w.add(time.Millisecond)
+	}
+
+	if len(w.samples) != latencyWindowSize {
+		t.Errorf("len(samples) = %d, want %d", len(w.samples), latencyWindowSize)
+	}
+	if got := w.percentiles(0.99)[0]; got != time.Millisecond {
+		t.Errorf("p99 = %v, want %v (old samples should be overwritten)", got, time.Millisecond)
+	}
+}
+
+func TestSyncStats_ClonePercentiles(t *testing.T) {
+	stats := &SyncStats{}
+	for i := 1; i <= 100; i++ {
+		stats.latencies.add(time.Duration(i) * time.Millisecond)
+	}
+
+	clone := stats.Clone()
+	if clone.P50Latency != 50*time.Millisecond || clone.P95Latency != 95*time.Millisecond || clone.P99Latency != 99*time.Millisecond {
+		t.Errorf("percentiles = %v/%v/%v, want 50ms/95ms/99ms", clone.P50Latency, clone.P95Latency, clone.P99Latency)
+	}
+
+	// Cloning a clone keeps its percentiles
+	again := clone.Clone()
+	if again.P99Latency != clone.P99Latency {
+		t.Errorf("clone of clone P99Latency = %v, want %v", again.P99Latency, clone.P99Latency)
+	}
+}
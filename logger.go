@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Logger is a minimal structured logging interface used throughout the cache.
+// Each method takes a message followed by alternating key-value pairs, e.g.
+//
+//	logger.Warn("failed to cache resource", "key", key, "error", err)
+//
+// *slog.Logger satisfies this interface directly; NewSlogLogger is provided
+// for convenience and nil-safety.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// NopLogger returns a Logger that discards all messages.
+// It is the default when no logger is configured.
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+// SlogLogger adapts a *slog.Logger to the Logger interface.
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger creates a Logger backed by log/slog.
+// If logger is nil, slog.Default() is used.
+//
+// Example:
+//
+//	config := cache.DefaultCachedClientConfig()
+//	config.Logger = cache.NewSlogLogger(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogLogger{logger: logger}
+}
+
+// Debug logs at slog.LevelDebug.
+func (l *SlogLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelDebug, msg, keysAndValues...)
+}
+
+// Info logs at slog.LevelInfo.
+func (l *SlogLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelInfo, msg, keysAndValues...)
+}
+
+// Warn logs at slog.LevelWarn.
+func (l *SlogLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelWarn, msg, keysAndValues...)
+}
+
+// Error logs at slog.LevelError.
+func (l *SlogLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelError, msg, keysAndValues...)
+}
+
+// loggerOrNop returns logger, or a no-op logger if logger is nil.
+func loggerOrNop(logger Logger) Logger {
+	if logger == nil {
+		return nopLogger{}
+	}
+	return logger
+}
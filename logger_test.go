@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+// logRecord is a single message captured by recordingLogger.
+type logRecord struct {
+	level string
+	msg   string
+	kv    []interface{}
+}
+
+// recordingLogger captures log messages for assertions.
+type recordingLogger struct {
+	mu      sync.Mutex
+	records []logRecord
+}
+
+func (l *recordingLogger) log(level, msg string, kv []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, logRecord{level: level, msg: msg, kv: kv})
+}
+
+func (l *recordingLogger) Debug(msg string, kv ...interface{}) { l.log("debug", msg, kv) }
+func (l *recordingLogger) Info(msg string, kv ...interface{})  { l.log("info", msg, kv) }
+func (l *recordingLogger) Warn(msg string, kv ...interface{})  { l.log("warn", msg, kv) }
+func (l *recordingLogger) Error(msg string, kv ...interface{}) { l.log("error", msg, kv) }
+
+func (l *recordingLogger) count(level string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, r := range l.records {
+		if r.level == level {
+			n++
+		}
+	}
+	return n
+}
+
+// failingSetBackend is a mockBackend whose Set and Delete always fail.
+type failingSetBackend struct {
+	*mockBackend
+}
+
+func (b *failingSetBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errors.New("backend unavailable")
+}
+
+func (b *failingSetBackend) Delete(ctx context.Context, key string) error {
+	return errors.New("backend unavailable")
+}
+
+func TestNopLogger(t *testing.T) {
+	logger := NopLogger()
+
+	// Should not panic
+	logger.Debug("debug", "key", "value")
+	logger.Info("info")
+	logger.Warn("warn", "count", 1)
+	logger.Error("error", "error", errors.New("boom"))
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	logger := NewSlogLogger(slog.New(handler))
+
+	logger.Debug("debug message", "key", "light:1")
+	logger.Info("info message")
+	logger.Warn("warn message")
+	logger.Error("error message", "error", "boom")
+
+	out := buf.String()
+	for _, want := range []string{
+		"level=DEBUG msg=\"debug message\" key=light:1",
+		"level=INFO msg=\"info message\"",
+		"level=WARN msg=\"warn message\"",
+		"level=ERROR msg=\"error message\" error=boom",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q\ngot: %s", want, out)
+		}
+	}
+}
+
+func TestSlogLogger_NilUsesDefault(t *testing.T) {
+	logger := NewSlogLogger(nil)
+	if logger.logger != slog.Default() {
+		t.Error("NewSlogLogger(nil) should use slog.Default()")
+	}
+}
+
+func TestCachedLightClient_LogsPopulateFailure(t *testing.T) {
+	backend := &failingSetBackend{newMockBackend()}
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	logger := &recordingLogger{}
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	client.configure(&CachedClientConfig{Logger: logger})
+
+	// Get should still succeed even though the cache can't be populated
+	if _, err := client.Get(context.Background(), "light-1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if logger.count("warn") != 1 {
+		t.Errorf("Expected 1 warning after failed populate, got %d", logger.count("warn"))
+	}
+
+	// Update should still succeed even though invalidation fails
+	if err := client.Update(context.Background(), "light-1", resources.LightUpdate{}); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+
+	if logger.count("warn") != 2 {
+		t.Errorf("Expected 2 warnings after failed invalidation, got %d", logger.count("warn"))
+	}
+}
+
+func TestSyncEngine_LogsErrors(t *testing.T) {
+	logger := &recordingLogger{}
+	engine := &SyncEngine{
+		backend:    newMockBackend(),
+		keyBuilder: NewKeyBuilder(),
+		stats:      &SyncStats{},
+		config:     &SyncConfig{Logger: logger},
+	}
+
+	engine.handleError(errors.New("test error"))
+
+	if logger.count("error") != 1 {
+		t.Errorf("Expected 1 error log, got %d", logger.count("error"))
+	}
+}
@@ -1,8 +1,12 @@
 package cache
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,6 +21,12 @@ type CacheManager struct {
 	client     *hue.Client
 	keyBuilder *KeyBuilder
 	mu         sync.RWMutex
+
+	// loads shares GetOrFetch loads among concurrent callers
+	loads flightGroup
+
+	// codec serializes the resources the manager caches and reads
+	codec Codec
 }
 
 // NewCacheManager creates a new cache manager.
@@ -25,19 +35,52 @@ func NewCacheManager(backend Backend, client *hue.Client) *CacheManager {
 		backend:    backend,
 		client:     client,
 		keyBuilder: NewKeyBuilder(),
+		codec:      JSONCodec(),
 	}
 }
 
-// ClearAll clears all entries from the cache.
+// NewCacheManagerWithPrefix creates a cache manager for the keys in one
+// namespace (see NewKeyBuilderWithPrefix). Its clears, warms, and counts
+// only touch keys under prefix.
+func NewCacheManagerWithPrefix(backend Backend, client *hue.Client, prefix string) *CacheManager {
+	m := NewCacheManager(backend, client)
+	m.keyBuilder = NewKeyBuilderWithPrefix(prefix)
+	return m
+}
+
+// SetCodec sets the codec the manager warms, reconciles, and reads
+// resources with (see CachedClientConfig.Codec). Call it before using the
+// manager.
+// Default: JSONCodec()
+func (m *CacheManager) SetCodec(codec Codec) {
+	m.codec = codecOrJSON(codec)
+}
+
+// ClearAll clears all entries from the cache. With a namespace, only the
+// entries in the namespace are cleared.
 func (m *CacheManager) ClearAll(ctx context.Context) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	return m.clear(ctx)
+}
+
+// clear clears the manager's namespace, or the whole backend without one.
+// Must be called with mu held.
+func (m *CacheManager) clear(ctx context.Context) error {
+	if m.keyBuilder.Prefix() != "" {
+		_, err := DeletePattern(ctx, m.backend, m.keyBuilder.All())
+		return err
+	}
 	return m.backend.Clear(ctx)
 }
 
 // ClearPattern clears all entries matching the pattern.
 // Pattern uses glob syntax: * matches any sequence of characters.
+// If the backend implements PatternDeleter, the clear is a single atomic
+// operation; otherwise keys are deleted one by one (see DeletePattern).
+// If the backend can't list keys, "*" clears the whole backend and any
+// other pattern returns ErrUnsupported.
 //
 // Examples:
 //   - "light:*" - clear all lights
@@ -47,19 +90,24 @@ func (m *CacheManager) ClearPattern(ctx context.Context, pattern string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	keys, err := m.backend.Keys(ctx, pattern)
-	if err != nil {
-		return fmt.Errorf("getting keys for pattern %q: %w", pattern, err)
+	_, err := DeletePattern(ctx, m.backend, pattern)
+	if errors.Is(err, ErrUnsupported) && pattern == "*" {
+		return m.backend.Clear(ctx)
 	}
+	return err
+}
 
-	for _, key := range keys {
-		if err := m.backend.Delete(ctx, key); err != nil {
-			// Continue on error, try to delete as many as possible
-			continue
-		}
-	}
+// ClearTag clears all entries tagged with tag (see SetWithTags). Tags cut
+// across resource types, and aren't namespaced by the key prefix: entries
+// of other managers sharing the backend with the same tag are cleared too.
+// If the backend implements TagDeleter, the clear is a single atomic
+// operation; otherwise keys are scanned and deleted one by one.
+func (m *CacheManager) ClearTag(ctx context.Context, tag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	return nil
+	_, err := DeleteTag(ctx, m.backend, tag)
+	return err
 }
 
 // ClearLights clears all light entries from the cache.
@@ -114,10 +162,62 @@ type WarmConfig struct {
 	// Set to 0 for no expiration (rely on SSE sync).
 	TTL time.Duration
 
+	// TTLJitter randomizes each warmed entry's TTL by up to this fraction
+	// in either direction (0.1 = ±10%), so warmed entries don't all expire
+	// at once and cause a burst of bridge requests. Jitter only applies
+	// when TTL > 0.
+	TTLJitter float64
+
+	// TTLJitterSeed makes jitter deterministic per key when non-zero.
+	TTLJitterSeed uint64
+
+	// ItemConcurrency is the maximum number of items of one resource type
+	// fetched concurrently while warming. Warming loads each listed item
+	// individually, so large types (e.g. scenes) benefit from parallelism;
+	// types themselves are always warmed concurrently. Keep it small so
+	// warming doesn't overwhelm the bridge. Values below 1 warm items one
+	// at a time. Items that fail are listed in WarmStats.Failed.
+	// Default: 4
+	ItemConcurrency int
+
+	// ReferencedOnly limits warmed lights and grouped lights to those
+	// referenced by a scene: lights a scene targets, and grouped lights
+	// of the rooms and zones scenes belong to or target. Scenes are listed
+	// first to find the references; if that fails, OnError is called with
+	// "scene_references" and no lights or grouped lights are warmed.
+	// Unreferenced resources are still cached on demand.
+	// Default: false
+	ReferencedOnly bool
+
+	// Capabilities, if set, is asked which resource types the bridge
+	// supports before warming. Enabled types the bridge doesn't support
+	// are listed in WarmStats.Skipped instead of failing with an error.
+	// If the probe itself fails, OnError is called with "capabilities"
+	// and every enabled type is warmed.
+	Capabilities CapabilityProber
+
 	// OnError is called when warming fails for a resource type.
 	OnError func(resourceType string, err error)
 }
 
+// CapabilityProber reports which resource types a bridge supports, using
+// the Hue API type names ("light", "room", "zone", "scene",
+// "grouped_light").
+type CapabilityProber interface {
+	SupportedResourceTypes(ctx context.Context) ([]string, error)
+}
+
+// CapabilityProberFunc adapts a function to the CapabilityProber interface.
+type CapabilityProberFunc func(ctx context.Context) ([]string, error)
+
+// SupportedResourceTypes calls f(ctx).
+func (f CapabilityProberFunc) SupportedResourceTypes(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// defaultWarmItemConcurrency is the default WarmConfig.ItemConcurrency.
+const defaultWarmItemConcurrency = 4
+
 // DefaultWarmConfig returns default warming configuration.
 // Warms all resource types with no TTL (rely on SSE).
 func DefaultWarmConfig() *WarmConfig {
@@ -128,6 +228,7 @@ func DefaultWarmConfig() *WarmConfig {
 		WarmScenes:        true,
 		WarmGroupedLights: true,
 		TTL:               0,
+		ItemConcurrency:   defaultWarmItemConcurrency,
 		OnError: func(resourceType string, err error) {
 			// Default: silent failure (cache warming is best-effort)
 		},
@@ -153,17 +254,50 @@ func (m *CacheManager) WarmCache(ctx context.Context, config *WarmConfig) (*Warm
 		StartTime: time.Now(),
 	}
 
+	supported := m.probeCapabilities(ctx, config)
+
+	// shouldWarm reports whether an enabled resource type should be
+	// warmed, recording it as skipped if the bridge doesn't support it.
+	shouldWarm := func(enabled bool, resourceType string) bool {
+		if !enabled {
+			return false
+		}
+		if supported != nil && !supported[resourceType] {
+			stats.Skipped = append(stats.Skipped, resourceType)
+			return false
+		}
+		return true
+	}
+
+	warmLights := shouldWarm(config.WarmLights, "light")
+	warmGroupedLights := shouldWarm(config.WarmGroupedLights, "grouped_light")
+
+	// With ReferencedOnly, refs holds the IDs scenes refer to
+	var refs map[string]bool
+	if config.ReferencedOnly && (warmLights || warmGroupedLights) {
+		var err error
+		refs, err = sceneReferences(ctx, m.client.Scenes())
+		if err != nil {
+			stats.Errors = append(stats.Errors, fmt.Errorf("scene references: %w", err))
+			if config.OnError != nil {
+				config.OnError("scene_references", err)
+			}
+			warmLights, warmGroupedLights = false, false
+		}
+	}
+
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 
 	// Warm lights
-	if config.WarmLights {
+	if warmLights {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			count, err := m.warmLights(ctx, config.TTL)
+			count, failed, err := m.warmLights(ctx, config, m.client.Lights(), refs)
 			mu.Lock()
 			stats.LightsWarmed = count
+			stats.Failed = append(stats.Failed, failed...)
 			if err != nil {
 				stats.Errors = append(stats.Errors, fmt.Errorf("lights: %w", err))
 				if config.OnError != nil {
@@ -175,13 +309,14 @@ func (m *CacheManager) WarmCache(ctx context.Context, config *WarmConfig) (*Warm
 	}
 
 	// Warm rooms
-	if config.WarmRooms {
+	if shouldWarm(config.WarmRooms, "room") {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			count, err := m.warmRooms(ctx, config.TTL)
+			count, failed, err := m.warmRooms(ctx, config)
 			mu.Lock()
 			stats.RoomsWarmed = count
+			stats.Failed = append(stats.Failed, failed...)
 			if err != nil {
 				stats.Errors = append(stats.Errors, fmt.Errorf("rooms: %w", err))
 				if config.OnError != nil {
@@ -193,13 +328,14 @@ func (m *CacheManager) WarmCache(ctx context.Context, config *WarmConfig) (*Warm
 	}
 
 	// Warm zones
-	if config.WarmZones {
+	if shouldWarm(config.WarmZones, "zone") {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			count, err := m.warmZones(ctx, config.TTL)
+			count, failed, err := m.warmZones(ctx, config)
 			mu.Lock()
 			stats.ZonesWarmed = count
+			stats.Failed = append(stats.Failed, failed...)
 			if err != nil {
 				stats.Errors = append(stats.Errors, fmt.Errorf("zones: %w", err))
 				if config.OnError != nil {
@@ -211,13 +347,14 @@ func (m *CacheManager) WarmCache(ctx context.Context, config *WarmConfig) (*Warm
 	}
 
 	// Warm scenes
-	if config.WarmScenes {
+	if shouldWarm(config.WarmScenes, "scene") {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			count, err := m.warmScenes(ctx, config.TTL)
+			count, failed, err := m.warmScenes(ctx, config)
 			mu.Lock()
 			stats.ScenesWarmed = count
+			stats.Failed = append(stats.Failed, failed...)
 			if err != nil {
 				stats.Errors = append(stats.Errors, fmt.Errorf("scenes: %w", err))
 				if config.OnError != nil {
@@ -229,13 +366,14 @@ func (m *CacheManager) WarmCache(ctx context.Context, config *WarmConfig) (*Warm
 	}
 
 	// Warm grouped lights
-	if config.WarmGroupedLights {
+	if warmGroupedLights {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			count, err := m.warmGroupedLights(ctx, config.TTL)
+			count, failed, err := m.warmGroupedLights(ctx, config, m.client.GroupedLights(), refs)
 			mu.Lock()
 			stats.GroupedLightsWarmed = count
+			stats.Failed = append(stats.Failed, failed...)
 			if err != nil {
 				stats.Errors = append(stats.Errors, fmt.Errorf("grouped_lights: %w", err))
 				if config.OnError != nil {
@@ -248,6 +386,11 @@ func (m *CacheManager) WarmCache(ctx context.Context, config *WarmConfig) (*Warm
 
 	wg.Wait()
 
+	// Types finish in any order
+	slices.SortFunc(stats.Failed, func(a, b WarmFailure) int {
+		return cmp.Or(cmp.Compare(a.ResourceType, b.ResourceType), cmp.Compare(a.ID, b.ID))
+	})
+
 	stats.Duration = time.Since(stats.StartTime)
 	stats.TotalWarmed = stats.LightsWarmed + stats.RoomsWarmed +
 		stats.ZonesWarmed + stats.ScenesWarmed + stats.GroupedLightsWarmed
@@ -255,6 +398,46 @@ func (m *CacheManager) WarmCache(ctx context.Context, config *WarmConfig) (*Warm
 	return stats, nil
 }
 
+// probeCapabilities returns the set of resource types the bridge supports,
+// or nil if no probe is configured or it fails (warm everything).
+func (m *CacheManager) probeCapabilities(ctx context.Context, config *WarmConfig) map[string]bool {
+	if config.Capabilities == nil {
+		return nil
+	}
+
+	types, err := config.Capabilities.SupportedResourceTypes(ctx)
+	if err != nil {
+		if config.OnError != nil {
+			config.OnError("capabilities", err)
+		}
+		return nil
+	}
+
+	supported := make(map[string]bool, len(types))
+	for _, resourceType := range types {
+		supported[resourceType] = true
+	}
+	return supported
+}
+
+// clientConfig returns the cached client settings used to warm entries.
+func (c *WarmConfig) clientConfig() *CachedClientConfig {
+	return &CachedClientConfig{
+		TTL:           c.TTL,
+		TTLJitter:     c.TTLJitter,
+		TTLJitterSeed: c.TTLJitterSeed,
+	}
+}
+
+// warmClientConfig returns the cached client settings used to warm
+// entries under the manager's namespace.
+func (m *CacheManager) warmClientConfig(config *WarmConfig) *CachedClientConfig {
+	clientConfig := config.clientConfig()
+	clientConfig.KeyPrefix = m.keyBuilder.Prefix()
+	clientConfig.Codec = m.codec
+	return clientConfig
+}
+
 // WarmStats contains statistics about cache warming operations.
 type WarmStats struct {
 	StartTime           time.Time
@@ -265,83 +448,238 @@ type WarmStats struct {
 	ScenesWarmed        int
 	GroupedLightsWarmed int
 	TotalWarmed         int
-	Errors              []error
+
+	// Errors holds one error per resource type that couldn't be listed.
+	Errors []error
+
+	// Failed lists the individual resources that were listed but
+	// couldn't be fetched, sorted by type and ID. They aren't counted as
+	// warmed.
+	Failed []WarmFailure
+
+	// Skipped lists enabled resource types that weren't warmed because
+	// WarmConfig.Capabilities reported the bridge doesn't support them.
+	Skipped []string
 }
 
-// warmLights populates the cache with all lights from the bridge.
-func (m *CacheManager) warmLights(ctx context.Context, ttl time.Duration) (int, error) {
-	lights, err := m.client.Lights().List(ctx)
+// WarmFailure records a resource that failed to warm.
+type WarmFailure struct {
+	// ResourceType is the Hue API type name (e.g. "light", "scene").
+	ResourceType string
+
+	// ID is the resource ID.
+	ID string
+
+	// Err is why fetching it failed; ctx.Err() for items not fetched
+	// because the context was done.
+	Err error
+}
+
+// warmLights populates the cache with lights from sdk: all of them, or
+// only those in refs if it is non-nil.
+func (m *CacheManager) warmLights(ctx context.Context, config *WarmConfig, sdk hue.LightClient, refs map[string]bool) (int, []WarmFailure, error) {
+	lights, err := sdk.List(ctx)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 
-	cached := NewCachedLightClient(m.backend, m.client.Lights(), ttl)
-	for _, light := range lights {
-		// Use Get to populate cache (which handles serialization)
-		_, _ = cached.Get(ctx, light.ID)
+	cached := NewCachedLightClient(m.backend, sdk, config.TTL)
+	cached.configure(m.warmClientConfig(config))
+	ids := make([]string, 0, len(lights))
+	for i := range lights {
+		if refs == nil || refs[lights[i].ID] {
+			ids = append(ids, lights[i].ID)
+		}
 	}
+	// Use Get to populate cache (which handles serialization)
+	warmed, failed := warmItems(ctx, "light", ids, config.ItemConcurrency, func(ctx context.Context, id string) error {
+		_, err := cached.Get(ctx, id)
+		return err
+	})
 
-	return len(lights), nil
+	return warmed, failed, nil
 }
 
 // warmRooms populates the cache with all rooms from the bridge.
-func (m *CacheManager) warmRooms(ctx context.Context, ttl time.Duration) (int, error) {
+func (m *CacheManager) warmRooms(ctx context.Context, config *WarmConfig) (int, []WarmFailure, error) {
 	rooms, err := m.client.Rooms().List(ctx)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 
-	cached := NewCachedRoomClient(m.backend, m.client.Rooms(), ttl)
-	for _, room := range rooms {
-		_, _ = cached.Get(ctx, room.ID)
+	cached := NewCachedRoomClient(m.backend, m.client.Rooms(), config.TTL)
+	cached.configure(m.warmClientConfig(config))
+	ids := make([]string, len(rooms))
+	for i := range rooms {
+		ids[i] = rooms[i].ID
 	}
+	warmed, failed := warmItems(ctx, "room", ids, config.ItemConcurrency, func(ctx context.Context, id string) error {
+		_, err := cached.Get(ctx, id)
+		return err
+	})
 
-	return len(rooms), nil
+	return warmed, failed, nil
 }
 
 // warmZones populates the cache with all zones from the bridge.
-func (m *CacheManager) warmZones(ctx context.Context, ttl time.Duration) (int, error) {
+func (m *CacheManager) warmZones(ctx context.Context, config *WarmConfig) (int, []WarmFailure, error) {
 	zones, err := m.client.Zones().List(ctx)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 
-	cached := NewCachedZoneClient(m.backend, m.client.Zones(), ttl)
-	for _, zone := range zones {
-		_, _ = cached.Get(ctx, zone.ID)
+	cached := NewCachedZoneClient(m.backend, m.client.Zones(), config.TTL)
+	cached.configure(m.warmClientConfig(config))
+	ids := make([]string, len(zones))
+	for i := range zones {
+		ids[i] = zones[i].ID
 	}
+	warmed, failed := warmItems(ctx, "zone", ids, config.ItemConcurrency, func(ctx context.Context, id string) error {
+		_, err := cached.Get(ctx, id)
+		return err
+	})
 
-	return len(zones), nil
+	return warmed, failed, nil
 }
 
 // warmScenes populates the cache with all scenes from the bridge.
-func (m *CacheManager) warmScenes(ctx context.Context, ttl time.Duration) (int, error) {
+func (m *CacheManager) warmScenes(ctx context.Context, config *WarmConfig) (int, []WarmFailure, error) {
 	scenes, err := m.client.Scenes().List(ctx)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 
-	cached := NewCachedSceneClient(m.backend, m.client.Scenes(), ttl)
-	for _, scene := range scenes {
-		_, _ = cached.Get(ctx, scene.ID)
+	cached := NewCachedSceneClient(m.backend, m.client.Scenes(), config.TTL)
+	cached.configure(m.warmClientConfig(config))
+	ids := make([]string, len(scenes))
+	for i := range scenes {
+		ids[i] = scenes[i].ID
 	}
+	warmed, failed := warmItems(ctx, "scene", ids, config.ItemConcurrency, func(ctx context.Context, id string) error {
+		_, err := cached.Get(ctx, id)
+		return err
+	})
 
-	return len(scenes), nil
+	return warmed, failed, nil
 }
 
-// warmGroupedLights populates the cache with all grouped lights from the bridge.
-func (m *CacheManager) warmGroupedLights(ctx context.Context, ttl time.Duration) (int, error) {
-	groupedLights, err := m.client.GroupedLights().List(ctx)
+// warmGroupedLights populates the cache with grouped lights from sdk: all
+// of them, or only those referenced by refs if it is non-nil. A grouped
+// light is referenced by its own ID or by its owner's.
+func (m *CacheManager) warmGroupedLights(ctx context.Context, config *WarmConfig, sdk hue.GroupedLightClient, refs map[string]bool) (int, []WarmFailure, error) {
+	groupedLights, err := sdk.List(ctx)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
+	}
+
+	cached := NewCachedGroupedLightClient(m.backend, sdk, config.TTL)
+	cached.configure(m.warmClientConfig(config))
+	ids := make([]string, 0, len(groupedLights))
+	for i := range groupedLights {
+		if refs == nil || refs[groupedLights[i].ID] || referencesAny(groupedLights[i], refs) {
+			ids = append(ids, groupedLights[i].ID)
+		}
+	}
+	warmed, failed := warmItems(ctx, "grouped_light", ids, config.ItemConcurrency, func(ctx context.Context, id string) error {
+		_, err := cached.Get(ctx, id)
+		return err
+	})
+
+	return warmed, failed, nil
+}
+
+// warmItems calls get for each id of resourceType, with at most
+// concurrency calls in flight, and returns how many succeeded and the
+// failures sorted by ID. It returns once every call has finished. When ctx
+// is done it stops starting new calls, and the ids not started fail with
+// ctx.Err().
+func warmItems(ctx context.Context, resourceType string, ids []string, concurrency int, get func(ctx context.Context, id string) error) (int, []WarmFailure) {
+	if concurrency < 1 {
+		concurrency = 1
 	}
 
-	cached := NewCachedGroupedLightClient(m.backend, m.client.GroupedLights(), ttl)
-	for _, gl := range groupedLights {
-		_, _ = cached.Get(ctx, gl.ID)
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		warmed int
+		failed []WarmFailure
+	)
+	fail := func(id string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failed = append(failed, WarmFailure{ResourceType: resourceType, ID: id, Err: err})
 	}
 
-	return len(groupedLights), nil
+	sem := make(chan struct{}, concurrency)
+	for i, id := range ids {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			for _, id := range ids[i:] {
+				fail(id, err)
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := get(ctx, id); err != nil {
+				fail(id, err)
+				return
+			}
+			mu.Lock()
+			warmed++
+			mu.Unlock()
+		}(id)
+	}
+
+	wg.Wait()
+
+	slices.SortFunc(failed, func(a, b WarmFailure) int { return cmp.Compare(a.ID, b.ID) })
+	return warmed, failed
+}
+
+// GetOrFetch returns the value cached under key, or on a miss calls
+// loader, caches its result for ttl (0 = no expiration), and returns it.
+// It brings the cached clients' read-through behavior to resources they
+// don't cover, such as sensors or buttons. Concurrent misses for the same
+// key share one loader call. Loader errors are returned and not cached.
+// If storing the loaded value fails, the value is still returned, and the
+// next call loads again. With a namespace (see NewCacheManagerWithPrefix),
+// key is stored inside it.
+//
+// Example:
+//
+//	data, err := manager.GetOrFetch(ctx, "sensor:"+id, time.Minute, func(ctx context.Context) ([]byte, error) {
+//	    sensor, err := fetchSensor(ctx, id)
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return json.Marshal(sensor)
+//	})
+func (m *CacheManager) GetOrFetch(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	key = m.keyBuilder.prefix + key
+
+	entry, err := m.backend.Get(ctx, key)
+	if err == nil {
+		return entry.Value, nil
+	}
+	if !isMiss(err) {
+		return nil, NewError("GetOrFetch", key, err)
+	}
+
+	return m.loads.do(ctx, key, func(ctx context.Context) ([]byte, error) {
+		value, err := loader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		_ = m.backend.Set(ctx, key, value, ttl)
+		return value, nil
+	})
 }
 
 // GetStats returns current cache statistics.
@@ -349,33 +687,114 @@ func (m *CacheManager) GetStats(ctx context.Context) (*Stats, error) {
 	return m.backend.Stats(ctx)
 }
 
-// CountByType returns the number of cached entries by resource type.
-func (m *CacheManager) CountByType(ctx context.Context) (*TypeCounts, error) {
-	counts := &TypeCounts{}
+// Ping reports whether the backend is functioning, for readiness probes
+// such as an HTTP /healthz handler. See Pinger.
+func (m *CacheManager) Ping(ctx context.Context) error {
+	return Ping(ctx, m.backend)
+}
+
+// ResetStats resets the backend's hit, miss, eviction, and error
+// counters while leaving cached entries intact. Call it after WarmCache
+// so the hit rate reflects steady-state traffic. Returns an error
+// wrapping errors.ErrUnsupported if the backend isn't a StatsResetter.
+func (m *CacheManager) ResetStats(ctx context.Context) error {
+	return ResetStats(ctx, m.backend)
+}
+
+// Entries returns the key and metadata (age, size, hits, TTL) of every
+// entry matching pattern, for admin and debugging endpoints. Backends
+// implementing EntryLister answer in one pass. See ListEntries.
+func (m *CacheManager) Entries(ctx context.Context, pattern string) ([]*EntryInfo, error) {
+	return ListEntries(ctx, m.backend, pattern)
+}
 
-	// Count lights
-	if keys, err := m.backend.Keys(ctx, m.keyBuilder.AllLights()); err == nil {
-		counts.Lights = len(keys)
+// ListKeys returns the cached keys of resourceType (e.g. "light"), or of
+// every type if resourceType is "", in a stable order for admin views and
+// pagination: by type, then by ID, with runs of digits in IDs compared
+// numerically ("light:2" before "light:10"). Keys are returned as stored,
+// including any namespace prefix.
+func (m *CacheManager) ListKeys(ctx context.Context, resourceType string) ([]string, error) {
+	pattern := m.keyBuilder.All()
+	if resourceType != "" {
+		pattern = m.keyBuilder.AllResources(resourceType)
 	}
 
-	// Count rooms
-	if keys, err := m.backend.Keys(ctx, m.keyBuilder.AllRooms()); err == nil {
-		counts.Rooms = len(keys)
+	keys, err := m.backend.Keys(ctx, pattern)
+	if err != nil {
+		return nil, err
 	}
 
-	// Count zones
-	if keys, err := m.backend.Keys(ctx, m.keyBuilder.AllZones()); err == nil {
-		counts.Zones = len(keys)
+	slices.SortFunc(keys, func(a, b string) int {
+		typeA, idA, _ := m.keyBuilder.ParseKey(a)
+		typeB, idB, _ := m.keyBuilder.ParseKey(b)
+		return cmp.Or(cmp.Compare(typeA, typeB), compareNatural(idA, idB), cmp.Compare(a, b))
+	})
+	return keys, nil
+}
+
+// compareNatural compares a and b lexically, except that runs of digits
+// are compared by their numeric value.
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			runA, restA := cutDigits(a)
+			runB, restB := cutDigits(b)
+			trimmedA := strings.TrimLeft(runA, "0")
+			trimmedB := strings.TrimLeft(runB, "0")
+			// More significant digits is a larger number
+			if c := cmp.Or(cmp.Compare(len(trimmedA), len(trimmedB)), cmp.Compare(trimmedA, trimmedB)); c != 0 {
+				return c
+			}
+			a, b = restA, restB
+			continue
+		}
+		if c := cmp.Compare(a[0], b[0]); c != 0 {
+			return c
+		}
+		a, b = a[1:], b[1:]
 	}
+	return cmp.Compare(len(a), len(b))
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
 
-	// Count scenes
-	if keys, err := m.backend.Keys(ctx, m.keyBuilder.AllScenes()); err == nil {
-		counts.Scenes = len(keys)
+// cutDigits splits s after its leading run of digits.
+func cutDigits(s string) (digits, rest string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
 	}
+	return s[:i], s[i:]
+}
+
+// CountByType returns the number of cached entries by resource type.
+func (m *CacheManager) CountByType(ctx context.Context) (*TypeCounts, error) {
+	counts := &TypeCounts{}
 
-	// Count grouped lights
-	if keys, err := m.backend.Keys(ctx, m.keyBuilder.AllGroupedLights()); err == nil {
-		counts.GroupedLights = len(keys)
+	// One listing, bucketed by the type prefix of each key
+	keys, err := m.backend.Keys(ctx, m.keyBuilder.All())
+	if err != nil {
+		return counts, nil
+	}
+	for _, key := range keys {
+		resourceType, _, ok := m.keyBuilder.ParseKey(key)
+		if !ok {
+			continue
+		}
+		switch resourceType {
+		case "light":
+			counts.Lights++
+		case "room":
+			counts.Rooms++
+		case "zone":
+			counts.Zones++
+		case "scene":
+			counts.Scenes++
+		case "grouped_light":
+			counts.GroupedLights++
+		}
 	}
 
 	counts.Total = counts.Lights + counts.Rooms + counts.Zones +
@@ -2,8 +2,14 @@ package cache
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/rmrfslashbin/hue-sdk/resources"
 )
@@ -304,3 +310,751 @@ func TestCacheManager_ConcurrentClear(t *testing.T) {
 		t.Errorf("Expected 0 light keys after concurrent clear, got %d", len(lightKeys))
 	}
 }
+
+func TestCacheManager_WarmCache_SkipsUnsupported(t *testing.T) {
+	backend := newMockBackend()
+	// No SDK client: warming any type would panic, so every enabled
+	// type must be skipped by the capability probe
+	manager := NewCacheManager(backend, nil)
+
+	config := &WarmConfig{
+		WarmZones:  true,
+		WarmScenes: true,
+		Capabilities: CapabilityProberFunc(func(ctx context.Context) ([]string, error) {
+			return []string{"light", "room", "grouped_light"}, nil
+		}),
+	}
+
+	stats, err := manager.WarmCache(context.Background(), config)
+	if err != nil {
+		t.Fatalf("WarmCache() failed: %v", err)
+	}
+
+	if len(stats.Errors) != 0 {
+		t.Errorf("Expected no errors for unsupported types, got %v", stats.Errors)
+	}
+
+	skipped := make(map[string]bool)
+	for _, resourceType := range stats.Skipped {
+		skipped[resourceType] = true
+	}
+	if len(skipped) != 2 || !skipped["zone"] || !skipped["scene"] {
+		t.Errorf("Skipped = %v, want [zone scene]", stats.Skipped)
+	}
+	if stats.TotalWarmed != 0 {
+		t.Errorf("TotalWarmed = %d, want 0", stats.TotalWarmed)
+	}
+}
+
+func TestCacheManager_WarmCache_ProbeFailure(t *testing.T) {
+	manager := NewCacheManager(newMockBackend(), nil)
+
+	var failed []string
+	config := &WarmConfig{
+		Capabilities: CapabilityProberFunc(func(ctx context.Context) ([]string, error) {
+			return nil, errors.New("bridge unreachable")
+		}),
+		OnError: func(resourceType string, err error) {
+			failed = append(failed, resourceType)
+		},
+	}
+
+	stats, err := manager.WarmCache(context.Background(), config)
+	if err != nil {
+		t.Fatalf("WarmCache() failed: %v", err)
+	}
+
+	if len(failed) != 1 || failed[0] != "capabilities" {
+		t.Errorf("OnError calls = %v, want [capabilities]", failed)
+	}
+	if len(stats.Skipped) != 0 {
+		t.Errorf("Expected nothing skipped when probe fails, got %v", stats.Skipped)
+	}
+}
+
+// mockSceneClient implements hue.SceneClient's List for testing
+type mockSceneClient struct {
+	scenes []resources.Scene
+	err    error
+}
+
+func (m *mockSceneClient) List(ctx context.Context) ([]resources.Scene, error) {
+	return m.scenes, m.err
+}
+
+func (m *mockSceneClient) Get(ctx context.Context, id string) (*resources.Scene, error) {
+	return nil, ErrNotFound
+}
+
+func (m *mockSceneClient) Create(ctx context.Context, scene resources.SceneCreate) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (m *mockSceneClient) Update(ctx context.Context, id string, update resources.SceneUpdate) error {
+	return errors.New("not implemented")
+}
+
+func (m *mockSceneClient) Delete(ctx context.Context, id string) error {
+	return errors.New("not implemented")
+}
+
+func TestCacheManager_WarmReferencedOnly(t *testing.T) {
+	// Scenes as the bridge reports them: light-1 and light-3 are targeted
+	var scenes []resources.Scene
+	err := json.Unmarshal([]byte(`[
+		{"id": "scene-1", "type": "scene",
+		 "group": {"rid": "room-1", "rtype": "room"},
+		 "actions": [{"target": {"rid": "light-1", "rtype": "light"}},
+		             {"target": {"rid": "light-3", "rtype": "light"}}]},
+		{"id": "scene-2", "type": "scene",
+		 "group": {"rid": "room-1", "rtype": "room"},
+		 "actions": [{"target": {"rid": "light-1", "rtype": "light"}}]}
+	]`), &scenes)
+	if err != nil {
+		t.Fatalf("decoding scenes: %v", err)
+	}
+
+	ctx := context.Background()
+	refs, err := sceneReferences(ctx, &mockSceneClient{scenes: scenes})
+	if err != nil {
+		t.Fatalf("sceneReferences() failed: %v", err)
+	}
+	if !refs["room-1"] {
+		t.Errorf("references = %v, want the scenes' group room-1 included", refs)
+	}
+
+	mockSDK := newMockLightClient()
+	for i := 1; i <= 4; i++ {
+		id := fmt.Sprintf("light-%d", i)
+		mockSDK.lights[id] = &resources.Light{ID: id, Type: "light"}
+	}
+
+	backend := newMockBackend()
+	manager := NewCacheManager(backend, nil)
+	config := &WarmConfig{ReferencedOnly: true, ItemConcurrency: 1}
+
+	count, failed, err := manager.warmLights(ctx, config, mockSDK, refs)
+	if err != nil {
+		t.Fatalf("warmLights() failed: %v", err)
+	}
+	if count != 2 || len(failed) != 0 {
+		t.Errorf("warmed %d lights with failures %v, want 2 and none", count, failed)
+	}
+
+	keys, _ := backend.Keys(ctx, "light:*")
+	warmed := make(map[string]bool)
+	for _, key := range keys {
+		warmed[key] = true
+	}
+	kb := NewKeyBuilder()
+	if len(warmed) != 2 || !warmed[kb.Light("light-1")] || !warmed[kb.Light("light-3")] {
+		t.Errorf("warmed keys = %v, want light-1 and light-3", keys)
+	}
+}
+
+func TestCacheManager_WarmReferencedOnly_ScenesFail(t *testing.T) {
+	_, err := sceneReferences(context.Background(), &mockSceneClient{err: errors.New("bridge unreachable")})
+	if err == nil {
+		t.Error("Expected sceneReferences() to fail when scenes can't be listed")
+	}
+}
+
+func TestCacheManager_Entries(t *testing.T) {
+	backend := newMockBackend()
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("value"), time.Minute)
+	backend.Set(ctx, "light:2", []byte("value"), 0)
+	backend.Set(ctx, "room:1", []byte("value"), 0)
+
+	manager := NewCacheManager(backend, nil)
+	infos, err := manager.Entries(ctx, "light:*")
+	if err != nil {
+		t.Fatalf("Entries() failed: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("Entries() returned %d entries, want 2", len(infos))
+	}
+	for _, info := range infos {
+		if info.Size != int64(len("value")) {
+			t.Errorf("%s size = %d, want %d", info.Key, info.Size, len("value"))
+		}
+	}
+
+	counts, err := manager.CountByType(ctx)
+	if err != nil {
+		t.Fatalf("CountByType() failed: %v", err)
+	}
+	if counts.Lights != 2 || counts.Rooms != 1 || counts.Total != 3 {
+		t.Errorf("CountByType() = %+v, want 2 lights and 1 room", counts)
+	}
+}
+
+func TestWarmItems_BoundedConcurrency(t *testing.T) {
+	ids := make([]string, 100)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("scene-%d", i)
+	}
+
+	var inFlight, maxInFlight atomic.Int32
+	var mu sync.Mutex
+	warmed := make(map[string]bool)
+
+	warmItems(context.Background(), "scene", ids, 4, func(ctx context.Context, id string) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			prev := maxInFlight.Load()
+			if n <= prev || maxInFlight.CompareAndSwap(prev, n) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond) // Simulate an SDK round trip
+
+		mu.Lock()
+		warmed[id] = true
+		mu.Unlock()
+		return nil
+	})
+
+	if len(warmed) != len(ids) {
+		t.Errorf("warmed %d items, want %d", len(warmed), len(ids))
+	}
+	if got := maxInFlight.Load(); got > 4 {
+		t.Errorf("max concurrency = %d, want at most 4", got)
+	}
+	if got := maxInFlight.Load(); got < 2 {
+		t.Errorf("max concurrency = %d, want items warmed in parallel", got)
+	}
+}
+
+func TestWarmItems_Serial(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	warmItems(context.Background(), "scene", []string{"a", "b", "c"}, 0, func(ctx context.Context, id string) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		if n > maxInFlight.Load() {
+			maxInFlight.Store(n)
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+
+	if got := maxInFlight.Load(); got != 1 {
+		t.Errorf("max concurrency = %d, want 1 for concurrency < 1", got)
+	}
+}
+
+func TestWarmItems_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls atomic.Int32
+	warmed, failed := warmItems(ctx, "scene", []string{"a", "b", "c", "d"}, 1, func(ctx context.Context, id string) error {
+		calls.Add(1)
+		cancel()
+		return nil
+	})
+
+	// One call may already be starting as the context is cancelled
+	if got := calls.Load(); got > 2 {
+		t.Errorf("get called %d times after cancel, want at most 2", got)
+	}
+
+	// The items never fetched are reported
+	if warmed+len(failed) != 4 {
+		t.Errorf("warmed %d and failed %d items, want 4 in all", warmed, len(failed))
+	}
+	for _, failure := range failed {
+		if !errors.Is(failure.Err, context.Canceled) {
+			t.Errorf("failure %s error = %v, want context.Canceled", failure.ID, failure.Err)
+		}
+	}
+}
+
+func TestWarmItems_CollectsFailures(t *testing.T) {
+	errBridge := errors.New("bridge busy")
+	ids := []string{"scene-4", "scene-3", "scene-2", "scene-1"}
+
+	warmed, failed := warmItems(context.Background(), "scene", ids, 4, func(ctx context.Context, id string) error {
+		if id == "scene-1" || id == "scene-3" {
+			return errBridge
+		}
+		return nil
+	})
+
+	if warmed != 2 {
+		t.Errorf("warmed %d items, want 2", warmed)
+	}
+	if len(failed) != 2 || failed[0].ID != "scene-1" || failed[1].ID != "scene-3" {
+		t.Fatalf("failed = %v, want scene-1 and scene-3 in order", failed)
+	}
+	for _, failure := range failed {
+		if failure.ResourceType != "scene" || !errors.Is(failure.Err, errBridge) {
+			t.Errorf("failure = %+v, want a scene failing with %v", failure, errBridge)
+		}
+	}
+}
+
+// phantomLightClient lists a light its Get can't find.
+type phantomLightClient struct {
+	*mockLightClient
+}
+
+func (c *phantomLightClient) List(ctx context.Context) ([]resources.Light, error) {
+	lights, err := c.mockLightClient.List(ctx)
+	return append(lights, resources.Light{ID: "light-gone", Type: "light"}), err
+}
+
+func TestCacheManager_WarmReportsFailedItems(t *testing.T) {
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	manager := NewCacheManager(newMockBackend(), nil)
+	config := &WarmConfig{ItemConcurrency: 1}
+
+	count, failed, err := manager.warmLights(context.Background(), config, &phantomLightClient{mockSDK}, nil)
+	if err != nil {
+		t.Fatalf("warmLights() failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("warmed %d lights, want 1", count)
+	}
+	if len(failed) != 1 || failed[0].ID != "light-gone" || failed[0].ResourceType != "light" {
+		t.Errorf("failed = %v, want light-gone", failed)
+	}
+}
+
+// resettableBackend is a mockBackend that implements StatsResetter.
+type resettableBackend struct {
+	*mockBackend
+}
+
+func (b *resettableBackend) ResetStats(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.hits, b.misses = 0, 0
+	return nil
+}
+
+func TestCacheManager_ResetStats(t *testing.T) {
+	ctx := context.Background()
+
+	backend := &resettableBackend{newMockBackend()}
+	backend.Set(ctx, "light:1", []byte("value"), 0)
+	backend.Get(ctx, "light:1")
+	backend.Get(ctx, "light:missing")
+
+	// Through the timeout wrapper, as cached clients and sync see it
+	manager := NewCacheManager(withBackendTimeout(backend, time.Second), nil)
+	if err := manager.ResetStats(ctx); err != nil {
+		t.Fatalf("ResetStats() failed: %v", err)
+	}
+	if backend.hits != 0 || backend.misses != 0 {
+		t.Errorf("After reset: %d hits, %d misses; want 0, 0", backend.hits, backend.misses)
+	}
+	if _, err := backend.Get(ctx, "light:1"); err != nil {
+		t.Errorf("Entry lost after reset: %v", err)
+	}
+
+	unsupported := NewCacheManager(newMockBackend(), nil)
+	if err := unsupported.ResetStats(ctx); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("ResetStats() on unsupported backend error = %v, want ErrUnsupported", err)
+	}
+}
+
+// transactionalBackend is a mockBackend that implements PatternDeleter by
+// deleting every matching key under one lock, like a database transaction.
+type transactionalBackend struct {
+	*mockBackend
+	deletes atomic.Int32
+}
+
+func (b *transactionalBackend) Delete(ctx context.Context, key string) error {
+	b.deletes.Add(1)
+	return b.mockBackend.Delete(ctx, key)
+}
+
+func (b *transactionalBackend) DeletePattern(ctx context.Context, pattern string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	deleted := 0
+	for key := range b.data {
+		if matchPattern(pattern, key) {
+			delete(b.data, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func TestCacheManager_ClearPattern_Atomic(t *testing.T) {
+	ctx := context.Background()
+
+	backend := &transactionalBackend{mockBackend: newMockBackend()}
+	const lights = 100
+	for i := 0; i < lights; i++ {
+		backend.Set(ctx, fmt.Sprintf("light:%d", i), []byte("value"), 0)
+	}
+	backend.Set(ctx, "room:1", []byte("value"), 0)
+
+	// Watch the light keys while they are cleared
+	stop := make(chan struct{})
+	partial := make(chan int, 1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			keys, _ := backend.Keys(ctx, "light:*")
+			if n := len(keys); n != 0 && n != lights {
+				select {
+				case partial <- n:
+				default:
+				}
+				return
+			}
+		}
+	}()
+
+	// Through the timeout wrapper, as cached clients and sync see it
+	manager := NewCacheManager(withBackendTimeout(backend, time.Second), nil)
+	if err := manager.ClearLights(ctx); err != nil {
+		t.Fatalf("ClearLights() failed: %v", err)
+	}
+	close(stop)
+	wg.Wait()
+
+	select {
+	case n := <-partial:
+		t.Errorf("Observed a partially cleared set of %d lights", n)
+	default:
+	}
+	if n := backend.deletes.Load(); n != 0 {
+		t.Errorf("ClearLights() made %d single-key deletes, want 0", n)
+	}
+	if keys, _ := backend.Keys(ctx, "light:*"); len(keys) != 0 {
+		t.Errorf("Keys(light:*) after clear = %v, want none", keys)
+	}
+	if _, err := backend.Get(ctx, "room:1"); err != nil {
+		t.Errorf("room:1 was cleared: %v", err)
+	}
+}
+
+func TestDeletePattern_Fallback(t *testing.T) {
+	ctx := context.Background()
+
+	backend := newMockBackend()
+	backend.Set(ctx, "light:1", []byte("value"), 0)
+	backend.Set(ctx, "light:2", []byte("value"), 0)
+	backend.Set(ctx, "room:1", []byte("value"), 0)
+
+	deleted, err := DeletePattern(ctx, backend, "light:*")
+	if err != nil {
+		t.Fatalf("DeletePattern() failed: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("DeletePattern() deleted %d keys, want 2", deleted)
+	}
+	if keys, _ := backend.Keys(ctx, "*"); len(keys) != 1 {
+		t.Errorf("Keys() after DeletePattern() = %v, want [room:1]", keys)
+	}
+}
+
+// unhealthyBackend is a mockBackend whose Ping fails.
+type unhealthyBackend struct {
+	*mockBackend
+	err error
+}
+
+func (b *unhealthyBackend) Ping(ctx context.Context) error {
+	return b.err
+}
+
+func TestCacheManager_Ping(t *testing.T) {
+	ctx := context.Background()
+
+	if err := NewCacheManager(newMockBackend(), nil).Ping(ctx); err != nil {
+		t.Errorf("Ping() failed: %v", err)
+	}
+
+	errDisk := errors.New("disk gone")
+	backend := &unhealthyBackend{mockBackend: newMockBackend(), err: errDisk}
+	if err := NewCacheManager(withBackendTimeout(backend, time.Second), nil).Ping(ctx); !errors.Is(err, errDisk) {
+		t.Errorf("Ping() error = %v, want %v", err, errDisk)
+	}
+
+	// Backends that don't implement Pinger are assumed healthy
+	if err := NewCacheManager(basicBackend{backend}, nil).Ping(ctx); err != nil {
+		t.Errorf("Ping() without Pinger = %v, want nil", err)
+	}
+}
+
+func TestCacheManager_ListKeys(t *testing.T) {
+	ctx := context.Background()
+	backend := newMockBackend()
+	for _, key := range []string{"light:10", "room:b", "light:2", "light:1", "room:a", "light:1a"} {
+		backend.Set(ctx, key, []byte("value"), 0)
+	}
+	manager := NewCacheManager(backend, nil)
+
+	lights, err := manager.ListKeys(ctx, "light")
+	if err != nil {
+		t.Fatalf("ListKeys(light) failed: %v", err)
+	}
+	if want := []string{"light:1", "light:1a", "light:2", "light:10"}; !slices.Equal(lights, want) {
+		t.Errorf("ListKeys(light) = %v, want %v", lights, want)
+	}
+
+	all, err := manager.ListKeys(ctx, "")
+	if err != nil {
+		t.Fatalf("ListKeys() failed: %v", err)
+	}
+	if want := []string{"light:1", "light:1a", "light:2", "light:10", "room:a", "room:b"}; !slices.Equal(all, want) {
+		t.Errorf("ListKeys() = %v, want %v", all, want)
+	}
+
+	// Only the manager's namespace is listed
+	backend.Set(ctx, "home:light:3", []byte("value"), 0)
+	namespaced, _ := NewCacheManagerWithPrefix(backend, nil, "home").ListKeys(ctx, "")
+	if len(namespaced) != 1 || namespaced[0] != "home:light:3" {
+		t.Errorf("ListKeys() in namespace = %v, want [home:light:3]", namespaced)
+	}
+}
+
+func TestCompareNatural(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2", "10", -1},
+		{"10", "2", 1},
+		{"a2", "a10", -1},
+		{"007", "7", 0},
+		{"abc", "abd", -1},
+		{"ab", "abc", -1},
+		{"1a", "1b", -1},
+		{"x", "x", 0},
+	}
+	for _, tt := range tests {
+		if got := compareNatural(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareNatural(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDeleteTag_Fallback(t *testing.T) {
+	ctx := context.Background()
+
+	// The mock can't store tags itself
+	backend := newMockBackend()
+	if err := SetWithTags(ctx, backend, "light:1", []byte("value"), 0, []string{"floor-2"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetWithTags() error = %v, want ErrUnsupported", err)
+	}
+	if err := SetWithTags(ctx, backend, "light:1", []byte("value"), 0, nil); err != nil {
+		t.Errorf("SetWithTags() without tags failed: %v", err)
+	}
+
+	for _, key := range []string{"light:2", "room:1"} {
+		entry := NewEntry(key, []byte("value"), 0)
+		entry.Tags = []string{"floor-2"}
+		backend.data[key] = entry
+	}
+
+	if err := NewCacheManager(backend, nil).ClearTag(ctx, "floor-2"); err != nil {
+		t.Fatalf("ClearTag() failed: %v", err)
+	}
+	if keys, _ := backend.Keys(ctx, "*"); len(keys) != 1 || keys[0] != "light:1" {
+		t.Errorf("Keys() after ClearTag() = %v, want [light:1]", keys)
+	}
+}
+
+func TestDeleteMany_Fallback(t *testing.T) {
+	ctx := context.Background()
+	backend := newMockBackend()
+	backend.Set(ctx, "light:1", []byte("one"), 0)
+	backend.Set(ctx, "light:2", []byte("two"), 0)
+	backend.Set(ctx, "room:1", []byte("room"), 0)
+
+	deleted, err := DeleteMany(ctx, backend, []string{"light:1", "room:1"})
+	if err != nil || deleted != 2 {
+		t.Fatalf("DeleteMany() = %d, %v; want 2", deleted, err)
+	}
+	if keys, _ := backend.Keys(ctx, "*"); len(keys) != 1 || keys[0] != "light:2" {
+		t.Errorf("Keys() = %v, want [light:2]", keys)
+	}
+
+	// Failures are joined, and the other keys still deleted
+	failing := &failingDeleteBackend{mockBackend: backend, fail: "light:2"}
+	failing.Set(ctx, "room:2", []byte("room"), 0)
+	deleted, err = DeleteMany(ctx, failing, []string{"light:2", "room:2"})
+	if !errors.Is(err, ErrBackendClosed) || deleted != 1 {
+		t.Errorf("DeleteMany() = %d, %v; want 1, ErrBackendClosed", deleted, err)
+	}
+	if _, err := backend.Get(ctx, "room:2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(room:2) error = %v, want ErrNotFound", err)
+	}
+}
+
+// failingDeleteBackend fails deletes of one key.
+type failingDeleteBackend struct {
+	*mockBackend
+	fail string
+}
+
+func (b *failingDeleteBackend) Delete(ctx context.Context, key string) error {
+	if key == b.fail {
+		return NewError("Delete", key, ErrBackendClosed)
+	}
+	return b.mockBackend.Delete(ctx, key)
+}
+
+func TestCacheManager_ClearPattern_KeysUnsupported(t *testing.T) {
+	ctx := context.Background()
+	backend := &unlistableBackend{newMockBackend()}
+	backend.Set(ctx, "light:1", []byte("light1"), 0)
+	manager := NewCacheManager(backend, nil)
+
+	if err := manager.ClearLights(ctx); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("ClearLights() error = %v, want ErrUnsupported", err)
+	}
+
+	// Clearing everything doesn't need a listing
+	if err := manager.ClearPattern(ctx, "*"); err != nil {
+		t.Fatalf("ClearPattern(*) failed: %v", err)
+	}
+	if _, err := backend.Get(ctx, "light:1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after ClearPattern(*) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCacheManager_GetOrFetch(t *testing.T) {
+	backend := newMockBackend()
+	manager := NewCacheManager(backend, nil)
+	ctx := context.Background()
+
+	var loads atomic.Int32
+	loader := func(ctx context.Context) ([]byte, error) {
+		loads.Add(1)
+		return []byte("sensor1"), nil
+	}
+
+	// Miss: loads and stores
+	data, err := manager.GetOrFetch(ctx, "sensor:1", time.Minute, loader)
+	if err != nil {
+		t.Fatalf("GetOrFetch() failed: %v", err)
+	}
+	if string(data) != "sensor1" {
+		t.Errorf("Expected sensor1, got %q", data)
+	}
+	entry, err := backend.Get(ctx, "sensor:1")
+	if err != nil {
+		t.Fatalf("Expected loaded value to be cached: %v", err)
+	}
+	if entry.TTL != time.Minute {
+		t.Errorf("Expected TTL 1m, got %v", entry.TTL)
+	}
+
+	// Hit: served from the cache
+	data, err = manager.GetOrFetch(ctx, "sensor:1", time.Minute, loader)
+	if err != nil {
+		t.Fatalf("GetOrFetch() failed: %v", err)
+	}
+	if string(data) != "sensor1" {
+		t.Errorf("Expected sensor1, got %q", data)
+	}
+	if n := loads.Load(); n != 1 {
+		t.Errorf("Expected 1 load, got %d", n)
+	}
+}
+
+func TestCacheManager_GetOrFetch_SharesLoad(t *testing.T) {
+	backend := newMockBackend()
+	manager := NewCacheManager(backend, nil)
+	ctx := context.Background()
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) ([]byte, error) {
+		loads.Add(1)
+		<-release
+		return []byte("button1"), nil
+	}
+
+	const callers = 10
+	var started, wg sync.WaitGroup
+	errs := make(chan error, callers)
+	started.Add(callers)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+			data, err := manager.GetOrFetch(ctx, "button:1", 0, loader)
+			if err == nil && string(data) != "button1" {
+				err = fmt.Errorf("got %q", data)
+			}
+			errs <- err
+		}()
+	}
+	started.Wait()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("GetOrFetch() failed: %v", err)
+		}
+	}
+	if n := loads.Load(); n != 1 {
+		t.Errorf("Expected 1 shared load, got %d", n)
+	}
+}
+
+func TestCacheManager_GetOrFetch_LoaderError(t *testing.T) {
+	backend := newMockBackend()
+	manager := NewCacheManager(backend, nil)
+	ctx := context.Background()
+
+	loadErr := errors.New("bridge unavailable")
+	_, err := manager.GetOrFetch(ctx, "sensor:1", 0, func(ctx context.Context) ([]byte, error) {
+		return nil, loadErr
+	})
+	if !errors.Is(err, loadErr) {
+		t.Fatalf("Expected loader error, got %v", err)
+	}
+	if _, err := backend.Get(ctx, "sensor:1"); err == nil {
+		t.Error("Expected loader error not to be cached")
+	}
+
+	// The next call loads again
+	data, err := manager.GetOrFetch(ctx, "sensor:1", 0, func(ctx context.Context) ([]byte, error) {
+		return []byte("sensor1"), nil
+	})
+	if err != nil || string(data) != "sensor1" {
+		t.Errorf("Expected sensor1 after retry, got %q, %v", data, err)
+	}
+}
+
+func TestCacheManager_GetOrFetch_Namespace(t *testing.T) {
+	backend := newMockBackend()
+	manager := NewCacheManagerWithPrefix(backend, nil, "home")
+	ctx := context.Background()
+
+	_, err := manager.GetOrFetch(ctx, "sensor:1", 0, func(ctx context.Context) ([]byte, error) {
+		return []byte("sensor1"), nil
+	})
+	if err != nil {
+		t.Fatalf("GetOrFetch() failed: %v", err)
+	}
+	if _, err := backend.Get(ctx, "home:sensor:1"); err != nil {
+		t.Errorf("Expected value stored in the namespace: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+func TestCachedLightClient_NegativeCaching(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	client.configure(&CachedClientConfig{NegativeTTL: 30 * time.Second})
+
+	ctx := context.Background()
+
+	// Repeated lookups of a missing light only reach the SDK once
+	for i := 0; i < 3; i++ {
+		_, err := client.Get(ctx, "missing")
+		if !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Get() error = %v, want ErrNotFound", err)
+		}
+	}
+	if mockSDK.calls["Get"] != 1 {
+		t.Errorf("Expected 1 SDK call, got %d", mockSDK.calls["Get"])
+	}
+
+	// Tombstone uses NegativeTTL, not the client TTL
+	entry, err := backend.Get(ctx, "light:missing")
+	if err != nil {
+		t.Fatalf("Expected tombstone entry: %v", err)
+	}
+	if entry.TTL != 30*time.Second {
+		t.Errorf("Tombstone TTL = %v, want 30s", entry.TTL)
+	}
+}
+
+func TestCachedLightClient_NegativeCachingDisabled(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+
+	for i := 0; i < 3; i++ {
+		_, _ = client.Get(context.Background(), "missing")
+	}
+	if mockSDK.calls["Get"] != 3 {
+		t.Errorf("Expected 3 SDK calls without negative caching, got %d", mockSDK.calls["Get"])
+	}
+}
+
+func TestNegativeCaching_SSEAddClearsTombstone(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	client.configure(&CachedClientConfig{NegativeTTL: time.Minute})
+
+	ctx := context.Background()
+	if _, err := client.Get(ctx, "light-1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() error = %v, want ErrNotFound", err)
+	}
+
+	// Light is created on the bridge and the SSE add event arrives
+	engine := &SyncEngine{
+		backend:    backend,
+		keyBuilder: NewKeyBuilder(),
+		stats:      &SyncStats{},
+		config:     DefaultSyncConfig(),
+	}
+	rawData, _ := json.Marshal(map[string]interface{}{"id": "light-1", "type": "light"})
+	err := engine.processEventData(ctx, resources.EventTypeAdd, &resources.EventData{
+		ID:      "light-1",
+		Type:    "light",
+		RawData: json.RawMessage(rawData),
+	})
+	if err != nil {
+		t.Fatalf("processEventData() failed: %v", err)
+	}
+
+	light, err := client.Get(ctx, "light-1")
+	if err != nil {
+		t.Fatalf("Get() after add event failed: %v", err)
+	}
+	if light.ID != "light-1" {
+		t.Errorf("Get() ID = %q, want light-1", light.ID)
+	}
+}
+
+func TestNegativeCaching_DeleteClearsTombstone(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockRoomClient()
+
+	client := NewCachedRoomClient(backend, mockSDK, 0)
+	client.configure(&CachedClientConfig{NegativeTTL: time.Minute})
+
+	ctx := context.Background()
+	if _, err := client.Get(ctx, "room-1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() error = %v, want ErrNotFound", err)
+	}
+
+	if err := client.Delete(ctx, "room-1"); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+
+	if _, err := backend.Get(ctx, "room:room-1"); err == nil {
+		t.Error("Expected Delete to remove the tombstone")
+	}
+}
+
+func TestNegativeCaching_ListSkipsTombstones(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	client.configure(&CachedClientConfig{NegativeTTL: time.Minute})
+
+	ctx := context.Background()
+	_, _ = client.Get(ctx, "missing")
+
+	// Only a tombstone is cached, so List must go to the SDK
+	lights, err := client.List(ctx)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(lights) != 1 || mockSDK.calls["List"] != 1 {
+		t.Fatalf("List() = %d lights with %d SDK calls, want 1 and 1", len(lights), mockSDK.calls["List"])
+	}
+
+	// Now served from cache, without the tombstone
+	lights, err = client.List(ctx)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(lights) != 1 {
+		t.Errorf("List() returned %d lights, want 1", len(lights))
+	}
+	if mockSDK.calls["List"] != 1 {
+		t.Errorf("Expected cached List, got %d SDK calls", mockSDK.calls["List"])
+	}
+}
+
+func TestNegativeCaching_CustomIsNotFound(t *testing.T) {
+	sdkNotFound := errors.New("sdk: resource not found")
+
+	backend := newMockBackend()
+	client := NewCachedLightClient(backend, &errorLightClient{err: sdkNotFound}, 0)
+	client.configure(&CachedClientConfig{
+		NegativeTTL: time.Minute,
+		IsNotFound: func(err error) bool {
+			return errors.Is(err, sdkNotFound)
+		},
+	})
+
+	_, _ = client.Get(context.Background(), "light-1")
+
+	entry, err := backend.Get(context.Background(), "light:light-1")
+	if err != nil || !isTombstone(entry.Value) {
+		t.Error("Expected custom NotFound error to be negatively cached")
+	}
+}
+
+// errorLightClient is a LightClient whose calls all fail with err.
+type errorLightClient struct {
+	err error
+}
+
+func (c *errorLightClient) List(ctx context.Context) ([]resources.Light, error) {
+	return nil, c.err
+}
+
+func (c *errorLightClient) Get(ctx context.Context, id string) (*resources.Light, error) {
+	return nil, c.err
+}
+
+func (c *errorLightClient) Update(ctx context.Context, id string, update resources.LightUpdate) error {
+	return c.err
+}
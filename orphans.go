@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+)
+
+// OrphanReport describes grouped lights whose owning room or zone is no
+// longer in the cache.
+type OrphanReport struct {
+	// Checked is the number of grouped lights examined.
+	Checked int
+
+	// Orphans lists the grouped lights whose owner is missing.
+	Orphans []OrphanedGroupedLight
+
+	// Removed is the number of orphans deleted from the cache.
+	// Always 0 for AuditOrphans.
+	Removed int
+}
+
+// OrphanedGroupedLight identifies a grouped light with a missing owner.
+type OrphanedGroupedLight struct {
+	// Key is the grouped light's cache key.
+	Key string
+
+	// ID is the grouped light's resource ID.
+	ID string
+
+	// OwnerType is the owner's resource type ("room" or "zone").
+	OwnerType string
+
+	// OwnerID is the missing owner's resource ID.
+	OwnerID string
+}
+
+// groupedLightOwner is the subset of a cached grouped light needed to
+// find its owner.
+type groupedLightOwner struct {
+	ID    string `json:"id"`
+	Owner struct {
+		RID   string `json:"rid"`
+		RType string `json:"rtype"`
+	} `json:"owner"`
+}
+
+// AuditOrphans cross-references cached grouped lights against cached rooms
+// and zones, and reports grouped lights whose owning room or zone is no
+// longer cached. This happens when a room is deleted but the grouped
+// light's delete event is missed.
+//
+// Owners of a type are only checked if at least one resource of that type
+// is cached, so a cache that never held rooms doesn't report every
+// room-owned grouped light as orphaned. Grouped lights owned by other
+// types (e.g. bridge_home) are not checked.
+//
+// Use RepairOrphans to also remove them.
+func (m *CacheManager) AuditOrphans(ctx context.Context) (*OrphanReport, error) {
+	return m.auditOrphans(ctx, false)
+}
+
+// RepairOrphans audits grouped lights like AuditOrphans and deletes every
+// orphan found from the cache.
+func (m *CacheManager) RepairOrphans(ctx context.Context) (*OrphanReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.auditOrphans(ctx, true)
+}
+
+// auditOrphans finds orphaned grouped lights, optionally removing them.
+func (m *CacheManager) auditOrphans(ctx context.Context, remove bool) (*OrphanReport, error) {
+	// Collect owners present in the cache
+	owners := make(map[string]bool)
+	ownerTypes := make(map[string]bool)
+	for _, ownerType := range []string{"room", "zone"} {
+		keys, err := m.backend.Keys(ctx, m.keyBuilder.AllResources(ownerType))
+		if err != nil {
+			return nil, fmt.Errorf("getting %s keys: %w", ownerType, err)
+		}
+		for _, key := range keys {
+			owners[key] = true
+		}
+		ownerTypes[ownerType] = len(keys) > 0
+	}
+
+	keys, err := m.backend.Keys(ctx, m.keyBuilder.AllGroupedLights())
+	if err != nil {
+		return nil, fmt.Errorf("getting grouped light keys: %w", err)
+	}
+
+	report := &OrphanReport{}
+	for _, key := range keys {
+		entry, err := Peek(ctx, m.backend, key)
+		if err != nil {
+			continue // Deleted or expired since Keys
+		}
+
+		var gl groupedLightOwner
+		if err := m.codec.Unmarshal(entry.Value, &gl); err != nil {
+			continue // Not a decodable resource (e.g. negative cache entry)
+		}
+		report.Checked++
+
+		if !ownerTypes[gl.Owner.RType] || gl.Owner.RID == "" {
+			continue
+		}
+
+		if owners[m.keyBuilder.Resource(gl.Owner.RType, gl.Owner.RID)] {
+			continue
+		}
+
+		report.Orphans = append(report.Orphans, OrphanedGroupedLight{
+			Key:       key,
+			ID:        gl.ID,
+			OwnerType: gl.Owner.RType,
+			OwnerID:   gl.Owner.RID,
+		})
+
+		if remove {
+			if err := m.backend.Delete(ctx, key); err != nil {
+				continue // Try to remove as many as possible
+			}
+			report.Removed++
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+// seedOrphanCache populates a backend with two rooms, one zone, and grouped
+// lights owned by each - plus one whose room has been deleted.
+func seedOrphanCache(t *testing.T, backend Backend) {
+	t.Helper()
+	ctx := context.Background()
+
+	entries := map[string]string{
+		"room:room-1":         `{"id":"room-1","type":"room"}`,
+		"room:room-2":         `{"id":"room-2","type":"room"}`,
+		"zone:zone-1":         `{"id":"zone-1","type":"zone"}`,
+		"grouped_light:gl-1":  `{"id":"gl-1","type":"grouped_light","owner":{"rid":"room-1","rtype":"room"}}`,
+		"grouped_light:gl-2":  `{"id":"gl-2","type":"grouped_light","owner":{"rid":"zone-1","rtype":"zone"}}`,
+		"grouped_light:gl-3":  `{"id":"gl-3","type":"grouped_light","owner":{"rid":"room-deleted","rtype":"room"}}`,
+		"grouped_light:gl-bh": `{"id":"gl-bh","type":"grouped_light","owner":{"rid":"home-1","rtype":"bridge_home"}}`,
+	}
+
+	for key, value := range entries {
+		if err := backend.Set(ctx, key, []byte(value), 0); err != nil {
+			t.Fatalf("Set(%s) failed: %v", key, err)
+		}
+	}
+}
+
+func TestCacheManager_AuditOrphans(t *testing.T) {
+	backend := newMockBackend()
+	seedOrphanCache(t, backend)
+	manager := NewCacheManager(backend, nil)
+
+	report, err := manager.AuditOrphans(context.Background())
+	if err != nil {
+		t.Fatalf("AuditOrphans() failed: %v", err)
+	}
+
+	if report.Checked != 4 {
+		t.Errorf("Checked = %d, want 4", report.Checked)
+	}
+
+	if len(report.Orphans) != 1 {
+		t.Fatalf("Found %d orphans, want 1: %+v", len(report.Orphans), report.Orphans)
+	}
+
+	orphan := report.Orphans[0]
+	if orphan.Key != "grouped_light:gl-3" || orphan.OwnerType != "room" || orphan.OwnerID != "room-deleted" {
+		t.Errorf("Unexpected orphan: %+v", orphan)
+	}
+
+	if report.Removed != 0 {
+		t.Errorf("AuditOrphans() should not remove entries, removed %d", report.Removed)
+	}
+
+	if _, err := backend.Get(context.Background(), "grouped_light:gl-3"); err != nil {
+		t.Error("AuditOrphans() should leave orphans in the cache")
+	}
+}
+
+func TestCacheManager_AuditOrphans_Peeks(t *testing.T) {
+	backend := &peekingBackend{mockBackend: newMockBackend()}
+	seedOrphanCache(t, backend)
+
+	if _, err := NewCacheManager(backend, nil).AuditOrphans(context.Background()); err != nil {
+		t.Fatalf("AuditOrphans() failed: %v", err)
+	}
+	if backend.peeks == 0 || backend.hits != 0 {
+		t.Errorf("AuditOrphans() made %d peeks and %d hits, want peeks only", backend.peeks, backend.hits)
+	}
+}
+
+func TestCacheManager_RepairOrphans(t *testing.T) {
+	backend := newMockBackend()
+	seedOrphanCache(t, backend)
+	manager := NewCacheManager(backend, nil)
+
+	report, err := manager.RepairOrphans(context.Background())
+	if err != nil {
+		t.Fatalf("RepairOrphans() failed: %v", err)
+	}
+
+	if len(report.Orphans) != 1 || report.Removed != 1 {
+		t.Errorf("RepairOrphans() orphans=%d removed=%d, want 1 and 1", len(report.Orphans), report.Removed)
+	}
+
+	if _, err := backend.Get(context.Background(), "grouped_light:gl-3"); err == nil {
+		t.Error("Expected orphan to be removed")
+	}
+
+	// Non-orphans are untouched
+	for _, key := range []string{"grouped_light:gl-1", "grouped_light:gl-2", "grouped_light:gl-bh"} {
+		if _, err := backend.Get(context.Background(), key); err != nil {
+			t.Errorf("Expected %s to remain: %v", key, err)
+		}
+	}
+}
+
+func TestCacheManager_AuditOrphans_NoOwnersCached(t *testing.T) {
+	backend := newMockBackend()
+	ctx := context.Background()
+	backend.Set(ctx, "grouped_light:gl-1", []byte(`{"id":"gl-1","owner":{"rid":"room-1","rtype":"room"}}`), 0)
+
+	manager := NewCacheManager(backend, nil)
+	report, err := manager.AuditOrphans(ctx)
+	if err != nil {
+		t.Fatalf("AuditOrphans() failed: %v", err)
+	}
+
+	// With no rooms cached at all, ownership can't be judged
+	if len(report.Orphans) != 0 {
+		t.Errorf("Expected no orphans when no rooms are cached, got %d", len(report.Orphans))
+	}
+}
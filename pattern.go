@@ -0,0 +1,103 @@
+package cache
+
+import "strings"
+
+// MatchPattern reports whether key matches a glob pattern.
+//
+// Pattern syntax:
+//   - "*" matches any sequence of characters (including none)
+//   - "?" matches exactly one character
+//   - "\" escapes the next character, so "\*" matches a literal "*"
+//   - every other character matches itself
+//
+// Wildcards may appear anywhere and any number of times, e.g.
+// "light:*:temp" or "*light*". Backends should use MatchPattern (or an
+// equivalent translation) so that Keys behaves the same everywhere.
+func MatchPattern(pattern, key string) bool {
+	// Fast paths for the common simple forms
+	if pattern == "*" {
+		return true
+	}
+	if !strings.ContainsAny(pattern, `*?\`) {
+		return key == pattern
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok && !strings.ContainsAny(prefix, `*?\`) {
+		return strings.HasPrefix(key, prefix)
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok && !strings.ContainsAny(suffix, `*?\`) {
+		return strings.HasSuffix(key, suffix)
+	}
+
+	return matchGlob(compileGlob(pattern), []rune(key))
+}
+
+// globToken is one element of a compiled glob pattern.
+type globToken struct {
+	// wildcard is '*' or '?' for wildcards, or 0 for a literal.
+	wildcard rune
+
+	// literal is the character to match when wildcard is 0.
+	literal rune
+}
+
+// compileGlob splits a pattern into tokens, resolving escapes.
+func compileGlob(pattern string) []globToken {
+	runes := []rune(pattern)
+	tokens := make([]globToken, 0, len(runes))
+
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			// Consecutive stars are equivalent to one
+			if len(tokens) > 0 && tokens[len(tokens)-1].wildcard == '*' {
+				continue
+			}
+			tokens = append(tokens, globToken{wildcard: '*'})
+		case '?':
+			tokens = append(tokens, globToken{wildcard: '?'})
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				tokens = append(tokens, globToken{literal: runes[i]})
+			} else {
+				// Trailing backslash matches itself
+				tokens = append(tokens, globToken{literal: '\\'})
+			}
+		default:
+			tokens = append(tokens, globToken{literal: r})
+		}
+	}
+
+	return tokens
+}
+
+// matchGlob matches key against compiled tokens. It backtracks only to the
+// most recent star, so it runs in O(len(tokens) * len(key)) time.
+func matchGlob(tokens []globToken, key []rune) bool {
+	t, k := 0, 0
+	starToken, starKey := -1, 0
+
+	for k < len(key) {
+		switch {
+		case t < len(tokens) && tokens[t].wildcard == '*':
+			// Record the star and first try matching it against nothing
+			starToken, starKey = t, k
+			t++
+		case t < len(tokens) && (tokens[t].wildcard == '?' || (tokens[t].wildcard == 0 && tokens[t].literal == key[k])):
+			t++
+			k++
+		case starToken >= 0:
+			// Mismatch - let the last star absorb one more character
+			starKey++
+			t, k = starToken+1, starKey
+		default:
+			return false
+		}
+	}
+
+	// Remaining tokens must all be stars
+	for t < len(tokens) && tokens[t].wildcard == '*' {
+		t++
+	}
+	return t == len(tokens)
+}
@@ -0,0 +1,77 @@
+package cache
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		key     string
+		want    bool
+	}{
+		// Simple forms (fast paths)
+		{name: "all", pattern: "*", key: "light:1", want: true},
+		{name: "all matches empty", pattern: "*", key: "", want: true},
+		{name: "exact", pattern: "light:1", key: "light:1", want: true},
+		{name: "exact mismatch", pattern: "light:1", key: "light:12", want: false},
+		{name: "prefix", pattern: "light:*", key: "light:abc", want: true},
+		{name: "prefix mismatch", pattern: "light:*", key: "grouped_light:abc", want: false},
+		{name: "suffix", pattern: "*:abc", key: "room:abc", want: true},
+		{name: "suffix mismatch", pattern: "*:abc", key: "room:abcd", want: false},
+
+		// Mid-key and multiple wildcards
+		{name: "middle star", pattern: "light:*:temp", key: "light:abc:temp", want: true},
+		{name: "middle star spans colons", pattern: "light:*:temp", key: "light:a:b:temp", want: true},
+		{name: "middle star mismatch", pattern: "light:*:temp", key: "light:abc:color", want: false},
+		{name: "contains", pattern: "*light*", key: "grouped_light:1", want: true},
+		{name: "contains at start", pattern: "*light*", key: "light:1", want: true},
+		{name: "contains mismatch", pattern: "*light*", key: "room:1", want: false},
+		{name: "many stars", pattern: "*a*b*c*", key: "xaybzc", want: true},
+		{name: "many stars out of order", pattern: "*a*b*c*", key: "xcybza", want: false},
+		{name: "consecutive stars", pattern: "light:**", key: "light:1", want: true},
+		{name: "backtracking", pattern: "*ab", key: "aaab", want: true},
+
+		// Single-character wildcard
+		{name: "question", pattern: "light:?", key: "light:1", want: true},
+		{name: "question needs one char", pattern: "light:?", key: "light:", want: false},
+		{name: "question only one char", pattern: "light:?", key: "light:12", want: false},
+		{name: "question and star", pattern: "?oom:*", key: "room:1", want: true},
+		{name: "question multibyte", pattern: "scene:?", key: "scene:é", want: true},
+
+		// Empty segments
+		{name: "empty id", pattern: "light:*", key: "light:", want: true},
+		{name: "empty middle segment", pattern: "a:*:c", key: "a::c", want: true},
+		{name: "empty pattern", pattern: "", key: "", want: true},
+		{name: "empty pattern nonempty key", pattern: "", key: "a", want: false},
+
+		// Literal special characters
+		{name: "regex chars are literal", pattern: "light.[1]+", key: "light.[1]+", want: true},
+		{name: "regex chars not regex", pattern: "light.[1]+", key: "lightx1", want: false},
+		{name: "escaped star", pattern: `light:\*`, key: "light:*", want: true},
+		{name: "escaped star is literal", pattern: `light:\*`, key: "light:1", want: false},
+		{name: "escaped question", pattern: `what\?`, key: "what?", want: true},
+		{name: "escaped backslash", pattern: `a\\b`, key: `a\b`, want: true},
+		{name: "trailing backslash", pattern: `a\`, key: `a\`, want: true},
+		{name: "escape with star", pattern: `*\*`, key: "light:*", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchPattern(tt.pattern, tt.key); got != tt.want {
+				t.Errorf("MatchPattern(%q, %q) = %v, want %v", tt.pattern, tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkMatchPattern_Prefix(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		MatchPattern("light:*", "light:abc-123")
+	}
+}
+
+func BenchmarkMatchPattern_Glob(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		MatchPattern("*light*:?bc-*", "grouped_light:abc-123")
+	}
+}
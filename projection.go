@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Projection strips cached resources down to an allowlist of JSON fields
+// per resource type before they are stored, shrinking the cache when
+// callers only read a few fields (e.g. a light's on state, brightness,
+// and name). Share one Projection between CachedClientConfig and
+// SyncConfig, so entries written by either are projected alike.
+//
+// Projected resources are partial: Get and List return them with every
+// other field at its zero value, which is indistinguishable from a real
+// zero (a light at 0 brightness, say). Only project fields nothing reads.
+// The "id" and "type" fields are always kept, so cache keys, validation,
+// and ID lookups keep working. Raw entries (CacheRaw) are not projected.
+//
+// Example:
+//
+//	projection := cache.NewProjection(map[string][]string{
+//	    "light": {"on", "dimming.brightness", "metadata.name"},
+//	})
+//	config := cache.DefaultCachedClientConfig()
+//	config.Projection = projection
+//	config.SyncConfig.Projection = projection
+type Projection struct {
+	// fields maps resource types to the paths of the fields to keep.
+	fields map[string][][]string
+
+	keyBuilder *KeyBuilder
+}
+
+// NewProjection creates a Projection that keeps only fields[resourceType]
+// of resources of that type. Fields are JSON names, with dots selecting
+// fields of nested objects ("dimming.brightness"). Types without fields
+// are cached in full.
+func NewProjection(fields map[string][]string) *Projection {
+	p := &Projection{
+		fields:     make(map[string][][]string, len(fields)),
+		keyBuilder: NewKeyBuilder(),
+	}
+	for resourceType, names := range fields {
+		paths := [][]string{{"id"}, {"type"}}
+		for _, name := range names {
+			paths = append(paths, strings.Split(name, "."))
+		}
+		p.fields[resourceType] = paths
+	}
+	return p
+}
+
+// Apply returns the JSON of the resource cached under key reduced to the
+// fields configured for its type. Values of other types, and values that
+// aren't JSON objects, are returned unchanged. A nil Projection returns
+// every value unchanged.
+func (p *Projection) Apply(key string, value []byte) ([]byte, error) {
+	if p == nil {
+		return value, nil
+	}
+
+	resourceType, _, _ := p.keyBuilder.ParseKey(key)
+	paths, ok := p.fields[resourceType]
+	if !ok {
+		return value, nil
+	}
+
+	var resource map[string]json.RawMessage
+	if err := json.Unmarshal(value, &resource); err != nil || resource == nil {
+		return value, nil
+	}
+
+	projected := make(map[string]json.RawMessage)
+	for _, path := range paths {
+		if err := keepPath(projected, resource, path); err != nil {
+			return nil, NewError("Project", key, err)
+		}
+	}
+	return json.Marshal(projected)
+}
+
+// keepPath copies the field at path from src to dst, creating the nested
+// objects leading to it. Missing fields are skipped.
+func keepPath(dst, src map[string]json.RawMessage, path []string) error {
+	field, ok := src[path[0]]
+	if !ok {
+		return nil
+	}
+	if len(path) == 1 {
+		dst[path[0]] = field
+		return nil
+	}
+
+	var srcChild map[string]json.RawMessage
+	if err := json.Unmarshal(field, &srcChild); err != nil || srcChild == nil {
+		// Not an object; nothing nested to keep
+		return nil
+	}
+
+	dstChild := make(map[string]json.RawMessage)
+	if existing, ok := dst[path[0]]; ok {
+		if err := json.Unmarshal(existing, &dstChild); err != nil {
+			return fmt.Errorf("projecting %q: %w", path[0], err)
+		}
+	}
+	if err := keepPath(dstChild, srcChild, path[1:]); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(dstChild)
+	if err != nil {
+		return err
+	}
+	dst[path[0]] = data
+	return nil
+}
+
+// projectResource serializes a resource for the cache under key with
+// codec, keeping only the fields projection allows.
+func projectResource(codec Codec, projection *Projection, key string, resource any) ([]byte, error) {
+	if projection == nil {
+		return codec.Marshal(resource)
+	}
+
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = projection.Apply(key, data); err != nil {
+		return nil, err
+	}
+	return transcodeJSON(codec, data)
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+func TestProjection_Apply(t *testing.T) {
+	projection := NewProjection(map[string][]string{
+		"light": {"on", "dimming.brightness", "metadata.name", "metadata.missing", "missing"},
+	})
+	light := []byte(`{"id":"1","type":"light","on":{"on":true},"dimming":{"brightness":50,"min_dim_level":2},` +
+		`"metadata":{"name":"Desk","archetype":"sultan_bulb"},"color":{"xy":{"x":0.3,"y":0.3}}}`)
+
+	projected, err := projection.Apply("light:1", light)
+	if err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(projected, &got); err != nil {
+		t.Fatalf("projected value is not JSON: %v", err)
+	}
+	want := map[string]any{
+		"id":       "1",
+		"type":     "light",
+		"on":       map[string]any{"on": true},
+		"dimming":  map[string]any{"brightness": 50.0},
+		"metadata": map[string]any{"name": "Desk"},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Apply() = %v, want %v", got, want)
+	}
+
+	// Other types, non-objects, and a nil Projection pass through
+	room := []byte(`{"id":"1","type":"room","children":[]}`)
+	if got, _ := projection.Apply("room:1", room); string(got) != string(room) {
+		t.Errorf("Apply(room) = %s, want unchanged", got)
+	}
+	if got, _ := projection.Apply("light:1", []byte(`[1]`)); string(got) != `[1]` {
+		t.Errorf("Apply(array) = %s, want unchanged", got)
+	}
+	if got, _ := (*Projection)(nil).Apply("light:1", light); string(got) != string(light) {
+		t.Errorf("nil Apply() = %s, want unchanged", got)
+	}
+}
+
+func TestCachedLightClient_Projection(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{
+		ID:       "light-1",
+		Type:     "light",
+		On:       resources.OnState{On: true},
+		Metadata: resources.Metadata{Name: "Desk"},
+	}
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	client.configure(&CachedClientConfig{
+		Projection: NewProjection(map[string][]string{"light": {"metadata.name"}}),
+	})
+
+	if _, err := client.Get(context.Background(), "light-1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	// Served from the projected entry: on is dropped, name kept
+	light, err := client.Get(context.Background(), "light-1")
+	if err != nil {
+		t.Fatalf("cached Get() failed: %v", err)
+	}
+	if mockSDK.calls["Get"] != 1 {
+		t.Errorf("SDK Get called %d times, want 1", mockSDK.calls["Get"])
+	}
+	if light.ID != "light-1" || light.Type != "light" || light.Metadata.Name != "Desk" || light.On.On {
+		t.Errorf("light = %+v, want projected to id, type, and name", light)
+	}
+}
+
+// TestProjection_Savings measures the cache size of 300 lights with full
+// bridge JSON against the same lights projected to on, brightness, and
+// name.
+func TestProjection_Savings(t *testing.T) {
+	size := func(projection *Projection) int64 {
+		backend := newMockBackend()
+		config := DefaultSyncConfig()
+		config.Projection = projection
+		engine := NewSyncEngine(backend, nil, config)
+
+		for i := range 300 {
+			id := fmt.Sprintf("light-%03d", i)
+			data := &resources.EventData{ID: id, Type: "light", RawData: bridgeLightJSON(id)}
+			if err := engine.processEventData(context.Background(), resources.EventTypeAdd, data); err != nil {
+				t.Fatalf("processEventData() failed: %v", err)
+			}
+		}
+
+		var total int64
+		for _, entry := range backend.data {
+			total += entry.Size
+		}
+		return total
+	}
+
+	full := size(nil)
+	projected := size(NewProjection(map[string][]string{
+		"light": {"on", "dimming.brightness", "metadata.name"},
+	}))
+	t.Logf("300 lights: %d bytes full, %d bytes projected (%.0f%% smaller)",
+		full, projected, 100*(1-float64(projected)/float64(full)))
+
+	if projected*4 > full {
+		t.Errorf("projected size %d is more than a quarter of full size %d", projected, full)
+	}
+}
+
+// bridgeLightJSON returns a light resource shaped like the bridge's.
+func bridgeLightJSON(id string) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"id":%q,"id_v1":"/lights/1","type":"light",`+
+		`"owner":{"rid":"7ee6b4a5-2a5e-4e2c-9d5d-3c1d2b1e0f9a","rtype":"device"},`+
+		`"metadata":{"name":"Light %s","archetype":"sultan_bulb","function":"mixed"},`+
+		`"product_data":{"function":"mixed"},"identify":{},`+
+		`"on":{"on":true},"dimming":{"brightness":75.5,"min_dim_level":0.2},"dimming_delta":{},`+
+		`"color_temperature":{"mirek":366,"mirek_valid":true,"mirek_schema":{"mirek_minimum":153,"mirek_maximum":500}},`+
+		`"color_temperature_delta":{},`+
+		`"color":{"xy":{"x":0.4573,"y":0.41},"gamut":{"red":{"x":0.6915,"y":0.3083},"green":{"x":0.17,"y":0.7},"blue":{"x":0.1532,"y":0.0475}},"gamut_type":"C"},`+
+		`"dynamics":{"status":"none","status_values":["none","dynamic_palette"],"speed":0,"speed_valid":false},`+
+		`"alert":{"action_values":["breathe"]},"signaling":{"signal_values":["no_signal","on_off","on_off_color","alternating"]},`+
+		`"mode":"normal","effects":{"status_values":["no_effect","candle","fire","prism","sparkle","opal","glisten"],"status":"no_effect","effect_values":["no_effect","candle","fire","prism","sparkle","opal","glisten"]},`+
+		`"powerup":{"preset":"safety","configured":true,"on":{"mode":"on","on":{"on":true}},"dimming":{"mode":"dimming","dimming":{"brightness":100}},"color":{"mode":"color_temperature","color_temperature":{"mirek":366}}}}`,
+		id, id))
+}
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+// rawLightClient is a light client that also serves raw bridge JSON,
+// including a field the SDK's Light type doesn't model.
+type rawLightClient struct {
+	*mockLightClient
+}
+
+func (m *rawLightClient) GetRaw(ctx context.Context, id string) (json.RawMessage, error) {
+	m.calls["GetRaw"]++
+	return json.RawMessage(`{"id":"` + id + `","type":"light","on":{"on":true},"x_unmodeled":42}`), nil
+}
+
+func TestCachedLightClient_CacheRaw(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	client.configure(&CachedClientConfig{CacheRaw: true})
+	ctx := context.Background()
+
+	if _, err := client.Get(ctx, "light-1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	// Both the parsed and raw entries are cached
+	if _, err := backend.Get(ctx, "light:light-1"); err != nil {
+		t.Errorf("Parsed entry not cached: %v", err)
+	}
+	if _, err := backend.Get(ctx, "raw:light:light-1"); err != nil {
+		t.Errorf("Raw entry not cached: %v", err)
+	}
+
+	raw, err := client.GetRaw(ctx, "light-1")
+	if err != nil {
+		t.Fatalf("GetRaw() failed: %v", err)
+	}
+	var light resources.Light
+	if err := json.Unmarshal(raw, &light); err != nil || light.ID != "light-1" {
+		t.Errorf("GetRaw() = %s, want light-1 JSON", raw)
+	}
+	if mockSDK.calls["Get"] != 1 {
+		t.Errorf("Expected GetRaw to be served from cache, got %d SDK Gets", mockSDK.calls["Get"])
+	}
+
+	// Raw entries don't show up as resources
+	lights, err := client.List(ctx)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(lights) != 1 {
+		t.Errorf("List() returned %d lights, want 1", len(lights))
+	}
+
+	// Writes invalidate both entries together
+	if err := client.Update(ctx, "light-1", resources.LightUpdate{On: &resources.OnState{On: true}}); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if _, err := backend.Get(ctx, "light:light-1"); err == nil {
+		t.Error("Parsed entry should be invalidated by Update")
+	}
+	if _, err := backend.Get(ctx, "raw:light:light-1"); err == nil {
+		t.Error("Raw entry should be invalidated by Update")
+	}
+}
+
+func TestCachedLightClient_CacheRaw_RawGetter(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := &rawLightClient{newMockLightClient()}
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	client.configure(&CachedClientConfig{CacheRaw: true})
+	ctx := context.Background()
+
+	light, err := client.Get(ctx, "light-1")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if !light.On.On {
+		t.Error("Expected light decoded from raw JSON")
+	}
+	if mockSDK.calls["GetRaw"] != 1 || mockSDK.calls["Get"] != 0 {
+		t.Errorf("Expected one GetRaw and no Get, got %v", mockSDK.calls)
+	}
+
+	// The raw entry keeps fields the SDK type drops
+	raw, err := client.GetRaw(ctx, "light-1")
+	if err != nil {
+		t.Fatalf("GetRaw() failed: %v", err)
+	}
+	var fields map[string]interface{}
+	json.Unmarshal(raw, &fields)
+	if fields["x_unmodeled"] != float64(42) {
+		t.Errorf("GetRaw() = %s, want verbatim bridge JSON", raw)
+	}
+}
+
+func TestSyncEngine_CacheRaw(t *testing.T) {
+	backend := newMockBackend()
+	config := DefaultSyncConfig()
+	config.CacheRaw = true
+	engine := NewSyncEngine(backend, nil, config)
+	ctx := context.Background()
+
+	raw := json.RawMessage(`{"id":"light-1","type":"light"}`)
+	data := &resources.EventData{ID: "light-1", Type: "light", RawData: raw}
+
+	engine.processEventData(ctx, resources.EventTypeAdd, data)
+	if entry, err := backend.Get(ctx, "raw:light:light-1"); err != nil || string(entry.Value) != string(raw) {
+		t.Errorf("Add event should store raw JSON, got %v", err)
+	}
+
+	engine.processEventData(ctx, resources.EventTypeUpdate, data)
+	if _, err := backend.Get(ctx, "raw:light:light-1"); err == nil {
+		t.Error("Update event should remove the raw entry")
+	}
+
+	engine.processEventData(ctx, resources.EventTypeAdd, data)
+	engine.processEventData(ctx, resources.EventTypeDelete, data)
+	if _, err := backend.Get(ctx, "raw:light:light-1"); err == nil {
+		t.Error("Delete event should remove the raw entry")
+	}
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+// ReconcileReport describes the changes Reconcile made to bring the cache
+// back in line with the bridge.
+type ReconcileReport struct {
+	// Types holds the counts for each reconciled resource type
+	// (e.g. "light").
+	Types map[string]*ReconcileCounts
+
+	// Errors lists resource types that couldn't be reconciled.
+	Errors []error
+}
+
+// ReconcileCounts counts the changes made for one resource type.
+type ReconcileCounts struct {
+	// Added is the number of resources missing from the cache.
+	Added int
+
+	// Updated is the number of cached resources that differed.
+	Updated int
+
+	// Removed is the number of cached resources no longer on the bridge.
+	Removed int
+
+	// Unchanged is the number of cached resources already up to date.
+	Unchanged int
+}
+
+// Total returns the number of changes made across all resource types.
+func (r *ReconcileReport) Total() int {
+	total := 0
+	for _, counts := range r.Types {
+		total += counts.Added + counts.Updated + counts.Removed
+	}
+	return total
+}
+
+// Reconcile compares the cache with the bridge and repairs drift, e.g.
+// after SSE events were missed during an outage. For each resource type
+// it lists resources from the bridge, adds ones missing from the cache,
+// updates ones whose cached bytes differ, and removes cached resources
+// that no longer exist. Entries are written without a TTL, like sync.
+//
+// Unlike ClearAll followed by WarmCache, Reconcile only touches entries
+// that are wrong, and the cache stays populated throughout. A type whose
+// list request fails is recorded in ReconcileReport.Errors and left as is.
+// It requires the manager to have an SDK client.
+func (m *CacheManager) Reconcile(ctx context.Context) (*ReconcileReport, error) {
+	if m.client == nil {
+		return nil, errors.New("reconciling requires an SDK client")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := &ReconcileReport{Types: make(map[string]*ReconcileCounts)}
+
+	reconcile := func(resourceType string, counts *ReconcileCounts, err error) {
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("%s: %w", resourceType, err))
+			return
+		}
+		report.Types[resourceType] = counts
+	}
+
+	counts, err := reconcileType(ctx, m, "light", m.client.Lights().List, func(r *resources.Light) string { return r.ID })
+	reconcile("light", counts, err)
+
+	counts, err = reconcileType(ctx, m, "room", m.client.Rooms().List, func(r *resources.Room) string { return r.ID })
+	reconcile("room", counts, err)
+
+	counts, err = reconcileType(ctx, m, "zone", m.client.Zones().List, func(r *resources.Zone) string { return r.ID })
+	reconcile("zone", counts, err)
+
+	counts, err = reconcileType(ctx, m, "scene", m.client.Scenes().List, func(r *resources.Scene) string { return r.ID })
+	reconcile("scene", counts, err)
+
+	counts, err = reconcileType(ctx, m, "grouped_light", m.client.GroupedLights().List, func(r *resources.GroupedLight) string { return r.ID })
+	reconcile("grouped_light", counts, err)
+
+	return report, nil
+}
+
+// reconcileType reconciles the cached resources of one type against the
+// list returned by the SDK.
+func reconcileType[T any](ctx context.Context, m *CacheManager, resourceType string, list func(context.Context) ([]T, error), idOf func(*T) string) (*ReconcileCounts, error) {
+	items, err := list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cachedKeys, err := m.backend.Keys(ctx, m.keyBuilder.AllResources(resourceType))
+	if err != nil {
+		return nil, fmt.Errorf("getting keys: %w", err)
+	}
+
+	counts := &ReconcileCounts{}
+	current := make(map[string]bool, len(items))
+	for i := range items {
+		key := m.keyBuilder.Resource(resourceType, idOf(&items[i]))
+		current[key] = true
+
+		data, err := m.codec.Marshal(&items[i])
+		if err != nil {
+			return nil, fmt.Errorf("marshaling %s: %w", key, err)
+		}
+
+		entry, err := m.backend.Get(ctx, key)
+		switch {
+		case err != nil || isTombstone(entry.Value):
+			counts.Added++
+		case bytes.Equal(entry.Value, data):
+			counts.Unchanged++
+			continue
+		default:
+			counts.Updated++
+		}
+
+		if err := m.backend.Set(ctx, key, data, 0); err != nil {
+			return nil, fmt.Errorf("storing %s: %w", key, err)
+		}
+	}
+
+	var stale []string
+	for _, key := range cachedKeys {
+		if current[key] {
+			continue
+		}
+
+		// Negative entries already record that the resource is absent
+		if entry, err := m.backend.Get(ctx, key); err == nil && isTombstone(entry.Value) {
+			continue
+		}
+
+		stale = append(stale, key)
+	}
+
+	if len(stale) > 0 {
+		removed, err := DeleteMany(ctx, m.backend, stale)
+		counts.Removed = removed
+		if err != nil {
+			return nil, fmt.Errorf("removing stale entries: %w", err)
+		}
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+func TestReconcileType(t *testing.T) {
+	ctx := context.Background()
+	backend := newMockBackend()
+	manager := NewCacheManager(backend, nil)
+
+	mockSDK := newMockRoomClient()
+	mockSDK.rooms["room-1"] = &resources.Room{ID: "room-1", Type: "room"}
+	mockSDK.rooms["room-2"] = &resources.Room{ID: "room-2", Type: "room"}
+	mockSDK.rooms["room-3"] = &resources.Room{ID: "room-3", Type: "room"}
+
+	// room-1 is up to date, room-2 has drifted, room-3 is missing,
+	// room-gone was deleted on the bridge during an outage, and
+	// room-absent is a negative cache entry
+	cached := NewCachedRoomClient(backend, mockSDK, 0)
+	cached.store(ctx, "room:room-1", mockSDK.rooms["room-1"])
+	cached.store(ctx, "room:room-2", &resources.Room{ID: "room-2", Type: "room", Metadata: resources.Metadata{Name: "Old"}})
+	cached.store(ctx, "room:room-gone", &resources.Room{ID: "room-gone", Type: "room"})
+	backend.Set(ctx, "room:room-absent", tombstoneValue, 0)
+
+	counts, err := reconcileType(ctx, manager, "room", mockSDK.List, func(r *resources.Room) string { return r.ID })
+	if err != nil {
+		t.Fatalf("reconcileType() failed: %v", err)
+	}
+
+	want := ReconcileCounts{Added: 1, Updated: 1, Removed: 1, Unchanged: 1}
+	if *counts != want {
+		t.Errorf("counts = %+v, want %+v", *counts, want)
+	}
+
+	for _, key := range []string{"room:room-1", "room:room-2", "room:room-3", "room:room-absent"} {
+		if _, err := backend.Get(ctx, key); err != nil {
+			t.Errorf("Expected %s to be cached: %v", key, err)
+		}
+	}
+	if _, err := backend.Get(ctx, "room:room-gone"); err == nil {
+		t.Error("Expected room-gone to be removed")
+	}
+
+	// A second pass finds nothing to do
+	counts, _ = reconcileType(ctx, manager, "room", mockSDK.List, func(r *resources.Room) string { return r.ID })
+	if want := (ReconcileCounts{Unchanged: 3}); *counts != want {
+		t.Errorf("second pass counts = %+v, want %+v", *counts, want)
+	}
+}
+
+func TestReconcileType_ListError(t *testing.T) {
+	ctx := context.Background()
+	backend := newMockBackend()
+	backend.Set(ctx, "light:light-1", []byte(`{"id":"light-1"}`), 0)
+	manager := NewCacheManager(backend, nil)
+
+	list := func(ctx context.Context) ([]resources.Light, error) {
+		return nil, errors.New("bridge unreachable")
+	}
+
+	if _, err := reconcileType(ctx, manager, "light", list, func(l *resources.Light) string { return l.ID }); err == nil {
+		t.Fatal("Expected error when the SDK list fails")
+	}
+
+	// Nothing is removed when the bridge can't be consulted
+	if _, err := backend.Get(ctx, "light:light-1"); err != nil {
+		t.Error("Cache should be untouched after a failed list")
+	}
+}
+
+func TestCacheManager_Reconcile_NoClient(t *testing.T) {
+	manager := NewCacheManager(newMockBackend(), nil)
+	if report, err := manager.Reconcile(context.Background()); err == nil {
+		t.Errorf("Reconcile() without an SDK client = %+v, want error", report)
+	}
+}
+
+func TestReconcileReport_Total(t *testing.T) {
+	report := &ReconcileReport{Types: map[string]*ReconcileCounts{
+		"light": {Added: 1, Updated: 2, Unchanged: 10},
+		"room":  {Removed: 3},
+	}}
+
+	if got := report.Total(); got != 6 {
+		t.Errorf("Total() = %d, want 6", got)
+	}
+}
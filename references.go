@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/rmrfslashbin/hue-sdk"
+)
+
+// sceneReferences lists scenes and returns the IDs of every resource they
+// refer to: action targets (lights, rooms, zones) and the scene's group.
+// References are found in the scenes' JSON, as "rid" fields of Hue
+// resource identifiers, so they are collected wherever the API nests them.
+func sceneReferences(ctx context.Context, sdk hue.SceneClient) (map[string]bool, error) {
+	scenes, err := sdk.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]bool)
+	for _, scene := range scenes {
+		for _, rid := range resourceIDs(scene) {
+			refs[rid] = true
+		}
+	}
+	return refs, nil
+}
+
+// referencesAny reports whether resource refers to any ID in refs.
+func referencesAny(resource any, refs map[string]bool) bool {
+	for _, rid := range resourceIDs(resource) {
+		if refs[rid] {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceIDs returns the "rid" values of all resource identifiers in
+// resource's JSON form.
+func resourceIDs(resource any) []string {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil
+	}
+
+	var rids []string
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if rid, ok := v["rid"].(string); ok && rid != "" {
+				rids = append(rids, rid)
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(decoded)
+	return rids
+}
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// resourceRef is a reference from one resource to another.
+type resourceRef struct {
+	RID   string `json:"rid"`
+	RType string `json:"rtype"`
+}
+
+// resourceRelations is the subset of a resource's JSON needed to follow
+// its references.
+type resourceRelations struct {
+	Children []resourceRef `json:"children"`
+	Services []resourceRef `json:"services"`
+	Owner    *resourceRef  `json:"owner"`
+	Group    *resourceRef  `json:"group"`
+}
+
+// InvalidateRelated deletes the cache entries of the resources related to
+// the resource identified by resourceType and id. The resource itself is
+// left cached. Supported relationships:
+//
+//   - room → its lights: children of type light, and the lights of child
+//     devices whose device resource is cached
+//   - zone → its lights: children of type light
+//   - grouped_light → the lights of its owning room or zone
+//   - scene → its group (the room or zone it belongs to)
+//
+// The resource is read from the cache, or from the SDK on a miss. Raw
+// entries of the related resources are deleted too.
+func (m *CacheManager) InvalidateRelated(ctx context.Context, resourceType, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys, err := m.relatedKeys(ctx, resourceType, id)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := m.backend.Delete(ctx, key); err != nil {
+			return fmt.Errorf("invalidating %s: %w", key, err)
+		}
+		if err := m.backend.Delete(ctx, m.keyBuilder.Raw(key)); err != nil {
+			return fmt.Errorf("invalidating %s: %w", m.keyBuilder.Raw(key), err)
+		}
+	}
+
+	return nil
+}
+
+// relatedKeys resolves the cache keys of the resources related to a
+// resource. See InvalidateRelated for the supported relationships.
+func (m *CacheManager) relatedKeys(ctx context.Context, resourceType, id string) ([]string, error) {
+	switch resourceType {
+	case "room", "zone", "scene", "grouped_light":
+	default:
+		return nil, fmt.Errorf("related resources of type %q are not supported", resourceType)
+	}
+
+	relations, err := m.loadRelations(ctx, resourceType, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resourceType {
+	case "scene":
+		if relations.Group == nil {
+			return nil, nil
+		}
+		return []string{m.keyBuilder.Resource(relations.Group.RType, relations.Group.RID)}, nil
+
+	case "grouped_light":
+		if relations.Owner == nil || (relations.Owner.RType != "room" && relations.Owner.RType != "zone") {
+			return nil, nil
+		}
+		return m.relatedKeys(ctx, relations.Owner.RType, relations.Owner.RID)
+	}
+
+	// Rooms and zones: member lights
+	var keys []string
+	for _, child := range relations.Children {
+		switch child.RType {
+		case "light":
+			keys = append(keys, m.keyBuilder.Light(child.RID))
+		case "device":
+			keys = append(keys, m.deviceLightKeys(ctx, child.RID)...)
+		}
+	}
+	return keys, nil
+}
+
+// deviceLightKeys returns the keys of the lights a cached device exposes.
+// Devices aren't fetched from the SDK, so an uncached device yields none.
+func (m *CacheManager) deviceLightKeys(ctx context.Context, id string) []string {
+	entry, err := m.backend.Get(ctx, m.keyBuilder.Device(id))
+	if err != nil {
+		return nil
+	}
+
+	var device resourceRelations
+	if err := m.codec.Unmarshal(entry.Value, &device); err != nil {
+		return nil
+	}
+
+	var keys []string
+	for _, service := range device.Services {
+		if service.RType == "light" {
+			keys = append(keys, m.keyBuilder.Light(service.RID))
+		}
+	}
+	return keys
+}
+
+// loadRelations reads a resource's references from its cache entry, or
+// from the SDK if it isn't cached.
+func (m *CacheManager) loadRelations(ctx context.Context, resourceType, id string) (*resourceRelations, error) {
+	key := m.keyBuilder.Resource(resourceType, id)
+
+	var relations resourceRelations
+	if entry, err := m.backend.Get(ctx, key); err == nil && !isTombstone(entry.Value) {
+		if err := m.codec.Unmarshal(entry.Value, &relations); err == nil {
+			return &relations, nil
+		}
+	}
+
+	resource, err := m.fetchResource(ctx, resourceType, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", key, err)
+	}
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s: %w", key, err)
+	}
+	if err := json.Unmarshal(data, &relations); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", key, err)
+	}
+	return &relations, nil
+}
+
+// fetchResource gets a room, zone, scene, or grouped light from the SDK.
+func (m *CacheManager) fetchResource(ctx context.Context, resourceType, id string) (interface{}, error) {
+	if m.client == nil {
+		return nil, NewError("InvalidateRelated", m.keyBuilder.Resource(resourceType, id), ErrNotFound)
+	}
+
+	switch resourceType {
+	case "room":
+		return m.client.Rooms().Get(ctx, id)
+	case "zone":
+		return m.client.Zones().Get(ctx, id)
+	case "scene":
+		return m.client.Scenes().Get(ctx, id)
+	case "grouped_light":
+		return m.client.GroupedLights().Get(ctx, id)
+	default:
+		return nil, fmt.Errorf("fetching %s resources is not supported", resourceType)
+	}
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+)
+
+// seedRelatedCache populates a backend with a room of one light and one
+// device, a zone, their grouped lights, a scene, and unrelated lights.
+func seedRelatedCache(t *testing.T, backend Backend) {
+	t.Helper()
+	ctx := context.Background()
+
+	entries := map[string]string{
+		"room:room-1":        `{"id":"room-1","children":[{"rid":"light-1","rtype":"light"},{"rid":"dev-2","rtype":"device"}]}`,
+		"zone:zone-1":        `{"id":"zone-1","children":[{"rid":"light-2","rtype":"light"},{"rid":"light-3","rtype":"light"}]}`,
+		"device:dev-2":       `{"id":"dev-2","services":[{"rid":"light-2","rtype":"light"},{"rid":"zb-2","rtype":"zigbee_connectivity"}]}`,
+		"grouped_light:gl-1": `{"id":"gl-1","owner":{"rid":"room-1","rtype":"room"}}`,
+		"grouped_light:gl-2": `{"id":"gl-2","owner":{"rid":"zone-1","rtype":"zone"}}`,
+		"scene:scene-1":      `{"id":"scene-1","group":{"rid":"zone-1","rtype":"zone"}}`,
+		"light:light-1":      `{"id":"light-1"}`,
+		"light:light-2":      `{"id":"light-2"}`,
+		"light:light-3":      `{"id":"light-3"}`,
+		"light:light-4":      `{"id":"light-4"}`,
+		"raw:light:light-1":  `{"id":"light-1"}`,
+	}
+
+	for key, value := range entries {
+		if err := backend.Set(ctx, key, []byte(value), 0); err != nil {
+			t.Fatalf("Set(%s) failed: %v", key, err)
+		}
+	}
+}
+
+func TestCacheManager_InvalidateRelated(t *testing.T) {
+	tests := []struct {
+		name         string
+		resourceType string
+		id           string
+		removed      []string
+	}{
+		{name: "room lights and device lights", resourceType: "room", id: "room-1",
+			removed: []string{"light:light-1", "light:light-2", "raw:light:light-1"}},
+		{name: "zone lights", resourceType: "zone", id: "zone-1",
+			removed: []string{"light:light-2", "light:light-3"}},
+		{name: "grouped light owner's lights", resourceType: "grouped_light", id: "gl-1",
+			removed: []string{"light:light-1", "light:light-2", "raw:light:light-1"}},
+		{name: "scene group", resourceType: "scene", id: "scene-1",
+			removed: []string{"zone:zone-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newMockBackend()
+			seedRelatedCache(t, backend)
+			manager := NewCacheManager(backend, nil)
+			ctx := context.Background()
+
+			before, _ := backend.Keys(ctx, "*")
+			if err := manager.InvalidateRelated(ctx, tt.resourceType, tt.id); err != nil {
+				t.Fatalf("InvalidateRelated() failed: %v", err)
+			}
+			after, _ := backend.Keys(ctx, "*")
+
+			remaining := make(map[string]bool)
+			for _, key := range after {
+				remaining[key] = true
+			}
+			var removed []string
+			for _, key := range before {
+				if !remaining[key] {
+					removed = append(removed, key)
+				}
+			}
+			sort.Strings(removed)
+
+			if len(removed) != len(tt.removed) {
+				t.Fatalf("removed %v, want %v", removed, tt.removed)
+			}
+			for i := range removed {
+				if removed[i] != tt.removed[i] {
+					t.Errorf("removed %v, want %v", removed, tt.removed)
+					break
+				}
+			}
+
+			// The resource itself stays cached
+			if !remaining[NewKeyBuilder().Resource(tt.resourceType, tt.id)] {
+				t.Errorf("%s:%s itself was removed", tt.resourceType, tt.id)
+			}
+		})
+	}
+}
+
+func TestCacheManager_InvalidateRelatedErrors(t *testing.T) {
+	backend := newMockBackend()
+	seedRelatedCache(t, backend)
+	manager := NewCacheManager(backend, nil)
+	ctx := context.Background()
+
+	if err := manager.InvalidateRelated(ctx, "light", "light-1"); err == nil {
+		t.Error("InvalidateRelated() of a light should be unsupported")
+	}
+
+	// Not cached, and no SDK client to fetch it from
+	if err := manager.InvalidateRelated(ctx, "room", "room-missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("InvalidateRelated() of missing room error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,357 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Replicator is a Backend that writes to a primary backend and streams
+// every successful mutation to a replica backend in the background.
+//
+// It separates the write path from the read path: point the SyncEngine and
+// CacheManager at the Replicator (so sync and warming write to the primary)
+// and point the cached clients at the replica. The replica is eventually
+// consistent with the primary; changes are applied in the order the
+// primary applied them. Writes through the Replicator are serialized to
+// keep that order, even concurrent writes to the same key.
+//
+// Example:
+//
+//	primary := backends.NewMemory()
+//	replica := backends.NewMemory()
+//	replicator := cache.NewReplicator(primary, replica, nil)
+//	defer replicator.Close()
+//	defer replica.Close()
+//
+//	syncEngine := cache.NewSyncEngine(replicator, sdkClient)
+//	manager := cache.NewCacheManager(replicator, sdkClient)
+//	cachedClient := cache.NewCachedClient(replica, sdkClient, nil)
+type Replicator struct {
+	primary Backend
+	replica Backend
+	logger  Logger
+
+	changes chan replicaChange
+	done    chan struct{}
+
+	// writeMu is held across each primary write and the queueing of its
+	// change, so changes reach the replica in the order the primary
+	// applied them
+	writeMu sync.Mutex
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// ReplicatorConfig contains configuration for a Replicator.
+type ReplicatorConfig struct {
+	// QueueSize is the number of pending changes buffered for the replica.
+	// When the queue is full, primary writes block until the replica
+	// catches up, so the replica never silently misses a change.
+	// Default: 1024
+	QueueSize int
+
+	// Logger receives replica apply failures.
+	// Default: no-op logger
+	Logger Logger
+}
+
+// DefaultReplicatorConfig returns default replicator configuration.
+func DefaultReplicatorConfig() *ReplicatorConfig {
+	return &ReplicatorConfig{
+		QueueSize: 1024,
+	}
+}
+
+// replicaOp identifies the kind of change being replicated.
+type replicaOp int
+
+const (
+	replicaSet replicaOp = iota
+	replicaDelete
+	replicaTouch
+	replicaClear
+	replicaDeletePattern
+	replicaDeleteTag
+)
+
+// replicaChange is a single entry in the change feed.
+type replicaChange struct {
+	op replicaOp
+
+	// key is the pattern for replicaDeletePattern and the tag for
+	// replicaDeleteTag
+	key   string
+	value []byte
+	ttl   time.Duration
+	tags  []string
+}
+
+// NewReplicator creates a Replicator that writes to primary and replicates
+// to replica. If config is nil, defaults are used.
+func NewReplicator(primary, replica Backend, config *ReplicatorConfig) *Replicator {
+	if config == nil {
+		config = DefaultReplicatorConfig()
+	}
+
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultReplicatorConfig().QueueSize
+	}
+
+	r := &Replicator{
+		primary: primary,
+		replica: replica,
+		logger:  loggerOrNop(config.Logger),
+		changes: make(chan replicaChange, queueSize),
+		done:    make(chan struct{}),
+	}
+
+	go r.replicateLoop()
+
+	return r
+}
+
+// Get retrieves a value from the primary.
+func (r *Replicator) Get(ctx context.Context, key string) (*Entry, error) {
+	return r.primary.Get(ctx, key)
+}
+
+// GetMeta reads entry metadata from the primary. See MetaGetter.
+func (r *Replicator) GetMeta(ctx context.Context, key string) (*EntryMeta, error) {
+	return GetMeta(ctx, r.primary, key)
+}
+
+// Peek reads an entry from the primary without side effects. See Peeker.
+func (r *Replicator) Peek(ctx context.Context, key string) (*Entry, error) {
+	return Peek(ctx, r.primary, key)
+}
+
+// Set stores a value in the primary and queues it for the replica.
+func (r *Replicator) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	if err := r.primary.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	valueCopy := make([]byte, len(value))
+	copy(valueCopy, value)
+	return r.publish(replicaChange{op: replicaSet, key: key, value: valueCopy, ttl: ttl})
+}
+
+// SetWithTags stores a tagged value in the primary and queues it for the
+// replica. See Tagger.
+func (r *Replicator) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	if err := SetWithTags(ctx, r.primary, key, value, ttl, tags); err != nil {
+		return err
+	}
+
+	valueCopy := make([]byte, len(value))
+	copy(valueCopy, value)
+	return r.publish(replicaChange{op: replicaSet, key: key, value: valueCopy, ttl: ttl, tags: slices.Clone(tags)})
+}
+
+// SetIf conditionally stores a value in the primary and, if it was
+// stored, queues it for the replica. The replica applies it
+// unconditionally, since it mirrors the primary. See ConditionalSetter.
+func (r *Replicator) SetIf(ctx context.Context, key string, value []byte, ttl time.Duration, cond func(*Entry) bool) (bool, error) {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	applied, err := SetIf(ctx, r.primary, key, value, ttl, cond)
+	if err != nil || !applied {
+		return false, err
+	}
+
+	valueCopy := make([]byte, len(value))
+	copy(valueCopy, value)
+	return true, r.publish(replicaChange{op: replicaSet, key: key, value: valueCopy, ttl: ttl})
+}
+
+// Delete removes a key from the primary and queues the delete for the replica.
+func (r *Replicator) Delete(ctx context.Context, key string) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	if err := r.primary.Delete(ctx, key); err != nil {
+		return err
+	}
+	return r.publish(replicaChange{op: replicaDelete, key: key})
+}
+
+// Touch extends an entry's TTL in the primary and queues the touch for
+// the replica. See Toucher.
+func (r *Replicator) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	if err := Touch(ctx, r.primary, key, ttl); err != nil {
+		return err
+	}
+	return r.publish(replicaChange{op: replicaTouch, key: key, ttl: ttl})
+}
+
+// Clear removes all entries from the primary and queues the clear for the replica.
+func (r *Replicator) Clear(ctx context.Context) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	if err := r.primary.Clear(ctx); err != nil {
+		return err
+	}
+	return r.publish(replicaChange{op: replicaClear})
+}
+
+// DeletePattern deletes matching keys from the primary and queues the
+// delete for the replica. See PatternDeleter.
+func (r *Replicator) DeletePattern(ctx context.Context, pattern string) (int, error) {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	deleted, err := DeletePattern(ctx, r.primary, pattern)
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, r.publish(replicaChange{op: replicaDeletePattern, key: pattern})
+}
+
+// DeleteMany deletes keys from the primary and queues a delete of each
+// for the replica. See BatchDeleter.
+func (r *Replicator) DeleteMany(ctx context.Context, keys []string) (int, error) {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	deleted, err := DeleteMany(ctx, r.primary, keys)
+	if err != nil {
+		return deleted, err
+	}
+	for _, key := range keys {
+		if err := r.publish(replicaChange{op: replicaDelete, key: key}); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// DeleteTag deletes tagged entries from the primary and queues the
+// delete for the replica. See TagDeleter.
+func (r *Replicator) DeleteTag(ctx context.Context, tag string) (int, error) {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	deleted, err := DeleteTag(ctx, r.primary, tag)
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, r.publish(replicaChange{op: replicaDeleteTag, key: tag})
+}
+
+// Keys returns keys matching pattern from the primary.
+func (r *Replicator) Keys(ctx context.Context, pattern string) ([]string, error) {
+	return r.primary.Keys(ctx, pattern)
+}
+
+// Entries lists entry metadata from the primary. See EntryLister.
+func (r *Replicator) Entries(ctx context.Context, pattern string) ([]*EntryInfo, error) {
+	return ListEntries(ctx, r.primary, pattern)
+}
+
+// Ping checks the primary, which serves all reads. The replica is
+// written asynchronously, so its failures are logged rather than
+// reported. See Pinger.
+func (r *Replicator) Ping(ctx context.Context) error {
+	return Ping(ctx, r.primary)
+}
+
+// Stats returns statistics for the primary.
+func (r *Replicator) Stats(ctx context.Context) (*Stats, error) {
+	return r.primary.Stats(ctx)
+}
+
+// DetailedStats summarizes the primary's entries. See
+// DetailedStatsProvider.
+func (r *Replicator) DetailedStats(ctx context.Context) (*DetailedStats, error) {
+	return GetDetailedStats(ctx, r.primary)
+}
+
+// ResetStats resets the primary's statistics, which Stats reports.
+// See StatsResetter.
+func (r *Replicator) ResetStats(ctx context.Context) error {
+	return ResetStats(ctx, r.primary)
+}
+
+// Primary returns the backend that receives writes.
+func (r *Replicator) Primary() Backend {
+	return r.primary
+}
+
+// Replica returns the backend that receives replicated changes.
+func (r *Replicator) Replica() Backend {
+	return r.replica
+}
+
+// Close stops replication after applying all queued changes, then closes
+// the primary. The replica is not closed, since readers may still be
+// using it; close it separately.
+func (r *Replicator) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.changes)
+	r.mu.Unlock()
+
+	<-r.done
+
+	return r.primary.Close()
+}
+
+// publish adds a change to the feed.
+func (r *Replicator) publish(change replicaChange) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return NewError("Replicate", change.key, ErrBackendClosed)
+	}
+
+	r.changes <- change
+	return nil
+}
+
+// replicateLoop applies queued changes to the replica in order.
+func (r *Replicator) replicateLoop() {
+	defer close(r.done)
+
+	ctx := context.Background()
+	for change := range r.changes {
+		var err error
+		switch change.op {
+		case replicaSet:
+			err = SetWithTags(ctx, r.replica, change.key, change.value, change.ttl, change.tags)
+		case replicaDelete:
+			err = r.replica.Delete(ctx, change.key)
+		case replicaTouch:
+			err = Touch(ctx, r.replica, change.key, change.ttl)
+		case replicaClear:
+			err = r.replica.Clear(ctx)
+		case replicaDeletePattern:
+			_, err = DeletePattern(ctx, r.replica, change.key)
+		case replicaDeleteTag:
+			_, err = DeleteTag(ctx, r.replica, change.key)
+		}
+
+		if err != nil {
+			r.logger.Warn("failed to apply change to replica", "key", change.key, "error", err)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+// waitFor polls cond until it returns true or the timeout elapses.
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return cond()
+}
+
+func TestReplicator_SyncToPrimaryReadFromReplica(t *testing.T) {
+	primary := newMockBackend()
+	replica := newMockBackend()
+	replicator := NewReplicator(primary, replica, nil)
+	defer replicator.Close()
+
+	// Sync engine writes to the primary via the replicator
+	engine := &SyncEngine{
+		backend:    replicator,
+		keyBuilder: NewKeyBuilder(),
+		stats:      &SyncStats{},
+		config:     DefaultSyncConfig(),
+	}
+
+	rawData, _ := json.Marshal(map[string]interface{}{"id": "light-1", "type": "light"})
+	engine.processEvent(&resources.Event{
+		Type: resources.EventTypeAdd,
+		ID:   "event-1",
+		Data: []resources.EventData{
+			{ID: "light-1", Type: "light", RawData: json.RawMessage(rawData)},
+		},
+	})
+
+	// Primary is updated synchronously
+	if _, err := primary.Get(context.Background(), "light:light-1"); err != nil {
+		t.Fatalf("Expected primary to contain light:light-1: %v", err)
+	}
+
+	// Cached client reads from the replica, with no SDK data available
+	mockSDK := newMockLightClient()
+	client := NewCachedLightClient(replica, mockSDK, 0)
+
+	ok := waitFor(t, time.Second, func() bool {
+		_, err := replica.Get(context.Background(), "light:light-1")
+		return err == nil
+	})
+	if !ok {
+		t.Fatal("Replica never received light:light-1")
+	}
+
+	light, err := client.Get(context.Background(), "light-1")
+	if err != nil {
+		t.Fatalf("Get() from replica failed: %v", err)
+	}
+	if light.ID != "light-1" {
+		t.Errorf("Get() ID = %q, want light-1", light.ID)
+	}
+	if mockSDK.calls["Get"] != 0 {
+		t.Errorf("Expected replica hit with no SDK calls, got %d", mockSDK.calls["Get"])
+	}
+
+	// Deletes via the manager propagate too
+	manager := NewCacheManager(replicator, nil)
+	if err := manager.ClearLights(context.Background()); err != nil {
+		t.Fatalf("ClearLights() failed: %v", err)
+	}
+
+	ok = waitFor(t, time.Second, func() bool {
+		_, err := replica.Get(context.Background(), "light:light-1")
+		return err != nil
+	})
+	if !ok {
+		t.Error("Replica still contains light:light-1 after primary clear")
+	}
+}
+
+func TestReplicator_CloseDrainsQueue(t *testing.T) {
+	primary := newMockBackend()
+	replica := newMockBackend()
+	replicator := NewReplicator(primary, replica, &ReplicatorConfig{QueueSize: 4})
+
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		key := "room:" + string(rune('a'+i))
+		if err := replicator.Set(ctx, key, []byte("value"), 0); err != nil {
+			t.Fatalf("Set() failed: %v", err)
+		}
+	}
+
+	if err := replicator.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	keys, _ := replica.Keys(ctx, "room:*")
+	if len(keys) != 20 {
+		t.Errorf("Replica has %d keys after Close, want 20", len(keys))
+	}
+
+	if err := replicator.Set(ctx, "room:z", []byte("value"), 0); err == nil {
+		t.Error("Set() after Close should fail")
+	}
+}
+
+// pausingBackend is a mockBackend that pauses after each Set and Delete,
+// widening the window between a Replicator's primary write and its
+// queueing of the change.
+type pausingBackend struct {
+	*mockBackend
+}
+
+func (b *pausingBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	defer time.Sleep(time.Duration(rand.IntN(100)) * time.Microsecond)
+	return b.mockBackend.Set(ctx, key, value, ttl)
+}
+
+func (b *pausingBackend) Delete(ctx context.Context, key string) error {
+	defer time.Sleep(time.Duration(rand.IntN(100)) * time.Microsecond)
+	return b.mockBackend.Delete(ctx, key)
+}
+
+func TestReplicator_ConcurrentWritesSameKey(t *testing.T) {
+	primary := &pausingBackend{newMockBackend()}
+	replica := newMockBackend()
+	replicator := NewReplicator(primary, replica, nil)
+
+	ctx := context.Background()
+	keys := []string{"light:1", "light:2", "light:3", "light:4"}
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				key := keys[i%len(keys)]
+				if (w+i)%5 == 0 {
+					replicator.Delete(ctx, key)
+				} else {
+					replicator.Set(ctx, key, []byte(fmt.Sprintf("%d-%d", w, i)), 0)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	want := make(map[string]string)
+	for _, key := range keys {
+		if entry, err := primary.Get(ctx, key); err == nil {
+			want[key] = string(entry.Value)
+		}
+	}
+	if err := replicator.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	for _, key := range keys {
+		got := ""
+		if entry, err := replica.Get(ctx, key); err == nil {
+			got = string(entry.Value)
+		}
+		if got != want[key] {
+			t.Errorf("replica %s = %q, primary has %q", key, got, want[key])
+		}
+	}
+}
+
+func TestReplicator_Touch(t *testing.T) {
+	primary := newMockBackend()
+	replica := newMockBackend()
+	replicator := NewReplicator(primary, replica, nil)
+
+	ctx := context.Background()
+	replicator.Set(ctx, "light:1", []byte("value"), time.Minute)
+	if err := replicator.Touch(ctx, "light:1", time.Hour); err != nil {
+		t.Fatalf("Touch() failed: %v", err)
+	}
+	if err := replicator.Touch(ctx, "light:missing", time.Hour); err == nil {
+		t.Error("Touch() of missing key should fail")
+	}
+
+	replicator.Close()
+
+	for name, backend := range map[string]*mockBackend{"primary": primary, "replica": replica} {
+		entry, err := backend.Get(ctx, "light:1")
+		if err != nil {
+			t.Fatalf("%s Get() failed: %v", name, err)
+		}
+		if entry.TTL != time.Hour {
+			t.Errorf("%s TTL = %v, want 1h", name, entry.TTL)
+		}
+	}
+}
@@ -0,0 +1,924 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"golang.org/x/time/rate"
+)
+
+// instrumentationName identifies this package to OpenTelemetry.
+const instrumentationName = "github.com/rmrfslashbin/hue-cache"
+
+// tombstoneValue marks a negative cache entry: a resource the SDK reported
+// as missing. It is not valid JSON, so it can never collide with a real
+// cached resource.
+var tombstoneValue = []byte("\x00hue-cache:tombstone")
+
+// isTombstone reports whether a cached value is a negative cache entry.
+func isTombstone(value []byte) bool {
+	return bytes.Equal(value, tombstoneValue)
+}
+
+// isNotFoundDefault is the default NotFound classifier for SDK errors.
+func isNotFoundDefault(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// resourceCache holds the state shared by all cached resource clients
+// and implements the read-through and write-through steps they have
+// in common.
+type resourceCache struct {
+	// name identifies the resource client in span names (e.g. "Lights").
+	name string
+
+	backend    Backend
+	keyBuilder *KeyBuilder
+	ttl        time.Duration
+	logger     Logger
+	tracer     trace.Tracer
+
+	// negativeTTL is how long to remember missing resources (0 = disabled).
+	negativeTTL time.Duration
+
+	// isNotFound classifies SDK errors that mean the resource doesn't exist.
+	isNotFound func(error) bool
+
+	// jitter randomizes the TTL of each stored entry.
+	jitter ttlJitter
+
+	// cacheRaw stores each resource's raw JSON under its raw key.
+	cacheRaw bool
+
+	// rawGetter fetches raw JSON from the SDK, if the SDK client supports it.
+	rawGetter RawGetter
+
+	// validation checks resources before they are cached (nil = disabled).
+	validation *Validation
+
+	// projection strips cached resources to allowed fields (nil = disabled).
+	projection *Projection
+
+	// cacheOnCreate fetches and caches resources after Create.
+	cacheOnCreate bool
+
+	// failOpen falls back to the SDK when the backend fails a read,
+	// instead of returning the backend error.
+	failOpen bool
+
+	// coalescer collapses Get misses into shared List calls (nil = disabled).
+	coalescer *listCoalescer
+
+	// versions claims resource versions on writes (nil = disabled).
+	versions *LastWriteWins
+
+	// codec serializes cached resources.
+	codec Codec
+
+	// staleWhileRevalidate serves Get hits past their TTL, within
+	// staleIfError, while refreshing them in the background.
+	staleWhileRevalidate bool
+	staleIfError         time.Duration
+
+	// revalidating holds the keys being refreshed in the background.
+	revalidating *sync.Map
+
+	// listRepairThreshold is the most unreadable entries a List fetches
+	// individually before refetching everything (0 = always refetch).
+	listRepairThreshold int
+
+	// limiter throttles SDK calls (nil = unlimited).
+	limiter *rate.Limiter
+}
+
+// RawGetter is implemented by SDK resource clients that can return the
+// bridge's JSON for a resource exactly as received. With
+// CachedClientConfig.CacheRaw enabled, cached clients use it on a miss so
+// the raw entry holds the bridge's bytes verbatim, including any fields
+// the SDK's types don't model.
+type RawGetter interface {
+	GetRaw(ctx context.Context, id string) (json.RawMessage, error)
+}
+
+// newResourceCache creates the shared state for a cached resource client
+// wrapping the SDK client sdk.
+func newResourceCache(name string, backend Backend, ttl time.Duration, sdk interface{}) resourceCache {
+	rawGetter, _ := sdk.(RawGetter)
+	return resourceCache{
+		name:       name,
+		backend:    backend,
+		keyBuilder: NewKeyBuilder(),
+		ttl:        ttl,
+		logger:     NopLogger(),
+		tracer:     noop.NewTracerProvider().Tracer(instrumentationName),
+		isNotFound: isNotFoundDefault,
+		rawGetter:  rawGetter,
+		failOpen:   true,
+		codec:      JSONCodec(),
+	}
+}
+
+// configure applies the optional settings from a CachedClientConfig.
+func (r *resourceCache) configure(config *CachedClientConfig) {
+	if config == nil {
+		return
+	}
+	r.logger = loggerOrNop(config.Logger)
+	if config.TracerProvider != nil {
+		r.tracer = config.TracerProvider.Tracer(instrumentationName)
+	}
+	r.negativeTTL = config.NegativeTTL
+	if config.IsNotFound != nil {
+		r.isNotFound = config.IsNotFound
+	}
+	r.jitter = ttlJitter{fraction: config.TTLJitter, seed: config.TTLJitterSeed}
+	r.backend = withBackendTimeout(r.backend, config.BackendTimeout)
+	r.cacheRaw = config.CacheRaw
+	r.validation = config.Validation
+	r.projection = config.Projection
+	r.cacheOnCreate = config.CacheOnCreate
+	r.failOpen = !config.FailClosed
+	r.coalescer = newListCoalescer(config.CoalesceWindow)
+	r.keyBuilder = NewKeyBuilderWithPrefix(config.KeyPrefix)
+	r.codec = codecOrJSON(config.Codec)
+	r.staleWhileRevalidate = config.StaleWhileRevalidate
+	r.staleIfError = config.StaleIfError
+	if r.staleIfError <= 0 {
+		r.staleIfError = DefaultStaleIfError
+	}
+	r.revalidating = &sync.Map{}
+	r.listRepairThreshold = config.ListRepairThreshold
+	r.limiter = newSDKLimiter(config)
+	r.versions = nil
+	if config.LastWriteWins {
+		r.versions = &LastWriteWins{backend: r.backend, keyBuilder: r.keyBuilder}
+	}
+}
+
+// isMiss reports whether a backend read error means the key isn't cached,
+// as opposed to the backend failing.
+func isMiss(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, ErrExpired)
+}
+
+// readFailed handles a backend read error that isn't a miss. The error is
+// logged; with fail-open it is swallowed (nil) so the caller falls back to
+// the SDK, otherwise it is returned for the caller to surface.
+func (r *resourceCache) readFailed(op, key string, err error) error {
+	r.logger.Warn("cache backend read failed", "op", op, "key", key, "error", err, "fail_open", r.failOpen)
+	if r.failOpen {
+		return nil
+	}
+	return NewError(op, key, err)
+}
+
+// decode unmarshals a cached value into resource. A value written by a
+// different codec is logged, since callers treat it as a miss and the
+// refetched resource silently overwrites it.
+func (r *resourceCache) decode(key string, value []byte, resource any) error {
+	err := r.codec.Unmarshal(value, resource)
+	if errors.Is(err, ErrCodecMismatch) {
+		r.logger.Warn("cached value was encoded by a different codec", "key", key)
+	}
+	return err
+}
+
+// entryTTL returns the TTL for a new entry under key, with jitter applied.
+// With stale-while-revalidate, entries are kept for the StaleIfError
+// window past it.
+func (r *resourceCache) entryTTL(key string) time.Duration {
+	ttl := r.jitter.apply(key, r.ttl)
+	if ttl > 0 && r.staleWhileRevalidate {
+		ttl += r.staleIfError
+	}
+	return ttl
+}
+
+// setEntry writes a resource's value under key with ttl, tagged with its
+// StaleIfError window under stale-while-revalidate (see staleTags). A
+// backend that can't store tags gets the value untagged, which is never
+// served past its TTL.
+func (r *resourceCache) setEntry(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	tags := r.staleTags(ttl)
+	err := SetWithTags(ctx, r.backend, key, value, ttl, tags)
+	if len(tags) > 0 && errors.Is(err, ErrUnsupported) {
+		err = r.backend.Set(ctx, key, value, ttl)
+	}
+	return err
+}
+
+// startSpan starts a span named "hue-cache.<client>.<op>".
+func (r *resourceCache) startSpan(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return r.tracer.Start(ctx, "hue-cache."+r.name+"."+op, trace.WithAttributes(attrs...))
+}
+
+// store serializes a resource and writes it to the cache, returning the
+// entry as written (nil if it couldn't be stored).
+// Population is best-effort: failures are logged but never returned,
+// since the caller already has the data it asked for.
+func (r *resourceCache) store(ctx context.Context, key string, resource interface{}) *Entry {
+	return r.storeWithRaw(ctx, key, resource, nil)
+}
+
+// storeWithRaw is store, also caching raw as the resource's raw JSON when
+// raw caching is enabled. If raw is nil, the resource's JSON is used.
+func (r *resourceCache) storeWithRaw(ctx context.Context, key string, resource interface{}, raw []byte) *Entry {
+	data, err := r.codec.Marshal(resource)
+	if err != nil {
+		r.logger.Warn("failed to marshal resource for cache", "key", key, "error", err)
+		return nil
+	}
+
+	if err := validateResource(r.validation, r.codec, r.keyBuilder.local(key), resource, data); err != nil {
+		r.logger.Warn("rejected invalid resource", "key", key, "error", err)
+		return nil
+	}
+
+	// The raw entry keeps the full resource
+	full := data
+	if r.projection != nil {
+		if data, err = projectResource(r.codec, r.projection, r.keyBuilder.local(key), resource); err != nil {
+			r.logger.Warn("failed to project resource for cache", "key", key, "error", err)
+			return nil
+		}
+	}
+
+	ttl := r.entryTTL(key)
+	if err := r.setEntry(ctx, key, data, ttl); err != nil {
+		r.logger.Warn("failed to populate cache", "key", key, "error", err)
+		return nil
+	}
+
+	if r.cacheRaw {
+		if raw == nil && isJSONCodec(r.codec) {
+			raw = full
+		} else if raw == nil {
+			raw, err = json.Marshal(resource)
+		}
+		if err == nil {
+			err = r.backend.Set(ctx, r.keyBuilder.Raw(key), raw, ttl)
+		}
+		if err != nil {
+			r.logger.Warn("failed to populate raw cache entry", "key", key, "error", err)
+		}
+	}
+
+	return NewEntry(key, data, ttl)
+}
+
+// storeTombstone records that the resource under key doesn't exist, so
+// repeated lookups are answered from the cache until negativeTTL elapses.
+func (r *resourceCache) storeTombstone(ctx context.Context, key string) {
+	if err := r.backend.Set(ctx, key, tombstoneValue, r.negativeTTL); err != nil {
+		r.logger.Warn("failed to store negative cache entry", "key", key, "error", err)
+	}
+	r.invalidateRaw(ctx, key)
+}
+
+// patch optimistically applies an update to the cached resource under key,
+// so reads observe a write before the bridge confirms it. If the resource
+// isn't cached or can't be patched, the entry is invalidated instead.
+func (r *resourceCache) patch(ctx context.Context, key string, update interface{}) {
+	entry, err := r.backend.Get(ctx, key)
+	if err != nil || isTombstone(entry.Value) {
+		r.invalidate(ctx, key)
+		return
+	}
+
+	updateJSON, err := json.Marshal(update)
+	if err != nil {
+		r.logger.Warn("failed to marshal update for cache", "key", key, "error", err)
+		r.invalidate(ctx, key)
+		return
+	}
+
+	merged, err := r.merge(key, entry.Value, updateJSON)
+	var patched []byte
+	if err == nil {
+		patched, err = r.codec.Marshal(merged)
+	}
+	if err != nil {
+		r.logger.Warn("failed to patch cached resource", "key", key, "error", err)
+		r.invalidate(ctx, key)
+		return
+	}
+
+	if err := validateResource(r.validation, r.codec, r.keyBuilder.local(key), merged, patched); err != nil {
+		r.logger.Warn("rejected invalid resource", "key", key, "error", err)
+		r.invalidate(ctx, key)
+		return
+	}
+
+	if err := r.setEntry(ctx, key, patched, r.entryTTL(key)); err != nil {
+		r.logger.Warn("failed to populate cache", "key", key, "error", err)
+	}
+
+	// The raw bridge response no longer matches the patched resource
+	r.invalidateRaw(ctx, key)
+}
+
+// merge decodes a cached resource and deep-merges the JSON object
+// updateJSON into it, like mergeJSON.
+func (r *resourceCache) merge(key string, value, updateJSON []byte) (map[string]interface{}, error) {
+	var base map[string]interface{}
+	if err := r.decode(key, value, &base); err != nil {
+		return nil, err
+	}
+
+	update, err := decodeJSON(updateJSON)
+	if err != nil {
+		return nil, err
+	}
+	patch, ok := update.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("update is not a JSON object")
+	}
+	return mergeMaps(base, patch), nil
+}
+
+// invalidate removes a cache entry after a write to the bridge.
+// Failures are logged; the entry will be refreshed by SSE or TTL expiry.
+func (r *resourceCache) invalidate(ctx context.Context, key string) {
+	if err := r.backend.Delete(ctx, key); err != nil {
+		r.logger.Warn("failed to invalidate cache entry", "key", key, "error", err)
+	}
+	r.invalidateRaw(ctx, key)
+}
+
+// invalidateRaw removes the raw entry for key, if raw caching is enabled.
+func (r *resourceCache) invalidateRaw(ctx context.Context, key string) {
+	if !r.cacheRaw {
+		return
+	}
+	if err := r.backend.Delete(ctx, r.keyBuilder.Raw(key)); err != nil {
+		r.logger.Warn("failed to invalidate raw cache entry", "key", key, "error", err)
+	}
+}
+
+// getThrough returns the resource cached under key, falling back to fetch
+// on a miss and populating the cache with the result.
+func getThrough[T any](ctx context.Context, r *resourceCache, key string, fetch func(context.Context) (*T, error)) (*T, error) {
+	resource, _, err := getWithMetaThrough(ctx, r, key, fetch)
+	return resource, err
+}
+
+// getWithMetaThrough is getThrough, also returning metadata for the cache
+// entry the resource was served from (or written to, on a miss).
+func getWithMetaThrough[T any](ctx context.Context, r *resourceCache, key string, fetch func(context.Context) (*T, error)) (*T, *EntryMeta, error) {
+	ctx, span := r.startSpan(ctx, "Get", attribute.String("cache.key", key))
+	defer span.End()
+
+	// Try cache first, unless the entry exceeds the caller's staleness budget
+	entry, err := r.backend.Get(ctx, key)
+	switch {
+	case err != nil && !isMiss(err):
+		// Backend failure - fall back to the SDK only if failing open
+		if err := r.readFailed("Get", key, err); err != nil {
+			recordSpanError(span, err)
+			return nil, nil, err
+		}
+	case err != nil:
+		// Cache miss
+	case isStale(ctx, entry):
+		span.SetAttributes(attribute.Bool("cache.stale", true))
+	case isTombstone(entry.Value):
+		// Known-missing resource - answer without calling the SDK
+		span.SetAttributes(
+			attribute.Bool("cache.hit", true),
+			attribute.Bool("cache.negative", true),
+			attribute.Bool("cache.sdk_called", false),
+		)
+		return nil, nil, NewError("Get", key, ErrNotFound)
+	default:
+		var resource T
+		if err := r.decode(key, entry.Value, &resource); err == nil {
+			span.SetAttributes(attribute.Bool("cache.hit", true), attribute.Bool("cache.sdk_called", false))
+			meta := newEntryMeta(entry, true)
+			if r.pastTTL(entry) {
+				// Serve the stale value now; the refresh replaces it
+				span.SetAttributes(attribute.Bool("cache.revalidating", true))
+				meta.Stale = true
+				revalidate(ctx, r, key, fetch)
+			}
+			return &resource, meta, nil
+		}
+	}
+
+	// Cache miss - fetch from SDK
+	span.SetAttributes(attribute.Bool("cache.hit", false), attribute.Bool("cache.sdk_called", true))
+	resource, raw, err := fetchWithRaw(ctx, r, key, fetch)
+	if err != nil {
+		if r.negativeTTL > 0 && r.isNotFound(err) {
+			r.storeTombstone(ctx, key)
+		}
+		recordSpanError(span, err)
+		return nil, nil, err
+	}
+
+	// Populate cache
+	stored := r.storeWithRaw(ctx, key, resource, raw)
+	if stored == nil {
+		// Not cached; describe the fresh value as a new, uncached entry
+		stored = NewEntry(key, nil, 0)
+	}
+
+	return resource, newEntryMeta(stored, false), nil
+}
+
+// throttle waits for a token from the SDK rate limiter before an SDK call
+// for op. It fails with ErrRateLimited, without waiting, if ctx would end
+// first.
+func (r *resourceCache) throttle(ctx context.Context, op, key string) error {
+	if r.limiter == nil {
+		return nil
+	}
+	if err := r.limiter.Wait(ctx); err != nil {
+		return NewError(op, key, fmt.Errorf("%w: %w", ErrRateLimited, err))
+	}
+	return nil
+}
+
+// fetchWithRaw fetches a resource from the SDK. When raw caching is on
+// and the SDK client implements RawGetter, it fetches the raw JSON and
+// decodes the resource from it, returning both; otherwise raw is nil.
+func fetchWithRaw[T any](ctx context.Context, r *resourceCache, key string, fetch func(context.Context) (*T, error)) (*T, []byte, error) {
+	if err := r.throttle(ctx, "Get", key); err != nil {
+		return nil, nil, err
+	}
+	if !r.cacheRaw || r.rawGetter == nil {
+		resource, err := fetch(ctx)
+		return resource, nil, err
+	}
+
+	_, id, _ := r.keyBuilder.ParseKey(key)
+	raw, err := r.rawGetter.GetRaw(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var resource T
+	if err := json.Unmarshal(raw, &resource); err != nil {
+		return nil, nil, fmt.Errorf("decoding %s: %w", key, err)
+	}
+	return &resource, raw, nil
+}
+
+// rawThrough returns the raw JSON cached for the resource under key. On a
+// miss it loads the resource with get (populating the cache) and returns
+// the raw entry written, or the serialized resource if raw caching is off.
+func rawThrough[T any](ctx context.Context, r *resourceCache, key string, get func(context.Context) (*T, error)) ([]byte, error) {
+	if r.cacheRaw {
+		entry, err := r.backend.Get(ctx, r.keyBuilder.Raw(key))
+		if err == nil {
+			return entry.Value, nil
+		}
+		if !isMiss(err) {
+			if err := r.readFailed("GetRaw", r.keyBuilder.Raw(key), err); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	resource, err := get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.cacheRaw {
+		if entry, err := r.backend.Get(ctx, r.keyBuilder.Raw(key)); err == nil {
+			return entry.Value, nil
+		}
+	}
+	return json.Marshal(resource)
+}
+
+// listThrough returns all resources matching pattern from the cache.
+// If the cache holds none, any entry can't be read, or the backend can't
+// list keys, it falls back to fetch and populates the cache with every
+// returned resource. With a repair threshold set, up to that many
+// unreadable entries are instead fetched one by one with get and merged
+// with the cached ones. Backend
+// failures (as opposed to misses) fall back too only when failing open. Negative
+// cache entries are not resources and are skipped, as are keys deleted
+// between Keys and Get, since a concurrent delete means the resource is
+// gone rather than that the cache is incomplete. With
+// stale-while-revalidate, entries past their TTL are served like Get
+// serves them, each refreshed in the background with get.
+func listThrough[T any](ctx context.Context, r *resourceCache, pattern string, keyOf func(*T) string, fetch func(context.Context) ([]T, error), get func(context.Context, string) (*T, error)) ([]T, error) {
+	ctx, span := r.startSpan(ctx, "List", attribute.String("cache.pattern", pattern))
+	defer span.End()
+
+	// Try to get all resources from cache using pattern
+	keys, err := r.backend.Keys(ctx, pattern)
+	if err != nil && !errors.Is(err, ErrUnsupported) {
+		// A failed Keys is not an empty cache
+		if err := r.readFailed("List", pattern, err); err != nil {
+			recordSpanError(span, err)
+			return nil, err
+		}
+	}
+	if err == nil && len(keys) > 0 {
+		var resources []T
+		var repair []string
+		allFound := true
+		revalidating := false
+
+		for _, key := range keys {
+			entry, err := r.backend.Get(ctx, key)
+			if errors.Is(err, ErrNotFound) {
+				// Deleted (e.g. by sync) since Keys - the resource is gone
+				continue
+			}
+			if err != nil && !isMiss(err) {
+				if err := r.readFailed("List", key, err); err != nil {
+					recordSpanError(span, err)
+					return nil, err
+				}
+			}
+
+			var resource T
+			switch {
+			case err != nil || isStale(ctx, entry):
+			case isTombstone(entry.Value):
+				// Negative entries record absent resources; skip them
+				continue
+			case r.decode(key, entry.Value, &resource) == nil:
+				resources = append(resources, resource)
+				if r.pastTTL(entry) {
+					// Serve the stale value now; the refresh replaces it
+					revalidateListed(ctx, r, key, get)
+					revalidating = true
+				}
+				continue
+			}
+
+			// Unreadable - repair it alone, or give up on the cache
+			repair = append(repair, key)
+			if len(repair) > r.listRepairThreshold {
+				allFound = false
+				break
+			}
+		}
+
+		if allFound && len(repair) > 0 {
+			repaired, ok := repairList(ctx, r, repair, get)
+			if ok {
+				span.SetAttributes(attribute.Int("cache.repaired", len(repair)))
+				resources = append(resources, repaired...)
+			} else {
+				allFound = false
+			}
+		}
+
+		if allFound && len(resources) > 0 {
+			span.SetAttributes(
+				attribute.Bool("cache.hit", true),
+				attribute.Bool("cache.sdk_called", len(repair) > 0),
+				attribute.Bool("cache.revalidating", revalidating),
+				attribute.Int("cache.entries", len(resources)),
+			)
+			return resources, nil
+		}
+	}
+
+	// Cache miss - fetch from SDK
+	span.SetAttributes(attribute.Bool("cache.hit", false), attribute.Bool("cache.sdk_called", true))
+	if err := r.throttle(ctx, "List", pattern); err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	resources, err := fetch(ctx)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+
+	// Populate cache
+	for i := range resources {
+		r.store(ctx, keyOf(&resources[i]), &resources[i])
+	}
+	span.SetAttributes(attribute.Int("cache.entries", len(resources)))
+
+	return resources, nil
+}
+
+// repairList fetches the resources cached under keys one by one with get
+// and re-caches them. Resources the SDK reports missing are dropped from
+// the cache. It reports false if any other fetch fails, so the caller can
+// fall back to a full List.
+func repairList[T any](ctx context.Context, r *resourceCache, keys []string, get func(context.Context, string) (*T, error)) ([]T, bool) {
+	resources := make([]T, 0, len(keys))
+	for _, key := range keys {
+		_, id, ok := r.keyBuilder.ParseKey(key)
+		if !ok {
+			return nil, false
+		}
+
+		if err := r.throttle(ctx, "List", key); err != nil {
+			r.logger.Warn("failed to repair cached list entry", "key", key, "error", err)
+			return nil, false
+		}
+		resource, err := get(ctx, id)
+		if err != nil {
+			if r.isNotFound(err) {
+				r.invalidate(ctx, key)
+				continue
+			}
+			r.logger.Warn("failed to repair cached list entry", "key", key, "error", err)
+			return nil, false
+		}
+
+		r.store(ctx, key, resource)
+		resources = append(resources, *resource)
+	}
+	return resources, true
+}
+
+// listPage returns the resources matching pattern at [offset, offset+limit)
+// in cache key order, plus the total number of resources. When served from
+// the cache, every entry is read to count the total and skip tombstones,
+// but only the entries on the page are unmarshaled. Misses and stale
+// entries fall back to fetch like listThrough, and the page is cut from
+// the fetched set in the same order. Entries on the page past their TTL
+// under stale-while-revalidate are served and refreshed with get.
+func listPage[T any](ctx context.Context, r *resourceCache, pattern string, offset, limit int, keyOf func(*T) string, fetch func(context.Context) ([]T, error), get func(context.Context, string) (*T, error)) ([]T, int, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, 0, NewError("ListPage", pattern, ErrInvalidValue)
+	}
+
+	ctx, span := r.startSpan(ctx, "ListPage",
+		attribute.String("cache.pattern", pattern),
+		attribute.Int("page.offset", offset),
+		attribute.Int("page.limit", limit),
+	)
+	defer span.End()
+
+	keys, err := r.backend.Keys(ctx, pattern)
+	if err != nil && !errors.Is(err, ErrUnsupported) {
+		if err := r.readFailed("ListPage", pattern, err); err != nil {
+			recordSpanError(span, err)
+			return nil, 0, err
+		}
+	}
+	if err == nil && len(keys) > 0 {
+		sort.Strings(keys)
+
+		var entries []*Entry
+		allFound := true
+		for _, key := range keys {
+			entry, err := r.backend.Get(ctx, key)
+			if errors.Is(err, ErrNotFound) {
+				// Deleted (e.g. by sync) since Keys - the resource is gone
+				continue
+			}
+			if err != nil && !isMiss(err) {
+				if err := r.readFailed("ListPage", key, err); err != nil {
+					recordSpanError(span, err)
+					return nil, 0, err
+				}
+			}
+			if err != nil || isStale(ctx, entry) {
+				allFound = false
+				break
+			}
+			if !isTombstone(entry.Value) {
+				entries = append(entries, entry)
+			}
+		}
+
+		if allFound && len(entries) > 0 {
+			start, end := pageBounds(len(entries), offset, limit)
+			page := make([]T, 0, end-start)
+			var stale []string
+			for _, entry := range entries[start:end] {
+				var resource T
+				if err := r.decode(entry.Key, entry.Value, &resource); err != nil {
+					allFound = false
+					break
+				}
+				page = append(page, resource)
+				if r.pastTTL(entry) {
+					stale = append(stale, entry.Key)
+				}
+			}
+
+			if allFound {
+				// Serve stale values on the page now; the refreshes replace them
+				for _, key := range stale {
+					revalidateListed(ctx, r, key, get)
+				}
+				span.SetAttributes(
+					attribute.Bool("cache.hit", true),
+					attribute.Bool("cache.sdk_called", false),
+					attribute.Bool("cache.revalidating", len(stale) > 0),
+					attribute.Int("cache.entries", len(entries)),
+				)
+				return page, len(entries), nil
+			}
+		}
+	}
+
+	// Cache miss - fetch everything from the SDK, then cut the page
+	span.SetAttributes(attribute.Bool("cache.hit", false), attribute.Bool("cache.sdk_called", true))
+	if err := r.throttle(ctx, "ListPage", pattern); err != nil {
+		recordSpanError(span, err)
+		return nil, 0, err
+	}
+	resources, err := fetch(ctx)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, 0, err
+	}
+
+	for i := range resources {
+		r.store(ctx, keyOf(&resources[i]), &resources[i])
+	}
+	span.SetAttributes(attribute.Int("cache.entries", len(resources)))
+
+	sort.SliceStable(resources, func(i, j int) bool {
+		return keyOf(&resources[i]) < keyOf(&resources[j])
+	})
+	start, end := pageBounds(len(resources), offset, limit)
+	return resources[start:end:end], len(resources), nil
+}
+
+// pageBounds clamps the page [offset, offset+limit) to a set of total items.
+func pageBounds(total, offset, limit int) (start, end int) {
+	start = min(offset, total)
+	end = start + min(limit, total-start)
+	return start, end
+}
+
+// listSorted lists resources with list and sorts the result stably by
+// less. List results are built per call, so sorting in place is safe.
+func listSorted[T any](ctx context.Context, list func(context.Context) ([]T, error), less func(a, b T) bool) ([]T, error) {
+	resources, err := list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(resources, func(i, j int) bool {
+		return less(resources[i], resources[j])
+	})
+	return resources, nil
+}
+
+// refreshThrough fetches a resource from the SDK, bypassing the cache, and
+// overwrites its cache entry. If the SDK reports the resource missing, the
+// stale entry is replaced by a tombstone (with negative caching enabled)
+// or removed.
+func refreshThrough[T any](ctx context.Context, r *resourceCache, key string, fetch func(context.Context) (*T, error)) (*T, error) {
+	ctx, span := r.startSpan(ctx, "Refresh", attribute.String("cache.key", key), attribute.Bool("cache.sdk_called", true))
+	defer span.End()
+
+	if err := r.throttle(ctx, "Refresh", key); err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	resource, err := fetch(ctx)
+	if err != nil {
+		if r.isNotFound(err) {
+			if r.negativeTTL > 0 {
+				r.storeTombstone(ctx, key)
+			} else {
+				r.invalidate(ctx, key)
+			}
+		}
+		recordSpanError(span, err)
+		return nil, err
+	}
+
+	r.store(ctx, key, resource)
+
+	return resource, nil
+}
+
+// refreshAllThrough re-lists resources from the SDK and replaces every
+// cache entry matching pattern: returned resources are overwritten and
+// cached entries for resources the SDK no longer returns are removed.
+func refreshAllThrough[T any](ctx context.Context, r *resourceCache, pattern string, keyOf func(*T) string, fetch func(context.Context) ([]T, error)) ([]T, error) {
+	ctx, span := r.startSpan(ctx, "RefreshAll", attribute.String("cache.pattern", pattern), attribute.Bool("cache.sdk_called", true))
+	defer span.End()
+
+	if err := r.throttle(ctx, "RefreshAll", pattern); err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	resources, err := fetch(ctx)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+
+	// Note existing keys before overwriting, so stale ones can be removed
+	existing, err := r.backend.Keys(ctx, pattern)
+	if err != nil && !errors.Is(err, ErrUnsupported) {
+		r.logger.Warn("failed to list cached keys for refresh", "pattern", pattern, "error", err)
+	}
+
+	current := make(map[string]bool, len(resources))
+	for i := range resources {
+		key := keyOf(&resources[i])
+		current[key] = true
+		r.store(ctx, key, &resources[i])
+	}
+
+	for _, key := range existing {
+		if !current[key] {
+			r.invalidate(ctx, key)
+		}
+	}
+	span.SetAttributes(attribute.Int("cache.entries", len(resources)))
+
+	return resources, nil
+}
+
+// writeThrough applies a write to the bridge and then invalidates the
+// cache entry for key so the next read (or SSE event) repopulates it.
+func (r *resourceCache) writeThrough(ctx context.Context, op, key string, write func(context.Context) error) error {
+	ctx, span := r.startSpan(ctx, op, attribute.String("cache.key", key), attribute.Bool("cache.sdk_called", true))
+	defer span.End()
+
+	// Write to SDK first
+	if err := r.throttle(ctx, op, key); err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+	started := time.Now()
+	if err := write(ctx); err != nil {
+		recordSpanError(span, err)
+		return err
+	}
+
+	// Events from before the write must not bring back the old state
+	if r.versions != nil {
+		if _, err := r.versions.Claim(ctx, key, started); err != nil {
+			r.logger.Warn("failed to claim version after write", "key", key, "error", err)
+		}
+	}
+
+	// Invalidate cache entry (SSE event will repopulate it)
+	r.invalidate(ctx, key)
+
+	return nil
+}
+
+// create creates a resource on the bridge. The new resource is not cached;
+// the SSE add event will populate it.
+func (r *resourceCache) create(ctx context.Context, create func(context.Context) (string, error)) (string, error) {
+	ctx, span := r.startSpan(ctx, "Create", attribute.Bool("cache.sdk_called", true))
+	defer span.End()
+
+	if err := r.throttle(ctx, "Create", ""); err != nil {
+		recordSpanError(span, err)
+		return "", err
+	}
+	id, err := create(ctx)
+	if err != nil {
+		recordSpanError(span, err)
+		return "", err
+	}
+
+	return id, nil
+}
+
+// createThrough creates a resource on the bridge like create. With
+// CacheOnCreate, it then fetches the new resource with get and caches it
+// under keyOf(id). Caching is best-effort: the create already succeeded,
+// so a failed fetch is only logged.
+func createThrough[T any](ctx context.Context, r *resourceCache, create func(context.Context) (string, error), keyOf func(string) string, get func(context.Context, string) (*T, error)) (string, error) {
+	id, err := r.create(ctx, create)
+	if err != nil || !r.cacheOnCreate {
+		return id, err
+	}
+
+	key := keyOf(id)
+	if err := r.throttle(ctx, "Create", key); err != nil {
+		r.logger.Warn("failed to cache created resource", "key", key, "error", err)
+		return id, nil
+	}
+	resource, err := get(ctx, id)
+	if err != nil {
+		r.logger.Warn("failed to cache created resource", "key", key, "error", err)
+		return id, nil
+	}
+	r.store(ctx, key, resource)
+
+	return id, nil
+}
+
+// recordSpanError marks a span as failed.
+func recordSpanError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
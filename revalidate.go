@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// DefaultStaleIfError is how long past its TTL an entry is kept for
+// stale-while-revalidate when CachedClientConfig.StaleIfError isn't set.
+const DefaultStaleIfError = 5 * time.Minute
+
+// staleTagPrefix starts the tag that records the StaleIfError window an
+// entry was stored with, e.g. "swr:5m0s". The entry's TTL includes the
+// window, so the tag is how pastTTL tells where the real TTL ends.
+const staleTagPrefix = "swr:"
+
+// staleTags returns the tags for a resource entry stored with ttl: the
+// window tag when stale-while-revalidate extended ttl, none otherwise.
+func (r *resourceCache) staleTags(ttl time.Duration) []string {
+	if !r.staleWhileRevalidate || ttl <= 0 {
+		return nil
+	}
+	return []string{staleTagPrefix + r.staleIfError.String()}
+}
+
+// staleWindow returns the StaleIfError window recorded on entry, if it
+// was stored with one.
+func staleWindow(entry *Entry) (time.Duration, bool) {
+	for _, tag := range entry.Tags {
+		if s, ok := strings.CutPrefix(tag, staleTagPrefix); ok {
+			window, err := time.ParseDuration(s)
+			return window, err == nil
+		}
+	}
+	return 0, false
+}
+
+// pastTTL reports whether entry has outlived its TTL and is only kept, for
+// stale-while-revalidate, until the StaleIfError window it was stored with
+// ends. Entries without a recorded window (written by sync, by clients
+// with stale-while-revalidate off, or to a backend that can't store tags)
+// are never past their TTL here; the backend expires them.
+func (r *resourceCache) pastTTL(entry *Entry) bool {
+	if !r.staleWhileRevalidate {
+		return false
+	}
+	window, ok := staleWindow(entry)
+	if !ok || entry.TTL <= window {
+		return false
+	}
+	return entry.Age() > entry.TTL-window
+}
+
+// revalidate refreshes the resource under key from the SDK in the
+// background, unless a refresh of it is already running. The refresh
+// outlives the read that started it. If it fails, the stale entry keeps
+// being served (and refreshed) until the StaleIfError window ends; if the
+// SDK reports the resource missing, the entry is removed.
+func revalidate[T any](ctx context.Context, r *resourceCache, key string, fetch func(context.Context) (*T, error)) {
+	if _, running := r.revalidating.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer r.revalidating.Delete(key)
+		if _, err := refreshThrough(ctx, r, key, fetch); err != nil {
+			r.logger.Warn("background refresh failed, serving stale", "key", key, "error", err)
+		}
+	}()
+}
+
+// revalidateListed is revalidate for an entry served by a list, which
+// refreshes it with the client's get.
+func revalidateListed[T any](ctx context.Context, r *resourceCache, key string, get func(context.Context, string) (*T, error)) {
+	_, id, _ := r.keyBuilder.ParseKey(key)
+	revalidate(ctx, r, key, func(ctx context.Context) (*T, error) {
+		return get(ctx, id)
+	})
+}
@@ -0,0 +1,293 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+// revalidatingSource is a thread-safe SDK stand-in for background
+// refreshes.
+type revalidatingSource struct {
+	mu    sync.Mutex
+	light *resources.Light
+	err   error
+	calls atomic.Int32
+}
+
+func (s *revalidatingSource) fetch(ctx context.Context) (*resources.Light, error) {
+	s.calls.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	light := *s.light
+	return &light, nil
+}
+
+func (s *revalidatingSource) set(light *resources.Light, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.light, s.err = light, err
+}
+
+// taggingBackend is a mockBackend that stores tags, so entries keep the
+// stale-while-revalidate window they were written with.
+type taggingBackend struct {
+	*mockBackend
+}
+
+func newTaggingBackend() *taggingBackend {
+	return &taggingBackend{newMockBackend()}
+}
+
+func (b *taggingBackend) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry := NewEntry(key, value, ttl)
+	entry.Tags = tags
+	b.data[key] = entry
+	return nil
+}
+
+func newRevalidatingCache(backend Backend, ttl time.Duration) *resourceCache {
+	r := newResourceCache("Lights", backend, ttl, nil)
+	r.configure(&CachedClientConfig{StaleWhileRevalidate: true, StaleIfError: time.Minute})
+	return &r
+}
+
+// waitForCalls waits until source has been called n times.
+func waitForCalls(t *testing.T, source *revalidatingSource, n int32) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for source.calls.Load() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d SDK calls, got %d", n, source.calls.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	backend := newTaggingBackend()
+	r := newRevalidatingCache(backend, 20*time.Millisecond)
+	ctx := context.Background()
+	key := r.keyBuilder.Light("light-1")
+
+	source := &revalidatingSource{light: &resources.Light{ID: "light-1", Metadata: resources.Metadata{Name: "Old"}}}
+	if _, _, err := getWithMetaThrough(ctx, r, key, source.fetch); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	// Past the TTL with the bridge down: the stale value is served
+	time.Sleep(30 * time.Millisecond)
+	source.set(nil, errors.New("bridge unreachable"))
+
+	light, meta, err := getWithMetaThrough(ctx, r, key, source.fetch)
+	if err != nil {
+		t.Fatalf("Get() past TTL failed: %v", err)
+	}
+	if light.Metadata.Name != "Old" || !meta.Stale || !meta.Cached {
+		t.Errorf("Get() = %q, stale %v, cached %v; want Old, stale, cached", light.Metadata.Name, meta.Stale, meta.Cached)
+	}
+	waitForCalls(t, source, 2)
+
+	// The failed refresh keeps the stale value
+	if light, _, err := getWithMetaThrough(ctx, r, key, source.fetch); err != nil || light.Metadata.Name != "Old" {
+		t.Fatalf("Get() after failed refresh = %v, %v; want Old", light, err)
+	}
+	waitForCalls(t, source, 3)
+
+	// Once the bridge is back, a refresh replaces it
+	source.set(&resources.Light{ID: "light-1", Metadata: resources.Metadata{Name: "New"}}, nil)
+	for deadline := time.Now().Add(2 * time.Second); ; {
+		light, meta, err := getWithMetaThrough(ctx, r, key, source.fetch)
+		if err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		if light.Metadata.Name == "New" {
+			if meta.Stale {
+				t.Error("Expected the refreshed value not to be stale")
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the background refresh to replace the stale value")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStaleWhileRevalidate_OneRefreshPerKey(t *testing.T) {
+	backend := newTaggingBackend()
+	r := newRevalidatingCache(backend, 10*time.Millisecond)
+	ctx := context.Background()
+	key := r.keyBuilder.Light("light-1")
+
+	source := &revalidatingSource{light: &resources.Light{ID: "light-1"}}
+	if _, _, err := getWithMetaThrough(ctx, r, key, source.fetch); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// Hold the refresh open while more stale reads arrive
+	release := make(chan struct{})
+	blocked := func(ctx context.Context) (*resources.Light, error) {
+		<-release
+		return source.fetch(ctx)
+	}
+	for i := 0; i < 10; i++ {
+		if _, meta, err := getWithMetaThrough(ctx, r, key, blocked); err != nil || !meta.Stale {
+			t.Fatalf("Get() = %+v, %v; want a stale hit", meta, err)
+		}
+	}
+	close(release)
+
+	waitForCalls(t, source, 2)
+	time.Sleep(10 * time.Millisecond)
+	if n := source.calls.Load(); n != 2 {
+		t.Errorf("Expected 1 background refresh, got %d", n-1)
+	}
+}
+
+func TestStaleWhileRevalidate_StoresWindow(t *testing.T) {
+	backend := newTaggingBackend()
+	r := newRevalidatingCache(backend, time.Minute)
+
+	source := &revalidatingSource{light: &resources.Light{ID: "light-1"}}
+	key := r.keyBuilder.Light("light-1")
+	if _, _, err := getWithMetaThrough(context.Background(), r, key, source.fetch); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	entry, err := backend.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("entry not cached: %v", err)
+	}
+	if entry.TTL != 2*time.Minute {
+		t.Errorf("Expected the entry kept for TTL+StaleIfError (2m), got %v", entry.TTL)
+	}
+	if !entry.HasTag("swr:1m0s") {
+		t.Errorf("Expected the entry tagged with its window, got %v", entry.Tags)
+	}
+	if r.pastTTL(entry) {
+		t.Error("Expected a new entry not to be past its TTL")
+	}
+}
+
+func TestStaleWhileRevalidate_IgnoresEntriesWithoutWindow(t *testing.T) {
+	backend := newTaggingBackend()
+	r := newRevalidatingCache(backend, time.Hour)
+	ctx := context.Background()
+	key := r.keyBuilder.Light("light-1")
+
+	// Written by a non-SWR writer (e.g. sync) with a TTL longer than the
+	// window: its age past TTL-StaleIfError doesn't make it stale
+	entry := NewEntry(key, []byte(`{"id":"light-1"}`), 2*time.Minute)
+	entry.CreatedAt = time.Now().Add(-90 * time.Second)
+	backend.data[key] = entry
+
+	if r.pastTTL(entry) {
+		t.Error("Expected an entry without a recorded window not to be past its TTL")
+	}
+
+	source := &revalidatingSource{light: &resources.Light{ID: "light-1"}}
+	if _, meta, err := getWithMetaThrough(ctx, r, key, source.fetch); err != nil || meta.Stale {
+		t.Fatalf("Get() = %+v, %v; want a fresh hit", meta, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n := source.calls.Load(); n != 0 {
+		t.Errorf("Expected no background refresh, got %d SDK calls", n)
+	}
+
+	// The same entry tagged with the window it was stored with is stale
+	entry.Tags = r.staleTags(entry.TTL)
+	if !r.pastTTL(entry) {
+		t.Error("Expected a tagged entry past TTL-StaleIfError to be past its TTL")
+	}
+}
+
+func TestStaleWhileRevalidate_UntaggedBackend(t *testing.T) {
+	backend := newMockBackend()
+	r := newRevalidatingCache(backend, time.Minute)
+
+	source := &revalidatingSource{light: &resources.Light{ID: "light-1"}}
+	key := r.keyBuilder.Light("light-1")
+	if _, _, err := getWithMetaThrough(context.Background(), r, key, source.fetch); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	// The backend can't store tags, so the entry is cached untagged
+	entry, err := backend.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("entry not cached: %v", err)
+	}
+	if len(entry.Tags) != 0 {
+		t.Errorf("Expected no tags, got %v", entry.Tags)
+	}
+}
+
+func TestStaleWhileRevalidate_Lists(t *testing.T) {
+	backend := newTaggingBackend()
+	r := newRevalidatingCache(backend, 20*time.Millisecond)
+	ctx := context.Background()
+	keyOf := func(l *resources.Light) string { return r.keyBuilder.Light(l.ID) }
+
+	var lists atomic.Int32
+	bridgeUp := atomic.Bool{}
+	bridgeUp.Store(true)
+	list := func(ctx context.Context) ([]resources.Light, error) {
+		lists.Add(1)
+		if !bridgeUp.Load() {
+			return nil, errors.New("bridge unreachable")
+		}
+		return []resources.Light{{ID: "light-1"}, {ID: "light-2"}}, nil
+	}
+	source := &revalidatingSource{err: errors.New("bridge unreachable")}
+	get := func(ctx context.Context, id string) (*resources.Light, error) {
+		return source.fetch(ctx)
+	}
+
+	if _, err := listThrough(ctx, r, r.keyBuilder.AllLights(), keyOf, list, get); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+
+	// Past the TTL with the bridge down: the stale values are served
+	time.Sleep(30 * time.Millisecond)
+	bridgeUp.Store(false)
+
+	lights, err := listThrough(ctx, r, r.keyBuilder.AllLights(), keyOf, list, get)
+	if err != nil || len(lights) != 2 {
+		t.Fatalf("List() past TTL = %d lights, %v; want 2 stale lights", len(lights), err)
+	}
+	waitForCalls(t, source, 2)
+
+	// Let the failed refreshes finish, so the page read starts another
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(time.Millisecond) {
+		idle := true
+		r.revalidating.Range(func(any, any) bool { idle = false; return false })
+		if idle {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the background refreshes to finish")
+		}
+	}
+
+	page, total, err := listPage(ctx, r, r.keyBuilder.AllLights(), 0, 1, keyOf, list, get)
+	if err != nil || len(page) != 1 || total != 2 {
+		t.Fatalf("ListPage() past TTL = %d lights of %d, %v; want 1 of 2", len(page), total, err)
+	}
+	waitForCalls(t, source, 3)
+
+	if n := lists.Load(); n != 1 {
+		t.Errorf("Expected stale lists not to refetch, got %d SDK List calls", n)
+	}
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// flightGroup shares one call of a load among concurrent callers for the
+// same key. The zero value is ready to use.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is one in-progress load. value and err are set before done is
+// closed.
+type flight struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// do runs load for key, unless a load for key is already running, in
+// which case it waits for that one's result. The load outlives the caller
+// that started it, so it runs without the caller's cancellation; each
+// caller stops waiting when its own ctx is done. Every caller gets its own
+// copy of the value.
+func (g *flightGroup) do(ctx context.Context, key string, load func(context.Context) ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	f, ok := g.flights[key]
+	if !ok {
+		f = &flight{done: make(chan struct{})}
+		g.flights[key] = f
+
+		loadCtx := context.WithoutCancel(ctx)
+		go func() {
+			f.value, f.err = load(loadCtx)
+
+			g.mu.Lock()
+			delete(g.flights, key)
+			g.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return bytes.Clone(f.value), f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// snapshotVersion is the current Export document format version.
+const snapshotVersion = 1
+
+// snapshot is the JSON document produced by Export.
+type snapshot struct {
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"created_at"`
+	Entries   []snapshotEntry `json:"entries"`
+}
+
+// snapshotEntry is one exported cache entry. Values are stored as bytes
+// (base64 in JSON) since not every cached value is JSON.
+type snapshotEntry struct {
+	Key       string        `json:"key"`
+	Value     []byte        `json:"value"`
+	CreatedAt time.Time     `json:"created_at"`
+	ExpiresAt time.Time     `json:"expires_at,omitzero"`
+	TTL       time.Duration `json:"ttl,omitempty"`
+	Tags      []string      `json:"tags,omitempty"`
+}
+
+// ImportConfig contains options for CacheManager.Import.
+type ImportConfig struct {
+	// Replace clears the cache before importing, so it holds exactly the
+	// snapshot's entries. Like ClearAll, a namespaced manager only clears
+	// its namespace. Otherwise snapshot entries are merged in, overwriting
+	// entries with the same key and leaving others untouched.
+	// Default: false (merge)
+	Replace bool
+}
+
+// Export serializes every entry in the cache, across all resource types,
+// to a versioned JSON document that Import can load into any backend.
+// Expiration times are included so imported entries keep their
+// remaining TTL. Entries are read with Peek, so with a Peeker backend an
+// export doesn't skew the statistics or eviction order it captures.
+func (m *CacheManager) Export(ctx context.Context) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys, err := m.backend.Keys(ctx, m.keyBuilder.All())
+	if err != nil {
+		return nil, fmt.Errorf("getting keys: %w", err)
+	}
+
+	snap := snapshot{
+		Version:   snapshotVersion,
+		CreatedAt: time.Now(),
+		Entries:   make([]snapshotEntry, 0, len(keys)),
+	}
+	for _, key := range keys {
+		entry, err := Peek(ctx, m.backend, key)
+		if err != nil {
+			continue // Deleted or expired since Keys
+		}
+
+		snap.Entries = append(snap.Entries, snapshotEntry{
+			Key:       entry.Key,
+			Value:     entry.Value,
+			CreatedAt: entry.CreatedAt,
+			ExpiresAt: entry.ExpiresAt,
+			TTL:       entry.TTL,
+			Tags:      entry.Tags,
+		})
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return nil, fmt.Errorf("encoding snapshot: %w", err)
+	}
+	return data, nil
+}
+
+// Import loads a snapshot produced by Export. Entries that have expired
+// since the export are skipped; the rest are stored with their remaining
+// TTL. If config is nil or omitted, entries are merged into the cache.
+func (m *CacheManager) Import(ctx context.Context, data []byte, config ...*ImportConfig) error {
+	cfg := &ImportConfig{}
+	if len(config) > 0 && config[0] != nil {
+		cfg = config[0]
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("decoding snapshot: %w", err)
+	}
+	return m.importSnapshot(ctx, &snap, cfg)
+}
+
+// importSnapshot stores a decoded snapshot's entries in the backend.
+func (m *CacheManager) importSnapshot(ctx context.Context, snap *snapshot, cfg *ImportConfig) error {
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cfg.Replace {
+		if err := m.clear(ctx); err != nil {
+			return fmt.Errorf("clearing cache: %w", err)
+		}
+	}
+
+	for _, entry := range snap.Entries {
+		var ttl time.Duration
+		if !entry.ExpiresAt.IsZero() {
+			ttl = time.Until(entry.ExpiresAt)
+			if ttl <= 0 {
+				continue // Expired since export
+			}
+		}
+
+		if err := SetWithTags(ctx, m.backend, entry.Key, entry.Value, ttl, entry.Tags); err != nil {
+			return fmt.Errorf("importing %q: %w", entry.Key, err)
+		}
+	}
+
+	return nil
+}
+
+// SnapshotWarmConfig contains options for CacheManager.WarmFromReader and
+// WarmFromFile.
+type SnapshotWarmConfig struct {
+	// Import configures how snapshot entries are loaded.
+	// Default: nil (merge into the cache)
+	Import *ImportConfig
+
+	// Reconcile starts a background Reconcile once the snapshot is loaded,
+	// repairing whatever changed on the bridge since it was exported.
+	// Requires the manager to have an SDK client.
+	// Default: false
+	Reconcile bool
+
+	// OnReconcile receives the result of the background Reconcile.
+	// Default: nil (result discarded)
+	OnReconcile func(*ReconcileReport, error)
+}
+
+// WarmFromReader warms the cache from a snapshot produced by Export,
+// whatever the backend. Loading a snapshot is much faster than WarmCache,
+// which lists every resource from the bridge, so it suits startup; set
+// config.Reconcile to catch up with the bridge in the background
+// afterwards. If config is nil, entries are merged in without reconciling.
+//
+// Example:
+//
+//	err := manager.WarmFromFile(ctx, "/var/cache/hue/snapshot.json", &cache.SnapshotWarmConfig{
+//	    Reconcile: true,
+//	    OnReconcile: func(report *cache.ReconcileReport, err error) {
+//	        log.Printf("reconciled: %+v, %v", report, err)
+//	    },
+//	})
+func (m *CacheManager) WarmFromReader(ctx context.Context, r io.Reader, config *SnapshotWarmConfig) error {
+	if config == nil {
+		config = &SnapshotWarmConfig{}
+	}
+	if config.Reconcile && m.client == nil {
+		return errors.New("reconciling after warming requires an SDK client")
+	}
+
+	var snap snapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return fmt.Errorf("decoding snapshot: %w", err)
+	}
+
+	importConfig := config.Import
+	if importConfig == nil {
+		importConfig = &ImportConfig{}
+	}
+	if err := m.importSnapshot(ctx, &snap, importConfig); err != nil {
+		return err
+	}
+
+	if config.Reconcile {
+		// Outlive the caller's startup context, but keep its values
+		ctx := context.WithoutCancel(ctx)
+		go func() {
+			report, err := m.Reconcile(ctx)
+			if config.OnReconcile != nil {
+				config.OnReconcile(report, err)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// WarmFromFile warms the cache from a file holding a snapshot from Export.
+// See WarmFromReader.
+func (m *CacheManager) WarmFromFile(ctx context.Context, path string, config *SnapshotWarmConfig) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening snapshot: %w", err)
+	}
+	defer f.Close()
+
+	return m.WarmFromReader(ctx, f, config)
+}
@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheManager_ExportImport(t *testing.T) {
+	ctx := context.Background()
+	source := newMockBackend()
+	source.Set(ctx, "light:1", []byte(`{"id":"1"}`), 0)
+	source.Set(ctx, "room:1", []byte(`{"id":"room-1"}`), time.Hour)
+	source.Set(ctx, "light:missing", tombstoneValue, time.Minute)
+
+	data, err := NewCacheManager(source, nil).Export(ctx)
+	if err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Export() is not valid JSON: %v", err)
+	}
+	if doc["version"] != float64(snapshotVersion) {
+		t.Errorf("version = %v, want %d", doc["version"], snapshotVersion)
+	}
+
+	target := newMockBackend()
+	if err := NewCacheManager(target, nil).Import(ctx, data); err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+
+	if len(target.data) != 3 {
+		t.Fatalf("Imported %d entries, want 3", len(target.data))
+	}
+	for key, want := range source.data {
+		got, ok := target.data[key]
+		if !ok {
+			t.Errorf("%s missing after import", key)
+			continue
+		}
+		if string(got.Value) != string(want.Value) {
+			t.Errorf("%s value = %q, want %q", key, got.Value, want.Value)
+		}
+	}
+
+	// Remaining TTL is preserved; no TTL stays no TTL
+	if ttl := target.data["room:1"].TTL; ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("room:1 TTL = %v, want just under 1h", ttl)
+	}
+	if ttl := target.data["light:1"].TTL; ttl != 0 {
+		t.Errorf("light:1 TTL = %v, want 0", ttl)
+	}
+}
+
+func TestCacheManager_Export_Peeks(t *testing.T) {
+	ctx := context.Background()
+	backend := &peekingBackend{mockBackend: newMockBackend()}
+	backend.Set(ctx, "light:1", []byte(`{"id":"1"}`), 0)
+
+	if _, err := NewCacheManager(backend, nil).Export(ctx); err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+	if backend.peeks != 1 || backend.hits != 0 {
+		t.Errorf("Export() made %d peeks and %d hits, want 1 and 0", backend.peeks, backend.hits)
+	}
+}
+
+func TestCacheManager_Import_SkipsExpired(t *testing.T) {
+	ctx := context.Background()
+	data, _ := json.Marshal(snapshot{
+		Version: snapshotVersion,
+		Entries: []snapshotEntry{
+			{Key: "light:1", Value: []byte(`{}`), ExpiresAt: time.Now().Add(-time.Minute), TTL: time.Hour},
+			{Key: "light:2", Value: []byte(`{}`)},
+		},
+	})
+
+	backend := newMockBackend()
+	if err := NewCacheManager(backend, nil).Import(ctx, data); err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+
+	if _, ok := backend.data["light:1"]; ok {
+		t.Error("Expired entry should not be imported")
+	}
+	if _, ok := backend.data["light:2"]; !ok {
+		t.Error("Non-expiring entry should be imported")
+	}
+}
+
+func TestCacheManager_Import_MergeVsReplace(t *testing.T) {
+	ctx := context.Background()
+	data, _ := json.Marshal(snapshot{
+		Version: snapshotVersion,
+		Entries: []snapshotEntry{{Key: "light:1", Value: []byte(`{"id":"new"}`)}},
+	})
+
+	seed := func() *mockBackend {
+		backend := newMockBackend()
+		backend.Set(ctx, "light:1", []byte(`{"id":"old"}`), 0)
+		backend.Set(ctx, "room:1", []byte(`{}`), 0)
+		return backend
+	}
+
+	merged := seed()
+	if err := NewCacheManager(merged, nil).Import(ctx, data, &ImportConfig{}); err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+	if len(merged.data) != 2 || string(merged.data["light:1"].Value) != `{"id":"new"}` {
+		t.Errorf("Merge import left %d entries, light:1 = %s", len(merged.data), merged.data["light:1"].Value)
+	}
+
+	replaced := seed()
+	if err := NewCacheManager(replaced, nil).Import(ctx, data, &ImportConfig{Replace: true}); err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+	if len(replaced.data) != 1 {
+		t.Errorf("Replace import left %d entries, want 1", len(replaced.data))
+	}
+}
+
+func TestCacheManager_Import_ReplaceNamespace(t *testing.T) {
+	ctx := context.Background()
+	backend := newMockBackend()
+	backend.Set(ctx, "bridge-A:light:1", []byte(`{"id":"old"}`), 0)
+	backend.Set(ctx, "bridge-A:room:1", []byte(`{}`), 0)
+	backend.Set(ctx, "bridge-B:light:1", []byte(`{}`), 0)
+
+	data, _ := json.Marshal(snapshot{
+		Version: snapshotVersion,
+		Entries: []snapshotEntry{{Key: "bridge-A:light:1", Value: []byte(`{"id":"new"}`)}},
+	})
+	manager := NewCacheManagerWithPrefix(backend, nil, "bridge-A")
+	if err := manager.Import(ctx, data, &ImportConfig{Replace: true}); err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+
+	// Replacing one namespace leaves the other
+	if _, ok := backend.data["bridge-A:room:1"]; ok {
+		t.Error("Replace import left an entry in its namespace")
+	}
+	if got := backend.data["bridge-A:light:1"]; got == nil || string(got.Value) != `{"id":"new"}` {
+		t.Errorf("bridge-A:light:1 = %v, want the imported entry", got)
+	}
+	if _, ok := backend.data["bridge-B:light:1"]; !ok {
+		t.Error("Replace import removed another namespace's entry")
+	}
+}
+
+func TestCacheManager_Import_Invalid(t *testing.T) {
+	manager := NewCacheManager(newMockBackend(), nil)
+	ctx := context.Background()
+
+	if err := manager.Import(ctx, []byte("not json")); err == nil {
+		t.Error("Expected error for malformed snapshot")
+	}
+	if err := manager.Import(ctx, []byte(`{"version":99,"entries":[]}`)); err == nil {
+		t.Error("Expected error for unsupported version")
+	}
+}
+
+func TestCacheManager_WarmFromFile(t *testing.T) {
+	ctx := context.Background()
+	source := newMockBackend()
+	source.Set(ctx, "light:1", []byte(`{"id":"1"}`), 0)
+	source.Set(ctx, "room:1", []byte(`{"id":"room-1"}`), time.Hour)
+
+	data, err := NewCacheManager(source, nil).Export(ctx)
+	if err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace drops entries the snapshot doesn't have
+	target := newMockBackend()
+	target.Set(ctx, "light:old", []byte(`{"id":"old"}`), 0)
+	manager := NewCacheManager(target, nil)
+	err = manager.WarmFromFile(ctx, path, &SnapshotWarmConfig{Import: &ImportConfig{Replace: true}})
+	if err != nil {
+		t.Fatalf("WarmFromFile() failed: %v", err)
+	}
+	if len(target.data) != 2 {
+		t.Errorf("Warmed %d entries, want 2", len(target.data))
+	}
+	if _, ok := target.data["light:old"]; ok {
+		t.Error("light:old should be replaced")
+	}
+
+	if err := manager.WarmFromFile(ctx, filepath.Join(t.TempDir(), "missing.json"), nil); err == nil {
+		t.Error("WarmFromFile() of a missing file should fail")
+	}
+}
+
+func TestCacheManager_WarmFromReader_Invalid(t *testing.T) {
+	ctx := context.Background()
+	manager := NewCacheManager(newMockBackend(), nil)
+
+	if err := manager.WarmFromReader(ctx, strings.NewReader("not json"), nil); err == nil {
+		t.Error("WarmFromReader() of invalid JSON should fail")
+	}
+	if err := manager.WarmFromReader(ctx, strings.NewReader(`{"version":99}`), nil); err == nil {
+		t.Error("WarmFromReader() of an unknown version should fail")
+	}
+
+	// Reconciling needs the bridge
+	snap := `{"version":1,"entries":[]}`
+	if err := manager.WarmFromReader(ctx, strings.NewReader(snap), &SnapshotWarmConfig{Reconcile: true}); err == nil {
+		t.Error("WarmFromReader() with Reconcile and no SDK client should fail")
+	}
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// maxStalenessKey is the context key for the per-call staleness budget.
+type maxStalenessKey struct{}
+
+// WithMaxStaleness returns a context that limits how old a cached entry
+// may be when read by the cached clients. If a cached entry's age (time
+// since its value was stored, see Entry.Age) exceeds d, the client
+// refreshes it from the SDK instead of serving it; otherwise the cached
+// value is returned. Touch (on a Toucher backend) and sliding TTLs extend
+// an entry's lifetime but not its age, since they don't refresh the value.
+//
+// The staleness budget is applied in addition to TTL, never instead of it:
+// an expired entry is always a miss, and an unexpired entry older than d is
+// also treated as a miss. Whichever limit is stricter wins. A d of 0 or less
+// forces every read to go to the SDK.
+//
+// Example:
+//
+//	// This request tolerates data up to 2 seconds old
+//	ctx = cache.WithMaxStaleness(ctx, 2*time.Second)
+//	light, err := cachedClient.Lights().Get(ctx, id)
+func WithMaxStaleness(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, maxStalenessKey{}, d)
+}
+
+// MaxStalenessFromContext returns the staleness budget set by
+// WithMaxStaleness, if any.
+func MaxStalenessFromContext(ctx context.Context) (time.Duration, bool) {
+	d, ok := ctx.Value(maxStalenessKey{}).(time.Duration)
+	return d, ok
+}
+
+// isStale reports whether entry's age, measured from its CreatedAt,
+// exceeds the staleness budget in ctx. Entries are never stale when no
+// budget is set.
+func isStale(ctx context.Context, entry *Entry) bool {
+	d, ok := MaxStalenessFromContext(ctx)
+	if !ok {
+		return false
+	}
+	return d <= 0 || entry.Age() > d
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+// ageEntry backdates a mock backend entry so it appears older than it is.
+func ageEntry(backend *mockBackend, key string, age time.Duration) {
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	backend.data[key].CreatedAt = time.Now().Add(-age)
+}
+
+func TestWithMaxStaleness(t *testing.T) {
+	ctx := context.Background()
+
+	if _, ok := MaxStalenessFromContext(ctx); ok {
+		t.Error("Expected no staleness budget on a plain context")
+	}
+
+	ctx = WithMaxStaleness(ctx, 5*time.Second)
+	d, ok := MaxStalenessFromContext(ctx)
+	if !ok || d != 5*time.Second {
+		t.Errorf("MaxStalenessFromContext() = %v, %v; want 5s, true", d, ok)
+	}
+}
+
+func TestCachedLightClient_Get_MaxStaleness(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	ctx := context.Background()
+
+	// Populate cache
+	if _, err := client.Get(ctx, "light-1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	ageEntry(backend, "light:light-1", 10*time.Second)
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		wantCalls int
+	}{
+		{name: "no budget serves cache", ctx: ctx, wantCalls: 1},
+		{name: "generous budget serves cache", ctx: WithMaxStaleness(ctx, time.Minute), wantCalls: 1},
+		{name: "exceeded budget refreshes", ctx: WithMaxStaleness(ctx, 5*time.Second), wantCalls: 2},
+		{name: "refreshed entry is fresh", ctx: WithMaxStaleness(ctx, 5*time.Second), wantCalls: 2},
+		{name: "zero budget always refreshes", ctx: WithMaxStaleness(ctx, 0), wantCalls: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.Get(tt.ctx, "light-1"); err != nil {
+				t.Fatalf("Get() failed: %v", err)
+			}
+			if mockSDK.calls["Get"] != tt.wantCalls {
+				t.Errorf("SDK Get calls = %d, want %d", mockSDK.calls["Get"], tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCachedLightClient_List_MaxStaleness(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+	mockSDK.lights["light-2"] = &resources.Light{ID: "light-2", Type: "light"}
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	ctx := context.Background()
+
+	if _, err := client.List(ctx); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+
+	// One stale entry is enough to force a refresh
+	ageEntry(backend, "light:light-2", time.Minute)
+
+	if _, err := client.List(WithMaxStaleness(ctx, time.Hour)); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if mockSDK.calls["List"] != 1 {
+		t.Errorf("Expected cached List within budget, got %d SDK calls", mockSDK.calls["List"])
+	}
+
+	if _, err := client.List(WithMaxStaleness(ctx, 30*time.Second)); err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if mockSDK.calls["List"] != 2 {
+		t.Errorf("Expected List refresh past budget, got %d SDK calls", mockSDK.calls["List"])
+	}
+}
@@ -1,6 +1,9 @@
 package cache
 
 import (
+	"context"
+	"errors"
+	"slices"
 	"sync/atomic"
 	"time"
 )
@@ -60,6 +63,137 @@ func (s *Stats) Clone() *Stats {
 	}
 }
 
+// Merge returns the combined statistics of s and others, as reported by a
+// backend composed of several parts (shards, tiers, nodes). Counters and
+// sizes are summed; LastError and LastErrorTime come from whichever stats
+// saw the most recent error. Nil stats are skipped and s is not modified.
+func (s *Stats) Merge(others ...*Stats) *Stats {
+	merged := s.Clone()
+
+	for _, other := range others {
+		if other == nil {
+			continue
+		}
+
+		merged.Hits += other.Hits
+		merged.Misses += other.Misses
+		merged.Evictions += other.Evictions
+		merged.Entries += other.Entries
+		merged.Size += other.Size
+		merged.Errors += other.Errors
+
+		if other.LastErrorTime.After(merged.LastErrorTime) {
+			merged.LastError = other.LastError
+			merged.LastErrorTime = other.LastErrorTime
+		}
+	}
+
+	return merged
+}
+
+// TypeStatsProvider is implemented by backends that can break their
+// statistics down by resource type (the key prefix, e.g. "light").
+type TypeStatsProvider interface {
+	// StatsByType returns statistics keyed by resource type.
+	StatsByType(ctx context.Context) (map[string]*Stats, error)
+}
+
+// DetailedStats describes the size and age distribution of the unexpired
+// entries in a cache, for capacity planning. Unlike Stats it is computed
+// by scanning every entry, so collect it on demand rather than on a hot
+// path.
+type DetailedStats struct {
+	// Entries is the number of entries scanned.
+	Entries int64
+
+	// AvgSize, MedianSize, and MaxSize describe entry value sizes in
+	// bytes.
+	AvgSize    int64
+	MedianSize int64
+	MaxSize    int64
+
+	// AvgAge is the average time since the entries were written, and
+	// OldestEntryAge the longest.
+	AvgAge         time.Duration
+	OldestEntryAge time.Duration
+}
+
+// NewDetailedStats summarizes the sizes and ages of a cache's entries, in
+// any order. It sorts sizes. Backends use it to implement
+// DetailedStatsProvider.
+func NewDetailedStats(sizes []int64, ages []time.Duration) *DetailedStats {
+	stats := &DetailedStats{Entries: int64(len(sizes))}
+	if len(sizes) > 0 {
+		slices.Sort(sizes)
+		var total int64
+		for _, size := range sizes {
+			total += size
+		}
+		stats.AvgSize = total / int64(len(sizes))
+		stats.MedianSize = sizes[len(sizes)/2]
+		stats.MaxSize = sizes[len(sizes)-1]
+	}
+
+	if len(ages) > 0 {
+		var total time.Duration
+		for _, age := range ages {
+			total += age
+			stats.OldestEntryAge = max(stats.OldestEntryAge, age)
+		}
+		stats.AvgAge = total / time.Duration(len(ages))
+	}
+	return stats
+}
+
+// DetailedStatsProvider is implemented by backends that can compute
+// DetailedStats in one pass over their entries.
+type DetailedStatsProvider interface {
+	// DetailedStats scans the unexpired entries and summarizes them.
+	DetailedStats(ctx context.Context) (*DetailedStats, error)
+}
+
+// GetDetailedStats returns backend's DetailedStats. It uses the backend's
+// DetailedStatsProvider implementation if it has one, and otherwise
+// summarizes the entries ListEntries reports.
+func GetDetailedStats(ctx context.Context, backend Backend) (*DetailedStats, error) {
+	if provider, ok := backend.(DetailedStatsProvider); ok {
+		return provider.DetailedStats(ctx)
+	}
+
+	infos, err := ListEntries(ctx, backend, "*")
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make([]int64, len(infos))
+	ages := make([]time.Duration, len(infos))
+	for i, info := range infos {
+		sizes[i] = info.Size
+		ages[i] = info.Age
+	}
+	return NewDetailedStats(sizes, ages), nil
+}
+
+// StatsResetter is implemented by backends whose statistics can be reset
+// while live, e.g. after warming so the hit rate reflects steady-state
+// traffic rather than warm-up misses.
+type StatsResetter interface {
+	// ResetStats zeroes the hit, miss, eviction, and error counters
+	// without touching cached entries. Entries and Size are not reset:
+	// they describe the cached data and stay accurate afterwards.
+	ResetStats(ctx context.Context) error
+}
+
+// ResetStats resets backend's statistics if it implements StatsResetter,
+// and returns an error wrapping errors.ErrUnsupported otherwise.
+func ResetStats(ctx context.Context, backend Backend) error {
+	sr, ok := backend.(StatsResetter)
+	if !ok {
+		return NewError("ResetStats", "", errors.ErrUnsupported)
+	}
+	return sr.ResetStats(ctx)
+}
+
 // StatsCollector provides thread-safe statistics collection.
 type StatsCollector struct {
 	hits          atomic.Int64
@@ -134,7 +268,8 @@ func (sc *StatsCollector) Stats() *Stats {
 	}
 }
 
-// Reset resets all statistics to zero.
+// Reset resets all statistics to zero. Backends that track entry counts
+// and sizes elsewhere should keep reporting those, not the zeroed values.
 func (sc *StatsCollector) Reset() {
 	sc.hits.Store(0)
 	sc.misses.Store(0)
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
@@ -90,6 +91,41 @@ func TestStats_Clone(t *testing.T) {
 	}
 }
 
+func TestStats_Merge(t *testing.T) {
+	now := time.Now()
+
+	a := &Stats{Hits: 10, Misses: 2, Evictions: 1, Entries: 5, Size: 100, Errors: 1,
+		LastError: "old error", LastErrorTime: now.Add(-time.Minute)}
+	b := &Stats{Hits: 5, Misses: 3, Evictions: 2, Entries: 7, Size: 50, Errors: 2,
+		LastError: "new error", LastErrorTime: now}
+	c := &Stats{Hits: 1, Entries: 1, Size: 10}
+
+	merged := a.Merge(b, nil, c)
+
+	want := &Stats{Hits: 16, Misses: 5, Evictions: 3, Entries: 13, Size: 160, Errors: 3,
+		LastError: "new error", LastErrorTime: now}
+	if *merged != *want {
+		t.Errorf("Merge() = %+v, want %+v", merged, want)
+	}
+
+	// Receiver is left untouched
+	if a.Hits != 10 || a.LastError != "old error" {
+		t.Errorf("Merge modified receiver: %+v", a)
+	}
+
+	// An older error never replaces a newer one, whatever the order
+	merged = b.Merge(a)
+	if merged.LastError != "new error" || !merged.LastErrorTime.Equal(now) {
+		t.Errorf("LastError = %q at %v, want %q at %v", merged.LastError, merged.LastErrorTime, "new error", now)
+	}
+
+	// Stats with no errors keep the other's error
+	merged = c.Merge(a)
+	if merged.LastError != "old error" {
+		t.Errorf("LastError = %q, want %q", merged.LastError, "old error")
+	}
+}
+
 func TestStatsCollector_RecordHit(t *testing.T) {
 	sc := NewStatsCollector()
 
@@ -301,3 +337,38 @@ func TestStatsCollector_Concurrency(t *testing.T) {
 		t.Errorf("Evictions = %v, want %v", stats.Evictions, expectedCount)
 	}
 }
+
+func TestNewDetailedStats(t *testing.T) {
+	stats := NewDetailedStats([]int64{40, 10, 100, 20, 30}, []time.Duration{time.Second, 5 * time.Second, 3 * time.Second})
+
+	if stats.Entries != 5 || stats.AvgSize != 40 || stats.MedianSize != 30 || stats.MaxSize != 100 {
+		t.Errorf("sizes = %+v, want 5 entries, avg 40, median 30, max 100", stats)
+	}
+	if stats.AvgAge != 3*time.Second || stats.OldestEntryAge != 5*time.Second {
+		t.Errorf("AvgAge = %v, OldestEntryAge = %v; want 3s, 5s", stats.AvgAge, stats.OldestEntryAge)
+	}
+
+	if empty := NewDetailedStats(nil, nil); *empty != (DetailedStats{}) {
+		t.Errorf("NewDetailedStats(nil, nil) = %+v, want zero", empty)
+	}
+}
+
+func TestGetDetailedStats_Fallback(t *testing.T) {
+	ctx := context.Background()
+	backend := newMockBackend()
+	backend.Set(ctx, "light:1", []byte("12345"), 0)
+	backend.Set(ctx, "light:2", []byte("123"), 0)
+
+	stats, err := GetDetailedStats(ctx, backend)
+	if err != nil {
+		t.Fatalf("GetDetailedStats() failed: %v", err)
+	}
+	if stats.Entries != 2 || stats.AvgSize != 4 || stats.MaxSize != 5 {
+		t.Errorf("GetDetailedStats() = %+v, want 2 entries, avg 4, max 5", stats)
+	}
+
+	// Wrapping keeps the backend's own implementation
+	if _, err := GetDetailedStats(ctx, withBackendTimeout(backend, time.Second)); err != nil {
+		t.Errorf("GetDetailedStats(wrapped) failed: %v", err)
+	}
+}
@@ -3,12 +3,16 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/rmrfslashbin/hue-sdk"
 	"github.com/rmrfslashbin/hue-sdk/resources"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 // SyncEngine automatically synchronizes a cache backend with SSE events
@@ -34,9 +38,21 @@ type SyncEngine struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	// done signals when the sync loop has stopped
+	// done signals when the sync loop has stopped, or is closed by Start
+	// if EnableAutoSync is off
 	done chan struct{}
 
+	// queues feed the workers applying events, if SyncConfig.Workers
+	// is set; an event's data elements are partitioned across them by
+	// resource ID
+	queues []chan *queuedData
+
+	// webhooks posts applied changes, if SyncConfig.Webhooks is set
+	webhooks *webhooks
+
+	// unknownTypes holds the unknown event types already logged
+	unknownTypes sync.Map
+
 	// mu protects the running state
 	mu      sync.RWMutex
 	running bool
@@ -52,13 +68,192 @@ type SyncConfig struct {
 	// Default: false
 	SyncOnStart bool
 
+	// SyncOnStartMaxAge skips SyncOnStart's full sync when the backend
+	// already holds entries and none is older than this, e.g. a File
+	// backend that just loaded a recent cache from disk. Only entries
+	// under KeyPrefix count. An empty or older cache is still synced. Age
+	// is measured from each entry's last write (see Entry.Age), which the
+	// File backend keeps across restarts, so resources that rarely change
+	// age the cache even while SSE keeps it current.
+	// Default: 0 (always sync)
+	SyncOnStartMaxAge time.Duration
+
+	// KeyPrefix namespaces the keys synced resources are written under.
+	// It must match CachedClientConfig.KeyPrefix of the clients reading
+	// them. See NewKeyBuilderWithPrefix.
+	// Default: "" (no namespace)
+	KeyPrefix string
+
 	// ErrorHandler is called when sync errors occur.
-	// If nil, errors are silently ignored.
+	// If nil, errors are silently ignored. Failures of the event stream
+	// are typed, so handlers can tell them apart with errors.As:
+	// *SubscribeError (couldn't connect), *StreamClosedError (the stream
+	// dropped), and *ProcessError (an event couldn't be applied).
 	ErrorHandler func(error)
 
 	// EventHandler is called for each event (for debugging/logging).
 	// If nil, events are not logged.
 	EventHandler func(*resources.Event)
+
+	// Logger receives sync diagnostics, including every error passed
+	// to ErrorHandler.
+	// Default: no-op logger
+	Logger Logger
+
+	// TracerProvider enables OpenTelemetry spans for event processing.
+	// Each event gets a "hue-cache.Sync.processEvent" span with a child
+	// span per data element.
+	// Default: nil (tracing disabled)
+	TracerProvider trace.TracerProvider
+
+	// ChangeLog, if set, durably records every mutation sync applies to
+	// the cache. The caller owns it and must close it after Stop.
+	// Default: nil (disabled)
+	ChangeLog *ChangeLog
+
+	// BackendTimeout bounds each backend operation made while applying
+	// events, so a wedged backend can't stall the event loop. A timed-out
+	// operation is reported as a sync error. The backend must honor
+	// context cancellation. Set to 0 to disable.
+	// Default: DefaultBackendTimeout (5 seconds)
+	BackendTimeout time.Duration
+
+	// EventBus, if set, receives every resource event from the bridge,
+	// independent of whether the cache was updated. The caller owns it.
+	// Default: nil (disabled)
+	EventBus *EventBus
+
+	// CacheRaw maintains raw entries (see CachedClientConfig.CacheRaw):
+	// add events store the event's JSON under the raw key, and update and
+	// delete events remove it. Enable this when cached clients use
+	// CacheRaw, so raw entries never outlive the resources they describe.
+	// Default: false
+	CacheRaw bool
+
+	// Validation checks the JSON of add and update events before it is
+	// cached. A rejected event leaves the cache unchanged and is reported
+	// as a sync error.
+	// Default: nil (no validation)
+	Validation *Validation
+
+	// Projection strips the resources sync caches to an allowlist of
+	// fields. Share it with CachedClientConfig.Projection.
+	// Default: nil (cache full resources)
+	Projection *Projection
+
+	// SyncTimeout bounds each resource type's SDK call during the full
+	// sync performed by SyncOnStart, so a hung bridge connection can't
+	// block startup forever. A timed-out sync is reported as an initial
+	// sync error. Set to 0 to disable.
+	// Default: DefaultSyncTimeout (30 seconds)
+	SyncTimeout time.Duration
+
+	// EventSource supplies the SSE events the engine applies. Override it
+	// to filter or replay events, or to test the engine without a bridge.
+	// Default: the SDK client's Events()
+	EventSource EventSource
+
+	// SoftDeleteGrace enables soft deletes: a delete event keeps the
+	// cached resource readable for this long instead of removing it at
+	// once, and an add or update within the grace period restores it.
+	// This closes the miss window when a delete is spurious or quickly
+	// followed by a re-add, at the cost of serving a deleted resource
+	// until the grace period ends. Set to 0 to delete immediately.
+	// Default: 0 (disabled)
+	SoftDeleteGrace time.Duration
+
+	// LastWriteWins rejects events older than the version already cached
+	// for their resource (see LastWriteWins). Enable it when several
+	// processes sync the same bridge into a shared backend, so a slow
+	// process can't overwrite fresher state with a stale event. Events are
+	// versioned by their creation time, falling back to the time they
+	// were received; full-sync writes by the time they were fetched.
+	// Default: false
+	LastWriteWins bool
+
+	// Workers applies events on this many goroutines instead of in the
+	// loop receiving them, so a slow backend doesn't back up the bridge's
+	// event stream. Each data element of an event is queued for the worker
+	// its resource ID hashes to, so the changes to one resource are still
+	// applied in order; changes to different resources may be applied out
+	// of order. Events still queued when the engine stops are discarded.
+	// Default: 0 (apply events in the receive loop)
+	Workers int
+
+	// QueueSize is the number of data elements each worker's queue holds.
+	// Default: DefaultSyncQueueSize
+	QueueSize int
+
+	// QueueFullPolicy chooses what the receive loop does with a data
+	// element whose worker's queue is full: wait for room, or drop it.
+	// Default: QueueBlock
+	QueueFullPolicy QueueFullPolicy
+
+	// Webhooks are HTTP endpoints the engine POSTs each change it applies
+	// to, if it matches the webhook's filter. Delivery is asynchronous,
+	// with a queue per webhook, so a slow or failing endpoint never
+	// stalls sync: changes for a webhook whose queue is full are dropped,
+	// and requests that still fail after retrying are logged. Both are
+	// counted in SyncStats.
+	// Default: nil (no webhooks)
+	Webhooks []Webhook
+
+	// Codec serializes the resources the engine caches. It must match
+	// CachedClientConfig.Codec of the clients reading them. Raw entries
+	// (see CacheRaw) hold the event's JSON whatever the codec.
+	// Default: JSONCodec()
+	Codec Codec
+
+	// UnknownEvents chooses what the engine does with event types other
+	// than add, update, and delete, which firmware updates may introduce:
+	// skip them with a log line, skip them silently, or report them as
+	// sync errors. Skipped events are counted in SyncStats.UnknownEvents.
+	// Resource types are never unknown: events for any type are cached
+	// under that type's key prefix.
+	// Default: UnknownEventLog
+	UnknownEvents UnknownEventPolicy
+}
+
+// UnknownEventPolicy selects what the sync engine does with an event of a
+// type it doesn't handle.
+type UnknownEventPolicy int
+
+const (
+	// UnknownEventLog skips the event, counting it in
+	// SyncStats.UnknownEvents, and logs a warning the first time each
+	// unknown type is seen.
+	UnknownEventLog UnknownEventPolicy = iota
+
+	// UnknownEventIgnore skips the event, counting it in
+	// SyncStats.UnknownEvents, without logging.
+	UnknownEventIgnore
+
+	// UnknownEventError reports the event as a sync error: it is counted
+	// in SyncStats.SyncErrors and passed to the ErrorHandler as a
+	// *ProcessError.
+	UnknownEventError
+)
+
+// String returns the policy's name.
+func (p UnknownEventPolicy) String() string {
+	switch p {
+	case UnknownEventLog:
+		return "log"
+	case UnknownEventIgnore:
+		return "ignore"
+	case UnknownEventError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// EventSource delivers resource events from the bridge. The SDK client's
+// Events() satisfies it.
+type EventSource interface {
+	// Subscribe streams events until ctx is done. The channel is closed
+	// when the stream ends.
+	Subscribe(ctx context.Context) (<-chan resources.Event, error)
 }
 
 // DefaultSyncConfig returns default sync configuration.
@@ -68,9 +263,15 @@ func DefaultSyncConfig() *SyncConfig {
 		SyncOnStart:    false,
 		ErrorHandler:   nil,
 		EventHandler:   nil,
+		BackendTimeout: DefaultBackendTimeout,
+		SyncTimeout:    DefaultSyncTimeout,
 	}
 }
 
+// DefaultSyncTimeout is the default per-resource-type timeout for the
+// full sync performed on start.
+const DefaultSyncTimeout = 30 * time.Second
+
 // SyncStats contains synchronization statistics.
 type SyncStats struct {
 	mu sync.RWMutex
@@ -90,6 +291,30 @@ type SyncStats struct {
 	// SyncErrors is the number of sync errors encountered.
 	SyncErrors int64
 
+	// StaleEvents is the number of events rejected by LastWriteWins.
+	StaleEvents int64
+
+	// UnknownEvents is the number of event data elements skipped because
+	// their event type is unknown (see SyncConfig.UnknownEvents).
+	UnknownEvents int64
+
+	// DroppedEvents is the number of event data elements dropped because
+	// their worker's queue was full (see SyncConfig.QueueFullPolicy).
+	DroppedEvents int64
+
+	// WebhooksDropped is the number of changes not posted because their
+	// webhook's queue was full (see SyncConfig.Webhooks).
+	WebhooksDropped int64
+
+	// WebhookFailures is the number of webhook posts that failed after
+	// all retries.
+	WebhookFailures int64
+
+	// QueueDepth is the number of event data elements waiting for a
+	// worker. It is sampled when the stats are cloned, so it is only set
+	// on SyncEngine.Stats results.
+	QueueDepth int
+
 	// LastEventTime is when the last event was processed.
 	LastEventTime time.Time
 
@@ -101,6 +326,16 @@ type SyncStats struct {
 
 	// Latency is the average event processing latency.
 	AvgLatency time.Duration
+
+	// P50Latency, P95Latency, and P99Latency are event processing latency
+	// percentiles over the most recent 1024 events. They are computed when
+	// the stats are cloned, so they are only set on SyncEngine.Stats results.
+	P50Latency time.Duration
+	P95Latency time.Duration
+	P99Latency time.Duration
+
+	// latencies holds recent samples for the percentiles.
+	latencies latencyWindow
 }
 
 // Clone creates a copy of the stats.
@@ -108,16 +343,32 @@ func (s *SyncStats) Clone() *SyncStats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	// Recompute percentiles from samples; a clone has none, so cloning a
+	// clone keeps the percentiles it was given
+	p := []time.Duration{s.P50Latency, s.P95Latency, s.P99Latency}
+	if len(s.latencies.samples) > 0 {
+		p = s.latencies.percentiles(0.50, 0.95, 0.99)
+	}
+
 	return &SyncStats{
 		EventsProcessed: s.EventsProcessed,
 		AddEvents:       s.AddEvents,
 		UpdateEvents:    s.UpdateEvents,
 		DeleteEvents:    s.DeleteEvents,
 		SyncErrors:      s.SyncErrors,
+		StaleEvents:     s.StaleEvents,
+		UnknownEvents:   s.UnknownEvents,
+		DroppedEvents:   s.DroppedEvents,
+		WebhooksDropped: s.WebhooksDropped,
+		WebhookFailures: s.WebhookFailures,
+		QueueDepth:      s.QueueDepth,
 		LastEventTime:   s.LastEventTime,
 		LastError:       s.LastError,
 		LastErrorTime:   s.LastErrorTime,
 		AvgLatency:      s.AvgLatency,
+		P50Latency:      p[0],
+		P95Latency:      p[1],
+		P99Latency:      p[2],
 	}
 }
 
@@ -130,16 +381,19 @@ func NewSyncEngine(backend Backend, client *hue.Client, config ...*SyncConfig) *
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &SyncEngine{
-		backend:    backend,
+	s := &SyncEngine{
+		backend:    withBackendTimeout(backend, cfg.BackendTimeout),
 		client:     client,
-		keyBuilder: NewKeyBuilder(),
+		keyBuilder: NewKeyBuilderWithPrefix(cfg.KeyPrefix),
 		stats:      &SyncStats{},
 		config:     cfg,
 		ctx:        ctx,
 		cancel:     cancel,
 		done:       make(chan struct{}),
+		queues:     newSyncQueues(cfg.Workers, cfg.QueueSize),
 	}
+	s.webhooks = newWebhooks(ctx, cfg.Webhooks, s.logger)
+	return s
 }
 
 // Start begins synchronizing the cache with SSE events.
@@ -153,15 +407,18 @@ func (s *SyncEngine) Start() error {
 	s.mu.Unlock()
 
 	// Perform initial sync if configured
-	if s.config.SyncOnStart {
+	if s.config.SyncOnStart && s.needsInitialSync() {
 		if err := s.fullSync(); err != nil {
 			s.handleError(fmt.Errorf("initial sync failed: %w", err))
 		}
 	}
 
-	// Start event subscription
+	// Start event subscription. Without it, nothing else closes done,
+	// which Stop waits on.
 	if s.config.EnableAutoSync {
 		go s.syncLoop()
+	} else {
+		close(s.done)
 	}
 
 	return nil
@@ -181,34 +438,59 @@ func (s *SyncEngine) Stop() error {
 	s.cancel()
 	<-s.done
 
+	if s.webhooks != nil {
+		s.webhooks.close()
+	}
+
 	return nil
 }
 
 // Stats returns current synchronization statistics.
 func (s *SyncEngine) Stats() *SyncStats {
-	return s.stats.Clone()
+	stats := s.stats.Clone()
+	for _, queue := range s.queues {
+		stats.QueueDepth += len(queue)
+	}
+	if s.webhooks != nil {
+		stats.WebhooksDropped = s.webhooks.targets.dropped.Load()
+		stats.WebhookFailures = s.webhooks.failures.Load()
+	}
+	return stats
 }
 
 // syncLoop subscribes to events and processes them.
 func (s *SyncEngine) syncLoop() {
 	defer close(s.done)
 
+	if len(s.queues) > 0 {
+		defer s.startWorkers()()
+	}
+
 	// Subscribe to events
-	events, err := s.client.Events().Subscribe(s.ctx)
+	events, err := s.eventSource().Subscribe(s.ctx)
 	if err != nil {
-		s.handleError(fmt.Errorf("failed to subscribe to events: %w", err))
+		s.handleError(&SubscribeError{Err: err})
 		return
 	}
 
+	var received int64
 	for {
 		select {
 		case event, ok := <-events:
 			if !ok {
-				// Event channel closed
+				// Event channel closed - dropped, unless we're stopping
+				if s.ctx.Err() == nil {
+					s.handleError(&StreamClosedError{Events: received})
+				}
 				return
 			}
 
-			s.processEvent(&event)
+			received++
+			if len(s.queues) > 0 {
+				s.queueEvent(&event)
+			} else {
+				s.processEvent(&event)
+			}
 
 		case <-s.ctx.Done():
 			return
@@ -220,6 +502,37 @@ func (s *SyncEngine) syncLoop() {
 func (s *SyncEngine) processEvent(event *resources.Event) {
 	start := time.Now()
 
+	ctx, span := s.beginEvent(event)
+	defer span.End()
+
+	// Process each data element
+	for i := range event.Data {
+		s.applyEventData(ctx, event, &event.Data[i])
+	}
+
+	s.recordLatency(time.Since(start))
+}
+
+// beginEvent starts the span of event and records it in the statistics.
+// The returned context versions the event's writes.
+func (s *SyncEngine) beginEvent(event *resources.Event) (context.Context, trace.Span) {
+	ctx, span := s.tracer().Start(context.Background(), "hue-cache.Sync.processEvent",
+		trace.WithAttributes(
+			attribute.String("event.id", event.ID),
+			attribute.String("event.type", event.Type),
+			attribute.Int("event.data_count", len(event.Data)),
+		),
+	)
+
+	s.logger().Debug("processing sync event", "event_id", event.ID, "type", event.Type, "items", len(event.Data))
+
+	// Version the event's writes by when the bridge created it
+	version := time.Now()
+	if created, err := time.Parse(time.RFC3339, event.CreationTime); err == nil {
+		version = created
+	}
+	ctx = withEventVersion(ctx, version)
+
 	// Call event handler if configured
 	if s.config.EventHandler != nil {
 		s.config.EventHandler(event)
@@ -231,15 +544,26 @@ func (s *SyncEngine) processEvent(event *resources.Event) {
 	s.stats.LastEventTime = time.Now()
 	s.stats.mu.Unlock()
 
-	// Process each data element
-	for _, data := range event.Data {
-		if err := s.processEventData(event.Type, &data); err != nil {
-			s.handleError(fmt.Errorf("failed to process event data: %w", err))
-		}
+	return ctx, span
+}
+
+// applyEventData applies one data element of event, reporting failures
+// to the error handler and the event's span.
+func (s *SyncEngine) applyEventData(ctx context.Context, event *resources.Event, data *resources.EventData) {
+	if err := s.processEventData(ctx, event.Type, data); err != nil {
+		recordSpanError(trace.SpanFromContext(ctx), err)
+		s.handleError(&ProcessError{
+			EventID:   event.ID,
+			EventType: event.Type,
+			Key:       s.keyBuilder.Resource(data.Type, data.ID),
+			Err:       err,
+		})
 	}
+	s.publishEvent(event.Type, data)
+}
 
-	// Update latency
-	latency := time.Since(start)
+// recordLatency adds the processing latency of an event to the statistics.
+func (s *SyncEngine) recordLatency(latency time.Duration) {
 	s.stats.mu.Lock()
 	if s.stats.AvgLatency == 0 {
 		s.stats.AvgLatency = latency
@@ -247,67 +571,323 @@ func (s *SyncEngine) processEvent(event *resources.Event) {
 		// Exponential moving average
 		s.stats.AvgLatency = (s.stats.AvgLatency*9 + latency) / 10
 	}
+	s.stats.latencies.add(latency)
 	s.stats.mu.Unlock()
 }
 
 // processEventData processes a single event data element.
-func (s *SyncEngine) processEventData(eventType string, data *resources.EventData) error {
-	ctx := context.Background()
-
+func (s *SyncEngine) processEventData(ctx context.Context, eventType string, data *resources.EventData) error {
 	// Build cache key
 	key := s.keyBuilder.Resource(data.Type, data.ID)
 
+	ctx, span := s.tracer().Start(ctx, "hue-cache.Sync.processEventData",
+		trace.WithAttributes(
+			attribute.String("event.type", eventType),
+			attribute.String("cache.key", key),
+		),
+	)
+	defer span.End()
+
+	var err error
 	switch eventType {
 	case resources.EventTypeAdd:
 		s.stats.mu.Lock()
 		s.stats.AddEvents++
 		s.stats.mu.Unlock()
-		return s.handleAdd(ctx, key, data)
+		if err = checkID(data.Type, data.ID); err == nil {
+			err = s.handleAdd(ctx, key, data)
+		}
 
 	case resources.EventTypeUpdate:
 		s.stats.mu.Lock()
 		s.stats.UpdateEvents++
 		s.stats.mu.Unlock()
-		return s.handleUpdate(ctx, key, data)
+		if err = checkID(data.Type, data.ID); err == nil {
+			err = s.handleUpdate(ctx, key, data)
+		}
 
 	case resources.EventTypeDelete:
 		s.stats.mu.Lock()
 		s.stats.DeleteEvents++
 		s.stats.mu.Unlock()
-		return s.handleDelete(ctx, key)
+		if err = checkID(data.Type, data.ID); err == nil {
+			err = s.handleDelete(ctx, key)
+		}
 
 	default:
-		return fmt.Errorf("unknown event type: %s", eventType)
+		return s.handleUnknown(eventType, key)
+	}
+
+	if errors.Is(err, errStaleWrite) {
+		s.stats.mu.Lock()
+		s.stats.StaleEvents++
+		s.stats.mu.Unlock()
+		s.logger().Debug("skipped stale event", "key", key, "type", eventType)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.recordChange(eventType, data)
+	s.notifyWebhooks(eventType, data)
+	return nil
+}
+
+// publishEvent notifies the event bus, if configured, of a resource event.
+func (s *SyncEngine) publishEvent(eventType string, data *resources.EventData) {
+	if s.config.EventBus == nil {
+		return
+	}
+
+	s.config.EventBus.Publish(ResourceEvent{
+		EventType: eventType,
+		Type:      data.Type,
+		ID:        data.ID,
+		Data:      data.RawData,
+	})
+}
+
+// notifyWebhooks queues an applied change for the webhooks, if any.
+func (s *SyncEngine) notifyWebhooks(eventType string, data *resources.EventData) {
+	if s.webhooks == nil {
+		return
+	}
+
+	payload := WebhookPayload{
+		EventType: eventType,
+		Type:      data.Type,
+		ID:        data.ID,
+		Time:      time.Now(),
+	}
+	if eventType != resources.EventTypeDelete {
+		payload.Data = data.RawData
+	}
+	s.webhooks.notify(payload)
+}
+
+// recordChange appends an applied mutation to the change-log, if enabled.
+// Change-log failures are logged but don't fail the event.
+func (s *SyncEngine) recordChange(eventType string, data *resources.EventData) {
+	if s.config.ChangeLog == nil {
+		return
+	}
+
+	record := ChangeRecord{
+		Time:      time.Now(),
+		EventType: eventType,
+		Type:      data.Type,
+		ID:        data.ID,
+	}
+	if err := s.config.ChangeLog.Append(record); err != nil {
+		s.logger().Warn("failed to record change", "type", data.Type, "id", data.ID, "error", err)
+	}
+}
+
+// eventJSON returns the resource JSON carried by an add or update event.
+// RawData is already JSON, so it is stored as received rather than re-encoded.
+func eventJSON(data *resources.EventData) ([]byte, error) {
+	if !json.Valid(data.RawData) {
+		return nil, errors.New("event data is not valid JSON")
 	}
+	return data.RawData, nil
 }
 
 // handleAdd handles an "add" event by caching the new resource.
 func (s *SyncEngine) handleAdd(ctx context.Context, key string, data *resources.EventData) error {
-	// Marshal the event data to JSON
-	jsonData, err := json.Marshal(data.RawData)
+	jsonData, err := eventJSON(data)
+	if err != nil {
+		return err
+	}
+
+	value, err := s.encode(key, jsonData)
 	if err != nil {
-		return fmt.Errorf("failed to marshal event data: %w", err)
+		return err
 	}
 
 	// Store in cache with no TTL (stays until deleted or updated)
-	return s.backend.Set(ctx, key, jsonData, 0)
+	if err := s.write(ctx, key, value); err != nil {
+		return err
+	}
+
+	if s.config.CacheRaw {
+		return s.backend.Set(ctx, s.keyBuilder.Raw(key), jsonData, 0)
+	}
+	return nil
 }
 
-// handleUpdate handles an "update" event by updating the cached resource.
+// handleUpdate handles an "update" event by merging it into the cached
+// resource. Update events carry only the changed fields, so they are
+// applied like a client's optimistic patch. If the resource isn't cached,
+// or the cached value can't be merged, the entry is removed instead and
+// the next read fetches the whole resource.
 func (s *SyncEngine) handleUpdate(ctx context.Context, key string, data *resources.EventData) error {
-	// Marshal the event data to JSON
-	jsonData, err := json.Marshal(data.RawData)
+	jsonData, err := eventJSON(data)
+	if err != nil {
+		return err
+	}
+
+	if err := s.claim(ctx, key); err != nil {
+		return err
+	}
+
+	entry, err := s.backend.Get(ctx, key)
+	if err != nil && !isMiss(err) {
+		return err
+	}
+	if err != nil || isTombstone(entry.Value) {
+		return s.remove(ctx, key)
+	}
+
+	merged, err := s.merge(entry.Value, jsonData)
 	if err != nil {
-		return fmt.Errorf("failed to marshal event data: %w", err)
+		s.logger().Warn("failed to merge update into cached resource", "key", key, "error", err)
+		return s.remove(ctx, key)
+	}
+
+	value, err := s.encode(key, merged)
+	if err != nil {
+		return err
 	}
 
 	// Update in cache with no TTL
-	return s.backend.Set(ctx, key, jsonData, 0)
+	if err := s.backend.Set(ctx, key, value, 0); err != nil {
+		return err
+	}
+
+	// Update events carry only changed fields, so the raw entry is stale
+	if s.config.CacheRaw {
+		return s.backend.Delete(ctx, s.keyBuilder.Raw(key))
+	}
+	return nil
+}
+
+// merge deep-merges the JSON object in an update event into a cached
+// value, returning the merged resource as JSON.
+func (s *SyncEngine) merge(value, updateJSON []byte) ([]byte, error) {
+	var base map[string]interface{}
+	if err := s.codec().Unmarshal(value, &base); err != nil {
+		return nil, err
+	}
+
+	update, err := decodeJSON(updateJSON)
+	if err != nil {
+		return nil, err
+	}
+	patch, ok := update.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("update is not a JSON object")
+	}
+	return json.Marshal(mergeMaps(base, patch))
+}
+
+// encode validates and projects a resource's JSON from an event and
+// encodes it with the configured codec for caching.
+func (s *SyncEngine) encode(key string, jsonData []byte) ([]byte, error) {
+	if err := s.config.Validation.Validate(s.keyBuilder.local(key), jsonData); err != nil {
+		return nil, err
+	}
+
+	projected, err := s.config.Projection.Apply(s.keyBuilder.local(key), jsonData)
+	if err != nil {
+		return nil, err
+	}
+	value, err := transcodeJSON(s.codec(), projected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event data: %w", err)
+	}
+	return value, nil
+}
+
+// handleUnknown handles an event of an unknown type according to
+// SyncConfig.UnknownEvents.
+func (s *SyncEngine) handleUnknown(eventType, key string) error {
+	if s.config.UnknownEvents == UnknownEventError {
+		return fmt.Errorf("unknown event type: %s", eventType)
+	}
+
+	s.stats.mu.Lock()
+	s.stats.UnknownEvents++
+	s.stats.mu.Unlock()
+
+	if s.config.UnknownEvents == UnknownEventLog {
+		if _, seen := s.unknownTypes.LoadOrStore(eventType, struct{}{}); !seen {
+			s.logger().Warn("skipping unknown event type", "type", eventType, "key", key)
+		}
+	}
+	return nil
 }
 
 // handleDelete handles a "delete" event by removing the resource from cache.
 func (s *SyncEngine) handleDelete(ctx context.Context, key string) error {
-	return s.backend.Delete(ctx, key)
+	if err := s.claim(ctx, key); err != nil {
+		return err
+	}
+
+	if s.config.SoftDeleteGrace > 0 {
+		return s.softDelete(ctx, key)
+	}
+	return s.remove(ctx, key)
+}
+
+// remove deletes the resource under key, and its raw entry.
+func (s *SyncEngine) remove(ctx context.Context, key string) error {
+	if err := s.backend.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	if s.config.CacheRaw {
+		return s.backend.Delete(ctx, s.keyBuilder.Raw(key))
+	}
+	return nil
+}
+
+// write stores a resource with no TTL. With LastWriteWins, it returns
+// errStaleWrite instead if a newer version is cached.
+func (s *SyncEngine) write(ctx context.Context, key string, data []byte) error {
+	if err := s.claim(ctx, key); err != nil {
+		return err
+	}
+	return s.backend.Set(ctx, key, data, 0)
+}
+
+// claim records the version of the write in progress for key if
+// LastWriteWins is enabled, returning errStaleWrite if a newer version is
+// cached.
+func (s *SyncEngine) claim(ctx context.Context, key string) error {
+	if !s.config.LastWriteWins {
+		return nil
+	}
+
+	lww := &LastWriteWins{backend: s.backend, keyBuilder: s.keyBuilder}
+	ok, err := lww.Claim(ctx, key, eventVersion(ctx))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errStaleWrite
+	}
+	return nil
+}
+
+// softDelete marks the resource under key deleted by touching it, and its
+// raw entry, to expire after SoftDeleteGrace. Touching keeps the entries'
+// values and tags, and doesn't count as a read. The backend removes them
+// once the grace period ends; an add or update before then rewrites them
+// without a TTL, restoring them.
+func (s *SyncEngine) softDelete(ctx context.Context, key string) error {
+	keys := []string{key}
+	if s.config.CacheRaw {
+		keys = append(keys, s.keyBuilder.Raw(key))
+	}
+
+	for _, k := range keys {
+		// A miss means nothing cached (or already expired) - nothing to keep
+		if err := Touch(ctx, s.backend, k, s.config.SoftDeleteGrace); err != nil && !isMiss(err) {
+			return err
+		}
+	}
+	return nil
 }
 
 // handleError handles sync errors according to configuration.
@@ -318,142 +898,207 @@ func (s *SyncEngine) handleError(err error) {
 	s.stats.LastErrorTime = time.Now()
 	s.stats.mu.Unlock()
 
+	s.logger().Error("sync error", "error", err)
+
 	if s.config.ErrorHandler != nil {
 		s.config.ErrorHandler(err)
 	}
 }
 
-// fullSync performs a full synchronization of all resources.
-// This is used for the initial sync when SyncOnStart is true.
-func (s *SyncEngine) fullSync() error {
-	ctx := context.Background()
-
-	// Sync lights
-	if err := s.syncLights(ctx); err != nil {
-		return fmt.Errorf("failed to sync lights: %w", err)
+// eventSource returns the configured event source, or the SDK client's.
+func (s *SyncEngine) eventSource() EventSource {
+	if s.config.EventSource != nil {
+		return s.config.EventSource
 	}
+	return s.client.Events()
+}
 
-	// Sync rooms
-	if err := s.syncRooms(ctx); err != nil {
-		return fmt.Errorf("failed to sync rooms: %w", err)
+// logger returns the configured logger, or a no-op logger if none is set.
+func (s *SyncEngine) logger() Logger {
+	return loggerOrNop(s.config.Logger)
+}
+
+// codec returns the configured codec, or JSONCodec if none is set.
+func (s *SyncEngine) codec() Codec {
+	return codecOrJSON(s.config.Codec)
+}
+
+// tracer returns a tracer from the configured provider, or a no-op tracer
+// if tracing is disabled.
+func (s *SyncEngine) tracer() trace.Tracer {
+	if s.config.TracerProvider == nil {
+		return noop.NewTracerProvider().Tracer(instrumentationName)
 	}
+	return s.config.TracerProvider.Tracer(instrumentationName)
+}
 
-	// Sync zones
-	if err := s.syncZones(ctx); err != nil {
-		return fmt.Errorf("failed to sync zones: %w", err)
+// needsInitialSync reports whether SyncOnStart's full sync should run,
+// given SyncOnStartMaxAge and the age of the entries under the engine's
+// key prefix. If the backend can't be inspected, it syncs.
+func (s *SyncEngine) needsInitialSync() bool {
+	if s.config.SyncOnStartMaxAge <= 0 {
+		return true
 	}
 
-	// Sync scenes
-	if err := s.syncScenes(ctx); err != nil {
-		return fmt.Errorf("failed to sync scenes: %w", err)
+	entries, err := ListEntries(s.ctx, s.backend, s.keyBuilder.All())
+	if err != nil {
+		s.logger().Warn("failed to inspect cache before initial sync", "error", err)
+		return true
+	}
+	if len(entries) == 0 {
+		return true
 	}
 
-	// Sync grouped lights
-	if err := s.syncGroupedLights(ctx); err != nil {
-		return fmt.Errorf("failed to sync grouped lights: %w", err)
+	var oldest time.Duration
+	for _, entry := range entries {
+		oldest = max(oldest, entry.Age)
+	}
+	if oldest > s.config.SyncOnStartMaxAge {
+		return true
 	}
 
-	return nil
+	s.logger().Info("skipping initial sync of recent cache", "entries", len(entries), "oldest_age", oldest)
+	return false
 }
 
-// syncLights syncs all lights to the cache.
-func (s *SyncEngine) syncLights(ctx context.Context) error {
-	lights, err := s.client.Lights().List(ctx)
-	if err != nil {
-		return err
+// fullSync performs a full synchronization of all resources.
+// This is used for the initial sync when SyncOnStart is true. Each
+// resource type is listed under its own SyncTimeout, derived from the
+// engine's context so Stop also cancels an in-progress sync.
+func (s *SyncEngine) fullSync() error {
+	steps := []struct {
+		name string
+		run  func(context.Context) error
+	}{
+		{"lights", s.syncLights},
+		{"rooms", s.syncRooms},
+		{"zones", s.syncZones},
+		{"scenes", s.syncScenes},
+		{"grouped lights", s.syncGroupedLights},
+		{"bridges", s.syncBridges},
+		{"bridge homes", s.syncBridgeHomes},
 	}
 
-	for _, light := range lights {
-		key := s.keyBuilder.Light(light.ID)
-		data, err := json.Marshal(light)
-		if err != nil {
-			return err
-		}
-		if err := s.backend.Set(ctx, key, data, 0); err != nil {
-			return err
+	for _, step := range steps {
+		if err := s.syncWithTimeout(step.run); err != nil {
+			return fmt.Errorf("failed to sync %s: %w", step.name, err)
 		}
 	}
 
 	return nil
 }
 
-// syncRooms syncs all rooms to the cache.
-func (s *SyncEngine) syncRooms(ctx context.Context) error {
-	rooms, err := s.client.Rooms().List(ctx)
-	if err != nil {
-		return err
+// syncWithTimeout runs one full-sync step under SyncTimeout. If the
+// deadline passes, the returned error wraps context.DeadlineExceeded.
+func (s *SyncEngine) syncWithTimeout(run func(context.Context) error) error {
+	ctx := s.ctx
+	if s.config.SyncTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.SyncTimeout)
+		defer cancel()
 	}
 
-	for _, room := range rooms {
-		key := s.keyBuilder.Room(room.ID)
-		data, err := json.Marshal(room)
-		if err != nil {
-			return err
-		}
-		if err := s.backend.Set(ctx, key, data, 0); err != nil {
-			return err
-		}
+	err := run(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %v: %w", s.config.SyncTimeout, context.DeadlineExceeded)
 	}
-
-	return nil
+	return err
 }
 
-// syncZones syncs all zones to the cache.
-func (s *SyncEngine) syncZones(ctx context.Context) error {
-	zones, err := s.client.Zones().List(ctx)
+// storeSynced caches a resource fetched during a full sync. Resources
+// rejected by validation are logged and skipped rather than failing the sync.
+func (s *SyncEngine) storeSynced(ctx context.Context, key string, resource any) error {
+	data, err := s.codec().Marshal(resource)
 	if err != nil {
 		return err
 	}
 
-	for _, zone := range zones {
-		key := s.keyBuilder.Zone(zone.ID)
-		data, err := json.Marshal(zone)
-		if err != nil {
-			return err
-		}
-		if err := s.backend.Set(ctx, key, data, 0); err != nil {
+	if err := validateResource(s.config.Validation, s.codec(), s.keyBuilder.local(key), resource, data); err != nil {
+		s.logger().Warn("rejected invalid resource", "key", key, "error", err)
+		return nil
+	}
+
+	if s.config.Projection != nil {
+		if data, err = projectResource(s.codec(), s.config.Projection, s.keyBuilder.local(key), resource); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	err = s.write(ctx, key, data)
+	if errors.Is(err, errStaleWrite) {
+		// An event newer than the listing already updated it
+		return nil
+	}
+	return err
 }
 
-// syncScenes syncs all scenes to the cache.
-func (s *SyncEngine) syncScenes(ctx context.Context) error {
-	scenes, err := s.client.Scenes().List(ctx)
-	if err != nil {
-		return err
-	}
+// syncLights syncs all lights to the cache.
+func (s *SyncEngine) syncLights(ctx context.Context) error {
+	return syncResources(ctx, s, s.client.Lights().List, func(light *resources.Light) string {
+		return s.keyBuilder.Light(light.ID)
+	})
+}
 
-	for _, scene := range scenes {
-		key := s.keyBuilder.Scene(scene.ID)
-		data, err := json.Marshal(scene)
-		if err != nil {
-			return err
-		}
-		if err := s.backend.Set(ctx, key, data, 0); err != nil {
-			return err
-		}
-	}
+// syncRooms syncs all rooms to the cache.
+func (s *SyncEngine) syncRooms(ctx context.Context) error {
+	return syncResources(ctx, s, s.client.Rooms().List, func(room *resources.Room) string {
+		return s.keyBuilder.Room(room.ID)
+	})
+}
 
-	return nil
+// syncZones syncs all zones to the cache.
+func (s *SyncEngine) syncZones(ctx context.Context) error {
+	return syncResources(ctx, s, s.client.Zones().List, func(zone *resources.Zone) string {
+		return s.keyBuilder.Zone(zone.ID)
+	})
+}
+
+// syncScenes syncs all scenes to the cache.
+func (s *SyncEngine) syncScenes(ctx context.Context) error {
+	return syncResources(ctx, s, s.client.Scenes().List, func(scene *resources.Scene) string {
+		return s.keyBuilder.Scene(scene.ID)
+	})
 }
 
 // syncGroupedLights syncs all grouped lights to the cache.
 func (s *SyncEngine) syncGroupedLights(ctx context.Context) error {
-	groupedLights, err := s.client.GroupedLights().List(ctx)
+	return syncResources(ctx, s, s.client.GroupedLights().List, func(gl *resources.GroupedLight) string {
+		return s.keyBuilder.GroupedLight(gl.ID)
+	})
+}
+
+// syncBridges syncs all bridges to the cache.
+func (s *SyncEngine) syncBridges(ctx context.Context) error {
+	return syncResources(ctx, s, s.client.Bridge().List, func(bridge *resources.Bridge) string {
+		return s.keyBuilder.Bridge(bridge.ID)
+	})
+}
+
+// syncBridgeHomes syncs all bridge homes to the cache.
+func (s *SyncEngine) syncBridgeHomes(ctx context.Context) error {
+	return syncResources(ctx, s, s.client.BridgeHome().List, func(home *resources.BridgeHome) string {
+		return s.keyBuilder.BridgeHome(home.ID)
+	})
+}
+
+// syncResources lists every resource of one type and caches each under
+// keyOf. It stops early once ctx is done, so a deadline bounds both the
+// SDK call and the writes that follow it.
+func syncResources[T any](ctx context.Context, s *SyncEngine, list func(context.Context) ([]T, error), keyOf func(*T) string) error {
+	// The listing is at least as new as the request
+	ctx = withEventVersion(ctx, time.Now())
+
+	items, err := list(ctx)
 	if err != nil {
 		return err
 	}
 
-	for _, gl := range groupedLights {
-		key := s.keyBuilder.GroupedLight(gl.ID)
-		data, err := json.Marshal(gl)
-		if err != nil {
+	for i := range items {
+		if err := ctx.Err(); err != nil {
 			return err
 		}
-		if err := s.backend.Set(ctx, key, data, 0); err != nil {
+
+		if err := s.storeSynced(ctx, keyOf(&items[i]), &items[i]); err != nil {
 			return err
 		}
 	}
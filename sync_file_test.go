@@ -0,0 +1,52 @@
+package cache_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cache "github.com/rmrfslashbin/hue-cache"
+	"github.com/rmrfslashbin/hue-cache/backends"
+)
+
+func TestSyncEngine_NeedsInitialSyncAfterFileLoad(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.gob")
+	load := func() *backends.File {
+		t.Helper()
+		backend, err := backends.NewFile(&backends.FileConfig{
+			FilePath:     path,
+			LoadOnStart:  true,
+			MemoryConfig: backends.DefaultMemoryConfig(),
+		})
+		if err != nil {
+			t.Fatalf("NewFile() failed: %v", err)
+		}
+		return backend
+	}
+
+	saved := load()
+	saved.Set(ctx, "light:light-1", []byte(`{"id":"light-1"}`), 0)
+	if err := saved.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	saved.Close()
+
+	config := cache.DefaultSyncConfig()
+	config.SyncOnStartMaxAge = 100 * time.Millisecond
+
+	recent := load()
+	if cache.NewSyncEngine(recent, nil, config).NeedsInitialSync() {
+		t.Error("cache loaded right after saving should not be synced")
+	}
+	recent.Close()
+
+	// Loading doesn't make the saved entries new again
+	time.Sleep(150 * time.Millisecond)
+	old := load()
+	defer old.Close()
+	if !cache.NewSyncEngine(old, nil, config).NeedsInitialSync() {
+		t.Error("cache saved longer than SyncOnStartMaxAge ago should be synced")
+	}
+}
@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultSyncQueueSize is the default number of event data elements each
+// sync worker queues.
+const DefaultSyncQueueSize = 256
+
+// QueueFullPolicy selects what the sync engine does with an event data
+// element whose worker's queue is full.
+type QueueFullPolicy int
+
+const (
+	// QueueBlock waits for room in the queue. Nothing is lost, but a
+	// backed-up worker stalls the receive loop, and with it the event
+	// stream, until it catches up.
+	QueueBlock QueueFullPolicy = iota
+
+	// QueueDrop drops the data element and counts it in
+	// SyncStats.DroppedEvents. The receive loop never stalls, but the
+	// cached resource misses the change until its next event or a
+	// full sync.
+	QueueDrop
+)
+
+// String returns the policy's name.
+func (p QueueFullPolicy) String() string {
+	switch p {
+	case QueueBlock:
+		return "block"
+	case QueueDrop:
+		return "drop"
+	default:
+		return "unknown"
+	}
+}
+
+// queuedEvent is an event whose data elements are being applied by the
+// sync workers. Its span ends, and its latency is recorded, when the last
+// of them is done.
+type queuedEvent struct {
+	event   *resources.Event
+	ctx     context.Context
+	start   time.Time
+	pending atomic.Int64
+}
+
+// queuedData is one data element of a queued event.
+type queuedData struct {
+	event *queuedEvent
+	data  *resources.EventData
+}
+
+// newSyncQueues returns a queue of size elements for each of workers
+// workers, or nil if workers is not positive.
+func newSyncQueues(workers, size int) []chan *queuedData {
+	if workers <= 0 {
+		return nil
+	}
+	if size <= 0 {
+		size = DefaultSyncQueueSize
+	}
+
+	queues := make([]chan *queuedData, workers)
+	for i := range queues {
+		queues[i] = make(chan *queuedData, size)
+	}
+	return queues
+}
+
+// startWorkers starts a worker per queue. The returned function closes
+// the queues and waits for the workers to exit.
+func (s *SyncEngine) startWorkers() (stop func()) {
+	var workers sync.WaitGroup
+	for _, queue := range s.queues {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			s.runWorker(queue)
+		}()
+	}
+
+	return func() {
+		for _, queue := range s.queues {
+			close(queue)
+		}
+		workers.Wait()
+	}
+}
+
+// runWorker applies the data elements of queue until it is closed,
+// discarding them once the engine is stopping.
+func (s *SyncEngine) runWorker(queue <-chan *queuedData) {
+	for item := range queue {
+		if s.ctx.Err() == nil {
+			s.applyEventData(item.event.ctx, item.event.event, item.data)
+		}
+		s.finishQueued(item.event)
+	}
+}
+
+// queueEvent queues the data elements of event for the workers, by the
+// hash of their resource ID.
+func (s *SyncEngine) queueEvent(event *resources.Event) {
+	start := time.Now()
+	ctx, _ := s.beginEvent(event)
+	queued := &queuedEvent{event: event, ctx: ctx, start: start}
+
+	// Hold a reference while queueing, so the event can't finish before
+	// all its elements are queued
+	queued.pending.Store(int64(len(event.Data)) + 1)
+	defer s.finishQueued(queued)
+
+	for i := range event.Data {
+		item := &queuedData{event: queued, data: &event.Data[i]}
+		queue := s.queues[queueIndex(item.data.ID, len(s.queues))]
+
+		if s.config.QueueFullPolicy == QueueDrop {
+			select {
+			case queue <- item:
+			default:
+				s.dropQueued(item)
+			}
+			continue
+		}
+
+		select {
+		case queue <- item:
+		case <-s.ctx.Done():
+			s.finishQueued(queued)
+		}
+	}
+}
+
+// dropQueued drops a data element whose worker's queue is full.
+func (s *SyncEngine) dropQueued(item *queuedData) {
+	s.stats.mu.Lock()
+	s.stats.DroppedEvents++
+	s.stats.mu.Unlock()
+
+	s.logger().Warn("sync queue full, dropped event",
+		"event_id", item.event.event.ID,
+		"key", s.keyBuilder.Resource(item.data.Type, item.data.ID))
+	s.finishQueued(item.event)
+}
+
+// finishQueued marks one data element of event done, ending the event
+// once all are.
+func (s *SyncEngine) finishQueued(event *queuedEvent) {
+	if event.pending.Add(-1) > 0 {
+		return
+	}
+	trace.SpanFromContext(event.ctx).End()
+	s.recordLatency(time.Since(event.start))
+}
+
+// queueIndex returns the queue of n that the resource id is applied on.
+func queueIndex(id string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return int(h.Sum32() % uint32(n))
+}
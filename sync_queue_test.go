@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+// gatedBackend blocks Set until release is closed, signalling entered
+// when a Set starts.
+type gatedBackend struct {
+	Backend
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (b *gatedBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	b.entered <- struct{}{}
+	<-b.release
+	return b.Backend.Set(ctx, key, value, ttl)
+}
+
+func updateEvent(id string, n int) resources.Event {
+	return resources.Event{
+		ID:   fmt.Sprintf("event-%s-%d", id, n),
+		Type: resources.EventTypeUpdate,
+		Data: []resources.EventData{{
+			ID:      id,
+			Type:    "light",
+			RawData: json.RawMessage(fmt.Sprintf(`{"id":%q,"n":%d}`, id, n)),
+		}},
+	}
+}
+
+func TestSyncEngine_WorkersPreserveOrder(t *testing.T) {
+	backend := newMockBackend()
+	source := &fakeEventSource{events: make(chan resources.Event)}
+	engine := NewSyncEngine(backend, nil, &SyncConfig{
+		EnableAutoSync: true,
+		EventSource:    source,
+		Workers:        4,
+		QueueSize:      8,
+	})
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer engine.Stop()
+
+	ids := []string{"light-1", "light-2", "light-3", "light-4", "light-5"}
+	for _, id := range ids {
+		backend.Set(context.Background(), "light:"+id, []byte(`{"id":"`+id+`"}`), 0)
+	}
+	const updates = 50
+	for n := 0; n < updates; n++ {
+		for _, id := range ids {
+			source.events <- updateEvent(id, n)
+		}
+	}
+	close(source.events)
+
+	// The loop exits once the workers have drained their queues
+	select {
+	case <-engine.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sync loop did not exit")
+	}
+
+	for _, id := range ids {
+		entry, err := backend.Get(context.Background(), "light:"+id)
+		if err != nil {
+			t.Fatalf("Get(%s) failed: %v", id, err)
+		}
+		var got struct{ N int }
+		if err := json.Unmarshal(entry.Value, &got); err != nil {
+			t.Fatalf("decoding %s: %v", id, err)
+		}
+		if got.N != updates-1 {
+			t.Errorf("%s has update %d, want the last (%d)", id, got.N, updates-1)
+		}
+	}
+
+	stats := engine.Stats()
+	if stats.EventsProcessed != int64(updates*len(ids)) || stats.UpdateEvents != int64(updates*len(ids)) {
+		t.Errorf("EventsProcessed = %d, UpdateEvents = %d; want %d", stats.EventsProcessed, stats.UpdateEvents, updates*len(ids))
+	}
+	if stats.QueueDepth != 0 || stats.DroppedEvents != 0 {
+		t.Errorf("QueueDepth = %d, DroppedEvents = %d; want 0, 0", stats.QueueDepth, stats.DroppedEvents)
+	}
+}
+
+func TestSyncEngine_QueueDrop(t *testing.T) {
+	backend := &gatedBackend{
+		Backend: newMockBackend(),
+		entered: make(chan struct{}, 3),
+		release: make(chan struct{}),
+	}
+	backend.Backend.Set(context.Background(), "light:light-1", []byte(`{"id":"light-1"}`), 0)
+	source := &fakeEventSource{events: make(chan resources.Event)}
+	engine := NewSyncEngine(backend, nil, &SyncConfig{
+		EnableAutoSync:  true,
+		EventSource:     source,
+		Workers:         1,
+		QueueSize:       1,
+		QueueFullPolicy: QueueDrop,
+	})
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer engine.Stop()
+
+	// The worker blocks applying the first event, the second fills the
+	// queue, and the third is dropped
+	source.events <- updateEvent("light-1", 0)
+	<-backend.entered
+	source.events <- updateEvent("light-1", 1)
+	source.events <- updateEvent("light-1", 2)
+
+	deadline := time.Now().Add(time.Second)
+	for engine.Stats().DroppedEvents == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	stats := engine.Stats()
+	if stats.DroppedEvents != 1 || stats.QueueDepth != 1 {
+		t.Errorf("DroppedEvents = %d, QueueDepth = %d; want 1, 1", stats.DroppedEvents, stats.QueueDepth)
+	}
+
+	close(backend.release)
+	close(source.events)
+	select {
+	case <-engine.done:
+	case <-time.After(time.Second):
+		t.Fatal("sync loop did not exit")
+	}
+
+	entry, err := backend.Get(context.Background(), "light:light-1")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if string(entry.Value) != `{"id":"light-1","n":1}` {
+		t.Errorf("cached %s, want the last queued update", entry.Value)
+	}
+}
+
+func TestQueueFullPolicy_String(t *testing.T) {
+	if QueueBlock.String() != "block" || QueueDrop.String() != "drop" || QueueFullPolicy(9).String() != "unknown" {
+		t.Errorf("String() = %q, %q, %q", QueueBlock, QueueDrop, QueueFullPolicy(9))
+	}
+}
@@ -1,8 +1,10 @@
 package cache
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -25,8 +27,9 @@ func newMockBackend() *mockBackend {
 }
 
 func (m *mockBackend) Get(ctx context.Context, key string) (*Entry, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	// Counting hits and misses writes, so take the write lock
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	entry, ok := m.data[key]
 	if !ok {
@@ -45,6 +48,17 @@ func (m *mockBackend) Set(ctx context.Context, key string, value []byte, ttl tim
 	return nil
 }
 
+func (m *mockBackend) SetIf(ctx context.Context, key string, value []byte, ttl time.Duration, cond func(*Entry) bool) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.data[key]; ok && !existing.IsExpired() && !cond(existing) {
+		return false, nil
+	}
+	m.data[key] = NewEntry(key, value, ttl)
+	return true, nil
+}
+
 func (m *mockBackend) Delete(ctx context.Context, key string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -53,6 +67,21 @@ func (m *mockBackend) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
+func (m *mockBackend) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.data[key]
+	if !ok || entry.IsExpired() {
+		return ErrNotFound
+	}
+	touched := NewEntry(key, entry.Value, ttl)
+	touched.CreatedAt = entry.CreatedAt
+	touched.Tags = entry.Tags
+	m.data[key] = touched
+	return nil
+}
+
 func (m *mockBackend) Clear(ctx context.Context) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -101,6 +130,10 @@ func (m *mockBackend) Stats(ctx context.Context) (*Stats, error) {
 	}, nil
 }
 
+func (m *mockBackend) Ping(ctx context.Context) error {
+	return nil
+}
+
 func (m *mockBackend) Close() error {
 	return nil
 }
@@ -164,7 +197,7 @@ func TestSyncEngine_ProcessEventData_Add(t *testing.T) {
 	}
 
 	// Process add event
-	err := engine.processEventData(resources.EventTypeAdd, eventData)
+	err := engine.processEventData(context.Background(), resources.EventTypeAdd, eventData)
 	if err != nil {
 		t.Fatalf("processEventData() failed: %v", err)
 	}
@@ -186,6 +219,71 @@ func TestSyncEngine_ProcessEventData_Add(t *testing.T) {
 	}
 }
 
+func TestSyncEngine_ProcessEventData_AddRoundTrip(t *testing.T) {
+	backend := newMockBackend()
+	defer backend.Close()
+
+	engine := NewSyncEngine(backend, nil, DefaultSyncConfig())
+	raw := json.RawMessage(`{"id":"light-123","type":"light","on":{"on":true},"metadata":{"name":"Test Light"}}`)
+	eventData := &resources.EventData{ID: "light-123", Type: "light", RawData: raw}
+
+	if err := engine.processEventData(context.Background(), resources.EventTypeAdd, eventData); err != nil {
+		t.Fatalf("processEventData() failed: %v", err)
+	}
+
+	entry, err := backend.Get(context.Background(), engine.keyBuilder.Light("light-123"))
+	if err != nil {
+		t.Fatalf("Cache Get() failed: %v", err)
+	}
+	if !bytes.Equal(entry.Value, raw) {
+		t.Errorf("cached value = %s, want %s", entry.Value, raw)
+	}
+
+	mockSDK := newMockLightClient()
+	client := NewCachedLightClient(backend, mockSDK, 0)
+	light, err := client.Get(context.Background(), "light-123")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if light.ID != "light-123" || light.Type != "light" || !light.On.On || light.Metadata.Name != "Test Light" {
+		t.Errorf("light = %+v", light)
+	}
+	if mockSDK.calls["Get"] != 0 {
+		t.Errorf("SDK Get called %d times, want 0", mockSDK.calls["Get"])
+	}
+}
+
+func TestSyncEngine_ProcessEventData_ReservedID(t *testing.T) {
+	backend := newMockBackend()
+	defer backend.Close()
+
+	engine := NewSyncEngine(backend, nil, DefaultSyncConfig())
+	backend.data["light:light-1"] = &Entry{Key: "light:light-1", Value: []byte(`{"id":"light-1"}`)}
+
+	for _, id := range []string{"", "light:1", "*"} {
+		for _, eventType := range []string{resources.EventTypeAdd, resources.EventTypeDelete} {
+			data := &resources.EventData{ID: id, Type: "light", RawData: json.RawMessage(`{"type":"light"}`)}
+			if err := engine.processEventData(context.Background(), eventType, data); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("processEventData(%s, %q) error = %v, want ErrInvalidKey", eventType, id, err)
+			}
+		}
+	}
+	if len(backend.data) != 1 || backend.data["light:light-1"] == nil {
+		t.Errorf("backend keys changed: %v", backend.data)
+	}
+
+	// Unknown event types are skipped by policy, whatever their ID
+	for _, id := range []string{"", "light:1", "*"} {
+		data := &resources.EventData{ID: id, Type: "light"}
+		if err := engine.processEventData(context.Background(), "resync", data); err != nil {
+			t.Errorf("processEventData(resync, %q) error = %v, want skipped", id, err)
+		}
+	}
+	if unknown := engine.Stats().UnknownEvents; unknown != 3 {
+		t.Errorf("UnknownEvents = %d, want 3", unknown)
+	}
+}
+
 func TestSyncEngine_ProcessEventData_Update(t *testing.T) {
 	backend := newMockBackend()
 	defer backend.Close()
@@ -212,7 +310,7 @@ func TestSyncEngine_ProcessEventData_Update(t *testing.T) {
 	}
 
 	// Add initial entry
-	engine.processEventData(resources.EventTypeAdd, eventData)
+	engine.processEventData(context.Background(), resources.EventTypeAdd, eventData)
 
 	// Update with new data
 	updatedData := map[string]interface{}{
@@ -229,7 +327,7 @@ func TestSyncEngine_ProcessEventData_Update(t *testing.T) {
 	}
 
 	// Process update event
-	err := engine.processEventData(resources.EventTypeUpdate, updatedEventData)
+	err := engine.processEventData(context.Background(), resources.EventTypeUpdate, updatedEventData)
 	if err != nil {
 		t.Fatalf("processEventData() update failed: %v", err)
 	}
@@ -255,6 +353,59 @@ func TestSyncEngine_ProcessEventData_Update(t *testing.T) {
 	}
 }
 
+func TestSyncEngine_ProcessEventData_UpdateMergesPartial(t *testing.T) {
+	backend := newMockBackend()
+	engine := NewSyncEngine(backend, nil, DefaultSyncConfig())
+	ctx := context.Background()
+	key := engine.keyBuilder.Light("light-1")
+
+	backend.Set(ctx, key, []byte(`{"id":"light-1","metadata":{"name":"Desk"},"on":{"on":false}}`), 0)
+
+	// Update events carry only the changed fields
+	update := &resources.EventData{ID: "light-1", Type: "light", RawData: json.RawMessage(`{"id":"light-1","on":{"on":true}}`)}
+	if err := engine.processEventData(ctx, resources.EventTypeUpdate, update); err != nil {
+		t.Fatalf("processEventData() update failed: %v", err)
+	}
+
+	entry, err := backend.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Cache Get() failed: %v", err)
+	}
+	var light resources.Light
+	if err := json.Unmarshal(entry.Value, &light); err != nil {
+		t.Fatalf("decoding cached light: %v", err)
+	}
+	if !light.On.On || light.Metadata.Name != "Desk" {
+		t.Errorf("cached light = %+v, want on with its name kept", light)
+	}
+}
+
+func TestSyncEngine_ProcessEventData_UpdateUncached(t *testing.T) {
+	backend := newMockBackend()
+	engine := NewSyncEngine(backend, nil, DefaultSyncConfig())
+	ctx := context.Background()
+	update := json.RawMessage(`{"on":{"on":true}}`)
+
+	// Nothing to merge into: the partial update isn't cached as the resource
+	data := &resources.EventData{ID: "light-1", Type: "light", RawData: update}
+	if err := engine.processEventData(ctx, resources.EventTypeUpdate, data); err != nil {
+		t.Fatalf("processEventData() update failed: %v", err)
+	}
+	if _, err := backend.Get(ctx, "light:light-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+
+	// A negative entry is removed, so the next read fetches the resource
+	backend.Set(ctx, "light:light-2", tombstoneValue, time.Minute)
+	data = &resources.EventData{ID: "light-2", Type: "light", RawData: update}
+	if err := engine.processEventData(ctx, resources.EventTypeUpdate, data); err != nil {
+		t.Fatalf("processEventData() update failed: %v", err)
+	}
+	if _, err := backend.Get(ctx, "light:light-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+}
+
 func TestSyncEngine_ProcessEventData_Delete(t *testing.T) {
 	backend := newMockBackend()
 	defer backend.Close()
@@ -279,10 +430,10 @@ func TestSyncEngine_ProcessEventData_Delete(t *testing.T) {
 		RawData: json.RawMessage(rawData),
 	}
 
-	engine.processEventData(resources.EventTypeAdd, eventData)
+	engine.processEventData(context.Background(), resources.EventTypeAdd, eventData)
 
 	// Process delete event
-	err := engine.processEventData(resources.EventTypeDelete, eventData)
+	err := engine.processEventData(context.Background(), resources.EventTypeDelete, eventData)
 	if err != nil {
 		t.Fatalf("processEventData() delete failed: %v", err)
 	}
@@ -359,6 +510,10 @@ func TestSyncEngine_ProcessEvent(t *testing.T) {
 	if engine.stats.AvgLatency == 0 {
 		t.Error("AvgLatency was not calculated")
 	}
+
+	if engine.Stats().P99Latency == 0 {
+		t.Error("P99Latency was not calculated")
+	}
 }
 
 func TestSyncEngine_HandleError(t *testing.T) {
@@ -536,3 +691,305 @@ func TestDefaultSyncConfig(t *testing.T) {
 		t.Error("Default EventHandler should be nil")
 	}
 }
+
+func TestSyncEngine_SoftDelete(t *testing.T) {
+	backend := newMockBackend()
+	defer backend.Close()
+
+	grace := 50 * time.Millisecond
+	engine := &SyncEngine{
+		backend:    backend,
+		keyBuilder: NewKeyBuilder(),
+		stats:      &SyncStats{},
+		config:     &SyncConfig{SoftDeleteGrace: grace},
+	}
+
+	ctx := context.Background()
+	data := resources.EventData{
+		ID:      "light-1",
+		Type:    "light",
+		RawData: json.RawMessage(`{"id":"light-1","type":"light"}`),
+	}
+	event := func(eventType string) *resources.Event {
+		return &resources.Event{Type: eventType, Data: []resources.EventData{data}}
+	}
+
+	engine.processEvent(event(resources.EventTypeAdd))
+	backend.data["light:light-1"].Tags = []string{"floor-2"}
+
+	// Delete then quickly re-add: the resource stays readable throughout
+	engine.processEvent(event(resources.EventTypeDelete))
+	if backend.hits != 0 {
+		t.Errorf("soft delete made %d hits, want 0", backend.hits)
+	}
+
+	entry, err := backend.Get(ctx, "light:light-1")
+	if err != nil {
+		t.Fatalf("soft-deleted entry should remain readable: %v", err)
+	}
+	if entry.TTL != grace {
+		t.Errorf("soft-deleted entry TTL = %v, want grace period %v", entry.TTL, grace)
+	}
+	if !entry.HasTag("floor-2") {
+		t.Errorf("soft-deleted entry tags = %v, want floor-2 kept", entry.Tags)
+	}
+
+	engine.processEvent(event(resources.EventTypeAdd))
+
+	entry, err = backend.Get(ctx, "light:light-1")
+	if err != nil {
+		t.Fatalf("re-added entry missing: %v", err)
+	}
+	if entry.TTL != 0 {
+		t.Errorf("re-added entry TTL = %v, want 0 (restored)", entry.TTL)
+	}
+	if backend.misses != 0 {
+		t.Errorf("misses = %d, want 0 (no miss window)", backend.misses)
+	}
+}
+
+func TestSyncEngine_SoftDeleteExpires(t *testing.T) {
+	backend := newMockBackend()
+	defer backend.Close()
+
+	grace := 10 * time.Millisecond
+	engine := &SyncEngine{
+		backend:    backend,
+		keyBuilder: NewKeyBuilder(),
+		stats:      &SyncStats{},
+		config:     &SyncConfig{SoftDeleteGrace: grace, CacheRaw: true},
+	}
+
+	ctx := context.Background()
+	engine.processEvent(&resources.Event{
+		Type: resources.EventTypeAdd,
+		Data: []resources.EventData{{ID: "light-1", Type: "light", RawData: json.RawMessage(`{"id":"light-1"}`)}},
+	})
+	engine.processEvent(&resources.Event{
+		Type: resources.EventTypeDelete,
+		Data: []resources.EventData{{ID: "light-1", Type: "light"}},
+	})
+
+	time.Sleep(2 * grace)
+
+	// The backend treats both entries as expired once the grace period ends
+	for _, key := range []string{"light:light-1", "raw:light:light-1"} {
+		entry, err := backend.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("%s missing: %v", key, err)
+		}
+		if !entry.IsExpired() {
+			t.Errorf("%s should expire after the grace period", key)
+		}
+	}
+
+	// Deleting a resource that isn't cached is not an error
+	if err := engine.softDelete(ctx, "light:unknown"); err != nil {
+		t.Errorf("softDelete() of missing key failed: %v", err)
+	}
+}
+
+// fakeEventSource is an EventSource that returns a fixed error or
+// channel from Subscribe.
+type fakeEventSource struct {
+	events chan resources.Event
+	err    error
+}
+
+func (f *fakeEventSource) Subscribe(ctx context.Context) (<-chan resources.Event, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.events, nil
+}
+
+// runSyncLoop starts an engine on source and returns the errors reported
+// to its ErrorHandler once the loop exits.
+func runSyncLoop(t *testing.T, source EventSource, feed func()) []error {
+	t.Helper()
+
+	var mu sync.Mutex
+	var errs []error
+	config := &SyncConfig{
+		EnableAutoSync: true,
+		EventSource:    source,
+		ErrorHandler: func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	}
+
+	engine := NewSyncEngine(newMockBackend(), nil, config)
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer engine.Stop()
+	if feed != nil {
+		feed()
+	}
+
+	select {
+	case <-engine.done:
+	case <-time.After(time.Second):
+		t.Fatal("sync loop did not exit")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return errs
+}
+
+func TestSyncEngine_SubscribeError(t *testing.T) {
+	cause := errors.New("connection refused")
+	errs := runSyncLoop(t, &fakeEventSource{err: cause}, nil)
+
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, want 1: %v", len(errs), errs)
+	}
+	var subErr *SubscribeError
+	if !errors.As(errs[0], &subErr) {
+		t.Fatalf("error = %v, want *SubscribeError", errs[0])
+	}
+	if !errors.Is(errs[0], cause) {
+		t.Errorf("SubscribeError should unwrap to the source error")
+	}
+}
+
+func TestSyncEngine_StreamClosedError(t *testing.T) {
+	source := &fakeEventSource{events: make(chan resources.Event)}
+	errs := runSyncLoop(t, source, func() {
+		source.events <- resources.Event{
+			Type: resources.EventTypeAdd,
+			Data: []resources.EventData{{ID: "light-1", Type: "light", RawData: json.RawMessage(`{"id":"light-1"}`)}},
+		}
+		close(source.events)
+	})
+
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, want 1: %v", len(errs), errs)
+	}
+	var closedErr *StreamClosedError
+	if !errors.As(errs[0], &closedErr) {
+		t.Fatalf("error = %v, want *StreamClosedError", errs[0])
+	}
+	if closedErr.Events != 1 {
+		t.Errorf("Events = %d, want 1", closedErr.Events)
+	}
+}
+
+func TestSyncEngine_StopIsNotStreamClosed(t *testing.T) {
+	var mu sync.Mutex
+	var errs []error
+	source := &fakeEventSource{events: make(chan resources.Event)}
+	engine := NewSyncEngine(newMockBackend(), nil, &SyncConfig{
+		EnableAutoSync: true,
+		EventSource:    source,
+		ErrorHandler: func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	})
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	if err := engine.Stop(); err != nil {
+		t.Fatalf("Stop() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 0 {
+		t.Errorf("Stop() reported errors: %v", errs)
+	}
+}
+
+func TestSyncEngine_ProcessError(t *testing.T) {
+	source := &fakeEventSource{events: make(chan resources.Event)}
+	errs := runSyncLoop(t, source, func() {
+		source.events <- resources.Event{
+			ID:   "event-1",
+			Type: resources.EventTypeAdd,
+			Data: []resources.EventData{{ID: "light-1", Type: "light", RawData: json.RawMessage("{")}},
+		}
+		close(source.events)
+	})
+
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, want 2: %v", len(errs), errs)
+	}
+	var procErr *ProcessError
+	if !errors.As(errs[0], &procErr) {
+		t.Fatalf("error = %v, want *ProcessError", errs[0])
+	}
+	if procErr.EventID != "event-1" || procErr.EventType != resources.EventTypeAdd || procErr.Key != "light:light-1" {
+		t.Errorf("ProcessError = %+v", procErr)
+	}
+	var closedErr *StreamClosedError
+	if !errors.As(errs[1], &closedErr) {
+		t.Errorf("second error = %v, want *StreamClosedError", errs[1])
+	}
+}
+
+func TestSyncEngine_UnknownEventType(t *testing.T) {
+	event := func() resources.Event {
+		return resources.Event{
+			ID:   "event-1",
+			Type: "firmware_novelty",
+			Data: []resources.EventData{{ID: "light-1", Type: "light", RawData: json.RawMessage(`{"id": "light-1"}`)}},
+		}
+	}
+
+	for _, policy := range []UnknownEventPolicy{UnknownEventLog, UnknownEventIgnore, UnknownEventError} {
+		t.Run(policy.String(), func(t *testing.T) {
+			backend := newMockBackend()
+			var errs []error
+			config := DefaultSyncConfig()
+			config.UnknownEvents = policy
+			config.ErrorHandler = func(err error) { errs = append(errs, err) }
+			engine := NewSyncEngine(backend, nil, config)
+
+			e := event()
+			engine.processEvent(&e)
+			e = event()
+			engine.processEvent(&e)
+
+			stats := engine.Stats()
+			if policy == UnknownEventError {
+				if stats.SyncErrors != 2 || len(errs) != 2 || stats.UnknownEvents != 0 {
+					t.Errorf("SyncErrors = %d, errors = %v, UnknownEvents = %d; want 2 errors", stats.SyncErrors, errs, stats.UnknownEvents)
+				}
+				return
+			}
+			if stats.SyncErrors != 0 || len(errs) != 0 {
+				t.Errorf("SyncErrors = %d, errors = %v; want none", stats.SyncErrors, errs)
+			}
+			if stats.UnknownEvents != 2 {
+				t.Errorf("UnknownEvents = %d, want 2", stats.UnknownEvents)
+			}
+			if keys, _ := backend.Keys(context.Background(), "*"); len(keys) != 0 {
+				t.Errorf("cached %v for an unknown event type, want nothing", keys)
+			}
+		})
+	}
+}
+
+func TestSyncEngine_UnknownResourceType(t *testing.T) {
+	backend := newMockBackend()
+	engine := NewSyncEngine(backend, nil, DefaultSyncConfig())
+
+	// A resource type the cache has no client for is cached under its
+	// own prefix
+	err := engine.processEventData(context.Background(), resources.EventTypeAdd, &resources.EventData{
+		ID:      "plug-1",
+		Type:    "smart_plug",
+		RawData: json.RawMessage(`{"id": "plug-1", "type": "smart_plug"}`),
+	})
+	if err != nil {
+		t.Fatalf("processEventData() failed: %v", err)
+	}
+	if _, err := backend.Get(context.Background(), NewKeyBuilder().Resource("smart_plug", "plug-1")); err != nil {
+		t.Errorf("Get(smart_plug:plug-1) failed: %v", err)
+	}
+}
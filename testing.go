@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -34,7 +35,11 @@ func RunBackendTests(t *testing.T, suite BackendTestSuite) {
 	t.Run("Clear", func(t *testing.T) { testBackendClear(t, suite) })
 	t.Run("Keys", func(t *testing.T) { testBackendKeys(t, suite) })
 	t.Run("Stats", func(t *testing.T) { testBackendStats(t, suite) })
+	t.Run("Ping", func(t *testing.T) { testBackendPing(t, suite) })
 	t.Run("TTL", func(t *testing.T) { testBackendTTL(t, suite) })
+	t.Run("Touch", func(t *testing.T) { testBackendTouch(t, suite) })
+	t.Run("SetIf", func(t *testing.T) { testBackendSetIf(t, suite) })
+	t.Run("Entries", func(t *testing.T) { testBackendEntries(t, suite) })
 	t.Run("Concurrency", func(t *testing.T) { testBackendConcurrency(t, suite) })
 }
 
@@ -263,6 +268,43 @@ func testBackendStats(t *testing.T, suite BackendTestSuite) {
 	_ = stats.Entries
 }
 
+func testBackendPing(t *testing.T, suite BackendTestSuite) {
+	backend := suite.NewBackend(t)
+	defer backend.Close()
+
+	pinger, ok := backend.(Pinger)
+	if !ok {
+		t.Skip("backend doesn't implement Pinger")
+	}
+
+	ctx := context.Background()
+	if err := backend.Set(ctx, "test:1", []byte("value"), 0); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	before, err := backend.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() failed: %v", err)
+	}
+
+	if err := pinger.Ping(ctx); err != nil {
+		t.Errorf("Ping() failed: %v", err)
+	}
+
+	// Ping must not touch statistics
+	after, err := backend.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() failed: %v", err)
+	}
+	if after.Hits != before.Hits || after.Misses != before.Misses || after.Entries != before.Entries {
+		t.Errorf("Stats() after Ping() = %+v, want %+v", after, before)
+	}
+
+	backend.Close()
+	if err := pinger.Ping(ctx); !errors.Is(err, ErrBackendClosed) {
+		t.Errorf("Ping() after Close() error = %v, want ErrBackendClosed", err)
+	}
+}
+
 func testBackendTTL(t *testing.T, suite BackendTestSuite) {
 	backend := suite.NewBackend(t)
 	defer backend.Close()
@@ -291,6 +333,146 @@ func testBackendTTL(t *testing.T, suite BackendTestSuite) {
 	}
 }
 
+func testBackendSetIf(t *testing.T, suite BackendTestSuite) {
+	backend := suite.NewBackend(t)
+	defer backend.Close()
+
+	ctx := context.Background()
+	never := func(*Entry) bool {
+		t.Error("cond called for an absent or expired key")
+		return false
+	}
+
+	// Exercises the backend's ConditionalSetter, or the Peek and Set
+	// fallback
+
+	// Absent keys are written without consulting cond
+	applied, err := SetIf(ctx, backend, "test:setif", []byte("v1"), time.Hour, never)
+	if err != nil || !applied {
+		t.Fatalf("SetIf() of absent key = %v, %v; want true, nil", applied, err)
+	}
+
+	// A rejecting cond leaves the entry alone
+	var seen string
+	applied, err = SetIf(ctx, backend, "test:setif", []byte("v2"), time.Hour, func(existing *Entry) bool {
+		seen = string(existing.Value)
+		return false
+	})
+	if err != nil || applied {
+		t.Errorf("SetIf() with false cond = %v, %v; want false, nil", applied, err)
+	}
+	if seen != "v1" {
+		t.Errorf("cond saw value %q, want v1", seen)
+	}
+
+	// An approving cond replaces it
+	applied, err = SetIf(ctx, backend, "test:setif", []byte("v3"), time.Hour, func(*Entry) bool { return true })
+	if err != nil || !applied {
+		t.Errorf("SetIf() with true cond = %v, %v; want true, nil", applied, err)
+	}
+
+	entry, err := backend.Get(ctx, "test:setif")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if string(entry.Value) != "v3" {
+		t.Errorf("After SetIf(), value = %q, want v3", entry.Value)
+	}
+
+	// Expired entries count as absent
+	if err := backend.Set(ctx, "test:setif-expired", []byte("old"), 10*time.Millisecond); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	applied, err = SetIf(ctx, backend, "test:setif-expired", []byte("new"), time.Hour, never)
+	if err != nil || !applied {
+		t.Errorf("SetIf() of expired key = %v, %v; want true, nil", applied, err)
+	}
+}
+
+func testBackendEntries(t *testing.T, suite BackendTestSuite) {
+	backend := suite.NewBackend(t)
+	defer backend.Close()
+
+	ctx := context.Background()
+	backend.Set(ctx, "light:1", []byte("one"), time.Hour)
+	backend.Set(ctx, "light:2", []byte("two!"), 0)
+	backend.Set(ctx, "room:1", []byte("room"), 0)
+	backend.Set(ctx, "light:expired", []byte("old"), 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	// Exercises the backend's EntryLister, or the Keys fallback
+	infos, err := ListEntries(ctx, backend, "light:*")
+	if err != nil {
+		t.Fatalf("ListEntries() failed: %v", err)
+	}
+
+	byKey := make(map[string]*EntryInfo)
+	for _, info := range infos {
+		byKey[info.Key] = info
+	}
+	if len(byKey) != 2 || byKey["light:1"] == nil || byKey["light:2"] == nil {
+		t.Fatalf("ListEntries() keys = %v, want light:1 and light:2", byKey)
+	}
+	if byKey["light:1"].TTL != time.Hour || byKey["light:1"].Size != 3 {
+		t.Errorf("light:1 info = %+v, want TTL 1h and size 3", byKey["light:1"])
+	}
+	if byKey["light:2"].Size != 4 || !byKey["light:2"].ExpiresAt.IsZero() {
+		t.Errorf("light:2 info = %+v, want size 4 and no expiry", byKey["light:2"])
+	}
+}
+
+func testBackendTouch(t *testing.T, suite BackendTestSuite) {
+	backend := suite.NewBackend(t)
+	defer backend.Close()
+
+	ctx := context.Background()
+
+	// Exercises the backend's Toucher, or the Peek and Set fallback
+
+	// Touch non-existent key
+	if err := Touch(ctx, backend, "nonexistent", time.Hour); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Touch() of non-existent key error = %v, want ErrNotFound", err)
+	}
+
+	// Extend a short TTL past its original expiry
+	err := backend.Set(ctx, "test:touch", []byte("value"), 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+
+	if err := Touch(ctx, backend, "test:touch", time.Hour); err != nil {
+		t.Fatalf("Touch() failed: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	entry, err := backend.Get(ctx, "test:touch")
+	if err != nil {
+		t.Fatalf("Get() failed after Touch(): %v", err)
+	}
+
+	if string(entry.Value) != "value" {
+		t.Errorf("After Touch(), value = %v, want value", entry.Value)
+	}
+	if entry.TTL != time.Hour {
+		t.Errorf("After Touch(), TTL = %v, want 1h", entry.TTL)
+	}
+
+	// Expired entries can't be touched back to life
+	err = backend.Set(ctx, "test:expired", []byte("value"), 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err := Touch(ctx, backend, "test:expired", time.Hour); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Touch() of expired key error = %v, want ErrNotFound", err)
+	}
+}
+
 func testBackendConcurrency(t *testing.T, suite BackendTestSuite) {
 	backend := suite.NewBackend(t)
 	defer backend.Close()
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultBackendTimeout is the default per-operation backend timeout for
+// cached clients and the sync engine.
+const DefaultBackendTimeout = 5 * time.Second
+
+// timeoutBackend bounds each backend operation whose context has no
+// deadline, so a wedged backend can't block callers indefinitely. The
+// wrapped backend must honor context cancellation for this to take effect.
+type timeoutBackend struct {
+	Backend
+	timeout time.Duration
+}
+
+// withBackendTimeout wraps backend so operations without a deadline time
+// out after timeout. A timeout <= 0 returns backend unchanged.
+func withBackendTimeout(backend Backend, timeout time.Duration) Backend {
+	if timeout <= 0 {
+		return backend
+	}
+	if tb, ok := backend.(*timeoutBackend); ok {
+		backend = tb.Backend
+	}
+	return &timeoutBackend{Backend: backend, timeout: timeout}
+}
+
+// withTimeout applies the timeout if ctx has no deadline of its own.
+func (b *timeoutBackend) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, b.timeout)
+}
+
+// Get retrieves a value, bounded by the timeout.
+func (b *timeoutBackend) Get(ctx context.Context, key string) (*Entry, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return b.Backend.Get(ctx, key)
+}
+
+// Set stores a value, bounded by the timeout.
+func (b *timeoutBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return b.Backend.Set(ctx, key, value, ttl)
+}
+
+// SetWithTags stores a tagged value, bounded by the timeout. Wrapping
+// must not hide a backend's Tagger implementation.
+func (b *timeoutBackend) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return SetWithTags(ctx, b.Backend, key, value, ttl, tags)
+}
+
+// SetIf conditionally stores a value, bounded by the timeout. Wrapping
+// must not hide a backend's ConditionalSetter implementation.
+func (b *timeoutBackend) SetIf(ctx context.Context, key string, value []byte, ttl time.Duration, cond func(*Entry) bool) (bool, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return SetIf(ctx, b.Backend, key, value, ttl, cond)
+}
+
+// Delete removes a key, bounded by the timeout.
+func (b *timeoutBackend) Delete(ctx context.Context, key string) error {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return b.Backend.Delete(ctx, key)
+}
+
+// Touch extends an entry's TTL, bounded by the timeout. Wrapping must not
+// hide a backend's Toucher implementation.
+func (b *timeoutBackend) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return Touch(ctx, b.Backend, key, ttl)
+}
+
+// Clear removes all entries, bounded by the timeout.
+func (b *timeoutBackend) Clear(ctx context.Context) error {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return b.Backend.Clear(ctx)
+}
+
+// Keys lists matching keys, bounded by the timeout.
+func (b *timeoutBackend) Keys(ctx context.Context, pattern string) ([]string, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return b.Backend.Keys(ctx, pattern)
+}
+
+// GetMeta reads entry metadata, bounded by the timeout. Wrapping must not
+// hide a backend's MetaGetter implementation.
+func (b *timeoutBackend) GetMeta(ctx context.Context, key string) (*EntryMeta, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return GetMeta(ctx, b.Backend, key)
+}
+
+// Peek reads an entry without side effects, bounded by the timeout.
+// Wrapping must not hide a backend's Peeker implementation.
+func (b *timeoutBackend) Peek(ctx context.Context, key string) (*Entry, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return Peek(ctx, b.Backend, key)
+}
+
+// Entries lists entry metadata, bounded by the timeout. Wrapping must not
+// hide a backend's EntryLister implementation.
+func (b *timeoutBackend) Entries(ctx context.Context, pattern string) ([]*EntryInfo, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return ListEntries(ctx, b.Backend, pattern)
+}
+
+// DeletePattern deletes matching keys, bounded by the timeout. Wrapping
+// must not hide a backend's PatternDeleter implementation.
+func (b *timeoutBackend) DeletePattern(ctx context.Context, pattern string) (int, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return DeletePattern(ctx, b.Backend, pattern)
+}
+
+// DeleteMany deletes keys, bounded by the timeout. Wrapping must not hide
+// a backend's BatchDeleter implementation.
+func (b *timeoutBackend) DeleteMany(ctx context.Context, keys []string) (int, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return DeleteMany(ctx, b.Backend, keys)
+}
+
+// DeleteTag deletes tagged entries, bounded by the timeout. Wrapping must
+// not hide a backend's TagDeleter implementation.
+func (b *timeoutBackend) DeleteTag(ctx context.Context, tag string) (int, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return DeleteTag(ctx, b.Backend, tag)
+}
+
+// ResetStats resets statistics, bounded by the timeout. Wrapping must
+// not hide a backend's StatsResetter implementation.
+func (b *timeoutBackend) ResetStats(ctx context.Context) error {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return ResetStats(ctx, b.Backend)
+}
+
+// DetailedStats summarizes entries, bounded by the timeout. Wrapping must
+// not hide a backend's DetailedStatsProvider implementation.
+func (b *timeoutBackend) DetailedStats(ctx context.Context) (*DetailedStats, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return GetDetailedStats(ctx, b.Backend)
+}
+
+// Ping checks the backend, bounded by the timeout. Wrapping must not
+// hide a backend's Pinger implementation.
+func (b *timeoutBackend) Ping(ctx context.Context) error {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return Ping(ctx, b.Backend)
+}
+
+// Stats returns statistics, bounded by the timeout.
+func (b *timeoutBackend) Stats(ctx context.Context) (*Stats, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+	return b.Backend.Stats(ctx)
+}
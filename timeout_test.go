@@ -0,0 +1,246 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+// blockingBackend is a backend whose reads and writes hang until the
+// context is done, like a wedged remote cache.
+type blockingBackend struct {
+	*mockBackend
+}
+
+func (b *blockingBackend) Get(ctx context.Context, key string) (*Entry, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (b *blockingBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b *blockingBackend) Delete(ctx context.Context, key string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestSyncEngine_BackendTimeout(t *testing.T) {
+	config := DefaultSyncConfig()
+	config.BackendTimeout = 20 * time.Millisecond
+
+	errs := make(chan error, 1)
+	config.ErrorHandler = func(err error) { errs <- err }
+
+	engine := NewSyncEngine(&blockingBackend{newMockBackend()}, nil, config)
+
+	raw, _ := json.Marshal(map[string]interface{}{"id": "light-1", "type": "light"})
+	event := &resources.Event{
+		ID:   "event-1",
+		Type: resources.EventTypeUpdate,
+		Data: []resources.EventData{{ID: "light-1", Type: "light", RawData: raw}},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		engine.processEvent(event)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("processEvent() hung on a blocking backend")
+	}
+
+	if stats := engine.Stats(); stats.SyncErrors != 1 {
+		t.Errorf("SyncErrors = %d, want 1", stats.SyncErrors)
+	}
+	if err := <-errs; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Sync error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestCachedLightClient_BackendTimeout(t *testing.T) {
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	client := NewCachedLightClient(&blockingBackend{newMockBackend()}, mockSDK, 0)
+	client.configure(&CachedClientConfig{BackendTimeout: 20 * time.Millisecond})
+
+	done := make(chan struct{})
+	var light *resources.Light
+	var err error
+	go func() {
+		light, err = client.Get(context.Background(), "light-1")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Get() hung on a blocking backend")
+	}
+
+	// The cache is unusable, but the SDK still answers
+	if err != nil || light.ID != "light-1" {
+		t.Errorf("Get() = %v, %v; want light-1 from the SDK", light, err)
+	}
+}
+
+func TestWithBackendTimeout_CallerDeadlineWins(t *testing.T) {
+	backend := withBackendTimeout(&blockingBackend{newMockBackend()}, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := backend.Get(ctx, "light:1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get() error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Get() took %v, caller deadline should apply", elapsed)
+	}
+}
+
+func TestWithBackendTimeout_Disabled(t *testing.T) {
+	backend := newMockBackend()
+	if got := withBackendTimeout(backend, 0); got != Backend(backend) {
+		t.Error("withBackendTimeout(0) should return the backend unchanged")
+	}
+}
+
+// blockingLightClient is a light client whose List hangs until the
+// context is done, like a hung bridge connection.
+type blockingLightClient struct {
+	*mockLightClient
+}
+
+func (c *blockingLightClient) List(ctx context.Context) ([]resources.Light, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestSyncEngine_SyncTimeout(t *testing.T) {
+	backend := newMockBackend()
+	defer backend.Close()
+
+	config := DefaultSyncConfig()
+	config.SyncTimeout = 20 * time.Millisecond
+	engine := NewSyncEngine(backend, nil, config)
+
+	client := &blockingLightClient{newMockLightClient()}
+	keyOf := func(light *resources.Light) string { return engine.keyBuilder.Light(light.ID) }
+
+	start := time.Now()
+	err := engine.syncWithTimeout(func(ctx context.Context) error {
+		return syncResources(ctx, engine, client.List, keyOf)
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("syncWithTimeout() error = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sync took %v, timeout did not fire", elapsed)
+	}
+}
+
+func TestSyncEngine_SyncTimeoutStop(t *testing.T) {
+	backend := newMockBackend()
+	defer backend.Close()
+
+	config := DefaultSyncConfig()
+	config.SyncTimeout = 0 // Disabled - only Stop can end the sync
+	engine := NewSyncEngine(backend, nil, config)
+
+	client := &blockingLightClient{newMockLightClient()}
+	keyOf := func(light *resources.Light) string { return engine.keyBuilder.Light(light.ID) }
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- engine.syncWithTimeout(func(ctx context.Context) error {
+			return syncResources(ctx, engine, client.List, keyOf)
+		})
+	}()
+
+	engine.cancel()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("syncWithTimeout() error = %v, want Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("sync did not stop when the engine was cancelled")
+	}
+}
+
+func TestSyncEngine_NeedsInitialSync(t *testing.T) {
+	backend := newMockBackend()
+	defer backend.Close()
+
+	config := DefaultSyncConfig()
+	config.SyncOnStartMaxAge = time.Hour
+	engine := NewSyncEngine(backend, nil, config)
+
+	if !engine.needsInitialSync() {
+		t.Error("empty cache should be synced")
+	}
+
+	backend.Set(context.Background(), "light:light-1", []byte(`{}`), 0)
+	if engine.needsInitialSync() {
+		t.Error("recent cache should not be synced")
+	}
+
+	backend.data["light:light-1"].CreatedAt = time.Now().Add(-2 * time.Hour)
+	if !engine.needsInitialSync() {
+		t.Error("cache older than SyncOnStartMaxAge should be synced")
+	}
+
+	// Entries outside the engine's key prefix don't count
+	prefixed := NewSyncEngine(backend, nil, &SyncConfig{SyncOnStartMaxAge: time.Hour, KeyPrefix: "home"})
+	if !prefixed.needsInitialSync() {
+		t.Error("cache without entries under the key prefix should be synced")
+	}
+	backend.Set(context.Background(), prefixed.keyBuilder.Light("light-1"), []byte(`{}`), 0)
+	if prefixed.needsInitialSync() {
+		t.Error("recent cache under the key prefix should not be synced")
+	}
+
+	engine.config.SyncOnStartMaxAge = 0
+	backend.data["light:light-1"].CreatedAt = time.Now()
+	if !engine.needsInitialSync() {
+		t.Error("SyncOnStartMaxAge 0 should always sync")
+	}
+}
+
+func TestSyncResources(t *testing.T) {
+	backend := newMockBackend()
+	defer backend.Close()
+
+	engine := NewSyncEngine(backend, nil)
+
+	client := newMockLightClient()
+	client.lights["light-1"] = &resources.Light{ID: "light-1"}
+	client.lights["light-2"] = &resources.Light{ID: "light-2"}
+	keyOf := func(light *resources.Light) string { return engine.keyBuilder.Light(light.ID) }
+
+	if err := syncResources(context.Background(), engine, client.List, keyOf); err != nil {
+		t.Fatalf("syncResources() failed: %v", err)
+	}
+
+	for _, key := range []string{"light:light-1", "light:light-2"} {
+		entry, err := backend.Get(context.Background(), key)
+		if err != nil {
+			t.Fatalf("%s not cached: %v", key, err)
+		}
+		var light resources.Light
+		if err := json.Unmarshal(entry.Value, &light); err != nil || "light:"+light.ID != key {
+			t.Errorf("%s cached as %s (err %v)", key, entry.Value, err)
+		}
+	}
+}
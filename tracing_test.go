@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanAttr returns the value of a span attribute, or an invalid value if unset.
+func spanAttr(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestCachedLightClient_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	client := NewCachedLightClient(backend, mockSDK, 5*time.Minute)
+	client.configure(&CachedClientConfig{TracerProvider: provider})
+
+	ctx := context.Background()
+
+	// Miss, then hit
+	if _, err := client.Get(ctx, "light-1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if _, err := client.Get(ctx, "light-1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if err := client.Update(ctx, "light-1", resources.LightUpdate{}); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+
+	tests := []struct {
+		name      string
+		hit       bool
+		sdkCalled bool
+	}{
+		{name: "hue-cache.Lights.Get", hit: false, sdkCalled: true},
+		{name: "hue-cache.Lights.Get", hit: true, sdkCalled: false},
+	}
+
+	for i, tt := range tests {
+		span := spans[i]
+		if span.Name() != tt.name {
+			t.Errorf("span[%d] name = %q, want %q", i, span.Name(), tt.name)
+		}
+		if got := spanAttr(span, "cache.key").AsString(); got != "light:light-1" {
+			t.Errorf("span[%d] cache.key = %q, want light:light-1", i, got)
+		}
+		if got := spanAttr(span, "cache.hit").AsBool(); got != tt.hit {
+			t.Errorf("span[%d] cache.hit = %v, want %v", i, got, tt.hit)
+		}
+		if got := spanAttr(span, "cache.sdk_called").AsBool(); got != tt.sdkCalled {
+			t.Errorf("span[%d] cache.sdk_called = %v, want %v", i, got, tt.sdkCalled)
+		}
+	}
+
+	if spans[2].Name() != "hue-cache.Lights.Update" {
+		t.Errorf("span[2] name = %q, want hue-cache.Lights.Update", spans[2].Name())
+	}
+}
+
+func TestCachedLightClient_TracingDisabledByDefault(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	client := NewCachedLightClient(backend, mockSDK, 0)
+
+	// Should work with the no-op tracer
+	if _, err := client.Get(context.Background(), "light-1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+}
+
+func TestSyncEngine_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	engine := &SyncEngine{
+		backend:    newMockBackend(),
+		keyBuilder: NewKeyBuilder(),
+		stats:      &SyncStats{},
+		config:     &SyncConfig{TracerProvider: provider},
+	}
+
+	rawData, _ := json.Marshal(map[string]interface{}{"id": "light-123", "type": "light"})
+	engine.processEvent(&resources.Event{
+		Type: resources.EventTypeAdd,
+		ID:   "event-123",
+		Data: []resources.EventData{
+			{ID: "light-123", Type: "light", RawData: json.RawMessage(rawData)},
+		},
+	})
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	child, parent := spans[0], spans[1]
+	if parent.Name() != "hue-cache.Sync.processEvent" {
+		t.Errorf("parent span name = %q", parent.Name())
+	}
+	if child.Name() != "hue-cache.Sync.processEventData" {
+		t.Errorf("child span name = %q", child.Name())
+	}
+	if child.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("processEventData span should be a child of processEvent span")
+	}
+	if got := spanAttr(child, "cache.key").AsString(); got != "light:light-123" {
+		t.Errorf("child cache.key = %q, want light:light-123", got)
+	}
+}
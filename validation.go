@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// Validator checks a resource's JSON before it is cached under key.
+// A non-nil error rejects the value.
+type Validator func(key string, value []byte) error
+
+// Validation runs per-type validators on values before cached clients and
+// the sync engine store them, so obviously broken data from the SDK or the
+// event stream is rejected instead of served from the cache. Rejections
+// are counted and logged by the caller. Share one Validation between
+// CachedClientConfig and SyncConfig to count rejections from both.
+//
+// Example:
+//
+//	validation := cache.NewValidation(map[string]cache.Validator{
+//	    "light": cache.ValidateID,
+//	    "room":  cache.ValidateID,
+//	})
+//	config := cache.DefaultCachedClientConfig()
+//	config.Validation = validation
+//	config.SyncConfig.Validation = validation
+type Validation struct {
+	// validators maps resource types to their validator.
+	validators map[string]Validator
+
+	keyBuilder *KeyBuilder
+	rejected   atomic.Int64
+}
+
+// NewValidation creates a Validation that runs validators[resourceType]
+// on values for that type. Types without a validator are not checked.
+func NewValidation(validators map[string]Validator) *Validation {
+	return &Validation{
+		validators: validators,
+		keyBuilder: NewKeyBuilder(),
+	}
+}
+
+// Validate runs the validator for key's resource type on value. Errors
+// wrap ErrInvalidValue and count toward Rejected. A nil Validation
+// accepts every value.
+func (v *Validation) Validate(key string, value []byte) error {
+	if v == nil {
+		return nil
+	}
+
+	resourceType, _, _ := v.keyBuilder.ParseKey(key)
+	validator, ok := v.validators[resourceType]
+	if !ok {
+		return nil
+	}
+
+	if err := validator(key, value); err != nil {
+		v.rejected.Add(1)
+		return NewError("Validate", key, fmt.Errorf("%w: %w", ErrInvalidValue, err))
+	}
+	return nil
+}
+
+// Rejected returns the number of values rejected so far.
+func (v *Validation) Rejected() int64 {
+	return v.rejected.Load()
+}
+
+// ValidateID is a Validator that rejects a resource whose JSON "id" field
+// is missing or doesn't match the ID in its cache key.
+func ValidateID(key string, value []byte) error {
+	_, id, ok := NewKeyBuilder().ParseKey(key)
+	if !ok {
+		return errors.New("key has no resource ID")
+	}
+
+	var resource struct {
+		ID *string `json:"id"`
+	}
+	if err := json.Unmarshal(value, &resource); err != nil {
+		return fmt.Errorf("decoding resource: %w", err)
+	}
+
+	switch {
+	case resource.ID == nil:
+		return errors.New("resource has no id")
+	case *resource.ID != id:
+		return fmt.Errorf("resource id %q does not match key", *resource.ID)
+	}
+	return nil
+}
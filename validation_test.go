@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+func TestValidateID(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		value   string
+		wantErr bool
+	}{
+		{name: "matching", key: "light:abc", value: `{"id":"abc","type":"light"}`},
+		{name: "mismatched", key: "light:abc", value: `{"id":"xyz"}`, wantErr: true},
+		{name: "missing id", key: "light:abc", value: `{"type":"light"}`, wantErr: true},
+		{name: "empty id", key: "light:abc", value: `{"id":""}`, wantErr: true},
+		{name: "not json", key: "light:abc", value: `not json`, wantErr: true},
+		{name: "key without id", key: "light", value: `{"id":"abc"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateID(tt.key, []byte(tt.value))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidation_Validate(t *testing.T) {
+	validation := NewValidation(map[string]Validator{"light": ValidateID})
+
+	if err := validation.Validate("light:abc", []byte(`{"id":"abc"}`)); err != nil {
+		t.Errorf("valid light rejected: %v", err)
+	}
+
+	// Types without a validator are accepted
+	if err := validation.Validate("room:abc", []byte(`{"id":"xyz"}`)); err != nil {
+		t.Errorf("unvalidated room rejected: %v", err)
+	}
+
+	err := validation.Validate("light:abc", []byte(`{"id":"xyz"}`))
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Validate() error = %v, want ErrInvalidValue", err)
+	}
+	if got := validation.Rejected(); got != 1 {
+		t.Errorf("Rejected() = %d, want 1", got)
+	}
+
+	// A nil Validation accepts everything
+	var disabled *Validation
+	if err := disabled.Validate("light:abc", []byte(`{"id":"xyz"}`)); err != nil {
+		t.Errorf("nil Validation rejected value: %v", err)
+	}
+}
+
+func TestSyncEngine_ValidationRejectsMismatchedID(t *testing.T) {
+	backend := newMockBackend()
+	defer backend.Close()
+
+	validation := NewValidation(map[string]Validator{"light": ValidateID})
+	engine := &SyncEngine{
+		backend:    backend,
+		keyBuilder: NewKeyBuilder(),
+		stats:      &SyncStats{},
+		config:     &SyncConfig{Validation: validation},
+	}
+
+	event := &resources.Event{
+		Type: resources.EventTypeAdd,
+		ID:   "event-1",
+		Data: []resources.EventData{
+			{ID: "light-1", Type: "light", RawData: json.RawMessage(`{"id":"light-2","type":"light"}`)},
+			{ID: "light-3", Type: "light", RawData: json.RawMessage(`{"id":"light-3","type":"light"}`)},
+		},
+	}
+	engine.processEvent(event)
+
+	ctx := context.Background()
+	if _, err := backend.Get(ctx, "light:light-1"); err == nil {
+		t.Error("light-1 with mismatched id should not be cached")
+	}
+	if _, err := backend.Get(ctx, "light:light-3"); err != nil {
+		t.Errorf("valid light-3 should be cached: %v", err)
+	}
+
+	if got := validation.Rejected(); got != 1 {
+		t.Errorf("Rejected() = %d, want 1", got)
+	}
+	if stats := engine.Stats(); stats.SyncErrors != 1 {
+		t.Errorf("SyncErrors = %d, want 1", stats.SyncErrors)
+	}
+}
+
+func TestCachedLightClient_ValidationRejectsMismatchedID(t *testing.T) {
+	backend := newMockBackend()
+	defer backend.Close()
+
+	// The SDK returns a light whose ID doesn't match the one requested
+	mockClient := newMockLightClient()
+	mockClient.lights["light-1"] = &resources.Light{ID: "light-2"}
+
+	validation := NewValidation(map[string]Validator{"light": ValidateID})
+	client := NewCachedLightClient(backend, mockClient, time.Minute)
+	client.configure(&CachedClientConfig{Validation: validation})
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		light, err := client.Get(ctx, "light-1")
+		if err != nil {
+			t.Fatalf("Get() failed: %v", err)
+		}
+		if light.ID != "light-2" {
+			t.Errorf("Get() returned ID %q, want the SDK's %q", light.ID, "light-2")
+		}
+	}
+
+	// Never cached, so both reads went to the SDK
+	if _, err := backend.Get(ctx, "light:light-1"); err == nil {
+		t.Error("light with mismatched id should not be cached")
+	}
+	if got := mockClient.calls["Get"]; got != 2 {
+		t.Errorf("SDK Get calls = %d, want 2", got)
+	}
+	if got := validation.Rejected(); got != 2 {
+		t.Errorf("Rejected() = %d, want 2", got)
+	}
+}
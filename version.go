@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// VersionKey is the cache key holding the bridge version stamp written by
+// CacheManager.CheckVersion. It is stored like any other entry, so
+// persistent backends such as backends.File save it with the cache. The
+// "meta:" prefix keeps it out of resource patterns like "light:*". A
+// manager with a namespace stores its stamp under the namespace.
+const VersionKey = "meta:bridge_version"
+
+// VersionPolicy determines what CheckVersion does with cached entries
+// when the bridge version changes.
+type VersionPolicy int
+
+const (
+	// VersionClear clears the cache, leaving it to fill on demand.
+	VersionClear VersionPolicy = iota
+
+	// VersionRewarm clears the cache and warms it again.
+	VersionRewarm
+
+	// VersionKeep keeps cached entries and only updates the stamp.
+	VersionKeep
+)
+
+// String returns the policy name.
+func (p VersionPolicy) String() string {
+	switch p {
+	case VersionClear:
+		return "clear"
+	case VersionRewarm:
+		return "rewarm"
+	case VersionKeep:
+		return "keep"
+	default:
+		return "unknown"
+	}
+}
+
+// VersionConfig contains configuration for CacheManager.CheckVersion.
+type VersionConfig struct {
+	// Version identifies the bridge software and configuration the cache
+	// is built from, e.g. the bridge's software version, optionally
+	// combined with its API version or a config hash. A firmware update
+	// may change resource shapes, so entries cached under another version
+	// can't be trusted.
+	Version string
+
+	// OnChange is applied when the stamped version differs from Version.
+	// Default: VersionClear
+	OnChange VersionPolicy
+
+	// WarmConfig configures warming for VersionRewarm.
+	// Default: DefaultWarmConfig()
+	WarmConfig *WarmConfig
+}
+
+// CheckVersion compares the version stamped in the cache with
+// config.Version. If they differ, it applies config.OnChange and stamps
+// the cache with the new version. Call it on startup, before serving
+// from a cache that may have outlived a bridge update (e.g. one loaded
+// by backends.File).
+//
+// A cache without a stamp - new, or written before stamping was used -
+// is treated as changed, since the version of its entries is unknown.
+// changed reports whether the version differed. Warming failures during
+// VersionRewarm are reported through the WarmConfig's OnError and don't
+// fail the check.
+//
+// Example:
+//
+//	manager := cache.NewCacheManager(backend, sdkClient)
+//	changed, err := manager.CheckVersion(ctx, &cache.VersionConfig{
+//	    Version:  bridgeConfig.SwVersion,
+//	    OnChange: cache.VersionRewarm,
+//	})
+func (m *CacheManager) CheckVersion(ctx context.Context, config *VersionConfig) (changed bool, err error) {
+	if config == nil || config.Version == "" {
+		return false, NewError("CheckVersion", VersionKey, ErrInvalidValue)
+	}
+
+	versionKey := m.keyBuilder.prefix + VersionKey
+	entry, err := m.backend.Get(ctx, versionKey)
+	switch {
+	case err == nil:
+		if string(entry.Value) == config.Version {
+			return false, nil
+		}
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrExpired):
+	default:
+		return false, fmt.Errorf("reading version stamp: %w", err)
+	}
+
+	if err := m.applyVersionPolicy(ctx, config); err != nil {
+		return true, fmt.Errorf("applying version change policy %s: %w", config.OnChange, err)
+	}
+
+	if err := m.backend.Set(ctx, versionKey, []byte(config.Version), 0); err != nil {
+		return true, fmt.Errorf("writing version stamp: %w", err)
+	}
+
+	return true, nil
+}
+
+// applyVersionPolicy applies config.OnChange to the cached entries.
+func (m *CacheManager) applyVersionPolicy(ctx context.Context, config *VersionConfig) error {
+	switch config.OnChange {
+	case VersionClear:
+		return m.ClearAll(ctx)
+	case VersionRewarm:
+		if err := m.ClearAll(ctx); err != nil {
+			return err
+		}
+		_, err := m.WarmCache(ctx, config.WarmConfig)
+		return err
+	case VersionKeep:
+		return nil
+	default:
+		return fmt.Errorf("unknown version policy %d", config.OnChange)
+	}
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCacheManager_CheckVersion(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		policy   VersionPolicy
+		wantKept bool
+		wantWarm bool
+	}{
+		{name: "clear", policy: VersionClear},
+		{name: "rewarm", policy: VersionRewarm, wantWarm: true},
+		{name: "keep", policy: VersionKeep, wantKept: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newMockBackend()
+			manager := NewCacheManager(backend, nil)
+
+			// Warming is observed through the capability probe, which
+			// reports no supported types so nothing is fetched
+			warmed := false
+			config := &VersionConfig{
+				Version:  "1.60.0",
+				OnChange: tt.policy,
+				WarmConfig: &WarmConfig{
+					WarmLights: true,
+					Capabilities: CapabilityProberFunc(func(ctx context.Context) ([]string, error) {
+						warmed = true
+						return nil, nil
+					}),
+				},
+			}
+
+			// First start stamps the cache
+			if changed, err := manager.CheckVersion(ctx, config); err != nil || !changed {
+				t.Fatalf("CheckVersion() on unstamped cache = %v, %v; want true, nil", changed, err)
+			}
+			backend.Set(ctx, "light:1", []byte(`{"id":"1"}`), 0)
+			warmed = false
+
+			// Restart with the same version keeps everything
+			if changed, err := manager.CheckVersion(ctx, config); err != nil || changed {
+				t.Fatalf("CheckVersion() with same version = %v, %v; want false, nil", changed, err)
+			}
+			if _, err := backend.Get(ctx, "light:1"); err != nil {
+				t.Fatalf("Entry lost without a version change: %v", err)
+			}
+
+			// Bridge firmware update
+			config.Version = "1.61.0"
+			if changed, err := manager.CheckVersion(ctx, config); err != nil || !changed {
+				t.Fatalf("CheckVersion() after update = %v, %v; want true, nil", changed, err)
+			}
+
+			_, err := backend.Get(ctx, "light:1")
+			if kept := err == nil; kept != tt.wantKept {
+				t.Errorf("Entry kept = %v, want %v", kept, tt.wantKept)
+			}
+			if warmed != tt.wantWarm {
+				t.Errorf("Warmed = %v, want %v", warmed, tt.wantWarm)
+			}
+
+			stamp, err := backend.Get(ctx, VersionKey)
+			if err != nil || string(stamp.Value) != "1.61.0" {
+				t.Errorf("Version stamp = %v, %v; want 1.61.0", stamp, err)
+			}
+		})
+	}
+}
+
+func TestCacheManager_CheckVersion_RequiresVersion(t *testing.T) {
+	manager := NewCacheManager(newMockBackend(), nil)
+
+	if _, err := manager.CheckVersion(context.Background(), &VersionConfig{}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("CheckVersion() without version error = %v, want ErrInvalidValue", err)
+	}
+}
@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultWebhookTimeout bounds each webhook request.
+	DefaultWebhookTimeout = 5 * time.Second
+
+	// DefaultWebhookRetries is how many times a failed webhook request is
+	// retried.
+	DefaultWebhookRetries = 3
+
+	// DefaultWebhookBackoff is the delay before the first retry. It
+	// doubles with each retry after that.
+	DefaultWebhookBackoff = time.Second
+
+	// webhookBufferSize is the number of payloads queued per webhook.
+	webhookBufferSize = 256
+)
+
+// Webhook is an HTTP endpoint the sync engine POSTs resource changes to.
+// See SyncConfig.Webhooks.
+type Webhook struct {
+	// URL receives a POST of a JSON WebhookPayload per matching change.
+	URL string
+
+	// Filter selects the changes posted, e.g. one room's lights.
+	// Default: every change
+	Filter EventFilter
+
+	// Headers are added to every request, e.g. for authentication.
+	Headers map[string]string
+
+	// Timeout bounds each request; retries get a fresh timeout.
+	// Default: DefaultWebhookTimeout
+	Timeout time.Duration
+
+	// Retries is how many times a failed request (an error or a non-2xx
+	// response) is retried. Set to a negative value to never retry.
+	// Default: DefaultWebhookRetries
+	Retries int
+
+	// RetryBackoff is the delay before the first retry, doubling with
+	// each retry after that.
+	// Default: DefaultWebhookBackoff
+	RetryBackoff time.Duration
+
+	// Client sends the requests.
+	// Default: http.DefaultClient
+	Client *http.Client
+}
+
+// WebhookPayload is the JSON body posted to a webhook.
+type WebhookPayload struct {
+	// EventType is the SSE event type ("add", "update", "delete").
+	EventType string `json:"event_type"`
+
+	// Type is the resource type (e.g. "light").
+	Type string `json:"type"`
+
+	// ID is the resource ID.
+	ID string `json:"id"`
+
+	// Data is the resource as now cached: the full resource for adds,
+	// the changed fields for updates, and empty for deletes.
+	Data json.RawMessage `json:"data,omitempty"`
+
+	// Time is when the change was applied.
+	Time time.Time `json:"time"`
+}
+
+// webhooks posts applied changes to the configured webhooks. Each
+// webhook has its own queue and goroutine, so a slow endpoint delays only
+// its own deliveries, and changes for it are dropped once its queue is
+// full.
+type webhooks struct {
+	ctx      context.Context
+	targets  *fanout[WebhookPayload]
+	failures atomic.Int64
+	logger   func() Logger
+}
+
+// newWebhooks starts delivering to targets until ctx is done, or returns
+// nil if there are none.
+func newWebhooks(ctx context.Context, targets []Webhook, logger func() Logger) *webhooks {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	w := &webhooks{ctx: ctx, targets: newFanout[WebhookPayload](webhookBufferSize), logger: logger}
+	for _, target := range targets {
+		accept := func(p WebhookPayload) bool {
+			return target.Filter.Matches(ResourceEvent{EventType: p.EventType, Type: p.Type, ID: p.ID})
+		}
+		w.targets.subscribe(accept, func(p WebhookPayload) { w.deliver(&target, p) })
+	}
+	return w
+}
+
+// notify queues payload for every webhook whose filter it matches,
+// without blocking.
+func (w *webhooks) notify(payload WebhookPayload) {
+	w.targets.publish(payload)
+}
+
+// deliver posts payload to target, retrying failures. Failures are logged
+// and counted once retries run out; nothing is delivered once the engine
+// stops.
+func (w *webhooks) deliver(target *Webhook, payload WebhookPayload) {
+	retries := target.Retries
+	if retries == 0 {
+		retries = DefaultWebhookRetries
+	}
+	backoff := target.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultWebhookBackoff
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		w.failed(target, payload, err)
+		return
+	}
+
+	for attempt := 0; ; attempt++ {
+		if w.ctx.Err() != nil {
+			return
+		}
+
+		err = w.post(target, body)
+		if err == nil {
+			return
+		}
+		if attempt >= retries {
+			w.failed(target, payload, err)
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-w.ctx.Done():
+			return
+		}
+	}
+}
+
+// post sends one request to target.
+func (w *webhooks) post(target *Webhook, body []byte) error {
+	timeout := target.Timeout
+	if timeout <= 0 {
+		timeout = DefaultWebhookTimeout
+	}
+	ctx, cancel := context.WithTimeout(w.ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range target.Headers {
+		req.Header.Set(name, value)
+	}
+
+	client := target.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (w *webhooks) failed(target *Webhook, payload WebhookPayload, err error) {
+	w.failures.Add(1)
+	w.logger().Warn("webhook delivery failed",
+		"url", target.URL,
+		"type", payload.Type,
+		"id", payload.ID,
+		"error", err)
+}
+
+// close stops accepting changes. Queued deliveries are abandoned once the
+// engine's context is done.
+func (w *webhooks) close() {
+	w.targets.close()
+}
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+// startWebhookEngine starts a sync engine posting to webhooks, fed by the
+// returned event source.
+func startWebhookEngine(t *testing.T, webhooks ...Webhook) (*SyncEngine, *fakeEventSource) {
+	t.Helper()
+	source := &fakeEventSource{events: make(chan resources.Event)}
+	engine := NewSyncEngine(newMockBackend(), nil, &SyncConfig{
+		EnableAutoSync: true,
+		EventSource:    source,
+		Webhooks:       webhooks,
+	})
+	if err := engine.Start(); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	t.Cleanup(func() { engine.Stop() })
+	return engine, source
+}
+
+func TestSyncEngine_Webhooks(t *testing.T) {
+	payloads := make(chan WebhookPayload, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("request = %s with Authorization %q", r.Method, r.Header.Get("Authorization"))
+		}
+		var payload WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		payloads <- payload
+	}))
+	defer server.Close()
+
+	_, source := startWebhookEngine(t, Webhook{
+		URL:     server.URL,
+		Filter:  EventFilter{IDs: []string{"light-1"}},
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	})
+
+	source.events <- updateEvent("light-2", 0)
+	source.events <- updateEvent("light-1", 1)
+	source.events <- resources.Event{
+		ID:   "event-delete",
+		Type: resources.EventTypeDelete,
+		Data: []resources.EventData{{ID: "light-1", Type: "light"}},
+	}
+
+	var got []WebhookPayload
+	for len(got) < 2 {
+		select {
+		case payload := <-payloads:
+			got = append(got, payload)
+		case <-time.After(time.Second):
+			t.Fatalf("received %d payloads, want 2", len(got))
+		}
+	}
+
+	if got[0].EventType != "update" || got[0].ID != "light-1" || string(got[0].Data) != `{"id":"light-1","n":1}` {
+		t.Errorf("first payload = %+v, want light-1's update", got[0])
+	}
+	if got[1].EventType != "delete" || got[1].Data != nil {
+		t.Errorf("second payload = %+v, want a delete without data", got[1])
+	}
+	select {
+	case payload := <-payloads:
+		t.Errorf("unexpected payload %+v for a filtered-out resource", payload)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestSyncEngine_WebhookRetries(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first two attempts
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	engine, source := startWebhookEngine(t,
+		Webhook{URL: server.URL, RetryBackoff: time.Millisecond},
+		Webhook{URL: failing.URL, Retries: 1, RetryBackoff: time.Millisecond},
+	)
+	source.events <- updateEvent("light-1", 0)
+
+	deadline := time.Now().Add(time.Second)
+	for requests.Load() < 3 || engine.Stats().WebhookFailures == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("requests = %d, WebhookFailures = %d; want 3, 1", requests.Load(), engine.Stats().WebhookFailures)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if failures := engine.Stats().WebhookFailures; failures != 1 {
+		t.Errorf("WebhookFailures = %d, want 1 (only the endpoint that never recovers)", failures)
+	}
+}
+
+func TestSyncEngine_SlowWebhookDoesNotStall(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	engine, source := startWebhookEngine(t, Webhook{URL: server.URL, Timeout: time.Minute})
+
+	// Far more events than the webhook can queue are all applied
+	const events = webhookBufferSize + 50
+	for n := 0; n < events; n++ {
+		select {
+		case source.events <- updateEvent("light-1", n):
+		case <-time.After(time.Second):
+			t.Fatalf("sync stalled after %d events", n)
+		}
+	}
+	close(source.events)
+	<-engine.done
+
+	stats := engine.Stats()
+	if stats.UpdateEvents != events {
+		t.Errorf("UpdateEvents = %d, want %d", stats.UpdateEvents, events)
+	}
+	if stats.WebhooksDropped == 0 {
+		t.Error("WebhooksDropped = 0, want the changes that didn't fit the queue")
+	}
+}
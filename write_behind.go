@@ -0,0 +1,270 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// WriteBehindConfig configures asynchronous (write-behind) updates.
+//
+// In write-behind mode, Update enqueues the change and returns immediately.
+// The cached entry is optimistically patched with the update so that
+// immediate reads observe it, and a background worker sends the update to
+// the SDK. If the SDK rejects the update, the cache entry is invalidated
+// and OnError is called.
+type WriteBehindConfig struct {
+	// QueueSize is the number of updates buffered before Update blocks.
+	// Default: 256
+	QueueSize int
+
+	// FlushInterval is how often coalesced updates are sent to the SDK.
+	// Ignored when Coalesce is false.
+	// Default: 100 milliseconds
+	FlushInterval time.Duration
+
+	// Coalesce merges queued updates for the same resource ID, so only the
+	// combined result is sent at each flush. When false, every update is
+	// sent individually, in order, as soon as the worker receives it.
+	// Default: true
+	Coalesce bool
+
+	// OnError is called when an asynchronous SDK update fails.
+	// If nil, errors are only logged.
+	OnError func(id string, err error)
+}
+
+// DefaultWriteBehindConfig returns default write-behind configuration.
+func DefaultWriteBehindConfig() *WriteBehindConfig {
+	return &WriteBehindConfig{
+		QueueSize:     256,
+		FlushInterval: 100 * time.Millisecond,
+		Coalesce:      true,
+	}
+}
+
+// pendingWrite is a queued update for a single resource.
+type pendingWrite[U any] struct {
+	id     string
+	update U
+}
+
+// writeBehind queues updates of type U and applies them in the background.
+type writeBehind[U any] struct {
+	config *WriteBehindConfig
+	write  func(ctx context.Context, id string, update U) error
+	logger Logger
+
+	queue   chan pendingWrite[U]
+	flushes chan chan struct{}
+	done    chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// newWriteBehind creates a write-behind queue and starts its worker.
+func newWriteBehind[U any](config *WriteBehindConfig, logger Logger, write func(ctx context.Context, id string, update U) error) *writeBehind[U] {
+	defaults := DefaultWriteBehindConfig()
+	cfg := *config
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaults.QueueSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaults.FlushInterval
+	}
+
+	w := &writeBehind[U]{
+		config:  &cfg,
+		write:   write,
+		logger:  loggerOrNop(logger),
+		queue:   make(chan pendingWrite[U], cfg.QueueSize),
+		flushes: make(chan chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go w.loop()
+
+	return w
+}
+
+// enqueue queues an update, blocking only if the queue is full.
+func (w *writeBehind[U]) enqueue(ctx context.Context, id string, update U) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return NewError("Update", id, ErrClientClosed)
+	}
+
+	select {
+	case w.queue <- pendingWrite[U]{id: id, update: update}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flush sends all updates queued so far to the SDK and waits for completion.
+func (w *writeBehind[U]) flush(ctx context.Context) error {
+	ack := make(chan struct{})
+
+	select {
+	case w.flushes <- ack:
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close stops accepting updates, flushes everything pending, and stops the worker.
+func (w *writeBehind[U]) close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	<-w.done
+}
+
+// loop is the background worker.
+func (w *writeBehind[U]) loop() {
+	defer close(w.done)
+
+	pending := make(map[string]U)
+	var order []string
+
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	add := func(pw pendingWrite[U]) {
+		if !w.config.Coalesce {
+			w.apply(pw.id, pw.update)
+			return
+		}
+		if prev, ok := pending[pw.id]; ok {
+			pending[pw.id] = w.merge(prev, pw.update)
+			return
+		}
+		pending[pw.id] = pw.update
+		order = append(order, pw.id)
+	}
+
+	flushPending := func() {
+		for _, id := range order {
+			w.apply(id, pending[id])
+			delete(pending, id)
+		}
+		order = order[:0]
+	}
+
+	for {
+		select {
+		case pw, ok := <-w.queue:
+			if !ok {
+				flushPending()
+				return
+			}
+			add(pw)
+
+		case <-ticker.C:
+			flushPending()
+
+		case ack := <-w.flushes:
+			// Pick up anything enqueued before the flush was requested
+			for drained := false; !drained; {
+				select {
+				case pw, ok := <-w.queue:
+					if !ok {
+						drained = true
+						break
+					}
+					add(pw)
+				default:
+					drained = true
+				}
+			}
+			flushPending()
+			close(ack)
+		}
+	}
+}
+
+// apply sends a single update to the SDK.
+func (w *writeBehind[U]) apply(id string, update U) {
+	if err := w.write(context.Background(), id, update); err != nil {
+		w.logger.Error("write-behind update failed", "id", id, "error", err)
+		if w.config.OnError != nil {
+			w.config.OnError(id, err)
+		}
+	}
+}
+
+// merge combines two updates for the same resource; fields set in next
+// take precedence over prev.
+func (w *writeBehind[U]) merge(prev, next U) U {
+	prevJSON, err := json.Marshal(prev)
+	if err != nil {
+		return next
+	}
+	nextJSON, err := json.Marshal(next)
+	if err != nil {
+		return next
+	}
+
+	merged, err := mergeJSON(prevJSON, nextJSON)
+	if err != nil {
+		w.logger.Warn("failed to coalesce updates, keeping latest", "error", err)
+		return next
+	}
+
+	var result U
+	if err := json.Unmarshal(merged, &result); err != nil {
+		w.logger.Warn("failed to coalesce updates, keeping latest", "error", err)
+		return next
+	}
+	return result
+}
+
+// mergeJSON deep-merges the JSON object patch into base. Nested objects are
+// merged recursively; any other value in patch replaces the one in base.
+func mergeJSON(base, patch []byte) ([]byte, error) {
+	var baseMap, patchMap map[string]interface{}
+	if err := json.Unmarshal(base, &baseMap); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &patchMap); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeMaps(baseMap, patchMap))
+}
+
+// mergeMaps recursively merges patch into base and returns base.
+func mergeMaps(base, patch map[string]interface{}) map[string]interface{} {
+	if base == nil {
+		base = make(map[string]interface{})
+	}
+	for k, v := range patch {
+		patchChild, patchIsMap := v.(map[string]interface{})
+		baseChild, baseIsMap := base[k].(map[string]interface{})
+		if patchIsMap && baseIsMap {
+			base[k] = mergeMaps(baseChild, patchChild)
+			continue
+		}
+		base[k] = v
+	}
+	return base
+}
@@ -0,0 +1,236 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rmrfslashbin/hue-sdk/resources"
+)
+
+// newWriteBehindLightClient creates a cached light client in write-behind mode.
+func newWriteBehindLightClient(backend Backend, sdk *mockLightClient, config *WriteBehindConfig) *CachedLightClient {
+	client := NewCachedLightClient(backend, sdk, 0)
+	client.configure(&CachedClientConfig{WriteBehind: config})
+	return client
+}
+
+func TestWriteBehind_CoalescesUpdates(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	client := newWriteBehindLightClient(backend, mockSDK, &WriteBehindConfig{
+		FlushInterval: time.Hour, // only flush explicitly
+		Coalesce:      true,
+	})
+	defer client.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		on := i%2 == 0
+		if err := client.Update(ctx, "light-1", resources.LightUpdate{On: &resources.OnState{On: on}}); err != nil {
+			t.Fatalf("Update() failed: %v", err)
+		}
+	}
+
+	if err := client.Flush(ctx); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	if mockSDK.calls["Update"] != 1 {
+		t.Errorf("Expected 1 coalesced SDK Update, got %d", mockSDK.calls["Update"])
+	}
+	if !mockSDK.lights["light-1"].On.On {
+		t.Error("Expected last queued state (on) to be applied")
+	}
+}
+
+func TestWriteBehind_NoCoalesce(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	client := newWriteBehindLightClient(backend, mockSDK, &WriteBehindConfig{
+		FlushInterval: time.Hour,
+		Coalesce:      false,
+	})
+	defer client.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := client.Update(ctx, "light-1", resources.LightUpdate{On: &resources.OnState{On: true}}); err != nil {
+			t.Fatalf("Update() failed: %v", err)
+		}
+	}
+
+	if err := client.Flush(ctx); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	if mockSDK.calls["Update"] != 3 {
+		t.Errorf("Expected 3 SDK Updates without coalescing, got %d", mockSDK.calls["Update"])
+	}
+}
+
+func TestWriteBehind_OptimisticRead(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{
+		ID:   "light-1",
+		Type: "light",
+		On:   resources.OnState{On: false},
+	}
+
+	client := newWriteBehindLightClient(backend, mockSDK, &WriteBehindConfig{
+		FlushInterval: time.Hour,
+		Coalesce:      true,
+	})
+	defer client.Close()
+
+	ctx := context.Background()
+
+	// Populate cache
+	if _, err := client.Get(ctx, "light-1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if err := client.Update(ctx, "light-1", resources.LightUpdate{On: &resources.OnState{On: true}}); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+
+	// Read before the SDK has been called reflects the pending update
+	light, err := client.Get(ctx, "light-1")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if !light.On.On {
+		t.Error("Expected cached light to reflect pending update")
+	}
+	if light.ID != "light-1" {
+		t.Errorf("Patched light lost its ID: %q", light.ID)
+	}
+	if mockSDK.calls["Update"] != 0 {
+		t.Errorf("Expected no SDK Update before flush, got %d", mockSDK.calls["Update"])
+	}
+	if mockSDK.calls["Get"] != 1 {
+		t.Errorf("Expected read from cache, got %d SDK Gets", mockSDK.calls["Get"])
+	}
+}
+
+func TestWriteBehind_CloseFlushes(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	client := newWriteBehindLightClient(backend, mockSDK, &WriteBehindConfig{
+		FlushInterval: time.Hour,
+		Coalesce:      true,
+	})
+
+	ctx := context.Background()
+	if err := client.Update(ctx, "light-1", resources.LightUpdate{On: &resources.OnState{On: true}}); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	if mockSDK.calls["Update"] != 1 {
+		t.Errorf("Expected pending update to be flushed on Close, got %d", mockSDK.calls["Update"])
+	}
+
+	err := client.Update(ctx, "light-1", resources.LightUpdate{})
+	if !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Update() after Close error = %v, want ErrClientClosed", err)
+	}
+}
+
+func TestWriteBehind_ErrorInvalidatesCache(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	var mu sync.Mutex
+	var failedIDs []string
+
+	client := newWriteBehindLightClient(backend, mockSDK, &WriteBehindConfig{
+		FlushInterval: time.Hour,
+		Coalesce:      true,
+		OnError: func(id string, err error) {
+			mu.Lock()
+			failedIDs = append(failedIDs, id)
+			mu.Unlock()
+		},
+	})
+	defer client.Close()
+
+	ctx := context.Background()
+	if _, err := client.Get(ctx, "light-1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	// The light disappears from the bridge before the update is flushed
+	delete(mockSDK.lights, "light-1")
+
+	if err := client.Update(ctx, "light-1", resources.LightUpdate{On: &resources.OnState{On: true}}); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if err := client.Flush(ctx); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(failedIDs) != 1 || failedIDs[0] != "light-1" {
+		t.Errorf("OnError calls = %v, want [light-1]", failedIDs)
+	}
+
+	if _, err := backend.Get(ctx, "light:light-1"); err == nil {
+		t.Error("Expected optimistic entry to be invalidated after SDK failure")
+	}
+}
+
+func TestWriteBehind_EnqueueErrorInvalidatesCache(t *testing.T) {
+	backend := newMockBackend()
+	mockSDK := newMockLightClient()
+	mockSDK.lights["light-1"] = &resources.Light{ID: "light-1", Type: "light"}
+
+	client := newWriteBehindLightClient(backend, mockSDK, &WriteBehindConfig{
+		FlushInterval: time.Hour,
+		Coalesce:      true,
+	})
+
+	ctx := context.Background()
+	if _, err := client.Get(ctx, "light-1"); err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	client.Close()
+
+	// The update is never sent, so the cache mustn't keep it
+	err := client.Update(ctx, "light-1", resources.LightUpdate{On: &resources.OnState{On: true}})
+	if !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("Update() after Close error = %v, want ErrClientClosed", err)
+	}
+	if _, err := backend.Get(ctx, "light:light-1"); err == nil {
+		t.Error("Expected optimistic entry to be invalidated after a failed enqueue")
+	}
+}
+
+func TestMergeJSON(t *testing.T) {
+	base := []byte(`{"id":"light-1","on":{"on":false},"dimming":{"brightness":50,"min_dim_level":1}}`)
+	patch := []byte(`{"on":{"on":true},"dimming":{"brightness":80}}`)
+
+	merged, err := mergeJSON(base, patch)
+	if err != nil {
+		t.Fatalf("mergeJSON() failed: %v", err)
+	}
+
+	want := `{"dimming":{"brightness":80,"min_dim_level":1},"id":"light-1","on":{"on":true}}`
+	if string(merged) != want {
+		t.Errorf("mergeJSON() = %s, want %s", merged, want)
+	}
+}